@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"git.crptech.ru/cloud/cloudgw/internal/client"
+	"git.crptech.ru/cloud/cloudgw/internal/service"
+)
+
+const fipTraceTimeout = 10 * time.Second
+
+// fipTrace requests the floating ip trace from running cloudgw (GET /api/v1/vpp/fips/{ip}) and prints it.
+// Exit code is 1 if any inconsistency found, 2 if the trace failed.
+func fipTrace(args []string) int {
+	flags := flag.NewFlagSet("fip-trace", flag.ContinueOnError)
+
+	var opts client.Options
+
+	flags.StringVar(&opts.URL, "url", "", "cloudgw api url, http(s)://host:port or unix:///path (default is taken from HTTP of the config file)")
+	flags.StringVar(&opts.Token, "token", os.Getenv("CLOUDGW_TOKEN"), "bearer token (default is taken from CLOUDGW_TOKEN)")
+	flags.StringVar(&opts.CAFile, "cacert", "", "ca certificate file to verify cloudgw https server")
+	flags.StringVar(&opts.CertFile, "cert", "", "client certificate file (mtls)")
+	flags.StringVar(&opts.KeyFile, "key", "", "client key file (mtls)")
+
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: cloudgw fip-trace [-url http://127.0.0.1:9101] [-token token] <floating ip>")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		flags.Usage()
+
+		return 2
+	}
+
+	opts.Timeout = fipTraceTimeout
+
+	apiClient, err := client.New(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create http client: %s\n", err)
+
+		return 2
+	}
+
+	var trace service.FIPTrace
+
+	if err = apiClient.Get(context.Background(), "/vpp/fips/"+url.PathEscape(flags.Arg(0)), nil, &trace); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to trace floating ip: %s\n", err)
+
+		return 2
+	}
+
+	client.WriteFIPTrace(os.Stdout, trace)
+
+	if len(trace.Inconsistencies) != 0 {
+		return 1
+	}
+
+	return 0
+}
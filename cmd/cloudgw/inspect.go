@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"git.crptech.ru/cloud/cloudgw/internal/snapshot"
+)
+
+// inspectSnapshot prints the state snapshot written by cloudgw (Snapshot.Path) for post-incident analysis.
+// Exit code is 1 if any inconsistency found, 2 if the snapshot can not be read.
+func inspectSnapshot(args []string) int {
+	flags := flag.NewFlagSet("inspect", flag.ContinueOnError)
+
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: cloudgw inspect <snapshot file>")
+	}
+
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		flags.Usage()
+
+		return 2
+	}
+
+	snap, err := snapshot.Read(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read snapshot: %s\n", err)
+
+		return 2
+	}
+
+	snapshot.WriteSummary(os.Stdout, snap)
+
+	if len(snapshot.Check(snap)) != 0 {
+		return 1
+	}
+
+	return 0
+}
@@ -2,11 +2,27 @@ package main
 
 import (
 	"context"
+	"os"
 
 	"git.crptech.ru/cloud/cloudgw/internal/app"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "fip-trace":
+			os.Exit(fipTrace(os.Args[2:]))
+		case "validate":
+			os.Exit(validateConfig(os.Args[2:]))
+		case "plan":
+			os.Exit(planConfig(os.Args[2:]))
+		case "inspect":
+			os.Exit(inspectSnapshot(os.Args[2:]))
+		case "replay":
+			os.Exit(replayMRT(os.Args[2:]))
+		}
+	}
+
 	ctx := context.Background()
 
 	a := app.Init(ctx)
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/netip"
+	"os"
+
+	"git.crptech.ru/cloud/cloudgw/internal/app"
+	"git.crptech.ru/cloud/cloudgw/pkg/logger"
+)
+
+// replayMRT replays bgp updates of mrt files dumped by cloudgw (MRT of the config) against dry-run vpp and prints
+// floating ip route changes. Exit code is 1 if the replay failed, 2 if arguments or the config are wrong.
+func replayMRT(args []string) int {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+
+	configPath := os.Getenv("CLOUDGW_CONFIG_PATH")
+
+	if configPath == "" {
+		configPath = "/etc/cloudgw/config.yml"
+	}
+
+	flags.StringVar(&configPath, "config", configPath, "config file of the gateway the files are dumped by, CLOUDGW_CONFIG_PATH if it is set")
+	fipArg := flags.String("fip", "", "print changes of the floating ip only")
+
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: cloudgw replay [-config /etc/cloudgw/config.yml] [-fip floating ip] <mrt file>...")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil || flags.NArg() == 0 {
+		flags.Usage()
+
+		return 2
+	}
+
+	var fip string
+
+	if *fipArg != "" {
+		addr, err := netip.ParseAddr(*fipArg)
+		if err != nil || !addr.Is4() {
+			fmt.Fprintf(os.Stderr, "floating ip %q is not an ipv4 address\n", *fipArg)
+
+			return 2
+		}
+
+		fip = netip.PrefixFrom(addr, addr.BitLen()).String()
+	}
+
+	cfg, code := loadConfig(configPath)
+	if cfg == nil {
+		return code
+	}
+
+	// failed route changes are logged apart from the replay output
+
+	logger.Init(logger.WithLevel("warn"), logger.WithFormat("console"), logger.WithOutput("stderr"))
+
+	if err := app.ReplayMRT(context.Background(), os.Stdout, cfg, flags.Args(), fip); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to replay: %s\n", err)
+
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"git.crptech.ru/cloud/cloudgw/internal/app"
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+)
+
+// validateConfig parses and validates the config file and prints found problems.
+// Exit code is 1 if the config is invalid, 2 if the config can not be parsed.
+func validateConfig(args []string) int {
+	cfg, code := parseConfigArg("validate", "usage: cloudgw validate <config file>", args)
+	if cfg == nil {
+		return code
+	}
+
+	fmt.Println("config is valid")
+
+	return 0
+}
+
+// planConfig prints vpp and gobgp objects cloudgw creates with the config file without requesting vpp.
+// Exit code is 1 if the config is invalid, 2 if the config can not be parsed.
+func planConfig(args []string) int {
+	cfg, code := parseConfigArg("plan", "usage: cloudgw plan <config file>", args)
+	if cfg == nil {
+		return code
+	}
+
+	if err := app.WritePlan(os.Stdout, cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to plan: %s\n", err)
+
+		return 1
+	}
+
+	return 0
+}
+
+// parseConfigArg parses and validates the config file of the command argument, the config is nil on failure
+func parseConfigArg(name, usage string, args []string) (*config.Config, int) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), usage)
+	}
+
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		flags.Usage()
+
+		return nil, 2
+	}
+
+	return loadConfig(flags.Arg(0))
+}
+
+// loadConfig parses and validates the config file, found problems are printed, the config is nil on failure
+func loadConfig(path string) (*config.Config, int) {
+	cfg, err := config.ParseConfig(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to parse config file: %s\n", err)
+
+		return nil, 2
+	}
+
+	errs := config.Validate(cfg)
+
+	for _, err = range errs {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+	}
+
+	if len(errs) != 0 {
+		fmt.Fprintf(os.Stderr, "config is invalid: %d error(s)\n", len(errs))
+
+		return nil, 1
+	}
+
+	return cfg, 0
+}
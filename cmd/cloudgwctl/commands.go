@@ -0,0 +1,511 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.crptech.ru/cloud/cloudgw/internal/client"
+	controller "git.crptech.ru/cloud/cloudgw/internal/controller/http/v1"
+	"git.crptech.ru/cloud/cloudgw/internal/events"
+	"git.crptech.ru/cloud/cloudgw/internal/ha"
+	"git.crptech.ru/cloud/cloudgw/internal/service"
+)
+
+func (c ctl) showSummary(ctx context.Context, args []string) error {
+	if _, err := parseArgs(flag.NewFlagSet("show summary", flag.ContinueOnError), args); err != nil {
+		return err
+	}
+
+	var summary controller.SummaryStatus
+
+	if err := c.client.Get(ctx, "/summary", nil, &summary); err != nil {
+		return err
+	}
+
+	return c.print(summary, func(w io.Writer) {
+		fmt.Fprintf(w, "bgp peers\t%d (%d established)\n", summary.BGPPeerTotal, summary.BGPPeerActive)
+		fmt.Fprintf(w, "floating ip routes\tmemory %d\tvpp %d\n", summary.MemVPPFIPRouteTotal, summary.VPPFIPRouteTotal)
+		fmt.Fprintf(w, "udp tunnels\tmemory %d\tvpp %d\n", summary.MemVPPUDPTunnelTotal, summary.VPPUDPTunnelTotal)
+
+		for _, e := range summary.Errors {
+			fmt.Fprintf(w, "ERROR: %s\n", e)
+		}
+	})
+}
+
+func (c ctl) showPeers(ctx context.Context, args []string) error {
+	if _, err := parseArgs(flag.NewFlagSet("show peers", flag.ContinueOnError), args); err != nil {
+		return err
+	}
+
+	peers, err := client.List[controller.BGPPeer](ctx, c.client, "/bgp/peers", nil)
+	if err != nil {
+		return err
+	}
+
+	return c.print(peers, func(w io.Writer) {
+		fmt.Fprintln(w, "ADDRESS\tTYPE\tASN\tVRF\tSTATE\tSINCE\tBFD\tADMIN")
+
+		for _, peer := range peers {
+			bfdState := "-"
+
+			if peer.BFD != nil && peer.BFD.Enabled {
+				bfdState = valueOr(peer.BFD.State, "none")
+			}
+
+			adminState := "up"
+
+			if peer.AdminDisabled {
+				adminState = "down"
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\n", peer.Address, peer.Type, peer.ASN, valueOr(peer.VRF, "-"),
+				peer.State, since(peer.LastActivity), bfdState, adminState)
+		}
+	})
+}
+
+func (c ctl) showVRF(ctx context.Context, args []string) error {
+	values, err := parseArgs(flag.NewFlagSet("show vrf", flag.ContinueOnError), args, "name")
+	if err != nil {
+		return err
+	}
+
+	var vrf struct {
+		BGP *controller.BGPVRF `json:"BGP"` // absent if the vrf is not created in gobgp
+		VPP *controller.VPPVRF `json:"VPP"` // absent if the vrf is not created in vpp
+	}
+
+	name := url.PathEscape(values[0])
+
+	if err = c.client.Get(ctx, "/bgp/vrfs/"+name, nil, &vrf.BGP); err != nil && !client.IsNotFound(err) {
+		return err
+	}
+
+	if err = c.client.Get(ctx, "/vpp/vrfs/"+name, nil, &vrf.VPP); err != nil && !client.IsNotFound(err) {
+		return err
+	}
+
+	if vrf.BGP == nil && vrf.VPP == nil {
+		return fmt.Errorf("vrf %s is not found", values[0])
+	}
+
+	return c.print(vrf, func(w io.Writer) {
+		if vrf.BGP != nil {
+			fmt.Fprintf(w, "gobgp vrf\t%s (id %d)\n", vrf.BGP.Name, vrf.BGP.ID)
+			fmt.Fprintf(w, "  asn\tlocal %d\tpeer %d\n", vrf.BGP.LocalASN, vrf.BGP.PeerASN)
+			fmt.Fprintf(w, "  rd\t%s\n", vrf.BGP.RD)
+			fmt.Fprintf(w, "  import rt\t%s\n", strings.Join(vrf.BGP.ImportRTs, " "))
+			fmt.Fprintf(w, "  export rt\t%s\n", strings.Join(vrf.BGP.ExportRTs, " "))
+		} else {
+			fmt.Fprintln(w, "gobgp vrf\tabsent")
+		}
+
+		if vrf.VPP != nil {
+			subInterface := "absent"
+
+			if vrf.VPP.SubInterfaceID != nil {
+				subInterface = strconv.FormatUint(uint64(*vrf.VPP.SubInterfaceID), 10)
+			}
+
+			fmt.Fprintf(w, "vpp vrf\t%s (id %d)\n", vrf.VPP.Name, vrf.VPP.ID)
+			fmt.Fprintf(w, "  interface\t%d\tsub-interface %s\tvlan %d\n", vrf.VPP.MainInterfaceID, subInterface, vrf.VPP.VLAN)
+			fmt.Fprintf(w, "  address\t%s\tnext-hop %s\n", vrf.VPP.LocalAddress, vrf.VPP.NextHop)
+			fmt.Fprintf(w, "  mpls local label\t%d\n", vrf.VPP.MPLSLocalLabel)
+			fmt.Fprintf(w, "  floating ip prefixes\t%s\n", strings.Join(vrf.VPP.FIPPrefixes, " "))
+			fmt.Fprintf(w, "  floating ips served\t%d\n", vrf.VPP.FIPServed)
+			fmt.Fprintf(w, "  link up\t%t\n", vrf.VPP.LinkUp)
+			fmt.Fprintf(w, "  drained\t%t\n", vrf.VPP.Drained)
+		} else {
+			fmt.Fprintln(w, "vpp vrf\tabsent")
+		}
+	})
+}
+
+func (c ctl) showFIPs(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("show fips", flag.ContinueOnError)
+
+	vrf := flags.String("vrf", "", "vrf name")
+	nextHop := flags.String("nexthop", "", "vrouter address")
+	tunnel := flags.String("tunnel", "", "udp tunnel id")
+
+	if _, err := parseArgs(flags, args); err != nil {
+		return err
+	}
+
+	query := url.Values{}
+
+	if *vrf != "" {
+		query.Set("vrf", *vrf)
+	}
+
+	if *nextHop != "" {
+		query.Set("nexthop", *nextHop)
+	}
+
+	if *tunnel != "" {
+		query.Set("tunnel", *tunnel)
+	}
+
+	routes, err := client.List[controller.FIPRoute](ctx, c.client, "/vpp/fips", query)
+	if err != nil {
+		return err
+	}
+
+	return c.print(routes, func(w io.Writer) {
+		fmt.Fprintln(w, "PREFIX\tVRF\tNEXT-HOP\tTUNNEL\tLABEL\tREACHABLE")
+
+		for _, route := range routes {
+			for i, path := range route.Paths {
+				prefix, vrfName := route.Prefix, route.VRF
+
+				// ecmp paths are listed under the prefix
+
+				if i != 0 {
+					prefix, vrfName = "", ""
+				}
+
+				tunnel := "-"
+
+				if path.TunnelID != nil {
+					tunnel = strconv.FormatUint(uint64(*path.TunnelID), 10)
+				}
+
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%t\n", prefix, vrfName, path.NextHop, tunnel, path.Label, path.Reachable)
+			}
+		}
+	})
+}
+
+func (c ctl) showTunnels(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("show tunnels", flag.ContinueOnError)
+
+	idle := flags.Bool("idle", false, "only tunnels serving no floating ip")
+
+	if _, err := parseArgs(flags, args); err != nil {
+		return err
+	}
+
+	query := url.Values{}
+
+	if *idle {
+		query.Set("idle", "true")
+	}
+
+	tunnels, err := client.List[controller.UDPTunnel](ctx, c.client, "/vpp/tunnels", query)
+	if err != nil {
+		return err
+	}
+
+	return c.print(tunnels, func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tSOURCE\tDESTINATION\tFIPS\tREACHABLE")
+
+		for _, tunnel := range tunnels {
+			fmt.Fprintf(w, "%d\t%s:%d\t%s:%d\t%d\t%t\n", tunnel.ID, tunnel.SrcIP, tunnel.SrcPort, tunnel.DstIP, tunnel.DstPort,
+				tunnel.FIPServed, tunnel.Reachable)
+		}
+	})
+}
+
+func (c ctl) showHA(ctx context.Context, args []string) error {
+	if _, err := parseArgs(flag.NewFlagSet("show ha", flag.ContinueOnError), args); err != nil {
+		return err
+	}
+
+	var status ha.Status
+
+	if err := c.client.Get(ctx, "/ha", nil, &status); err != nil {
+		return err
+	}
+
+	return c.print(status, func(w io.Writer) {
+		fmt.Fprintln(w, "NODE\tROLE\tPRIORITY\tHEALTHY\tFIPS\tTUNNELS\tSINCE")
+		fmt.Fprintf(w, "%s (local)\t%s\t%d\t%t\t%d\t%d\t%s\n", status.NodeID, status.Role, status.Priority, status.Healthy,
+			status.FIPRoutes, status.UDPTunnels, since(status.RoleChangedAt))
+
+		if status.Peer == nil {
+			fmt.Fprintln(w, "(peer)\t-\t-\t-\t-\t-\tnever seen")
+
+			return
+		}
+
+		peer := status.Peer
+		role := string(peer.Role)
+
+		if !peer.Alive {
+			role = "dead"
+		}
+
+		fmt.Fprintf(w, "%s (%s)\t%s\t%d\t%t\t%d\t%d\tseen %s ago\n", peer.NodeID, peer.Address, role, peer.Priority, peer.Healthy,
+			peer.FIPRoutes, peer.UDPTunnels, since(peer.LastSeen))
+	})
+}
+
+func (c ctl) traceFIP(ctx context.Context, args []string) (int, error) {
+	values, err := parseArgs(flag.NewFlagSet("trace fip", flag.ContinueOnError), args, "ip")
+	if err != nil {
+		return exitError, err
+	}
+
+	var trace service.FIPTrace
+
+	if err = c.client.Get(ctx, "/vpp/fips/"+url.PathEscape(values[0]), nil, &trace); err != nil {
+		return exitError, err
+	}
+
+	err = c.print(trace, func(w io.Writer) {
+		client.WriteFIPTrace(w, trace)
+	})
+
+	if err == nil && len(trace.Inconsistencies) != 0 {
+		return exitTrace, nil
+	}
+
+	return exitOK, err
+}
+
+func (c ctl) resetPeer(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("reset peer", flag.ContinueOnError)
+
+	mode := flags.String("mode", service.BGPPeerResetSoft, "reset mode: hard, soft, soft-in or soft-out")
+
+	values, err := parseArgs(flags, args, "ip")
+	if err != nil {
+		return err
+	}
+
+	var result controller.AdminResult
+
+	err = c.client.Post(ctx, "/admin/bgp/peers/"+url.PathEscape(values[0])+"/reset", url.Values{"mode": {*mode}}, &result)
+	if err != nil {
+		return err
+	}
+
+	return c.print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "%s: %s done\n", result.Target, result.Action)
+	})
+}
+
+func (c ctl) showDrain(ctx context.Context, args []string) error {
+	if _, err := parseArgs(flag.NewFlagSet("show drain", flag.ContinueOnError), args); err != nil {
+		return err
+	}
+
+	var status service.GatewayDrainStatus
+
+	if err := c.client.Get(ctx, "/drain", nil, &status); err != nil {
+		return err
+	}
+
+	return c.print(status, func(w io.Writer) { printDrainStatus(w, status) })
+}
+
+func (c ctl) showBMP(ctx context.Context, args []string) error {
+	if _, err := parseArgs(flag.NewFlagSet("show bmp", flag.ContinueOnError), args); err != nil {
+		return err
+	}
+
+	var stations []service.BMPStation
+
+	if err := c.client.Get(ctx, "/bgp/bmp", nil, &stations); err != nil {
+		return err
+	}
+
+	return c.print(stations, func(w io.Writer) {
+		fmt.Fprintln(w, "STATION\tSTATE\tSINCE")
+
+		for _, station := range stations {
+			state, changedAt := "down", station.Downtime
+
+			if station.Connected {
+				state, changedAt = "up", station.Uptime
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\n", net.JoinHostPort(station.Address, strconv.Itoa(int(station.Port))), state, since(changedAt))
+		}
+	})
+}
+
+// drain starts (isDrain) or cancels the gateway drain, with -wait the drain status is polled until prefixes are withdrawn
+func (c ctl) drain(ctx context.Context, args []string, isDrain bool) error {
+	name, path := "undrain", "/admin/undrain"
+
+	if isDrain {
+		name, path = "drain", "/admin/drain"
+	}
+
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+
+	var wait *bool
+
+	if isDrain {
+		wait = flags.Bool("wait", false, "wait until aggregated prefixes are withdrawn")
+	}
+
+	if _, err := parseArgs(flags, args); err != nil {
+		return err
+	}
+
+	var result controller.AdminResult
+
+	if err := c.client.Post(ctx, path, nil, &result); err != nil {
+		return err
+	}
+
+	status := *result.Drain
+
+	for wait != nil && *wait && status.Phase == service.DrainPhaseDepreferred {
+		time.Sleep(drainPollInterval)
+
+		if err := c.client.Get(ctx, "/drain", nil, &status); err != nil {
+			return err
+		}
+
+		if c.output == outputTable {
+			fmt.Fprintf(c.stdout, "%s: traffic %d pps, threshold %d pps\n", status.Phase, status.TrafficPPS, status.TrafficThreshold)
+		}
+	}
+
+	return c.print(status, func(w io.Writer) { printDrainStatus(w, status) })
+}
+
+func printDrainStatus(w io.Writer, status service.GatewayDrainStatus) {
+	fmt.Fprintf(w, "phase\t%s\n", status.Phase)
+
+	if status.Phase == service.DrainPhaseNone {
+		return
+	}
+
+	fmt.Fprintf(w, "started\t%s ago\n", since(status.StartedAt))
+	fmt.Fprintf(w, "traffic\t%d pps (threshold %d pps)\n", status.TrafficPPS, status.TrafficThreshold)
+
+	if status.Phase == service.DrainPhaseWithdrawn {
+		fmt.Fprintf(w, "withdrawn\t%s ago (timed out %t)\n", since(status.WithdrawnAt), status.TimedOut)
+	}
+}
+
+func (c ctl) reload(ctx context.Context, args []string) error {
+	if _, err := parseArgs(flag.NewFlagSet("reload", flag.ContinueOnError), args); err != nil {
+		return err
+	}
+
+	var result controller.ReloadResult
+
+	if err := c.client.Post(ctx, "/admin/reload", nil, &result); err != nil {
+		return err
+	}
+
+	return c.print(result, func(w io.Writer) {
+		if len(result.Applied) == 0 && len(result.RestartRequired) == 0 {
+			fmt.Fprintln(w, "config is not changed")
+
+			return
+		}
+
+		for _, setting := range result.Applied {
+			fmt.Fprintf(w, "applied\t%s\n", setting)
+		}
+
+		for _, setting := range result.RestartRequired {
+			fmt.Fprintf(w, "restart required\t%s\n", setting)
+		}
+	})
+}
+
+// watchEvents prints state change events as they come: json lines or a line per event
+func (c ctl) watchEvents(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("watch events", flag.ContinueOnError)
+
+	since := flags.String("since", "", "sequence of the last received event, new events only if empty")
+	types := flags.String("type", "", "comma separated event types")
+
+	if _, err := parseArgs(flags, args); err != nil {
+		return err
+	}
+
+	query := url.Values{}
+
+	if *since != "" {
+		query.Set("since", *since)
+	}
+
+	if *types != "" {
+		query.Set("type", *types)
+	}
+
+	encoder := json.NewEncoder(c.stdout)
+
+	return c.client.Events(ctx, query, func(event events.Event) error {
+		if c.output == outputJSON {
+			return encoder.Encode(event)
+		}
+
+		_, err := fmt.Fprintf(c.stdout, "%d %s %s %s\n", event.Seq, event.Time.Format(time.RFC3339), event.Type, eventDetails(event))
+
+		return err
+	})
+}
+
+// eventDetails returns the event fields related to its type, e.g. "10.0.0.5 established (was active)"
+func eventDetails(event events.Event) string {
+	var fields []string
+
+	if event.VRF != "" {
+		fields = append(fields, "vrf "+event.VRF)
+	}
+
+	if event.Prefix != "" {
+		fields = append(fields, event.Prefix)
+	}
+
+	if len(event.Prefixes) != 0 {
+		fields = append(fields, strings.Join(event.Prefixes, " "))
+	}
+
+	if event.NextHop != "" {
+		fields = append(fields, "via "+event.NextHop)
+	}
+
+	if event.TunnelID != nil {
+		fields = append(fields, fmt.Sprintf("tunnel %d", *event.TunnelID))
+	}
+
+	if event.Label != 0 {
+		fields = append(fields, fmt.Sprintf("label %d", event.Label))
+	}
+
+	if event.Peer != "" {
+		fields = append(fields, "peer "+event.Peer)
+	}
+
+	if event.State != "" {
+		fields = append(fields, fmt.Sprintf("%s (was %s)", event.State, valueOr(event.PrevState, "unknown")))
+	}
+
+	return strings.Join(fields, " ")
+}
+
+func valueOr(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+
+	return value
+}
+
+// since returns time passed since the moment, e.g. "3h25m10s"
+func since(moment time.Time) string {
+	if moment.IsZero() {
+		return "never"
+	}
+
+	return time.Since(moment).Truncate(time.Second).String()
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"git.crptech.ru/cloud/cloudgw/internal/client"
+)
+
+const (
+	exitOK    = 0
+	exitTrace = 1 // floating ip trace found inconsistencies
+	exitError = 2
+
+	outputTable = "table"
+	outputJSON  = "json"
+
+	drainPollInterval = 2 * time.Second
+)
+
+const usage = `usage: cloudgwctl [flags] <command>
+
+commands:
+  show summary                                          numbers of bgp peers, floating ip routes and udp tunnels
+  show peers                                            bgp peers
+  show vrf <name>                                       gobgp and vpp vrf
+  show fips [-vrf name] [-nexthop address] [-tunnel id] floating ip routes
+  show tunnels [-idle]                                  udp tunnels (-idle: serving no floating ip)
+  show ha                                               role of ha pair instances (HA)
+  show drain                                            gateway drain phase and traffic of vrf sub-interfaces
+  show bmp                                              bmp stations and their connection state (BMP)
+  trace fip <ip>                                        floating ip trace through bgp, memory storage and vpp
+  reset peer <ip> [-mode soft]                          reset bgp session (hard, soft, soft-in, soft-out)
+  watch events [-since seq] [-type types]               state change events stream (HTTP.Events), until interrupted
+  drain [-wait]                                         move traffic away and withdraw aggregated prefixes (Drain)
+  undrain                                               cancel the gateway drain
+  reload                                                re-read config file of cloudgw
+
+flags:
+`
+
+var errUsage = errors.New("invalid command")
+
+// ctl is a command context: api client and output format
+type ctl struct {
+	client *client.Client
+	output string
+	stdout io.Writer
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	flags := flag.NewFlagSet("cloudgwctl", flag.ContinueOnError)
+
+	var opts client.Options
+
+	flags.StringVar(&opts.URL, "url", "", "cloudgw api url, http(s)://host:port or unix:///path (default is taken from HTTP of the config file)")
+	flags.StringVar(&opts.Token, "token", os.Getenv("CLOUDGW_TOKEN"), "bearer token (default is taken from CLOUDGW_TOKEN)")
+	flags.StringVar(&opts.CAFile, "cacert", "", "ca certificate file to verify cloudgw https server")
+	flags.StringVar(&opts.CertFile, "cert", "", "client certificate file (mtls)")
+	flags.StringVar(&opts.KeyFile, "key", "", "client key file (mtls)")
+	flags.DurationVar(&opts.Timeout, "timeout", 30*time.Second, "request timeout")
+
+	output := flags.String("o", outputTable, "output format: table or json")
+
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+
+	if *output != outputTable && *output != outputJSON {
+		fmt.Fprintf(os.Stderr, "unknown output format %s\n", *output)
+
+		return exitError
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+
+		return exitError
+	}
+
+	apiClient, err := client.New(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create http client: %s\n", err)
+
+		return exitError
+	}
+
+	c := ctl{client: apiClient, output: *output, stdout: os.Stdout}
+
+	code, err := c.dispatch(context.Background(), flags.Args())
+	if errors.Is(err, errUsage) {
+		fmt.Fprintf(os.Stderr, "%s\n\n", err)
+		flags.Usage()
+
+		return exitError
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+
+		return exitError
+	}
+
+	return code
+}
+
+func (c ctl) dispatch(ctx context.Context, args []string) (int, error) {
+	command := strings.Join(args[:min(2, len(args))], " ")
+
+	switch {
+	case command == "show summary":
+		return exitOK, c.showSummary(ctx, args[2:])
+	case command == "show peers":
+		return exitOK, c.showPeers(ctx, args[2:])
+	case command == "show vrf":
+		return exitOK, c.showVRF(ctx, args[2:])
+	case command == "show fips":
+		return exitOK, c.showFIPs(ctx, args[2:])
+	case command == "show tunnels":
+		return exitOK, c.showTunnels(ctx, args[2:])
+	case command == "show ha":
+		return exitOK, c.showHA(ctx, args[2:])
+	case command == "show drain":
+		return exitOK, c.showDrain(ctx, args[2:])
+	case command == "show bmp":
+		return exitOK, c.showBMP(ctx, args[2:])
+	case command == "trace fip":
+		return c.traceFIP(ctx, args[2:])
+	case command == "reset peer":
+		return exitOK, c.resetPeer(ctx, args[2:])
+	case command == "watch events":
+		return exitOK, c.watchEvents(ctx, args[2:])
+	case args[0] == "drain":
+		return exitOK, c.drain(ctx, args[1:], true)
+	case args[0] == "undrain":
+		return exitOK, c.drain(ctx, args[1:], false)
+	case args[0] == "reload":
+		return exitOK, c.reload(ctx, args[1:])
+	}
+
+	return exitError, fmt.Errorf("%w: %s", errUsage, strings.Join(args, " "))
+}
+
+// parseArgs parses flags of the command placed before and after positional arguments and checks number of them
+func parseArgs(flags *flag.FlagSet, args []string, positionals ...string) ([]string, error) {
+	flags.SetOutput(io.Discard)
+
+	var values []string
+
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, fmt.Errorf("%w: %s", errUsage, err)
+		}
+
+		if flags.NArg() == 0 {
+			break
+		}
+
+		values = append(values, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+
+	if len(values) != len(positionals) {
+		if len(positionals) == 0 {
+			return nil, fmt.Errorf("%w: %s expects no arguments", errUsage, flags.Name())
+		}
+
+		return nil, fmt.Errorf("%w: %s expects <%s>", errUsage, flags.Name(), strings.Join(positionals, "> <"))
+	}
+
+	return values, nil
+}
+
+// print writes the value as json or as the table
+func (c ctl) print(value any, table func(w io.Writer)) error {
+	if c.output == outputJSON {
+		encoder := json.NewEncoder(c.stdout)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(value)
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+
+	table(w)
+
+	return w.Flush()
+}
@@ -2,30 +2,53 @@ package app
 
 import (
 	"context"
+	"errors"
 	"os"
 	"sync"
+	"time"
 
+	"go.fd.io/govpp/adapter"
 	"go.fd.io/govpp/adapter/statsclient"
 	vppapi "go.fd.io/govpp/api"
 	"go.fd.io/govpp/core"
 
 	"git.crptech.ru/cloud/cloudgw/internal/config"
+	controller "git.crptech.ru/cloud/cloudgw/internal/controller/http"
+	"git.crptech.ru/cloud/cloudgw/internal/events"
+	"git.crptech.ru/cloud/cloudgw/internal/ha"
+	"git.crptech.ru/cloud/cloudgw/internal/health"
 	"git.crptech.ru/cloud/cloudgw/internal/monitor"
 	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp/dryrun"
 	"git.crptech.ru/cloud/cloudgw/internal/service"
+	"git.crptech.ru/cloud/cloudgw/pkg/certreload"
 	"git.crptech.ru/cloud/cloudgw/pkg/closer"
 	"git.crptech.ru/cloud/cloudgw/pkg/logger"
 	"git.crptech.ru/cloud/cloudgw/pkg/pyroscope"
+	"git.crptech.ru/cloud/cloudgw/pkg/tracing"
 	"github.com/osrg/gobgp/v3/pkg/server"
 )
 
 type App struct {
-	Cfg       *config.Config
-	Storage   *imdb.Storage
-	BGPServer *server.BgpServer
-	VPPStream *vppapi.Stream
-	VPPEvent  chan core.ConnectionEvent
-	VPPStats  *core.StatsConnection
+	Cfg         *config.Config
+	Storage     *imdb.Storage
+	BGPServer   *server.BgpServer
+	VPPStream   *vppapi.Stream
+	VPPConn     vppapi.Connection
+	VPPEvent    chan core.ConnectionEvent
+	VPPStats    *core.StatsConnection
+	VPPStatsCli adapter.StatsAPI // nil if vpp stats are not connected, counter vectors not read by VPPStats
+	DryRun      *dryrun.Stream   // nil if vpp is connected
+	Health      *health.Checker
+	Events      *events.Hub // nil if state change events are disabled
+	HA          *ha.Node    // nil if ha pair is disabled
+	Drain       *service.GatewayDrain
+
+	configPath    string
+	isWarmStarted bool // udp tunnels are restored from state snapshot, the idle ones are deleted after initial sync
+	reloadMu      sync.Mutex
+	tokens        *controller.TokenStore // http api tokens replaced on reload
+	certReloader  *certreload.Reloader   // nil if tls is disabled
 }
 
 func Init(ctx context.Context) *App {
@@ -41,6 +64,8 @@ func Init(ctx context.Context) *App {
 		configPath = "/etc/cloudgw/config.yml"
 	}
 
+	a.configPath = configPath
+
 	a.Cfg, err = config.ParseConfig(configPath)
 	if err != nil {
 		logger.Fatal("failed to parse config file", "file path", configPath, "error", err)
@@ -58,6 +83,21 @@ func Init(ctx context.Context) *App {
 
 	logger.Info("global logger initialized successfully", "level", a.Cfg.Logging.Level)
 
+	// semantic problems are logged only to keep configs working before validation was added (see cloudgw validate),
+	// the app is not started with settings it would crash with
+
+	var fatal *config.FatalError
+
+	for _, err = range config.Validate(a.Cfg) {
+		logger.Error("config validation failed", "file", configPath, "error", err)
+
+		errors.As(err, &fatal)
+	}
+
+	if fatal != nil {
+		logger.Fatal("config has settings the app can not run with", "file", configPath, "error", fatal)
+	}
+
 	// storages
 
 	a.Storage, err = initStorages(a.Cfg)
@@ -67,22 +107,25 @@ func Init(ctx context.Context) *App {
 
 	// vpp bin and stats
 
-	stream, vppDisconnect, vppEvent, err := initVPP(ctx, &a)
+	stream, vppConn, vppDisconnect, vppEvent, err := initVPP(ctx, &a)
 	if err != nil {
 		logger.Fatal("failed to initialize vpp stream connection", "error", err)
 	}
 
 	a.VPPStream = &stream
+	a.VPPConn = vppConn
 	a.VPPEvent = vppEvent
 
-	closer.Add(func() error {
+	closer.SetTimeout(time.Duration(a.Cfg.Shutdown.PhaseTimeout) * time.Second)
+
+	closer.Add(closer.PhaseRelease, "vpp stream api", func(context.Context) error {
 		vppDisconnect()
 		logger.Info("vpp stream api disconnecting")
 
 		return nil
 	})
 
-	if a.Cfg.HTTP.Enable {
+	if a.Cfg.HTTP.Enable && !a.Cfg.VPP.DryRun {
 		vppStatsCli := statsclient.NewStatsClient("/run/vpp/stats.sock")
 
 		vppStats, err := core.ConnectStats(vppStatsCli)
@@ -92,7 +135,11 @@ func Init(ctx context.Context) *App {
 
 		a.VPPStats = vppStats
 
-		closer.Add(func() error {
+		if err == nil {
+			a.VPPStatsCli = vppStatsCli
+		}
+
+		closer.Add(closer.PhaseRelease, "vpp stats api", func(context.Context) error {
 			vppStats.Disconnect()
 			logger.Info("vpp stats api disconnecting")
 
@@ -107,7 +154,7 @@ func Init(ctx context.Context) *App {
 		logger.Fatal("failed to initialize gobgp server", "error", err)
 	}
 
-	closer.Add(func() error {
+	closer.Add(closer.PhaseRelease, "gobgp server", func(context.Context) error {
 		a.BGPServer.Stop()
 		logger.Info("gobgp server shutting down")
 
@@ -125,7 +172,7 @@ func Run(ctx context.Context, a *App) {
 
 	ctx, cancel := context.WithCancel(ctx)
 
-	closer.Add(func() error {
+	closer.Add(closer.PhaseSessions, "main context", func(context.Context) error {
 		cancel()
 		logger.Info("main context canceling")
 
@@ -142,9 +189,55 @@ func Run(ctx context.Context, a *App) {
 		}
 	}
 
+	// opentelemetry tracing of bgp updates to vpp fib, started before watching bgp updates
+
+	if a.Cfg.Tracing.Enable {
+		hostname, _ := os.Hostname()
+
+		shutdownTracing, err := tracing.EnableOTLPTracing(ctx, a.Cfg.Tracing.ServiceName, hostname, a.Cfg.Tracing.Endpoint,
+			a.Cfg.Tracing.TLS, a.Cfg.Tracing.SampleRatio)
+		if err != nil {
+			logger.Error("failed to enable opentelemetry tracing", "error", err)
+		} else {
+			closer.Add(closer.PhaseRelease, "opentelemetry tracing", shutdownTracing)
+		}
+	}
+
+	// health checks of http api and ha pair
+
+	if a.Cfg.HTTP.Enable || a.Cfg.HA.Enable {
+		a.Health = health.NewChecker(a.Cfg.HTTP.Health, a.Storage, a.BGPServer, a.VPPConn, a.VPPStats)
+
+		go a.Health.RunDriftCheck(ctx)
+	}
+
+	// state change events of /api/v1/events, started before bgp peering to report the first sessions and routes
+
+	if a.Cfg.HTTP.Enable && a.Cfg.HTTP.Events.Enable {
+		a.Events = events.NewHub(a.Storage, a.Cfg.HTTP.Events.Buffer)
+
+		go a.Events.Run(ctx)
+	}
+
+	// ha pair, the standby role is taken before any aggregated floating ip prefix is advertised
+
+	runHA(ctx, a)
+
 	// watch and handle bgp events from gobgp. NOTE: start watching before bgp peering to install routes correctly!
 
-	service.HandleBGPUpdate(ctx, a.VPPStream, a.BGPServer, *a.Cfg, a.Storage)
+	service.HandleBGPUpdate(ctx, a.VPPStream, a.BGPServer, *a.Cfg, a.Storage, a.isWarmStarted)
+
+	// bmp stations, added before bgp peers to stream their first session states
+
+	if err := service.AddBMPStations(ctx, a.BGPServer, a.Cfg.BMP); err != nil {
+		logger.Error("failed to add bmp stations", "error", err)
+	}
+
+	// mrt dump, enabled before bgp peers to record updates from the first session
+
+	if err := service.DumpMRT(ctx, a.BGPServer, a.Cfg.MRT); err != nil {
+		logger.Error("failed to dump bgp updates to mrt files", "error", err)
+	}
 
 	// gobgp peers
 
@@ -153,13 +246,33 @@ func Run(ctx context.Context, a *App) {
 		logger.Fatal(err.Error())
 	}
 
-	closer.Add(func() error {
+	closer.Add(closer.PhaseSessions, "bgp peers", func(context.Context) error {
 		deletePeers()
 		logger.Info("bgp peers deleting")
 
 		return nil
 	})
 
+	// gateway drain for maintenance
+
+	runGatewayDrain(ctx, a)
+
+	// withdrawn prefixes propagate to peers before bgp sessions are closed
+
+	closer.Add(closer.PhaseWithdraw, "aggregated floating ip prefixes", a.Drain.Shutdown)
+
+	closer.Add(closer.PhasePropagate, "propagation delay", func(ctx context.Context) error {
+		delay := time.NewTimer(time.Duration(a.Cfg.Shutdown.PropagationDelay) * time.Second)
+		defer delay.Stop()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-delay.C:
+			return nil
+		}
+	})
+
 	// metrics
 
 	if a.Cfg.HTTP.Enable {
@@ -169,15 +282,32 @@ func Run(ctx context.Context, a *App) {
 	// http server and prometheus metrics exposing
 
 	if a.Cfg.HTTP.Enable {
+		a.tokens = controller.NewTokenStore(a.Cfg.HTTP)
+
 		go initHTTPServer(ctx, a)
 	}
 
-	// monitor vpp main interface status
+	// state snapshot for warm start and post-incident analysis
+
+	runSnapshots(ctx, a)
+
+	// reload config on SIGHUP
+
+	go a.reloadOnSIGHUP(ctx)
 
-	bgpPeers := a.Storage.BGPPeerStorage.GetBGPPeers()
+	// monitor vpp main interface and sub-interfaces status, probe vrouters and prune paths through unreachable ones
+	// from floating ip routes. Both watch vpp events, so they are not started in dry-run mode
 
-	if err = monitor.VPPInterfaceStatus(ctx, a.Cfg, bgpPeers, a.BGPServer); err != nil {
-		logger.Error("vpp interface monitoring is not started, monitoring will be disabled", "error", err)
+	if a.DryRun != nil {
+		logger.Warn("vpp interface monitoring and tunnel probing are disabled in dry-run mode")
+	} else {
+		if err = monitor.VPPInterfaceStatus(ctx, a.Cfg, a.VPPConn, a.VPPStream, a.BGPServer, a.Storage); err != nil {
+			logger.Error("vpp interface monitoring is not started, monitoring will be disabled", "error", err)
+		}
+
+		if err = monitor.VPPTunnelStatus(ctx, a.Cfg, a.VPPConn, a.Storage); err != nil {
+			logger.Error("vpp tunnel probing is not started, probing will be disabled", "error", err)
+		}
 	}
 
 	// monitor vpp connection status and stop the app if the connection failed
@@ -186,7 +316,13 @@ func Run(ctx context.Context, a *App) {
 
 	wg.Add(1)
 
-	go monitor.VPPConnStatus(ctx, a.VPPEvent, &wg)
+	var onVPPConnStateChange func(core.ConnectionState)
+
+	if a.Health != nil {
+		onVPPConnStateChange = a.Health.SetVPPConnState
+	}
+
+	go monitor.VPPConnStatus(ctx, a.VPPEvent, onVPPConnStateChange, &wg)
 
 	wg.Wait()
 }
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	vppapi "go.fd.io/govpp/api"
+	"go.fd.io/govpp/binapi/interface_types"
+
+	"git.crptech.ru/cloud/cloudgw/internal/service"
+	"git.crptech.ru/cloud/cloudgw/pkg/closer"
+	"git.crptech.ru/cloud/cloudgw/pkg/logger"
+)
+
+// runGatewayDrain creates the gateway drain started by the admin api, cloudgwctl drain or SIGUSR1 and, if
+// Drain.OnShutdown is set, by SIGTERM and SIGINT before the app is closed
+func runGatewayDrain(ctx context.Context, a *App) {
+	var packets func() (uint64, error)
+
+	if a.VPPStats != nil {
+		packets = a.subInterfacePackets
+	}
+
+	a.Drain = service.NewGatewayDrain(ctx, a.BGPServer, *a.Cfg, a.Storage, packets)
+
+	if a.Cfg.Drain.OnShutdown {
+		closer.OnSignal(func() {
+			logger.Info("gateway is drained before shutdown, send the signal again to shut down at once")
+
+			a.Drain.Start()
+
+			// the drain waits for traffic up to Drain.Timeout, the margin is for bgp updates
+
+			waitCtx, cancel := context.WithTimeout(ctx, time.Duration(a.Cfg.Drain.Timeout)*time.Second+10*time.Second)
+			defer cancel()
+
+			if err := a.Drain.Wait(waitCtx); err != nil {
+				logger.Error("gateway is not drained before shutdown", "error", err)
+			}
+		})
+	}
+
+	go func() {
+		ch := make(chan os.Signal, 1)
+
+		signal.Notify(ch, syscall.SIGUSR1)
+
+		defer signal.Stop(ch)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ch:
+				a.Drain.Start()
+			}
+		}
+	}()
+}
+
+// subInterfacePackets returns packets received and sent by vrf sub-interfaces
+func (a *App) subInterfacePackets() (uint64, error) {
+	stats := new(vppapi.InterfaceStats)
+
+	if err := a.VPPStats.GetInterfaceStats(stats); err != nil {
+		return 0, err
+	}
+
+	subInterfaces := make(map[interface_types.InterfaceIndex]bool)
+
+	for _, vrf := range a.Storage.VPPVRFStorage.GetVRFs() {
+		if vrf.ID != 0 {
+			subInterfaces[vrf.SubInterfaceID] = true
+		}
+	}
+
+	var packets uint64
+
+	for _, counters := range stats.Interfaces {
+		if subInterfaces[interface_types.InterfaceIndex(counters.InterfaceIndex)] {
+			packets += counters.Rx.Packets + counters.Tx.Packets
+		}
+	}
+
+	return packets, nil
+}
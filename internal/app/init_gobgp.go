@@ -10,7 +10,6 @@ import (
 	"git.crptech.ru/cloud/cloudgw/internal/model"
 	"git.crptech.ru/cloud/cloudgw/internal/repository/gobgp"
 	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
-	"git.crptech.ru/cloud/cloudgw/pkg/exporter/gobgpexporter"
 	"git.crptech.ru/cloud/cloudgw/pkg/logger"
 )
 
@@ -41,16 +40,12 @@ func ConfigureGoBGP(ctx context.Context, storage *imdb.Storage, bgpSrv *server.B
 		if err := gobgp.AddGoBGPVRF(ctx, bgpSrv, vrf); err != nil {
 			return nil, fmt.Errorf("failed to create bgp vrf %q: %w", vrf.Name, err)
 		}
-
-		gobgpexporter.GoBGPGeneralMetrics.IncVRFCount()
 	}
 
-	// add bgp peers (tungsten fabric and physical network) and update metrics
+	// add bgp peers (tungsten fabric and physical network)
 
 	bgpPeers := storage.BGPPeerStorage.GetBGPPeers()
 
-	gobgpexporter.GoBGPGeneralMetrics.PeerCount = int32(len(bgpPeers))
-
 	for _, peer := range bgpPeers {
 		if err := gobgp.AddBGPPeer(ctx, bgpSrv, peer); err != nil {
 			return nil, fmt.Errorf("failed to create bgp peer %s: %w", peer.PeerAddress, err)
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"context"
+	"net"
+
+	"git.crptech.ru/cloud/cloudgw/internal/ha"
+	"git.crptech.ru/cloud/cloudgw/internal/service"
+	"git.crptech.ru/cloud/cloudgw/pkg/closer"
+	"git.crptech.ru/cloud/cloudgw/pkg/logger"
+)
+
+// runHA starts the instance of ha pair as standby, so aggregated floating ip prefixes are not advertised with normal
+// attributes before the peer is heard. The role follows readiness of the instance (bgp, bfd and dataplane health).
+func runHA(ctx context.Context, a *App) {
+	if !a.Cfg.HA.Enable {
+		return
+	}
+
+	service.SetHARole(ctx, a.BGPServer, *a.Cfg, a.Storage, true)
+
+	conn, err := net.ListenPacket("udp", a.Cfg.HA.Listen)
+	if err != nil {
+		logger.Fatal("failed to listen for ha heartbeats", "address", a.Cfg.HA.Listen, "error", err)
+	}
+
+	closer.Add(closer.PhaseSessions, "ha heartbeats", func(context.Context) error {
+		logger.Info("ha heartbeats stopping")
+
+		return conn.Close()
+	})
+
+	local := func(ctx context.Context) ha.Local {
+		return ha.Local{
+			Healthy:    a.Health.Ready(ctx).OK,
+			FIPRoutes:  len(a.Storage.VPPFIPRouteStorage.GetFIPRoutes()),
+			UDPTunnels: len(a.Storage.VPPUDPTunnelStorage.GetUDPTunnels()),
+		}
+	}
+
+	onRoleChange := func(ctx context.Context, role ha.Role) {
+		service.SetHARole(ctx, a.BGPServer, *a.Cfg, a.Storage, role == ha.RoleStandby)
+	}
+
+	a.HA, err = ha.New(a.Cfg.HA, conn, local, onRoleChange)
+	if err != nil {
+		logger.Fatal("failed to start ha pair", "error", err)
+	}
+
+	go a.HA.Run(ctx)
+
+	logger.Info("ha pair started as standby", "node id", a.HA.Status().NodeID, "peer", a.Cfg.HA.Peer, "standby mode", a.Cfg.HA.StandbyMode)
+}
@@ -2,32 +2,151 @@ package app
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"time"
 
+	"git.crptech.ru/cloud/cloudgw/internal/config"
 	controller "git.crptech.ru/cloud/cloudgw/internal/controller/http"
+	"git.crptech.ru/cloud/cloudgw/pkg/audit"
+	"git.crptech.ru/cloud/cloudgw/pkg/certreload"
 	"git.crptech.ru/cloud/cloudgw/pkg/closer"
 	"git.crptech.ru/cloud/cloudgw/pkg/logger"
 )
 
 func initHTTPServer(ctx context.Context, a *App) {
-	engine := controller.NewRouter(a.Storage, *a.VPPStream)
+	auditLogger, err := audit.New(a.Cfg.HTTP.Admin.AuditLogPath)
+	if err != nil {
+		logger.Error("failed to open audit log, audit records are written to app log only", "error", err)
+
+		auditLogger, _ = audit.New("")
+	}
+
+	closer.Add(closer.PhaseRelease, "audit log", func(context.Context) error {
+		return auditLogger.Close()
+	})
+
+	engine := controller.NewRouter(*a.Cfg, a.Storage, *a.VPPStream, a.BGPServer, a.Health, auditLogger, a.tokens, a.Reload, a.DryRun, a.Events, a.HA, a.Drain)
 
 	srv := http.Server{
 		Addr:    a.Cfg.HTTP.Address,
 		Handler: engine,
 	}
+
+	if a.Cfg.HTTP.TLS.Enable {
+		// http server is not started without tls if tls is enabled, but cert or key can not be loaded
+		tlsConfig, reloader, err := newTLSConfig(ctx, a.Cfg.HTTP.TLS)
+		if err != nil {
+			logger.Error("failed to start http server", "error", err)
+
+			return
+		}
+
+		srv.TLSConfig = tlsConfig
+
+		a.setCertReloader(reloader)
+	}
+
 	go func() {
-		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		var err error
+
+		if srv.TLSConfig != nil {
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			logger.Error("failed to start http server", "error", err)
 		}
 	}()
 
-	closer.Add(func() error {
+	closer.Add(closer.PhaseSessions, "http server", func(ctx context.Context) error {
 		logger.Info("http server shutting down")
 
 		return srv.Shutdown(ctx)
 	})
 
-	logger.Info("http server and prometheus metric exposing started")
+	if a.Cfg.HTTP.UnixSocket != "" {
+		serveUnixSocket(ctx, a.Cfg.HTTP.UnixSocket, engine)
+	}
+
+	logger.Info("http server and prometheus metric exposing started", "tls", a.Cfg.HTTP.TLS.Enable)
+}
+
+// serveUnixSocket serves http api on the unix socket (accessible by owner and group only)
+func serveUnixSocket(ctx context.Context, path string, handler http.Handler) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Error("failed to remove stale http unix socket", "path", path, "error", err)
+
+		return
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		logger.Error("failed to listen http unix socket", "path", path, "error", err)
+
+		return
+	}
+
+	if err = os.Chmod(path, 0o660); err != nil {
+		logger.Error("failed to set http unix socket permissions", "path", path, "error", err)
+	}
+
+	srv := http.Server{Handler: handler}
+
+	go func() {
+		if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("failed to serve http unix socket", "path", path, "error", err)
+		}
+	}()
+
+	closer.Add(closer.PhaseSessions, "http unix socket server", func(ctx context.Context) error {
+		logger.Info("http unix socket server shutting down")
+
+		return srv.Shutdown(ctx)
+	})
+
+	logger.Info("http api is served on unix socket", "path", path)
+}
+
+// newTLSConfig returns tls config with the certificate reloaded on cert or key files change and client certificates
+// verification (if client ca file is set)
+func newTLSConfig(ctx context.Context, cfg config.TLS) (*tls.Config, *certreload.Reloader, error) {
+	reloader, err := certreload.New(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load tls certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	if cfg.ClientCAFile != "" {
+		clientCA, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read client ca file: %w", err)
+		}
+
+		clientCAPool := x509.NewCertPool()
+
+		if !clientCAPool.AppendCertsFromPEM(clientCA) {
+			return nil, nil, fmt.Errorf("no certificates found in client ca file %s", cfg.ClientCAFile)
+		}
+
+		tlsConfig.ClientCAs = clientCAPool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	if cfg.ReloadInterval > 0 {
+		go reloader.Run(ctx, time.Duration(cfg.ReloadInterval)*time.Second)
+	}
+
+	return tlsConfig, reloader, nil
 }
@@ -13,38 +13,24 @@ import (
 )
 
 func initStorages(cfg *config.Config) (*imdb.Storage, error) {
-	BGPPeerStorage, err := initBGPPeerStorage(cfg)
-	if err != nil {
-		return nil, err
-	}
+	storage := imdb.NewStorage() // floating ip routes and udp tunnels are added on bgp updates
 
-	BGPVRFStorage, err := initBGPVRFStorage(cfg)
-	if err != nil {
+	if err := initBGPPeerStorage(cfg, storage.BGPPeerStorage); err != nil {
 		return nil, err
 	}
 
-	VPPVRFStorage, err := initVPPVRFStorage(cfg)
-	if err != nil {
+	if err := initBGPVRFStorage(cfg, storage.BGPVRFStorage); err != nil {
 		return nil, err
 	}
 
-	VPPFIPRouteStorage := imdb.NewVPPFIPRouteStorage()
-
-	VPPUDPTunnelStorage := imdb.NewVPPUDPTunnelStorage()
-
-	storage := imdb.Storage{
-		BGPPeerStorage:      BGPPeerStorage,
-		BGPVRFStorage:       BGPVRFStorage,
-		VPPVRFStorage:       VPPVRFStorage,
-		VPPFIPRouteStorage:  VPPFIPRouteStorage,
-		VPPUDPTunnelStorage: VPPUDPTunnelStorage,
+	if err := initVPPVRFStorage(cfg, storage.VPPVRFStorage); err != nil {
+		return nil, err
 	}
 
-	return &storage, nil
+	return storage, nil
 }
 
-func initBGPPeerStorage(cfg *config.Config) (*imdb.BGPPeerStorage, error) {
-	peerStorage := imdb.NewBGPPeerStorage()
+func initBGPPeerStorage(cfg *config.Config, peerStorage *imdb.BGPPeerStorage) error {
 
 	// tungsten fabric controllers
 	for _, ip := range cfg.TFController.Address {
@@ -62,7 +48,7 @@ func initBGPPeerStorage(cfg *config.Config) (*imdb.BGPPeerStorage, error) {
 		)
 
 		if err := peerStorage.AddBGPPeer(&bgpPeer); err != nil {
-			return nil, fmt.Errorf("failed to add bgp peer %s: %w", ip, err)
+			return fmt.Errorf("failed to add bgp peer %s: %w", ip, err)
 		}
 	}
 
@@ -81,6 +67,12 @@ func initBGPPeerStorage(cfg *config.Config) (*imdb.BGPPeerStorage, error) {
 			vrf.BGPHoldTimer,
 		)
 
+		bfdEchoInterval := 0
+
+		if vrf.BFDEchoEnable {
+			bfdEchoInterval = vrf.BFDEchoInterval
+		}
+
 		bfdPeering := model.NewBFDPeer(
 			vrf.BFDEnable,
 			vrf.BGPPeerIP,
@@ -88,26 +80,27 @@ func initBGPPeerStorage(cfg *config.Config) (*imdb.BGPPeerStorage, error) {
 			vrf.BFDTxRate,
 			vrf.BFDRxMin,
 			vrf.BFDMultiplier,
+			bfdEchoInterval,
 		)
 
 		bgpPeer.BFDPeering = &bfdPeering
 
 		if err := peerStorage.AddBGPPeer(&bgpPeer); err != nil {
-			return nil, fmt.Errorf("failed to add bgp peer ip %s: %w", vrf.BGPPeerIP, err)
+			return fmt.Errorf("failed to add bgp peer ip %s: %w", vrf.BGPPeerIP, err)
 		}
 	}
 
 	bgpPeers := peerStorage.GetBGPPeers()
 
 	if len(bgpPeers) < 2 {
-		return nil, fmt.Errorf("found %d bgp peers (needed at least 2)", len(bgpPeers))
+		return fmt.Errorf("found %d bgp peers (needed at least 2)", len(bgpPeers))
 	}
 
-	return peerStorage, nil
+	return nil
 }
 
-func initBGPVRFStorage(cfg *config.Config) (*imdb.BGPVRFStorage, error) {
-	bgpVrfStorage := imdb.NewBGPVRFStorage() // routing tables without grt
+// initBGPVRFStorage adds routing tables without grt
+func initBGPVRFStorage(cfg *config.Config, bgpVrfStorage *imdb.BGPVRFStorage) error {
 
 	for _, vrf := range cfg.VRF {
 		vrfTbl := model.NewBGPVRFTable(
@@ -127,21 +120,21 @@ func initBGPVRFStorage(cfg *config.Config) (*imdb.BGPVRFStorage, error) {
 		)
 
 		if err := bgpVrfStorage.AddVRF(&vrfTbl); err != nil {
-			return nil, fmt.Errorf("failed to add bgp vrf %s: %w", vrf.VRFName, err)
+			return fmt.Errorf("failed to add bgp vrf %s: %w", vrf.VRFName, err)
 		}
 	}
 
 	bgpVRFs := bgpVrfStorage.GetVRFs()
 
 	if len(bgpVRFs) < 1 {
-		return nil, fmt.Errorf("found %d bgp vrfs (needed at least 1)", len(bgpVRFs))
+		return fmt.Errorf("found %d bgp vrfs (needed at least 1)", len(bgpVRFs))
 	}
 
-	return bgpVrfStorage, nil
+	return nil
 }
 
-func initVPPVRFStorage(cfg *config.Config) (*imdb.VPPVRFStorage, error) {
-	VPPVRFStorage := imdb.NewVPPVRFStorage() // routing tables with grt
+// initVPPVRFStorage adds routing tables with grt
+func initVPPVRFStorage(cfg *config.Config, VPPVRFStorage *imdb.VPPVRFStorage) error {
 
 	// global routing table (id = 0)
 	VPPRoutingTbl := model.NewVPPVRFTable(
@@ -158,14 +151,14 @@ func initVPPVRFStorage(cfg *config.Config) (*imdb.VPPVRFStorage, error) {
 	)
 
 	if err := VPPVRFStorage.AddVRF(&VPPRoutingTbl); err != nil {
-		return nil, fmt.Errorf("failed to add vpp vrf id %d: %w", VPPRoutingTbl.ID, err)
+		return fmt.Errorf("failed to add vpp vrf id %d: %w", VPPRoutingTbl.ID, err)
 	}
 
 	// vrfs (id = 1, ...)
 	for _, vrf := range cfg.VRF {
 		mplsLocalLabel, err := netutils.MPLSLabel(vrf.LocalIP)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create mpls local label: %w", err)
+			return fmt.Errorf("failed to create mpls local label: %w", err)
 		}
 
 		vppRoutingTbl := model.NewVPPVRFTable(
@@ -182,13 +175,13 @@ func initVPPVRFStorage(cfg *config.Config) (*imdb.VPPVRFStorage, error) {
 		)
 
 		if err = VPPVRFStorage.AddVRF(&vppRoutingTbl); err != nil {
-			return nil, fmt.Errorf("failed to add vpp vrf id %d: %w", VPPRoutingTbl.ID, err)
+			return fmt.Errorf("failed to add vpp vrf id %d: %w", VPPRoutingTbl.ID, err)
 		}
 	}
 
 	if len(VPPVRFStorage.GetVRFs()) < 2 {
-		return nil, fmt.Errorf("found %d vrf(s) in storage (need at least 2)", len(VPPVRFStorage.GetVRFs()))
+		return fmt.Errorf("found %d vrf(s) in storage (need at least 2)", len(VPPVRFStorage.GetVRFs()))
 	}
 
-	return VPPVRFStorage, nil
+	return nil
 }
@@ -12,22 +12,10 @@ func initMetric(ctx context.Context, a *App) {
 	vppPollTimer := a.Cfg.VPP.MetricPollingInterval
 	gobgpPollTimer := a.Cfg.GoBGP.MetricPollingInterval
 
-	vppVRFs := a.Storage.VPPVRFStorage.GetVRFs()
-
-	for _, vrf := range vppVRFs {
-		if vrf.ID == 0 {
-			continue
-		}
-
-		vppexporter.VPPVRFMetrics[vrf.ID] = vppexporter.NewVPPVRFMetric(vrf.Name)
-	}
-
-	// update metrics
-
-	bgpPeers := a.Storage.BGPPeerStorage.GetBGPPeers()
+	// vrfs and peers are read from storages on every update, so metrics follow vrfs and peers changed at runtime
 
 	go func() {
-		if err := gobgpexporter.UpdateGoBGPMetrics(ctx, a.BGPServer, gobgpPollTimer, bgpPeers); err != nil {
+		if err := gobgpexporter.UpdateGoBGPMetrics(ctx, a.BGPServer, gobgpPollTimer, a.Storage.BGPPeerStorage); err != nil {
 			logger.Error("failed to update gobgp metrics", "error", err)
 		}
 	}()
@@ -38,8 +26,19 @@ func initMetric(ctx context.Context, a *App) {
 		}
 	}()
 
+	if counters := a.Cfg.VPP.TrafficCounters; counters.Enable {
+		go func() {
+			err := vppexporter.UpdateVPPTrafficMetrics(
+				ctx, a.VPPStatsCli, a.VPPConn, vppPollTimer, a.Storage, counters.MaxSeries, counters.TopN,
+			)
+			if err != nil {
+				logger.Error("failed to update vpp floating ip and tunnel traffic metrics", "error", err)
+			}
+		}()
+	}
+
 	go func() {
-		if err := vppexporter.UpdateVPPUDPVRFMetrics(ctx, a.VPPStream, vppPollTimer, vppVRFs); err != nil {
+		if err := vppexporter.UpdateVPPUDPVRFMetrics(ctx, a.VPPStream, vppPollTimer, a.Storage.VPPVRFStorage); err != nil {
 			logger.Error("failed to update vpp tunnel and route metrics", "error", err)
 		}
 	}()
@@ -0,0 +1,107 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+
+	"go.fd.io/govpp/api"
+
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp/initialize"
+	"git.crptech.ru/cloud/cloudgw/internal/snapshot"
+	"git.crptech.ru/cloud/cloudgw/pkg/closer"
+	"git.crptech.ru/cloud/cloudgw/pkg/logger"
+	"git.crptech.ru/cloud/cloudgw/pkg/netutils"
+)
+
+// warmStart re-creates udp tunnels of the previous run from the state snapshot (if any) after vpp config is cleared,
+// so the tunnels keep their source ports. A missed or broken snapshot leads to a cold start.
+func warmStart(stream api.Stream, a *App) {
+	cfg := a.Cfg.Snapshot
+
+	if !cfg.Enable || !cfg.WarmStart {
+		return
+	}
+
+	snap, err := snapshot.Read(cfg.Path)
+
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		logger.Info("no state snapshot found, cold start", "file", cfg.Path)
+
+		return
+	case err != nil:
+		logger.Error("failed to read state snapshot, cold start", "file", cfg.Path, "error", err)
+
+		return
+	}
+
+	// sub-interface ids are assigned by vpp and labels are derived from the config, changes are only logged
+
+	for _, prev := range snap.VRFs {
+		vrf := a.Storage.VPPVRFStorage.GetVRF(prev.ID)
+		if vrf == nil || prev.ID == 0 {
+			continue
+		}
+
+		if vrf.SubInterfaceID != prev.SubInterfaceID || vrf.MPLSLocalLabel != prev.MPLSLocalLabel {
+			logger.Info(
+				"vrf changed since previous run",
+				"vrf", vrf.Name,
+				"previous sub-interface", prev.SubInterfaceID,
+				"sub-interface", vrf.SubInterfaceID,
+				"previous label", prev.MPLSLocalLabel,
+				"label", vrf.MPLSLocalLabel,
+			)
+		}
+	}
+
+	restored, changed, err := initialize.RestoreUDPTunnels(stream, snap.Tunnels, netutils.Addr(a.Cfg.VPP.TunLocalIP), a.Storage.VPPUDPTunnelStorage)
+
+	a.isWarmStarted = restored != 0
+
+	if err != nil {
+		logger.Error("failed to restore udp tunnels from state snapshot", "restored", restored, "error", err)
+
+		return
+	}
+
+	logger.Info(
+		"warm start from state snapshot",
+		"file", cfg.Path,
+		"snapshot time", snap.Time,
+		"udp tunnels restored", restored,
+		"udp tunnel ids changed", changed,
+	)
+}
+
+// runSnapshots writes the state snapshot periodically and on shutdown
+func runSnapshots(ctx context.Context, a *App) {
+	cfg := a.Cfg.Snapshot
+
+	if !cfg.Enable {
+		return
+	}
+
+	// the snapshot of the real dataplane is not replaced by the dry-run state
+
+	if a.DryRun != nil {
+		logger.Warn("state snapshot is not written in dry-run mode")
+
+		return
+	}
+
+	closer.Add(closer.PhaseWithdraw, "state snapshot", func(context.Context) error {
+		if err := snapshot.Save(cfg.Path, a.Storage); err != nil {
+			logger.Error("failed to write state snapshot on shutdown", "file", cfg.Path, "error", err)
+
+			return err
+		}
+
+		logger.Info("state snapshot written", "file", cfg.Path)
+
+		return nil
+	})
+
+	go snapshot.Run(ctx, cfg, a.Storage)
+}
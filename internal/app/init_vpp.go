@@ -8,34 +8,58 @@ import (
 	"go.fd.io/govpp/core"
 
 	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp/dryrun"
 	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp/initialize"
+	"git.crptech.ru/cloud/cloudgw/pkg/exporter/convergenceexporter"
 	"git.crptech.ru/cloud/cloudgw/pkg/logger"
 )
 
-func initVPP(ctx context.Context, a *App) (api.Stream, func(), chan core.ConnectionEvent, error) {
-	stream, disconnect, vppEvent, err := vpp.ConnectToVPPAPIAsync(ctx, a.Cfg.VPP.BinAPISock)
-	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to connect to vpp stream api: %w", err)
+func initVPP(ctx context.Context, a *App) (api.Stream, api.Connection, func(), chan core.ConnectionEvent, error) {
+	var (
+		stream     api.Stream
+		conn       api.Connection
+		disconnect func()
+		vppEvent   chan core.ConnectionEvent
+		err        error
+	)
+
+	if a.Cfg.VPP.DryRun {
+		// requests are recorded by the dry-run stream, the connection event is never sent
+		a.DryRun = dryrun.NewStream(ctx)
+		stream, disconnect, vppEvent = a.DryRun, func() {}, make(chan core.ConnectionEvent)
+
+		logger.Warn("vpp dry-run mode enabled, vpp requests are recorded and not sent")
+	} else {
+		stream, conn, disconnect, vppEvent, err = vpp.ConnectToVPPAPIAsync(ctx, a.Cfg.VPP.BinAPISock)
+		if err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("failed to connect to vpp stream api: %w", err)
+		}
 	}
 
+	// latency of every binary api call is exposed by message
+
+	vpp.SetCallObserver(convergenceexporter.UpdateMetrics.ObserveVPPCall)
+
 	version, err := vpp.GetVPPVersion(stream)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to get vpp version: %w", err)
+		return nil, nil, nil, nil, fmt.Errorf("failed to get vpp version: %w", err)
 	}
 
 	logger.Info("connected to vpp stream api", "vpp version", version)
 
 	if err = initialize.ClearVPPConfig(stream, a.Cfg.VPP.MainInterfaceID, a.Storage.VPPVRFStorage); err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to clear vpp config: %w", err)
+		return nil, nil, nil, nil, fmt.Errorf("failed to clear vpp config: %w", err)
 	}
 
 	logger.Info("vpp configuration cleared")
 
 	if err = initialize.AddVPPInitConfig(stream, a.Storage.VPPVRFStorage, a.Cfg.VPP.MainInterfaceID, a.Cfg.VPP.TunDefaultGW); err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to add vpp static config: %w", err)
+		return nil, nil, nil, nil, fmt.Errorf("failed to add vpp static config: %w", err)
 	}
 
 	logger.Info("vpp static config added")
 
-	return stream, disconnect, vppEvent, nil
+	warmStart(stream, a)
+
+	return stream, conn, disconnect, vppEvent, nil
 }
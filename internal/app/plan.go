@@ -0,0 +1,114 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/osrg/gobgp/v3/pkg/apiutil"
+	"github.com/osrg/gobgp/v3/pkg/packet/bgp"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+)
+
+// WritePlan writes vpp and gobgp objects created by cloudgw on start with the config. Neither vpp nor gobgp is
+// requested, the objects are built from the same storages as on start.
+func WritePlan(w io.Writer, cfg *config.Config) error {
+	storage, err := initStorages(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize storages: %w", err)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	// vpp
+
+	vppVRFs := storage.VPPVRFStorage.GetVRFs()
+
+	fmt.Fprintln(tw, "VPP")
+	fmt.Fprintf(tw, "  main interface\t%d\taddress %s/%d, mpls enabled\n",
+		cfg.VPP.MainInterfaceID, vppVRFs[0].LocalAddr, vppVRFs[0].LocalAddrLen)
+	fmt.Fprintf(tw, "  global routing table\t0\troute 0.0.0.0/0 via %s\n", cfg.VPP.TunDefaultGW)
+	fmt.Fprintln(tw, "  mpls table\t0\t")
+	fmt.Fprintln(tw, "  udp decap\tport 6635\tmpls over udp from vrouters")
+
+	for _, vrf := range vppVRFs[1:] {
+		fmt.Fprintf(tw, "  vrf %s\ttable %d\t\n", vrf.Name, vrf.ID)
+		fmt.Fprintf(tw, "    sub-interface\t%d.%d\tvlan %d, address %s/%d, mpls enabled\n",
+			vrf.MainInterfaceID, vrf.VLAN, vrf.VLAN, vrf.LocalAddr, vrf.LocalAddrLen)
+		fmt.Fprintf(tw, "    mpls local label\t%d\tlookup in table %d\n", vrf.MPLSLocalLabel, vrf.ID)
+
+		for _, prefix := range vrf.FIPPrefixes {
+			fmt.Fprintf(tw, "    blackhole route\t%s\t\n", prefix)
+		}
+	}
+
+	// gobgp
+
+	fmt.Fprintln(tw, "\nGoBGP")
+	fmt.Fprintf(tw, "  global\tasn %d\trouter id %s, port %d, grpc %s\n",
+		cfg.GoBGP.BGPLocalASN, cfg.GoBGP.RID, cfg.GoBGP.BGPLocalPort, cfg.GoBGP.GRPCListenAddress)
+
+	for _, vrf := range storage.BGPVRFStorage.GetVRFs() {
+		fmt.Fprintf(tw, "  vrf %s\tid %d\trd %s, import rt %s, export rt %s\n",
+			vrf.Name, vrf.ID, rdString(vrf), strings.Join(rtStrings(vrf.ImportRT), " "), strings.Join(rtStrings(vrf.ExportRT), " "))
+	}
+
+	var tfPeers, phyNetPeers []string
+
+	for _, peer := range storage.BGPPeerStorage.GetBGPPeers() {
+		peerType, vrf := "tungsten fabric", "global"
+
+		if peer.PeerType == model.PHYNET {
+			peerType, vrf = "physical network", peer.VRFName
+			phyNetPeers = append(phyNetPeers, peer.PeerAddress+"/32")
+		} else {
+			tfPeers = append(tfPeers, peer.PeerAddress+"/32")
+		}
+
+		fmt.Fprintf(tw, "  peer %s\tasn %d\t%s, vrf %s, family %s, ttl %d, keepalive %d, hold %d, md5 %t",
+			peer.PeerAddress, peer.PeerASN, peerType, vrf, bgp.AfiSafiToRouteFamily(uint16(peer.AFI), uint8(peer.SAFI)),
+			peer.EbgpMultiHopTTL, peer.KeepAliveTimer, peer.HoldTimer, peer.Md5Password != "")
+
+		if peer.BFDPeering != nil && peer.BFDPeering.BFDEnabled {
+			fmt.Fprintf(tw, ", bfd from %s tx %d rx %d x%d", peer.BFDPeering.BFDLocalIP,
+				peer.BFDPeering.BFDTxRate, peer.BFDPeering.BFDRxMin, peer.BFDPeering.BFDMultiplier)
+		}
+
+		fmt.Fprintln(tw)
+	}
+
+	// the same policy as gobgp.CreateGoBGPPolicy creates
+
+	fmt.Fprintln(tw, "  global export policy\t\t")
+	fmt.Fprintf(tw, "    reject\t0.0.0.0/0 masklen 32..32\tto %s\n", strings.Join(phyNetPeers, " "))
+	fmt.Fprintf(tw, "    reject\t0.0.0.0/0 masklen 0..0\tto %s\n", strings.Join(phyNetPeers, " "))
+	fmt.Fprintf(tw, "    reject\t0.0.0.0/0 masklen 1..32\tto %s\n", strings.Join(tfPeers, " "))
+	fmt.Fprintln(tw, "    accept\tany\tdefault")
+
+	return tw.Flush()
+}
+
+func rdString(vrf *model.BGPVRFTable) string {
+	rd, err := apiutil.UnmarshalRD(vrf.RD)
+	if err != nil {
+		return "invalid"
+	}
+
+	return rd.String()
+}
+
+func rtStrings(rts []*anypb.Any) []string {
+	values := make([]string, 0, len(rts))
+
+	for _, rt := range rts {
+		if decodedRT, err := apiutil.UnmarshalRT(rt); err == nil {
+			values = append(values, decodedRT.String())
+		}
+	}
+
+	return values
+}
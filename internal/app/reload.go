@@ -0,0 +1,95 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/pkg/certreload"
+	"git.crptech.ru/cloud/cloudgw/pkg/logger"
+)
+
+// Reload re-reads the config file and applies log level, http api tokens and tls certificate (if its files changed).
+// Other changes are returned as restart required.
+func (a *App) Reload() (applied, restartRequired []string, err error) {
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+
+	cfg, err := config.ParseConfig(a.configPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse config file %s: %w", a.configPath, err)
+	}
+
+	// applied fields are merged to the running config, so the next reload compares against them, other changes stay
+	// restart required
+
+	for _, change := range config.Changes(a.Cfg, cfg) {
+		switch {
+		case change == "Logging.Level":
+			logger.SetLevel(cfg.Logging.Level)
+
+			a.Cfg.Logging.Level = cfg.Logging.Level
+
+			applied = append(applied, change)
+		case change == "HTTP.Auth.Tokens" && a.tokens != nil:
+			a.Cfg.HTTP.Auth.Tokens = cfg.HTTP.Auth.Tokens
+
+			applied = append(applied, change)
+		case change == "HTTP.Admin.Token" && a.tokens != nil:
+			a.Cfg.HTTP.Admin.Token = cfg.HTTP.Admin.Token
+
+			applied = append(applied, change)
+		default:
+			restartRequired = append(restartRequired, change)
+		}
+	}
+
+	if a.tokens != nil {
+		a.tokens.Set(cfg.HTTP)
+	}
+
+	if a.certReloader != nil {
+		isReloaded, err := a.certReloader.Reload()
+		if err != nil {
+			return applied, restartRequired, fmt.Errorf("failed to reload tls certificate: %w", err)
+		}
+
+		if isReloaded {
+			applied = append(applied, "HTTP.TLS certificate")
+		}
+	}
+
+	logger.Info("config reloaded", "file", a.configPath, "applied", applied, "restart required", restartRequired)
+
+	return applied, restartRequired, nil
+}
+
+func (a *App) setCertReloader(reloader *certreload.Reloader) {
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+
+	a.certReloader = reloader
+}
+
+// reloadOnSIGHUP reloads config on SIGHUP
+func (a *App) reloadOnSIGHUP(ctx context.Context) {
+	ch := make(chan os.Signal, 1)
+
+	signal.Notify(ch, syscall.SIGHUP)
+
+	defer signal.Stop(ch)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ch:
+			if _, _, err := a.Reload(); err != nil {
+				logger.Error("failed to reload config", "error", err)
+			}
+		}
+	}
+}
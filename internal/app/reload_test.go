@@ -0,0 +1,62 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/pkg/logger"
+)
+
+func TestReload(t *testing.T) {
+	const testConfigPath = "../config/config_test.yml"
+
+	data, err := os.ReadFile(testConfigPath)
+	require.NoError(t, err)
+
+	cfg, err := config.ParseConfig(testConfigPath)
+	require.NoError(t, err)
+
+	configPath := filepath.Join(t.TempDir(), "config.yml")
+
+	a := &App{Cfg: cfg, configPath: configPath}
+
+	defer logger.SetLevel(cfg.Logging.Level)
+
+	writeConfig := func(level, format string) {
+		content := strings.Replace(string(data), `Level: "info"`, `Level: "`+level+`"`, 1)
+		content = strings.Replace(content, `Format: "console"`, `Format: "`+format+`"`, 1)
+
+		require.NoError(t, os.WriteFile(configPath, []byte(content), 0o600))
+	}
+
+	steps := []struct {
+		name            string
+		level           string
+		format          string
+		applied         []string
+		restartRequired []string
+	}{
+		{name: "level changed", level: "debug", format: "console", applied: []string{"Logging.Level"}},
+		{name: "same config", level: "debug", format: "console"},
+		{name: "level changed back", level: "info", format: "console", applied: []string{"Logging.Level"}},
+		// not applied changes are reported until restart
+		{name: "format changed", level: "info", format: "json", restartRequired: []string{"Logging.Format"}},
+		{name: "format still changed", level: "info", format: "json", restartRequired: []string{"Logging.Format"}},
+	}
+
+	for _, step := range steps {
+		writeConfig(step.level, step.format)
+
+		applied, restartRequired, err := a.Reload()
+		require.NoError(t, err, step.name)
+		require.Equal(t, step.applied, applied, step.name)
+		require.Equal(t, step.restartRequired, restartRequired, step.name)
+		require.Equal(t, step.level, a.Cfg.Logging.Level, step.name)
+		require.Equal(t, "console", a.Cfg.Logging.Format, step.name)
+	}
+}
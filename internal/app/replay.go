@@ -0,0 +1,88 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp/dryrun"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp/initialize"
+	"git.crptech.ru/cloud/cloudgw/internal/service"
+)
+
+// ReplayMRT replays bgp updates of mrt files in the given order against dry-run vpp initialized with the config and
+// writes floating ip route changes (of fip only, if it is set) and the summary. Neither vpp nor gobgp is requested.
+func ReplayMRT(ctx context.Context, w io.Writer, cfg *config.Config, files []string, fip string) error {
+	storage, err := initStorages(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize storages: %w", err)
+	}
+
+	stream := dryrun.NewStream(ctx)
+
+	if err = initialize.AddVPPInitConfig(stream, storage.VPPVRFStorage, cfg.VPP.MainInterfaceID, cfg.VPP.TunDefaultGW); err != nil {
+		return fmt.Errorf("failed to initialize dry-run vpp: %w", err)
+	}
+
+	replayer, err := service.NewMRTReplayer(ctx, stream, *cfg, storage)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "TIME\tPEER\tACTION\tNLRI\tFLOATING IP\tNEXT-HOPS")
+
+	for _, file := range files {
+		if err = replayFile(replayer, file, func(event service.MRTReplayEvent) {
+			if fip != "" && event.FIP != fip {
+				return
+			}
+
+			action, nextHops := "advertise", strings.Join(event.NextHops, ",")
+
+			if event.IsWithdraw {
+				action = "withdraw"
+			}
+
+			if nextHops == "" {
+				nextHops = "deleted"
+			}
+
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", event.Time.Format(time.RFC3339), event.PeerIP, action, event.NLRI,
+				event.FIP, nextHops)
+		}); err != nil {
+			return err
+		}
+	}
+
+	stats := replayer.Stats()
+	fib := stream.FIB()
+
+	fmt.Fprintf(tw, "\nfiles %d, updates %d, paths %d, best path changes %d, floating ip changes %d\n",
+		len(files), stats.Updates, stats.Paths, stats.BestPaths, stats.FIPPaths)
+	fmt.Fprintf(tw, "floating ip routes %d, udp tunnels %d\n",
+		len(storage.VPPFIPRouteStorage.GetFIPRoutes()), len(fib.Tunnels))
+
+	return tw.Flush()
+}
+
+func replayFile(replayer *service.MRTReplayer, file string, fn func(service.MRTReplayEvent)) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("failed to open mrt file: %w", err)
+	}
+
+	defer f.Close()
+
+	if err = replayer.Replay(f, fn); err != nil {
+		return fmt.Errorf("failed to replay %s: %w", file, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,238 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	controller "git.crptech.ru/cloud/cloudgw/internal/controller/http/v1"
+)
+
+const (
+	unixScheme = "unix://"
+	unixHost   = "http://cloudgw" // host of requests sent over unix socket
+	pageLimit  = 1000
+)
+
+// Options are options of cloudgw api client. URL is http(s)://host:port or unix:///path/to/socket.
+type Options struct {
+	URL      string
+	Token    string // bearer token
+	CAFile   string // ca certificate to verify cloudgw https server
+	CertFile string // client certificate (mtls)
+	KeyFile  string // client key (mtls)
+	Timeout  time.Duration
+}
+
+// Client requests cloudgw /api/v1
+type Client struct {
+	http    http.Client
+	baseURL string
+	token   string
+}
+
+// Error is an error replied by cloudgw api
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func New(opts Options) (*Client, error) {
+	baseURL := opts.URL
+
+	if baseURL == "" {
+		baseURL = DefaultURL()
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if opts.CAFile != "" {
+		ca, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca file: %w", err)
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in ca file %s", opts.CAFile)
+		}
+	}
+
+	if opts.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client cert and key: %w", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := &http.Transport{TLSClientConfig: tlsConfig}
+
+	if socketPath, ok := strings.CutPrefix(baseURL, unixScheme); ok {
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+
+			return dialer.DialContext(ctx, "unix", socketPath)
+		}
+
+		baseURL = unixHost
+	}
+
+	return &Client{
+		http:    http.Client{Timeout: opts.Timeout, Transport: transport},
+		baseURL: strings.TrimRight(baseURL, "/") + controller.APIPrefix,
+		token:   opts.Token,
+	}, nil
+}
+
+// Get requests the api path and decodes the reply to out
+func (c *Client) Get(ctx context.Context, path string, query url.Values, out any) error {
+	return c.do(ctx, http.MethodGet, path, query, out)
+}
+
+// Post requests the api path and decodes the reply to out
+func (c *Client) Post(ctx context.Context, path string, query url.Values, out any) error {
+	return c.do(ctx, http.MethodPost, path, query, out)
+}
+
+// List requests all pages of the list api path
+func List[T any](ctx context.Context, c *Client, path string, query url.Values) ([]T, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+
+	var items []T
+
+	for {
+		query.Set("offset", strconv.Itoa(len(items)))
+		query.Set("limit", strconv.Itoa(pageLimit))
+
+		var page controller.Page[T]
+
+		if err := c.Get(ctx, path, query, &page); err != nil {
+			return nil, err
+		}
+
+		items = append(items, page.Items...)
+
+		if len(page.Items) == 0 || len(items) >= page.Total {
+			return items, nil
+		}
+	}
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, out any) error {
+	requestURL := c.baseURL + path
+
+	if len(query) != 0 {
+		requestURL += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, http.NoBody)
+	if err != nil {
+		return err
+	}
+
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read reply: %w", err)
+	}
+
+	// health checks reply with report and 503
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+		return newError(resp.StatusCode, body)
+	}
+
+	if err = json.Unmarshal(body, out); err != nil {
+		var apiError controller.APIError
+
+		if json.Unmarshal(body, &apiError) == nil && apiError.Error.Code != "" {
+			return &Error{Status: resp.StatusCode, Code: apiError.Error.Code, Message: apiError.Error.Message}
+		}
+
+		return fmt.Errorf("failed to parse reply: %w", err)
+	}
+
+	return nil
+}
+
+// newError returns the api error of the reply body, the body is the message if it is not an api error envelope
+func newError(status int, body []byte) *Error {
+	var apiError controller.APIError
+
+	if err := json.Unmarshal(body, &apiError); err != nil || apiError.Error.Code == "" {
+		return &Error{Status: status, Code: strconv.Itoa(status), Message: strings.TrimSpace(string(body))}
+	}
+
+	return &Error{Status: status, Code: apiError.Error.Code, Message: apiError.Error.Message}
+}
+
+// IsNotFound checks if the error is api not found error
+func IsNotFound(err error) bool {
+	var apiError *Error
+
+	return errors.As(err, &apiError) && apiError.Status == http.StatusNotFound
+}
+
+// DefaultURL returns url of cloudgw api from the config file (the same as cloudgw uses): unix socket if it is set,
+// https or http server address otherwise
+func DefaultURL() string {
+	configPath := os.Getenv("CLOUDGW_CONFIG_PATH")
+
+	if configPath == "" {
+		configPath = "/etc/cloudgw/config.yml"
+	}
+
+	scheme, address := "http", ":9101"
+
+	if cfg, err := config.ParseConfig(configPath); err == nil {
+		if cfg.HTTP.UnixSocket != "" {
+			return unixScheme + cfg.HTTP.UnixSocket
+		}
+
+		if cfg.HTTP.Address != "" {
+			address = cfg.HTTP.Address
+		}
+
+		if cfg.HTTP.TLS.Enable {
+			scheme = "https"
+		}
+	}
+
+	if strings.HasPrefix(address, ":") {
+		address = "127.0.0.1" + address
+	}
+
+	return scheme + "://" + address
+}
@@ -0,0 +1,140 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"git.crptech.ru/cloud/cloudgw/internal/client"
+	controller "git.crptech.ru/cloud/cloudgw/internal/controller/http/v1"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet(t *testing.T) {
+	srv := httptest.NewServer(testHandler(t))
+	defer srv.Close()
+
+	apiClient, err := client.New(client.Options{URL: srv.URL, Token: "secret", Timeout: time.Second})
+	require.NoError(t, err)
+
+	var summary controller.SummaryStatus
+
+	require.NoError(t, apiClient.Get(context.Background(), "/summary", nil, &summary))
+	require.Equal(t, 2, summary.BGPPeerTotal)
+
+	// error envelope
+
+	err = apiClient.Get(context.Background(), "/vpp/vrfs/absent", nil, &controller.VPPVRF{})
+	require.Error(t, err)
+	require.True(t, client.IsNotFound(err))
+
+	var apiError *client.Error
+
+	require.ErrorAs(t, err, &apiError)
+	require.Equal(t, "not_found", apiError.Code)
+	require.Equal(t, "vrf absent is not found", apiError.Message)
+
+	// token is not sent
+
+	apiClient, err = client.New(client.Options{URL: srv.URL, Timeout: time.Second})
+	require.NoError(t, err)
+
+	err = apiClient.Get(context.Background(), "/summary", nil, &summary)
+	require.ErrorAs(t, err, &apiError)
+	require.Equal(t, http.StatusUnauthorized, apiError.Status)
+}
+
+func TestList(t *testing.T) {
+	srv := httptest.NewServer(testHandler(t))
+	defer srv.Close()
+
+	apiClient, err := client.New(client.Options{URL: srv.URL, Token: "secret", Timeout: time.Second})
+	require.NoError(t, err)
+
+	tunnels, err := client.List[controller.UDPTunnel](context.Background(), apiClient, "/vpp/tunnels", nil)
+	require.NoError(t, err)
+	require.Len(t, tunnels, 2500)
+	require.Equal(t, uint32(2499), tunnels[2499].ID)
+}
+
+func TestUnixSocket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "cloudgw.sock")
+
+	listener, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+
+	srv := httptest.NewUnstartedServer(testHandler(t))
+	srv.Listener = listener
+	srv.Start()
+
+	defer srv.Close()
+
+	apiClient, err := client.New(client.Options{URL: "unix://" + socketPath, Token: "secret", Timeout: time.Second})
+	require.NoError(t, err)
+
+	var summary controller.SummaryStatus
+
+	require.NoError(t, apiClient.Get(context.Background(), "/summary", nil, &summary))
+	require.Equal(t, 2, summary.BGPPeerTotal)
+}
+
+// testHandler replies as cloudgw /api/v1 does
+func testHandler(t *testing.T) http.Handler {
+	t.Helper()
+
+	mux := http.NewServeMux()
+
+	reply := func(w http.ResponseWriter, status int, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		require.NoError(t, json.NewEncoder(w).Encode(v))
+	}
+
+	auth := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				reply(w, http.StatusUnauthorized, controller.APIError{
+					Error: controller.APIErrorBody{Code: "unauthorized", Message: "unauthorized"},
+				})
+
+				return
+			}
+
+			next(w, r)
+		}
+	}
+
+	mux.HandleFunc("GET /api/v1/summary", auth(func(w http.ResponseWriter, _ *http.Request) {
+		reply(w, http.StatusOK, controller.SummaryStatus{BGPPeerTotal: 2})
+	}))
+
+	mux.HandleFunc("GET /api/v1/vpp/vrfs/{name}", auth(func(w http.ResponseWriter, r *http.Request) {
+		reply(w, http.StatusNotFound, controller.APIError{
+			Error: controller.APIErrorBody{Code: "not_found", Message: "vrf " + r.PathValue("name") + " is not found"},
+		})
+	}))
+
+	mux.HandleFunc("GET /api/v1/vpp/tunnels", auth(func(w http.ResponseWriter, r *http.Request) {
+		const total = 2500
+
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+		page := controller.Page[controller.UDPTunnel]{Total: total, Offset: offset, Limit: limit, Items: []controller.UDPTunnel{}}
+
+		for i := offset; i < total && i < offset+limit; i++ {
+			page.Items = append(page.Items, controller.UDPTunnel{ID: uint32(i)})
+		}
+
+		reply(w, http.StatusOK, page)
+	}))
+
+	return mux
+}
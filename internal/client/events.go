@@ -0,0 +1,92 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"git.crptech.ru/cloud/cloudgw/internal/events"
+)
+
+// Events reads state change events stream of /api/v1/events and calls fn for each event until the context is canceled,
+// the stream is ended by cloudgw or fn returns an error. Query is since (the last received sequence) and type.
+func (c *Client) Events(ctx context.Context, query url.Values, fn func(events.Event) error) error {
+	requestURL := c.baseURL + "/events"
+
+	if len(query) != 0 {
+		requestURL += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, http.NoBody)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "text/event-stream")
+
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	// the stream is not limited by the request timeout
+
+	httpClient := c.http
+	httpClient.Timeout = 0
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read reply: %w", err)
+		}
+
+		return newError(resp.StatusCode, body)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	// only data lines are parsed, id and event name are duplicated in the event
+
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+
+		var event events.Event
+
+		if err = json.Unmarshal([]byte(data), &event); err != nil {
+			return fmt.Errorf("failed to parse event: %w", err)
+		}
+
+		if err = fn(event); err != nil {
+			return err
+		}
+	}
+
+	if err = scanner.Err(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("failed to read events: %w", err)
+	}
+
+	return nil
+}
+
+// IsGone checks if the error is api gone error (events after the sequence are not kept)
+func IsGone(err error) bool {
+	var apiError *Error
+
+	return errors.As(err, &apiError) && apiError.Status == http.StatusGone
+}
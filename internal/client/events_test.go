@@ -0,0 +1,95 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"git.crptech.ru/cloud/cloudgw/internal/client"
+	controller "git.crptech.ru/cloud/cloudgw/internal/controller/http/v1"
+	"git.crptech.ru/cloud/cloudgw/internal/events"
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+)
+
+var errStop = errors.New("stop")
+
+// TestEvents streams events of the real /api/v1/events handler
+func TestEvents(t *testing.T) {
+	storage := imdb.NewStorage()
+	hub := events.NewHub(storage, 100)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go hub.Run(ctx)
+
+	gin.SetMode(gin.ReleaseMode)
+
+	engine := gin.New()
+	controller.RegisterAPI(engine.Group(""), engine.Group(""), nil, controller.APIDeps{Storage: storage, Events: hub}, false)
+
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+
+	apiClient, err := client.New(client.Options{URL: srv.URL, Timeout: time.Second})
+	require.NoError(t, err)
+
+	// events are differences of storage states, so each change is waited for
+
+	for i, change := range []func() error{
+		func() error {
+			return storage.VPPUDPTunnelStorage.AddUDPTunnel(&model.VPPUDPTunnel{TunnelID: 1, DstIP: "10.0.0.1"})
+		},
+		func() error { return storage.VPPUDPTunnelStorage.DelUDPTunnel("10.0.0.1") },
+		func() error {
+			return storage.VPPUDPTunnelStorage.AddUDPTunnel(&model.VPPUDPTunnel{TunnelID: 2, DstIP: "10.0.0.2"})
+		},
+	} {
+		require.NoError(t, change())
+		require.Eventually(t, func() bool { return hub.Last() == uint64(i+1) }, time.Second, 10*time.Millisecond)
+	}
+
+	// resumed after the sequence with the type filter, the stream is not limited by the client timeout
+
+	var received []events.Event
+
+	go func() {
+		time.Sleep(1500 * time.Millisecond)
+
+		_ = storage.VPPUDPTunnelStorage.DelUDPTunnel("10.0.0.2")
+	}()
+
+	query := url.Values{"since": {"0"}, "type": {string(events.TunnelDeleted)}}
+
+	err = apiClient.Events(context.Background(), query, func(event events.Event) error {
+		received = append(received, event)
+
+		if len(received) == 2 {
+			return errStop
+		}
+
+		return nil
+	})
+	require.ErrorIs(t, err, errStop)
+	require.Equal(t, "10.0.0.1", received[0].NextHop)
+	require.Equal(t, "10.0.0.2", received[1].NextHop)
+	require.Greater(t, received[1].Seq, received[0].Seq)
+
+	// unknown sequence and type
+
+	err = apiClient.Events(context.Background(), url.Values{"since": {"1000"}}, func(events.Event) error { return nil })
+	require.True(t, client.IsGone(err))
+
+	var apiError *client.Error
+
+	err = apiClient.Events(context.Background(), url.Values{"type": {"unknown"}}, func(events.Event) error { return nil })
+	require.ErrorAs(t, err, &apiError)
+	require.Equal(t, "bad_request", apiError.Code)
+}
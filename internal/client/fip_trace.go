@@ -0,0 +1,84 @@
+package client
+
+import (
+	"fmt"
+	"io"
+
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/service"
+)
+
+// WriteFIPTrace writes the floating ip trace in human readable form
+func WriteFIPTrace(w io.Writer, trace service.FIPTrace) {
+	fmt.Fprintf(w, "floating ip:  %s\n", trace.FIP)
+	fmt.Fprintf(w, "vrf:          %s (id %d, link up %t)\n", trace.VRFName, trace.VRFID, trace.VRFLinkUp)
+
+	fmt.Fprintln(w, "\nbgp paths from tungsten fabric:")
+
+	for _, path := range trace.BGPPaths {
+		fmt.Fprintf(w, "  peer %s  rd %s  label %d  next-hop %s  vrf %d  best %t\n",
+			path.Peer, path.RD, path.Label, path.NextHop, path.VRFID, path.Best)
+	}
+
+	fmt.Fprintln(w, "\nfloating ip route in memory storage:")
+
+	if trace.MemFIPRoute != nil {
+		fmt.Fprintf(w, "  next-hops %v  tunnels %v  labels %v\n",
+			trace.MemFIPRoute.NextHops, trace.MemFIPRoute.TunnelIDs, trace.MemFIPRoute.FIPMPLSLabels)
+	}
+
+	fmt.Fprintln(w, "\nfloating ip route in vpp:")
+
+	if trace.VPPFIPRoute != nil {
+		fmt.Fprintf(w, "  next-hops %v  tunnels %v  labels %v\n",
+			trace.VPPFIPRoute.NextHops, trace.VPPFIPRoute.TunnelIDs, trace.VPPFIPRoute.FIPMPLSLabels)
+	}
+
+	fmt.Fprintln(w, "\nudp tunnels:")
+
+	for _, tunnel := range trace.UDPTunnels {
+		fmt.Fprintf(w, "  %s  memory %s  vpp %s\n", tunnel.DstIP, tunnelString(tunnel.MemTunnel), tunnelString(tunnel.VPPTunnel))
+	}
+
+	fmt.Fprintln(w, "\naggregated prefixes to physical network:")
+
+	for _, aggr := range trace.AggrPrefixes {
+		fmt.Fprintf(w, "  %s  peer %s  advertised %t\n", aggr.Prefix, aggr.Peer, aggr.Advertised)
+	}
+
+	for _, e := range trace.Errors {
+		fmt.Fprintf(w, "\nERROR: %s", e)
+	}
+
+	if len(trace.Errors) != 0 {
+		fmt.Fprintln(w)
+	}
+
+	if len(trace.Notes) != 0 {
+		fmt.Fprintln(w, "\nnotes:")
+	}
+
+	for _, msg := range trace.Notes {
+		fmt.Fprintf(w, "  - %s\n", msg)
+	}
+
+	if len(trace.Inconsistencies) == 0 {
+		fmt.Fprintln(w, "\nno inconsistencies found")
+
+		return
+	}
+
+	fmt.Fprintln(w, "\nINCONSISTENCIES:")
+
+	for _, msg := range trace.Inconsistencies {
+		fmt.Fprintf(w, "  ! %s\n", msg)
+	}
+}
+
+func tunnelString(tunnel *model.VPPUDPTunnel) string {
+	if tunnel == nil {
+		return "absent"
+	}
+
+	return fmt.Sprintf("id %d %s:%d -> %s", tunnel.TunnelID, tunnel.SrcIP, tunnel.SrcPort, tunnel.DstIP)
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"reflect"
+
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
@@ -12,6 +14,13 @@ type Config struct { // https://yaml2go.prasadg.dev/
 	GoBGP        GoBGP        `yaml:"GoBGP" env-required:"true"`
 	VPP          VPP          `yaml:"VPP" env-required:"true"`
 	VRF          []VRF        `yaml:"VRF" env-required:"true"`
+	Snapshot     Snapshot     `yaml:"Snapshot"`
+	HA           HA           `yaml:"HA"`
+	Drain        Drain        `yaml:"Drain"`
+	Shutdown     Shutdown     `yaml:"Shutdown"`
+	BMP          BMP          `yaml:"BMP"`
+	MRT          MRT          `yaml:"MRT"`
+	Tracing      Tracing      `yaml:"Tracing"`
 }
 
 type Logging struct {
@@ -22,8 +31,154 @@ type Logging struct {
 }
 
 type HTTP struct {
-	Enable  bool   `yaml:"Enable" env-default:"false"`
-	Address string `yaml:"Address"`
+	Enable       bool         `yaml:"Enable" env-default:"false"`
+	Address      string       `yaml:"Address"`
+	UnixSocket   string       `yaml:"UnixSocket"` // http api is also served on the unix socket (without tls) if set
+	TLS          TLS          `yaml:"TLS"`
+	Auth         Auth         `yaml:"Auth"`
+	Health       Health       `yaml:"Health"`
+	Admin        Admin        `yaml:"Admin"`
+	Events       Events       `yaml:"Events"`
+	NodeExporter NodeExporter `yaml:"NodeExporter"`
+}
+
+// NodeExporter is the node_exporter collectors of host metrics exposed on /metrics with cloudgw metrics
+type NodeExporter struct {
+	Enable     bool     `yaml:"Enable" env-default:"false"`
+	Collectors []string `yaml:"Collectors"` // e.g. cpu, meminfo, systemd; collectors enabled by default if empty
+}
+
+type TLS struct {
+	Enable         bool   `yaml:"Enable" env-default:"false"`
+	CertFile       string `yaml:"CertFile"`
+	KeyFile        string `yaml:"KeyFile"`
+	ClientCAFile   string `yaml:"ClientCAFile"`                    // client certificates are required and verified (mtls) if set
+	ReloadInterval int    `yaml:"ReloadInterval" env-default:"60"` // sec, interval of cert and key files change check
+}
+
+type Auth struct {
+	Enable        bool    `yaml:"Enable" env-default:"false"` // bearer token authentication of http api
+	Tokens        []Token `yaml:"Tokens"`
+	MetricsNoAuth bool    `yaml:"MetricsNoAuth"` // /metrics is available without token (prometheus)
+	HealthNoAuth  bool    `yaml:"HealthNoAuth"`  // /health, /health/live and /health/ready are available without token (probes)
+}
+
+type Token struct {
+	Name  string `yaml:"Name"` // actor name in audit log
+	Token string `yaml:"Token"`
+	Role  string `yaml:"Role"` // read-only (default) or admin
+}
+
+type Admin struct {
+	Enable       bool   `yaml:"Enable" env-default:"false"` // administrative write api (/admin/...)
+	Token        string `yaml:"Token"`                      // bearer token with admin role in addition to HTTP.Auth.Tokens
+	AuditLogPath string `yaml:"AuditLogPath"`               // json lines audit log file (app log only if empty)
+}
+
+type Events struct {
+	Enable bool `yaml:"Enable" env-default:"false"` // state change events are streamed at /api/v1/events
+	Buffer int  `yaml:"Buffer" env-default:"10000"` // last events kept to resume a stream from a sequence
+}
+
+type Health struct {
+	TFPeersMin         int  `yaml:"TFPeersMin" env-default:"1"`          // established tungsten fabric peers needed for readiness
+	PhyNetPeersMin     int  `yaml:"PhyNetPeersMin"`                      // established physical network peers needed for readiness
+	EndOfRIBIgnore     bool `yaml:"EndOfRIBIgnore"`                      // do not wait for end-of-rib from tungsten fabric
+	BFDIgnore          bool `yaml:"BFDIgnore"`                           // do not check bfd sessions state
+	DriftCheckInterval int  `yaml:"DriftCheckInterval" env-default:"30"` // sec, dataplane drift check interval
+	DriftMax           int  `yaml:"DriftMax"`                            // max difference of floating ip routes and tunnels in memory and vpp
+}
+
+type Snapshot struct {
+	Enable    bool   `yaml:"Enable" env-default:"false"`                        // state snapshot is written periodically and on shutdown
+	Path      string `yaml:"Path" env-default:"/var/lib/cloudgw/snapshot.json"` // replaced atomically on each write
+	Interval  int    `yaml:"Interval" env-default:"60"`                         // sec, 0 - written on shutdown only
+	WarmStart bool   `yaml:"WarmStart" env-default:"true"`                      // udp tunnels of the snapshot are re-created on start
+}
+
+// standby modes of ha pair (HA.StandbyMode)
+const (
+	HAStandbyPrepend   = "prepend"
+	HAStandbyLocalPref = "local-pref"
+	HAStandbyWithdraw  = "withdraw"
+)
+
+// HA is active/standby pair of cloudgw instances: both learn floating ips from tungsten fabric and program vpp, the
+// standby advertises aggregated floating ip prefixes to physical network with StandbyMode attributes
+type HA struct {
+	Enable            bool   `yaml:"Enable" env-default:"false"`
+	NodeID            string `yaml:"NodeID"`                              // instance name, hostname if empty
+	Listen            string `yaml:"Listen" env-default:":9179"`          // udp address heartbeats are received on
+	Peer              string `yaml:"Peer"`                                // udp address of the other instance
+	Key               string `yaml:"Key"`                                 // shared secret, heartbeats are signed by hmac-sha256 if set
+	Priority          int    `yaml:"Priority" env-default:"100"`          // the healthy instance with higher priority is active
+	Preempt           bool   `yaml:"Preempt"`                             // higher priority instance takes the active role back
+	HeartbeatInterval int    `yaml:"HeartbeatInterval" env-default:"300"` // ms
+	DeadInterval      int    `yaml:"DeadInterval" env-default:"1000"`     // ms without heartbeats the peer is considered failed
+	StandbyMode       string `yaml:"StandbyMode" env-default:"prepend"`   // prepend, local-pref or withdraw
+	StandbyPrepend    int    `yaml:"StandbyPrepend" env-default:"3"`      // times local asn is prepended to as path (prepend)
+	StandbyLocalPref  uint32 `yaml:"StandbyLocalPref" env-default:"50"`   // local preference, ibgp physical network peers (local-pref)
+}
+
+// attributes aggregated floating ip prefixes are advertised with while the gateway is drained (Drain.Methods)
+const (
+	DrainPrepend = "prepend"
+	DrainMED     = "med"
+	DrainGShut   = "gshut"
+)
+
+// Drain is the gateway drain for maintenance: aggregated floating ip prefixes are advertised with Methods attributes
+// until traffic of vrf sub-interfaces falls below TrafficThreshold, then they are withdrawn
+type Drain struct {
+	Methods          []string `yaml:"Methods" env-default:"gshut"`        // prepend, med and gshut (community 65535:0)
+	Prepend          int      `yaml:"Prepend" env-default:"3"`            // times local asn is prepended to as path (prepend)
+	MED              uint32   `yaml:"MED" env-default:"1000"`             // multi exit discriminator (med)
+	TrafficThreshold uint64   `yaml:"TrafficThreshold" env-default:"100"` // pps, received and sent by vrf sub-interfaces
+	CheckInterval    int      `yaml:"CheckInterval" env-default:"5"`      // sec, interval of traffic counters check
+	Timeout          int      `yaml:"Timeout" env-default:"300"`          // sec, prefixes are withdrawn after it regardless of traffic
+	OnShutdown       bool     `yaml:"OnShutdown"`                         // SIGTERM and SIGINT drain the gateway before shutdown
+}
+
+// Shutdown is the phased shutdown: aggregated floating ip prefixes are withdrawn and the state snapshot is saved,
+// withdrawals propagate for PropagationDelay, then bgp and bfd sessions are closed and vpp connections released
+type Shutdown struct {
+	PropagationDelay int `yaml:"PropagationDelay" env-default:"2"` // sec, waited between withdrawal and closing bgp sessions
+	PhaseTimeout     int `yaml:"PhaseTimeout" env-default:"10"`    // sec, deadline of each shutdown phase
+}
+
+// route monitoring of bmp stations (BMP.Policy)
+const (
+	BMPPolicyPre   = "pre"   // pre-policy adj-rib-in
+	BMPPolicyPost  = "post"  // post-policy adj-rib-in
+	BMPPolicyLocal = "local" // loc-rib
+	BMPPolicyAll   = "all"   // pre-policy and post-policy adj-rib-in and loc-rib
+)
+
+// BMP is the bgp monitoring protocol (rfc 7854) export of gobgp peers (tungsten fabric and physical network) to stations
+type BMP struct {
+	Stations           []string `yaml:"Stations"`                            // address:port of bmp stations
+	Policy             string   `yaml:"Policy" env-default:"all"`            // pre, post, local or all
+	StatisticsInterval int      `yaml:"StatisticsInterval" env-default:"60"` // sec, interval of statistics reports, 0 disables them
+	SysName            string   `yaml:"SysName" env-default:"cloudgw"`       // sysName of initiation messages
+}
+
+// MRT is the dump of bgp updates received from all peers and periodic rib snapshots to mrt (rfc 6396) files of Dir,
+// the files are rotated and can be replayed offline by cloudgw replay
+type MRT struct {
+	Enable            bool   `yaml:"Enable" env-default:"false"`
+	Dir               string `yaml:"Dir" env-default:"/var/lib/cloudgw/mrt"`
+	RotationInterval  int    `yaml:"RotationInterval" env-default:"900"`   // sec, a new updates file is started after it
+	TableDumpInterval int    `yaml:"TableDumpInterval" env-default:"3600"` // sec, interval of rib snapshots, 0 disables them
+}
+
+// Tracing is the opentelemetry tracing of bgp updates from tungsten fabric to vpp fib (parsing, storage lookups, vpp
+// binary api calls and aggregated prefixes advertisement), spans are exported to otlp grpc collector
+type Tracing struct {
+	Enable      bool    `yaml:"Enable" env-default:"false"`
+	Endpoint    string  `yaml:"Endpoint" env-default:"localhost:4317"` // host:port of otlp grpc collector
+	TLS         bool    `yaml:"TLS" env-default:"false"`               // the collector is connected with tls
+	SampleRatio float64 `yaml:"SampleRatio" env-default:"1"`           // ratio of recorded traces, (0, 1]
+	ServiceName string  `yaml:"ServiceName" env-default:"cloudgw"`
 }
 
 type Pyroscope struct {
@@ -48,31 +203,51 @@ type GoBGP struct {
 }
 
 type VPP struct {
-	BinAPISock             string `yaml:"BinAPISock" env-default:"/home/enikolaev/vpp_api.sock"`
-	MainInterfaceID        uint32 `yaml:"MainInterfaceID" env-required:"true"`
-	TunLocalIP             string `yaml:"TunLocalIP" env-required:"true"`
-	TunDefaultGW           string `yaml:"TunDefaultGW" env-required:"true"`
-	InterfaceMonitorEnable bool   `yaml:"InterfaceMonitorEnable"`
-	MetricPollingInterval  int    `yaml:"MetricPollingInterval"`
+	BinAPISock             string          `yaml:"BinAPISock" env-default:"/home/enikolaev/vpp_api.sock"`
+	MainInterfaceID        uint32          `yaml:"MainInterfaceID" env-required:"true"`
+	TunLocalIP             string          `yaml:"TunLocalIP" env-required:"true"`
+	TunDefaultGW           string          `yaml:"TunDefaultGW" env-required:"true"`
+	InterfaceMonitorEnable bool            `yaml:"InterfaceMonitorEnable"`
+	MetricPollingInterval  int             `yaml:"MetricPollingInterval"`
+	TunnelProbe            TunnelProbe     `yaml:"TunnelProbe"`
+	TrafficCounters        TrafficCounters `yaml:"TrafficCounters"`
+	DryRun                 bool            `yaml:"DryRun"` // vpp is not connected, requests are recorded and exposed over http
+}
+
+type TunnelProbe struct {
+	Enable    bool   `yaml:"Enable" env-default:"false"`
+	Interval  int    `yaml:"Interval" env-default:"1000"` // msec, interval between probes of a vrouter
+	Count     uint32 `yaml:"Count" env-default:"3"`       // icmp echo requests per probe
+	Threshold int    `yaml:"Threshold" env-default:"3"`   // consecutive failed (successful) probes to mark vrouter unreachable (reachable)
+	Workers   int    `yaml:"Workers" env-default:"4"`     // vrouters probed concurrently, each on its own vpp api connection
+}
+
+// TrafficCounters is per floating ip and per udp tunnel counters read from vpp stats every MetricPollingInterval
+type TrafficCounters struct {
+	Enable    bool `yaml:"Enable" env-default:"false"`
+	MaxSeries int  `yaml:"MaxSeries" env-default:"1000"` // floating ips (and tunnels) exposed as metrics, the others are on /vpp/fips only
+	TopN      int  `yaml:"TopN" env-default:"0"`         // only N floating ips (and tunnels) with the most bytes per interval are exposed, 0 - disabled
 }
 
 type VRF struct {
-	FIPPrefixes   []string `yaml:"FIPPrefixes" env-required:"true"`
-	VRFName       string   `yaml:"VRFName" env-required:"true"`
-	VRFID         uint32   `yaml:"VRFID" env-required:"true"`
-	LocalIP       string   `yaml:"LocalIP" env-required:"true"`
-	VLANID        uint32   `yaml:"VLANID" env-required:"true"`
-	BGPPeerIP     string   `yaml:"BGPPeerIP" env-required:"true"`
-	BGPPeerASN    uint32   `yaml:"BGPPeerASN" env-required:"true"`
-	BGPTTL        uint32   `yaml:"BGPTTL" env-required:"true"`
-	BGPKeepAlive  uint64   `yaml:"BGPKeepAlive" env-required:"true"`
-	BGPHoldTimer  uint64   `yaml:"BGPHoldTimer" env-required:"true"`
-	BGPPassword   string   `yaml:"BGPPassword"`
-	BFDEnable     bool     `yaml:"BFDEnable"`
-	BFDLocalIP    string   `yaml:"BFDLocalIP"`
-	BFDTxRate     int      `yaml:"BFDTxRate"`
-	BFDRxMin      int      `yaml:"BFDRxMin"`
-	BFDMultiplier int      `yaml:"BFDMultiplier"`
+	FIPPrefixes     []string `yaml:"FIPPrefixes" env-required:"true"`
+	VRFName         string   `yaml:"VRFName" env-required:"true"`
+	VRFID           uint32   `yaml:"VRFID" env-required:"true"`
+	LocalIP         string   `yaml:"LocalIP" env-required:"true"`
+	VLANID          uint32   `yaml:"VLANID" env-required:"true"`
+	BGPPeerIP       string   `yaml:"BGPPeerIP" env-required:"true"`
+	BGPPeerASN      uint32   `yaml:"BGPPeerASN" env-required:"true"`
+	BGPTTL          uint32   `yaml:"BGPTTL" env-required:"true"`
+	BGPKeepAlive    uint64   `yaml:"BGPKeepAlive" env-required:"true"`
+	BGPHoldTimer    uint64   `yaml:"BGPHoldTimer" env-required:"true"`
+	BGPPassword     string   `yaml:"BGPPassword"`
+	BFDEnable       bool     `yaml:"BFDEnable"`
+	BFDLocalIP      string   `yaml:"BFDLocalIP"`
+	BFDTxRate       int      `yaml:"BFDTxRate"`
+	BFDRxMin        int      `yaml:"BFDRxMin"`
+	BFDMultiplier   int      `yaml:"BFDMultiplier"`
+	BFDEchoEnable   bool     `yaml:"BFDEchoEnable"`
+	BFDEchoInterval int      `yaml:"BFDEchoInterval" env-default:"50"`
 }
 
 func ParseConfig(configPath string) (*Config, error) {
@@ -84,3 +259,29 @@ func ParseConfig(configPath string) (*Config, error) {
 
 	return cfg, nil
 }
+
+// Changes returns paths of config fields changed in the reloaded config, e.g. "Logging.Level" or "HTTP.Auth.Tokens".
+// Structs are compared field by field, other fields (including lists) are compared as a whole.
+func Changes(old, changed *Config) []string {
+	return changes("", reflect.ValueOf(*old), reflect.ValueOf(*changed))
+}
+
+func changes(prefix string, old, changed reflect.Value) []string {
+	var paths []string
+
+	for i := range old.NumField() {
+		path := prefix + old.Type().Field(i).Name
+
+		if old.Field(i).Kind() == reflect.Struct {
+			paths = append(paths, changes(path+".", old.Field(i), changed.Field(i))...)
+
+			continue
+		}
+
+		if !reflect.DeepEqual(old.Field(i).Interface(), changed.Field(i).Interface()) {
+			paths = append(paths, path)
+		}
+	}
+
+	return paths
+}
@@ -36,6 +36,14 @@ func TestParseConfig(t *testing.T) {
 			require.Equal(t, 3, len(got.VRF))
 
 			require.True(t, got.HTTP.Enable)
+			require.False(t, got.HTTP.TLS.Enable)
+			require.Equal(t, 60, got.HTTP.TLS.ReloadInterval)
+			require.True(t, got.HTTP.Auth.Enable)
+			require.True(t, got.HTTP.Auth.MetricsNoAuth)
+			require.Equal(t, []Token{
+				{Name: "monitoring", Token: "read-secret"},
+				{Name: "noc", Token: "admin-secret", Role: "admin"},
+			}, got.HTTP.Auth.Tokens)
 			require.False(t, got.Pyroscope.Enable)
 
 			require.Equal(t, uint64(1), got.TFController.BGPKeepAlive)
@@ -46,3 +54,19 @@ func TestParseConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestChanges(t *testing.T) {
+	old, err := ParseConfig("config_test.yml")
+	require.NoError(t, err)
+
+	changed, err := ParseConfig("config_test.yml")
+	require.NoError(t, err)
+
+	require.Empty(t, Changes(old, changed))
+
+	changed.Logging.Level = "debug"
+	changed.HTTP.Auth.Tokens = append(changed.HTTP.Auth.Tokens, Token{Name: "ops", Token: "ops-secret"})
+	changed.VRF[0].BGPPeerIP = "203.0.113.100"
+
+	require.Equal(t, []string{"Logging.Level", "HTTP.Auth.Tokens", "VRF"}, Changes(old, changed))
+}
@@ -0,0 +1,351 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/netip"
+	"slices"
+	"time"
+
+	"git.crptech.ru/cloud/cloudgw/pkg/netutils"
+)
+
+const (
+	vlanIDMin = 1
+	vlanIDMax = 4094
+
+	minMRTInterval = 60 // sec
+
+	bfdEchoIntervalMin = 10    // msec
+	bfdEchoIntervalMax = 10000 // msec
+
+	tunnelProbeWorkersMax = 64 // vpp api connections
+)
+
+// FatalError is the validation error of the setting the app would crash with (e.g. zero interval of a timer), the app
+// is not started with it unlike the other problems
+type FatalError struct {
+	Err error
+}
+
+func (e *FatalError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FatalError) Unwrap() error {
+	return e.Err
+}
+
+type vrfPrefix struct {
+	prefix netip.Prefix
+	path   string
+}
+
+// Validate checks semantic of the config (addresses, subnets, identifiers and labels uniqueness) that is not checked
+// on parsing and returns all found problems
+func Validate(cfg *Config) []error {
+	var errs []error
+
+	addErr := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	addFatal := func(format string, args ...any) {
+		errs = append(errs, &FatalError{Err: fmt.Errorf(format, args...)})
+	}
+
+	// gobgp
+
+	if rid, err := netip.ParseAddr(cfg.GoBGP.RID); err != nil || !rid.Is4() {
+		addErr("GoBGP.RID %q is not an ipv4 address", cfg.GoBGP.RID)
+	}
+
+	// vpp main interface
+
+	tunLocalIP, err := netip.ParsePrefix(cfg.VPP.TunLocalIP)
+	if err != nil {
+		addErr("VPP.TunLocalIP %q is not an address with prefix length, e.g. 192.0.2.1/24", cfg.VPP.TunLocalIP)
+	}
+
+	tunDefaultGW, err := netip.ParseAddr(cfg.VPP.TunDefaultGW)
+
+	switch {
+	case err != nil:
+		addErr("VPP.TunDefaultGW %q is not an ip address", cfg.VPP.TunDefaultGW)
+	case tunLocalIP.IsValid() && !tunLocalIP.Masked().Contains(tunDefaultGW):
+		addErr("VPP.TunDefaultGW %s is outside of VPP.TunLocalIP subnet %s", tunDefaultGW, tunLocalIP.Masked())
+	case tunLocalIP.IsValid() && tunLocalIP.Addr() == tunDefaultGW:
+		addErr("VPP.TunDefaultGW %s is the same as VPP.TunLocalIP address", tunDefaultGW)
+	}
+
+	// tungsten fabric controllers
+
+	peerIPs := make(map[netip.Addr]string) // bgp peer address -> config path
+
+	for i, address := range cfg.TFController.Address {
+		path := fmt.Sprintf("TFController.Address[%d]", i)
+
+		ip, err := netip.ParseAddr(address)
+		if err != nil {
+			addErr("%s %q is not an ip address", path, address)
+
+			continue
+		}
+
+		if prevPath, ok := peerIPs[ip]; ok {
+			addErr("%s %s is already used by %s", path, ip, prevPath)
+
+			continue
+		}
+
+		peerIPs[ip] = path
+	}
+
+	// vrfs
+
+	if len(cfg.VRF) == 0 {
+		addErr("VRF: at least one vrf is needed")
+	}
+
+	var (
+		names       = make(map[string]string)
+		vrfIDs      = make(map[uint32]string)
+		vlanIDs     = make(map[uint32]string)
+		mplsLabels  = make(map[uint32]string)
+		fipPrefixes []vrfPrefix // floating ip prefixes of checked vrfs
+	)
+
+	for i, vrf := range cfg.VRF {
+		path := fmt.Sprintf("VRF[%d] %q", i, vrf.VRFName)
+
+		if vrf.VRFName == "" {
+			addErr("%s: VRFName is empty", path)
+		} else if prevPath, ok := names[vrf.VRFName]; ok {
+			addErr("%s: VRFName is already used by %s", path, prevPath)
+		} else {
+			names[vrf.VRFName] = path
+		}
+
+		// vrf id 0 is the vpp global routing table
+
+		if vrf.VRFID == 0 {
+			addErr("%s: VRFID 0 is reserved for the global routing table", path)
+		} else if prevPath, ok := vrfIDs[vrf.VRFID]; ok {
+			addErr("%s: VRFID %d is already used by %s", path, vrf.VRFID, prevPath)
+		} else {
+			vrfIDs[vrf.VRFID] = path
+		}
+
+		if vrf.VLANID < vlanIDMin || vrf.VLANID > vlanIDMax {
+			addErr("%s: VLANID %d is out of range %d-%d", path, vrf.VLANID, vlanIDMin, vlanIDMax)
+		} else if prevPath, ok := vlanIDs[vrf.VLANID]; ok {
+			addErr("%s: VLANID %d is already used by %s", path, vrf.VLANID, prevPath)
+		} else {
+			vlanIDs[vrf.VLANID] = path
+		}
+
+		// local address, bgp and bfd peering
+
+		localIP, err := netip.ParsePrefix(vrf.LocalIP)
+		if err != nil {
+			addErr("%s: LocalIP %q is not an address with prefix length, e.g. 192.0.2.1/24", path, vrf.LocalIP)
+		}
+
+		bgpPeerIP, err := netip.ParseAddr(vrf.BGPPeerIP)
+
+		switch {
+		case err != nil:
+			addErr("%s: BGPPeerIP %q is not an ip address", path, vrf.BGPPeerIP)
+		case localIP.IsValid() && !localIP.Masked().Contains(bgpPeerIP):
+			addErr("%s: BGPPeerIP %s is outside of LocalIP subnet %s", path, bgpPeerIP, localIP.Masked())
+		case localIP.IsValid() && localIP.Addr() == bgpPeerIP:
+			addErr("%s: BGPPeerIP %s is the same as LocalIP address", path, bgpPeerIP)
+		}
+
+		if bgpPeerIP.IsValid() {
+			if prevPath, ok := peerIPs[bgpPeerIP]; ok {
+				addErr("%s: BGPPeerIP %s is already used by %s", path, bgpPeerIP, prevPath)
+			} else {
+				peerIPs[bgpPeerIP] = path
+			}
+		}
+
+		if vrf.BFDEnable {
+			// bfd control packets are sent from the local address of the sub-interface
+			bfdLocalIP, err := netip.ParseAddr(vrf.BFDLocalIP)
+
+			switch {
+			case err != nil:
+				addErr("%s: BFDLocalIP %q is not an ip address", path, vrf.BFDLocalIP)
+			case localIP.IsValid() && localIP.Addr() != bfdLocalIP:
+				addErr("%s: BFDLocalIP %s does not match LocalIP address %s", path, bfdLocalIP, localIP.Addr())
+			}
+
+			if vrf.BFDTxRate <= 0 || vrf.BFDRxMin <= 0 || vrf.BFDMultiplier <= 0 {
+				addErr("%s: BFDTxRate, BFDRxMin and BFDMultiplier must be positive", path)
+			}
+
+			if vrf.BFDEchoEnable && (vrf.BFDEchoInterval < bfdEchoIntervalMin || vrf.BFDEchoInterval > bfdEchoIntervalMax) {
+				addErr("%s: BFDEchoInterval %d must be between %d and %d", path, vrf.BFDEchoInterval, bfdEchoIntervalMin, bfdEchoIntervalMax)
+			}
+		}
+
+		// mpls local label is derived from the local address
+
+		if localIP.IsValid() {
+			mplsLabel, err := netutils.MPLSLabel(vrf.LocalIP)
+
+			switch {
+			case err != nil:
+				addErr("%s: failed to derive mpls local label from LocalIP %s: %w", path, vrf.LocalIP, err)
+			case mplsLabels[mplsLabel] != "":
+				addErr("%s: mpls local label %d derived from LocalIP %s is already used by %s",
+					path, mplsLabel, vrf.LocalIP, mplsLabels[mplsLabel])
+			default:
+				mplsLabels[mplsLabel] = path
+			}
+		}
+
+		// floating ip prefixes
+
+		if len(vrf.FIPPrefixes) == 0 {
+			addErr("%s: FIPPrefixes is empty", path)
+		}
+
+		for _, fipPrefix := range vrf.FIPPrefixes {
+			prefix, err := netip.ParsePrefix(fipPrefix)
+			if err != nil {
+				addErr("%s: FIPPrefixes %q is not a prefix", path, fipPrefix)
+
+				continue
+			}
+
+			if prefix != prefix.Masked() {
+				addErr("%s: FIPPrefixes %s has host bits set, use %s", path, prefix, prefix.Masked())
+
+				continue
+			}
+
+			for _, prev := range fipPrefixes {
+				if prev.prefix.Overlaps(prefix) {
+					addErr("%s: FIPPrefixes %s overlaps with %s of %s", path, prefix, prev.prefix, prev.path)
+				}
+			}
+
+			fipPrefixes = append(fipPrefixes, vrfPrefix{prefix: prefix, path: path})
+		}
+	}
+
+	// ha pair
+
+	if cfg.HA.Enable {
+		if _, err := netip.ParseAddrPort(cfg.HA.Peer); err != nil {
+			addErr("HA.Peer %q is not an address with port, e.g. 192.0.2.2:9179", cfg.HA.Peer)
+		}
+
+		if !slices.Contains([]string{HAStandbyPrepend, HAStandbyLocalPref, HAStandbyWithdraw}, cfg.HA.StandbyMode) {
+			addErr("HA.StandbyMode %q is unknown, expected %s, %s or %s",
+				cfg.HA.StandbyMode, HAStandbyPrepend, HAStandbyLocalPref, HAStandbyWithdraw)
+		}
+
+		if cfg.HA.HeartbeatInterval <= 0 || cfg.HA.DeadInterval <= cfg.HA.HeartbeatInterval {
+			addFatal("HA.DeadInterval %d must be greater than positive HA.HeartbeatInterval %d",
+				cfg.HA.DeadInterval, cfg.HA.HeartbeatInterval)
+		}
+	}
+
+	// gateway drain
+
+	for _, method := range cfg.Drain.Methods {
+		if !slices.Contains([]string{DrainPrepend, DrainMED, DrainGShut}, method) {
+			addErr("Drain.Methods %q is unknown, expected %s, %s or %s", method, DrainPrepend, DrainMED, DrainGShut)
+		}
+	}
+
+	if cfg.Drain.CheckInterval <= 0 || cfg.Drain.Timeout < 0 {
+		addFatal("Drain.CheckInterval %d must be positive and Drain.Timeout %d not negative", cfg.Drain.CheckInterval, cfg.Drain.Timeout)
+	}
+
+	// bmp stations
+
+	for _, station := range cfg.BMP.Stations {
+		if _, err := netip.ParseAddrPort(station); err != nil {
+			addErr("BMP.Stations %q is not an address with port, e.g. 192.0.2.50:11019", station)
+		}
+	}
+
+	if !slices.Contains([]string{BMPPolicyPre, BMPPolicyPost, BMPPolicyLocal, BMPPolicyAll}, cfg.BMP.Policy) {
+		addErr("BMP.Policy %q is unknown, expected %s, %s, %s or %s",
+			cfg.BMP.Policy, BMPPolicyPre, BMPPolicyPost, BMPPolicyLocal, BMPPolicyAll)
+	}
+
+	if cfg.BMP.StatisticsInterval < 0 || cfg.BMP.StatisticsInterval > math.MaxUint16 {
+		addErr("BMP.StatisticsInterval %d must be between 0 and %d", cfg.BMP.StatisticsInterval, math.MaxUint16)
+	}
+
+	// mrt dump, gobgp does not rotate files more often than once a minute
+
+	if cfg.MRT.Enable {
+		// mrt file names are time layouts, layout elements of the directory (digits, month and day names) are replaced
+
+		if cfg.MRT.Dir == "" || time.Date(1999, 12, 31, 23, 59, 58, 0, time.UTC).Format(cfg.MRT.Dir) != cfg.MRT.Dir {
+			addErr("MRT.Dir %q must be set without digits, month and day names", cfg.MRT.Dir)
+		}
+
+		if cfg.MRT.RotationInterval < minMRTInterval {
+			addErr("MRT.RotationInterval %d must be at least %d", cfg.MRT.RotationInterval, minMRTInterval)
+		}
+
+		if cfg.MRT.TableDumpInterval != 0 && cfg.MRT.TableDumpInterval < minMRTInterval {
+			addErr("MRT.TableDumpInterval %d must be 0 or at least %d", cfg.MRT.TableDumpInterval, minMRTInterval)
+		}
+	}
+
+	// per floating ip and per tunnel counters, series are capped for large floating ip pools
+
+	if cfg.VPP.TrafficCounters.Enable {
+		if cfg.VPP.TrafficCounters.MaxSeries <= 0 {
+			addErr("VPP.TrafficCounters.MaxSeries %d must be positive", cfg.VPP.TrafficCounters.MaxSeries)
+		}
+
+		if cfg.VPP.TrafficCounters.TopN < 0 || cfg.VPP.TrafficCounters.TopN > cfg.VPP.TrafficCounters.MaxSeries {
+			addErr("VPP.TrafficCounters.TopN %d must be between 0 and VPP.TrafficCounters.MaxSeries %d",
+				cfg.VPP.TrafficCounters.TopN, cfg.VPP.TrafficCounters.MaxSeries)
+		}
+	}
+
+	// vrouter probing, every worker is a separate vpp api client
+
+	if probe := cfg.VPP.TunnelProbe; probe.Enable {
+		if probe.Interval <= 0 || probe.Count == 0 || probe.Threshold <= 0 {
+			addFatal("VPP.TunnelProbe.Interval %d, VPP.TunnelProbe.Count %d and VPP.TunnelProbe.Threshold %d must be positive",
+				probe.Interval, probe.Count, probe.Threshold)
+		}
+
+		if probe.Workers < 1 || probe.Workers > tunnelProbeWorkersMax {
+			addFatal("VPP.TunnelProbe.Workers %d must be between 1 and %d", probe.Workers, tunnelProbeWorkersMax)
+		}
+	}
+
+	// opentelemetry tracing
+
+	if cfg.Tracing.Enable {
+		if _, _, err := net.SplitHostPort(cfg.Tracing.Endpoint); err != nil {
+			addErr("Tracing.Endpoint %q is not a host with port, e.g. localhost:4317", cfg.Tracing.Endpoint)
+		}
+
+		if cfg.Tracing.SampleRatio <= 0 || cfg.Tracing.SampleRatio > 1 {
+			addErr("Tracing.SampleRatio %g must be in (0, 1]", cfg.Tracing.SampleRatio)
+		}
+	}
+
+	// phased shutdown, the propagation delay is a phase too
+
+	if cfg.Shutdown.PropagationDelay < 0 || cfg.Shutdown.PhaseTimeout <= cfg.Shutdown.PropagationDelay {
+		addErr("Shutdown.PhaseTimeout %d must be greater than not negative Shutdown.PropagationDelay %d",
+			cfg.Shutdown.PhaseTimeout, cfg.Shutdown.PropagationDelay)
+	}
+
+	return errs
+}
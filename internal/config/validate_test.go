@@ -0,0 +1,233 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(cfg *Config)
+		want   []string
+	}{
+		{
+			name:   "valid config",
+			change: func(*Config) {},
+		},
+		{
+			name: "overlapping floating ip prefixes",
+			change: func(cfg *Config) {
+				cfg.VRF[1].FIPPrefixes = []string{"172.16.1.128/25", "172.16.2.0/24"}
+				cfg.VRF[2].FIPPrefixes = []string{"172.16.4.1/24"}
+			},
+			want: []string{
+				`VRF[1] "vrf2": FIPPrefixes 172.16.1.128/25 overlaps with 172.16.1.0/24 of VRF[0] "vrf1"`,
+				`VRF[2] "vrf3": FIPPrefixes 172.16.4.1/24 has host bits set, use 172.16.4.0/24`,
+			},
+		},
+		{
+			name: "duplicate identifiers",
+			change: func(cfg *Config) {
+				cfg.VRF[1].VRFName = "vrf1"
+				cfg.VRF[1].VRFID = 1
+				cfg.VRF[2].VLANID = 10
+				cfg.VRF[2].VRFID = 0
+			},
+			want: []string{
+				`VRF[1] "vrf1": VRFName is already used by VRF[0] "vrf1"`,
+				`VRF[1] "vrf1": VRFID 1 is already used by VRF[0] "vrf1"`,
+				`VRF[2] "vrf3": VRFID 0 is reserved for the global routing table`,
+				`VRF[2] "vrf3": VLANID 10 is already used by VRF[0] "vrf1"`,
+			},
+		},
+		{
+			name: "peering addresses",
+			change: func(cfg *Config) {
+				cfg.VRF[0].BGPPeerIP = "192.0.2.254"
+				cfg.VRF[1].BFDEnable = true
+				cfg.VRF[1].BFDLocalIP = "192.0.2.3"
+				cfg.VRF[1].BFDEchoEnable = true
+				cfg.VRF[1].BFDEchoInterval = 0
+				cfg.VRF[2].BGPPeerIP = "192.0.0.11"
+				cfg.VRF[2].LocalIP = "192.0.0.12/24"
+			},
+			want: []string{
+				`VRF[0] "vrf1": BGPPeerIP 192.0.2.254 is outside of LocalIP subnet 192.0.1.0/24`,
+				`VRF[1] "vrf2": BFDLocalIP 192.0.2.3 does not match LocalIP address 192.0.2.2`,
+				`VRF[1] "vrf2": BFDEchoInterval 0 must be between 10 and 10000`,
+				`VRF[2] "vrf3": BGPPeerIP 192.0.0.11 is already used by TFController.Address[0]`,
+			},
+		},
+		{
+			name: "mpls label collision",
+			change: func(cfg *Config) {
+				// 10.2.0.11 and 102.0.1.1 give the same label
+				cfg.VRF[0].LocalIP, cfg.VRF[0].BGPPeerIP = "10.2.0.11/24", "10.2.0.1"
+				cfg.VRF[1].LocalIP, cfg.VRF[1].BGPPeerIP = "102.0.1.1/24", "102.0.1.2"
+			},
+			want: []string{
+				`VRF[1] "vrf2": mpls local label 1002011 derived from LocalIP 102.0.1.1/24 is already used by VRF[0] "vrf1"`,
+			},
+		},
+		{
+			name: "tunnel default gateway",
+			change: func(cfg *Config) {
+				cfg.VPP.TunDefaultGW = "192.0.1.254"
+				cfg.GoBGP.RID = "router-1"
+			},
+			want: []string{
+				`GoBGP.RID "router-1" is not an ipv4 address`,
+				`VPP.TunDefaultGW 192.0.1.254 is outside of VPP.TunLocalIP subnet 192.0.0.0/24`,
+			},
+		},
+		{
+			name: "ha pair",
+			change: func(cfg *Config) {
+				cfg.HA.Enable = true
+				cfg.HA.Peer = "192.0.0.2"
+				cfg.HA.StandbyMode = "none"
+				cfg.HA.DeadInterval = cfg.HA.HeartbeatInterval
+			},
+			want: []string{
+				`HA.Peer "192.0.0.2" is not an address with port, e.g. 192.0.2.2:9179`,
+				`HA.StandbyMode "none" is unknown, expected prepend, local-pref or withdraw`,
+				`HA.DeadInterval 300 must be greater than positive HA.HeartbeatInterval 300`,
+			},
+		},
+		{
+			name: "gateway drain",
+			change: func(cfg *Config) {
+				cfg.Drain.Methods = []string{DrainGShut, "as-path"}
+				cfg.Drain.CheckInterval = 0
+			},
+			want: []string{
+				`Drain.Methods "as-path" is unknown, expected prepend, med or gshut`,
+				`Drain.CheckInterval 0 must be positive and Drain.Timeout 300 not negative`,
+			},
+		},
+		{
+			name: "phased shutdown",
+			change: func(cfg *Config) {
+				cfg.Shutdown.PropagationDelay = 10
+			},
+			want: []string{
+				`Shutdown.PhaseTimeout 10 must be greater than not negative Shutdown.PropagationDelay 10`,
+			},
+		},
+		{
+			name: "bmp stations",
+			change: func(cfg *Config) {
+				cfg.BMP.Stations = []string{"192.0.2.50:11019", "192.0.2.51"}
+				cfg.BMP.Policy = "both"
+				cfg.BMP.StatisticsInterval = -1
+			},
+			want: []string{
+				`BMP.Stations "192.0.2.51" is not an address with port, e.g. 192.0.2.50:11019`,
+				`BMP.Policy "both" is unknown, expected pre, post, local or all`,
+				`BMP.StatisticsInterval -1 must be between 0 and 65535`,
+			},
+		},
+		{
+			name: "mrt dump",
+			change: func(cfg *Config) {
+				cfg.MRT.Enable = true
+				cfg.MRT.Dir = "/var/lib/cloudgw/mrt2"
+				cfg.MRT.RotationInterval = 30
+				cfg.MRT.TableDumpInterval = 59
+			},
+			want: []string{
+				`MRT.Dir "/var/lib/cloudgw/mrt2" must be set without digits, month and day names`,
+				`MRT.RotationInterval 30 must be at least 60`,
+				`MRT.TableDumpInterval 59 must be 0 or at least 60`,
+			},
+		},
+		{
+			name: "tracing",
+			change: func(cfg *Config) {
+				cfg.Tracing.Enable = true
+				cfg.Tracing.Endpoint = "localhost"
+				cfg.Tracing.SampleRatio = 0
+			},
+			want: []string{
+				`Tracing.Endpoint "localhost" is not a host with port, e.g. localhost:4317`,
+				`Tracing.SampleRatio 0 must be in (0, 1]`,
+			},
+		},
+		{
+			name: "traffic counters",
+			change: func(cfg *Config) {
+				cfg.VPP.TrafficCounters.Enable = true
+				cfg.VPP.TrafficCounters.MaxSeries = 10
+				cfg.VPP.TrafficCounters.TopN = 20
+			},
+			want: []string{
+				`VPP.TrafficCounters.TopN 20 must be between 0 and VPP.TrafficCounters.MaxSeries 10`,
+			},
+		},
+		{
+			name: "tunnel probe",
+			change: func(cfg *Config) {
+				cfg.VPP.TunnelProbe.Enable = true
+				cfg.VPP.TunnelProbe.Interval = 0
+				cfg.VPP.TunnelProbe.Workers = 0
+			},
+			want: []string{
+				`VPP.TunnelProbe.Interval 0, VPP.TunnelProbe.Count 3 and VPP.TunnelProbe.Threshold 3 must be positive`,
+				`VPP.TunnelProbe.Workers 0 must be between 1 and 64`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := ParseConfig("config_test.yml")
+			require.NoError(t, err)
+
+			tt.change(cfg)
+
+			var got []string
+
+			for _, err := range Validate(cfg) {
+				got = append(got, err.Error())
+			}
+
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidateFatal(t *testing.T) {
+	cfg, err := ParseConfig("config_test.yml")
+	require.NoError(t, err)
+
+	// zero intervals would crash the app, unknown bmp policy would not
+
+	cfg.Drain.CheckInterval = 0
+	cfg.HA.Enable = true
+	cfg.HA.Peer = "192.0.0.2:9179"
+	cfg.HA.HeartbeatInterval = 0
+	cfg.VPP.TunnelProbe.Enable = true
+	cfg.VPP.TunnelProbe.Interval = 0
+	cfg.BMP.Policy = "none"
+
+	var fatal []string
+
+	errs := Validate(cfg)
+
+	for _, err := range errs {
+		var fatalErr *FatalError
+		if errors.As(err, &fatalErr) {
+			fatal = append(fatal, err.Error())
+		}
+	}
+
+	require.Len(t, errs, 4)
+	require.Equal(t, []string{
+		`HA.DeadInterval 1000 must be greater than positive HA.HeartbeatInterval 0`,
+		`Drain.CheckInterval 0 must be positive and Drain.Timeout 300 not negative`,
+		`VPP.TunnelProbe.Interval 0, VPP.TunnelProbe.Count 3 and VPP.TunnelProbe.Threshold 3 must be positive`,
+	}, fatal)
+}
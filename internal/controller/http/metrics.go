@@ -0,0 +1,73 @@
+package rest
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/node_exporter/collector"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/pkg/exporter/convergenceexporter"
+	"git.crptech.ru/cloud/cloudgw/pkg/exporter/gobgpexporter"
+	"git.crptech.ru/cloud/cloudgw/pkg/exporter/vppexporter"
+	"git.crptech.ru/cloud/cloudgw/pkg/logger"
+)
+
+// nodeExporterFlagsMu guards kingpin command line of node_exporter settings
+var nodeExporterFlagsMu sync.Mutex
+
+// newMetricsRegistry returns the registry of /metrics: cloudgw, go runtime and process collectors and node_exporter
+// collectors if enabled. A node_exporter failure is logged, cloudgw metrics are exposed without host ones.
+func newMetricsRegistry(cfg config.NodeExporter, storage *imdb.Storage) *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		vppexporter.NewCloudgwExporter(),
+		gobgpexporter.NewCloudgwExporter(storage),
+		convergenceexporter.NewCloudgwExporter(),
+	)
+
+	if !cfg.Enable {
+		return registry
+	}
+
+	nodeExporter, err := newNodeCollector(cfg.Collectors)
+	if err != nil {
+		logger.Error("failed to create node_exporter collectors, host metrics are not exposed", "error", err)
+
+		return registry
+	}
+
+	if err = registry.Register(nodeExporter); err != nil {
+		logger.Error("failed to register node_exporter collectors, host metrics are not exposed", "error", err)
+	}
+
+	return registry
+}
+
+// newNodeCollector returns node_exporter collectors by name (enabled by default ones if names are empty).
+// node_exporter reads its settings (e.g. procfs path) and enabled collectors from kingpin flags, so the flags are
+// parsed from the names instead of the command line of cloudgw.
+func newNodeCollector(names []string) (*collector.NodeCollector, error) {
+	nodeExporterFlagsMu.Lock()
+	defer nodeExporterFlagsMu.Unlock()
+
+	args := make([]string, 0, len(names))
+
+	for _, name := range names {
+		args = append(args, "--collector."+name)
+	}
+
+	if _, err := kingpin.CommandLine.Parse(args); err != nil {
+		return nil, fmt.Errorf("failed to enable node_exporter collectors %v: %w", names, err)
+	}
+
+	return collector.NewNodeCollector(log.NewNopLogger(), names...)
+}
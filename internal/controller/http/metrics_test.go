@@ -0,0 +1,61 @@
+package rest
+
+import (
+	"strings"
+	"testing"
+
+	bgpapi "github.com/osrg/gobgp/v3/api"
+	"github.com/stretchr/testify/require"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+)
+
+func TestNewMetricsRegistry(t *testing.T) {
+	storage := imdb.NewStorage()
+
+	for _, ip := range []string{"10.0.0.10", "10.0.0.11"} {
+		peer := model.NewBGPPeer(model.TF, 64512, ip, 179, "", true, 2, "", 10, 30)
+		require.NoError(t, storage.BGPPeerStorage.AddBGPPeer(&peer))
+	}
+
+	require.NoError(t, storage.UpdateBGPPeerState("10.0.0.10", bgpapi.PeerState_UNKNOWN, bgpapi.PeerState_ESTABLISHED))
+
+	// gather returns values of metrics without labels and the number of node_exporter metrics
+
+	gather := func(cfg config.NodeExporter) (map[string]float64, int) {
+		families, err := newMetricsRegistry(cfg, storage).Gather()
+		require.NoError(t, err)
+
+		values, nodeMetrics := make(map[string]float64), 0
+
+		for _, family := range families {
+			if strings.HasPrefix(family.GetName(), "node_") {
+				nodeMetrics++
+			}
+
+			if metric := family.GetMetric()[0]; len(metric.GetLabel()) == 0 && metric.GetCounter() != nil {
+				values[family.GetName()] = metric.GetCounter().GetValue()
+			}
+		}
+
+		return values, nodeMetrics
+	}
+
+	// peers are counted from the storage
+
+	values, nodeMetrics := gather(config.NodeExporter{})
+	require.Equal(t, 2.0, values["gobgp_peer_total"])
+	require.Equal(t, 1.0, values["gobgp_active_peer_total"])
+	require.Zero(t, nodeMetrics)
+
+	// node_exporter collectors are selected by name, an unknown one disables host metrics only
+
+	_, nodeMetrics = gather(config.NodeExporter{Enable: true, Collectors: []string{"loadavg"}})
+	require.NotZero(t, nodeMetrics)
+
+	values, nodeMetrics = gather(config.NodeExporter{Enable: true, Collectors: []string{"unknown"}})
+	require.Equal(t, 2.0, values["gobgp_peer_total"])
+	require.Zero(t, nodeMetrics)
+}
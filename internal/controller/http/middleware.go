@@ -0,0 +1,141 @@
+package rest
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	controller "git.crptech.ru/cloud/cloudgw/internal/controller/http/v1"
+	"git.crptech.ru/cloud/cloudgw/pkg/logger"
+)
+
+const (
+	RoleReadOnly = "read-only" // read api only
+	RoleAdmin    = "admin"     // read and administrative api
+)
+
+// TokenStore keeps tokens of http api (HTTP.Auth.Tokens and HTTP.Admin.Token as a token with admin role), the tokens
+// can be replaced at runtime on config reload
+type TokenStore struct {
+	tokens atomic.Pointer[[]config.Token]
+}
+
+func NewTokenStore(cfg config.HTTP) *TokenStore {
+	store := &TokenStore{}
+
+	store.Set(cfg)
+
+	return store
+}
+
+// Set replaces tokens with tokens of the config
+func (s *TokenStore) Set(cfg config.HTTP) {
+	tokens := make([]config.Token, 0, len(cfg.Auth.Tokens)+1)
+
+	for _, token := range cfg.Auth.Tokens {
+		if token.Token == "" {
+			logger.Error("http api token is ignored as it is empty", "name", token.Name)
+
+			continue
+		}
+
+		if token.Role == "" {
+			token.Role = RoleReadOnly
+		}
+
+		if token.Role != RoleReadOnly && token.Role != RoleAdmin {
+			logger.Error("http api token is ignored as its role is unknown", "name", token.Name, "role", token.Role)
+
+			continue
+		}
+
+		if token.Name == "" {
+			token.Name = token.Role
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	if cfg.Admin.Token != "" {
+		tokens = append(tokens, config.Token{Name: RoleAdmin, Token: cfg.Admin.Token, Role: RoleAdmin})
+	}
+
+	s.tokens.Store(&tokens)
+}
+
+// Tokens returns current tokens
+func (s *TokenStore) Tokens() []config.Token {
+	return *s.tokens.Load()
+}
+
+// tokenAuth allows requests with a bearer token of one of the roles, name of the token is set as the actor
+func tokenAuth(tokens *TokenStore, roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestToken, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok {
+			abortAuth(c, http.StatusUnauthorized, "unauthorized")
+
+			return
+		}
+
+		token, ok := findToken(tokens.Tokens(), requestToken)
+		if !ok {
+			abortAuth(c, http.StatusUnauthorized, "unauthorized")
+
+			return
+		}
+
+		if !slices.Contains(roles, token.Role) {
+			abortAuth(c, http.StatusForbidden, "forbidden")
+
+			return
+		}
+
+		c.Set(controller.ActorKey, token.Name)
+
+		c.Next()
+	}
+}
+
+// abortAuth aborts the request with /api/v1 error envelope for /api/v1 urls and with legacy error otherwise
+func abortAuth(c *gin.Context, status int, message string) {
+	if strings.HasPrefix(c.Request.URL.Path, controller.APIPrefix+"/") {
+		controller.AbortWithAPIError(c, status, message)
+
+		return
+	}
+
+	c.AbortWithStatusJSON(status, gin.H{"error": message})
+}
+
+// findToken compares the request token with all tokens in constant time
+func findToken(tokens []config.Token, requestToken string) (config.Token, bool) {
+	var (
+		found   config.Token
+		isFound bool
+	)
+
+	for _, token := range tokens {
+		if subtle.ConstantTimeCompare([]byte(requestToken), []byte(token.Token)) == 1 && !isFound {
+			found, isFound = token, true
+		}
+	}
+
+	return found, isFound
+}
+
+// clientCertActor sets common name of verified client certificate (mtls) as the actor
+func clientCertActor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.TLS != nil && len(c.Request.TLS.VerifiedChains) != 0 && len(c.Request.TLS.VerifiedChains[0]) != 0 {
+			c.Set(controller.ActorKey, "cn="+c.Request.TLS.VerifiedChains[0][0].Subject.CommonName)
+		}
+
+		c.Next()
+	}
+}
@@ -2,53 +2,129 @@ package rest
 
 import (
 	"net/http"
+	"slices"
 
-	"github.com/alecthomas/kingpin/v2"
 	"github.com/gin-gonic/gin"
-	"github.com/go-kit/log"
-	"github.com/prometheus/client_golang/prometheus"
+	"github.com/osrg/gobgp/v3/pkg/server"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"github.com/prometheus/common/promlog"
-	"github.com/prometheus/common/promlog/flag"
-	"github.com/prometheus/node_exporter/collector"
 	vppapi "go.fd.io/govpp/api"
 
+	"git.crptech.ru/cloud/cloudgw/internal/config"
 	controller "git.crptech.ru/cloud/cloudgw/internal/controller/http/v1"
+	"git.crptech.ru/cloud/cloudgw/internal/events"
+	"git.crptech.ru/cloud/cloudgw/internal/ha"
+	"git.crptech.ru/cloud/cloudgw/internal/health"
 	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
-	"git.crptech.ru/cloud/cloudgw/pkg/exporter/gobgpexporter"
-	"git.crptech.ru/cloud/cloudgw/pkg/exporter/vppexporter"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp/dryrun"
+	"git.crptech.ru/cloud/cloudgw/internal/service"
+	"git.crptech.ru/cloud/cloudgw/pkg/audit"
 	"git.crptech.ru/cloud/cloudgw/pkg/logger"
 )
 
-func NewRouter(appStorage *imdb.Storage, stream vppapi.Stream) *gin.Engine {
+func NewRouter(
+	cfg config.Config,
+	appStorage *imdb.Storage,
+	stream vppapi.Stream,
+	bgpSrv *server.BgpServer,
+	healthChecker *health.Checker,
+	auditLogger *audit.Logger,
+	tokens *TokenStore,
+	reload func() (applied, restartRequired []string, err error),
+	dryRun *dryrun.Stream,
+	eventHub *events.Hub,
+	haNode *ha.Node,
+	gatewayDrain *service.GatewayDrain,
+) *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 
 	engine := gin.New()
 
-	engine.Use(gin.Recovery())
+	engine.Use(gin.Recovery(), clientCertActor())
 
-	flag.AddFlags(kingpin.CommandLine, &promlog.Config{})
-	kingpin.Parse()
+	registry := newMetricsRegistry(cfg.HTTP.NodeExporter, appStorage)
 
-	nodeExporter, err := collector.NewNodeCollector(log.NewNopLogger())
-	if err != nil {
-		logger.Fatal(err.Error())
+	// read api (read-only and admin tokens are allowed if authentication is enabled)
+
+	public, api := engine.Group(""), engine.Group("")
+
+	if cfg.HTTP.Auth.Enable {
+		api.Use(tokenAuth(tokens, RoleReadOnly, RoleAdmin))
+
+		if len(tokens.Tokens()) == 0 {
+			logger.Error("http api authentication is enabled, but no tokens configured")
+		}
+	}
+
+	healthAPI, metricsAPI := api, api
+
+	if cfg.HTTP.Auth.HealthNoAuth {
+		healthAPI = public
+	}
+
+	if cfg.HTTP.Auth.MetricsNoAuth {
+		metricsAPI = public
+	}
+
+	healthAPI.GET("/health", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"status": "OK"}) })
+	healthAPI.GET("/health/live", controller.HealthLive(healthChecker))
+	healthAPI.GET("/health/ready", controller.HealthReady(healthChecker))
+	metricsAPI.GET("/metrics", gin.WrapH(promhttp.HandlerFor(registry, promhttp.HandlerOpts{})))
+	api.GET("/summary", controller.Summary(*appStorage, stream))
+	api.GET("/bgp/vrfs", controller.BGPVRFs(appStorage.BGPVRFStorage))
+	api.GET("/bgp/peers", controller.BGPPeers(appStorage.BGPPeerStorage))
+	api.GET("/bgp/peers/:ip/adj-in", controller.BGPPeerAdjRIB(bgpSrv, appStorage, true))
+	api.GET("/bgp/peers/:ip/adj-out", controller.BGPPeerAdjRIB(bgpSrv, appStorage, false))
+	api.GET("/bgp/rib/vpnv4", controller.BGPVPNv4RIB(bgpSrv, appStorage))
+	api.GET("/bgp/vrfs/:name/rib", controller.BGPVRFRIB(bgpSrv, appStorage))
+	api.GET("/vpp/vrfs", controller.VPPVRFs(appStorage.VPPVRFStorage))
+	api.GET("/vpp/fips", controller.VPPFIPRoutes(appStorage.VPPFIPRouteStorage))
+	api.GET("/vpp/fips/:ip", controller.VPPFIPTrace(bgpSrv, stream, cfg, appStorage))
+	api.GET("/vpp/tunnels", controller.UDPTunnels(appStorage.VPPUDPTunnelStorage))
+
+	// administrative api, the tokens are checked on each request as they are changed by config reload
+
+	var apiAdmin *gin.RouterGroup
+
+	if cfg.HTTP.Admin.Enable {
+		apiAdmin = engine.Group("", tokenAuth(tokens, RoleAdmin))
+
+		if !slices.ContainsFunc(tokens.Tokens(), func(token config.Token) bool { return token.Role == RoleAdmin }) {
+			logger.Error("no token with admin role configured, administrative api requests are rejected until config reload")
+		}
 	}
 
-	vppExporter := vppexporter.NewCloudgwExporter()
+	// versioned api with generated openapi document
+
+	engine.NoRoute(controller.APINoRoute)
+
+	controller.RegisterAPI(public, api, apiAdmin, controller.APIDeps{
+		Cfg:         cfg,
+		Storage:     appStorage,
+		Stream:      stream,
+		BGPSrv:      bgpSrv,
+		Health:      healthChecker,
+		AuditLogger: auditLogger,
+		Reload:      reload,
+		DryRun:      dryRun,
+		Events:      eventHub,
+		HA:          haNode,
+		Drain:       gatewayDrain,
+	}, cfg.HTTP.Auth.Enable)
+
+	if apiAdmin == nil {
+		return engine
+	}
 
-	goBGPExporter := gobgpexporter.NewCloudgwExporter()
+	admin := apiAdmin.Group("/admin")
 
-	prometheus.MustRegister(vppExporter, goBGPExporter, nodeExporter)
+	admin.POST("/bgp/peers/:ip/reset", controller.AdminBGPPeerReset(bgpSrv, appStorage, auditLogger))
+	admin.POST("/bgp/peers/:ip/disable", controller.AdminBGPPeerAdminState(bgpSrv, appStorage, auditLogger, false))
+	admin.POST("/bgp/peers/:ip/enable", controller.AdminBGPPeerAdminState(bgpSrv, appStorage, auditLogger, true))
+	admin.POST("/vrfs/:name/drain", controller.AdminVRFDrain(bgpSrv, cfg, appStorage, auditLogger, true))
+	admin.POST("/vrfs/:name/undrain", controller.AdminVRFDrain(bgpSrv, cfg, appStorage, auditLogger, false))
+	admin.POST("/fips/resync", controller.AdminFIPResync(&stream, bgpSrv, cfg, appStorage, auditLogger))
 
-	engine.GET("/health", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"status": "OK"}) })
-	engine.GET("/metrics", gin.WrapH(promhttp.Handler()))
-	engine.GET("/summary", controller.Summary(*appStorage, stream))
-	engine.GET("/bgp/vrfs", controller.BGPVRFs(appStorage.BGPVRFStorage))
-	engine.GET("/bgp/peers", controller.BGPPeers(appStorage.BGPPeerStorage))
-	engine.GET("/vpp/vrfs", controller.VPPVRFs(appStorage.VPPVRFStorage))
-	engine.GET("/vpp/fips", controller.VPPFIPRoutes(appStorage.VPPFIPRouteStorage))
-	engine.GET("/vpp/tunnels", controller.UDPTunnels(appStorage.VPPUDPTunnelStorage))
+	logger.Info("administrative api enabled")
 
 	return engine
 }
@@ -0,0 +1,47 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/pkg/audit"
+)
+
+func TestNewRouterAdminToken(t *testing.T) {
+	cfg := config.Config{HTTP: config.HTTP{Admin: config.Admin{Enable: true}}}
+
+	auditLogger, err := audit.New("")
+	require.NoError(t, err)
+
+	tokens := NewTokenStore(cfg.HTTP)
+
+	engine := NewRouter(cfg, imdb.NewStorage(), nil, nil, nil, auditLogger, tokens, nil, nil, nil, nil, nil)
+
+	drain := func(token string) int {
+		req := httptest.NewRequest(http.MethodPost, "/admin/vrfs/vrf1/drain", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		w := httptest.NewRecorder()
+
+		engine.ServeHTTP(w, req)
+
+		return w.Code
+	}
+
+	// the administrative api is registered without admin token and rejects the requests
+
+	require.Equal(t, http.StatusUnauthorized, drain("admin-secret"))
+
+	// the token added by config reload is accepted, the vrf is unknown
+
+	cfg.HTTP.Admin.Token = "admin-secret"
+	tokens.Set(cfg.HTTP)
+
+	require.Equal(t, http.StatusNotFound, drain("admin-secret"))
+	require.Equal(t, http.StatusUnauthorized, drain("other-secret"))
+}
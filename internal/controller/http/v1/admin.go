@@ -0,0 +1,91 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/osrg/gobgp/v3/pkg/server"
+	vppapi "go.fd.io/govpp/api"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/internal/service"
+	"git.crptech.ru/cloud/cloudgw/pkg/audit"
+)
+
+// ActorKey is a gin context key of authenticated actor name (set by auth middleware)
+const ActorKey = "actor"
+
+// AdminBGPPeerReset resets bgp session with the peer, query: mode=hard|soft|soft-in|soft-out (soft by default)
+func AdminBGPPeerReset(bgpSrv *server.BgpServer, storage *imdb.Storage, auditLogger *audit.Logger) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		mode := c.DefaultQuery("mode", service.BGPPeerResetSoft)
+
+		err := service.ResetBGPPeer(c.Request.Context(), bgpSrv, storage, c.Param("ip"), mode)
+
+		adminReply(c, auditLogger, "bgp peer reset "+mode, c.Param("ip"), nil, err)
+	}
+
+	return fn
+}
+
+// AdminBGPPeerAdminState shuts down (isEnable = false) or brings up bgp session with the peer
+func AdminBGPPeerAdminState(bgpSrv *server.BgpServer, storage *imdb.Storage, auditLogger *audit.Logger, isEnable bool) gin.HandlerFunc {
+	action := "bgp peer disable"
+
+	if isEnable {
+		action = "bgp peer enable"
+	}
+
+	fn := func(c *gin.Context) {
+		err := service.SetBGPPeerAdminState(c.Request.Context(), bgpSrv, storage, c.Param("ip"), isEnable)
+
+		adminReply(c, auditLogger, action, c.Param("ip"), nil, err)
+	}
+
+	return fn
+}
+
+// AdminVRFDrain withdraws (isDrain) or advertises back aggregated floating ip prefixes of the vrf
+func AdminVRFDrain(bgpSrv *server.BgpServer, cfg config.Config, storage *imdb.Storage, auditLogger *audit.Logger, isDrain bool) gin.HandlerFunc {
+	action := "vrf undrain"
+
+	if isDrain {
+		action = "vrf drain"
+	}
+
+	fn := func(c *gin.Context) {
+		err := service.DrainVRF(c.Request.Context(), bgpSrv, cfg, storage, c.Param("name"), isDrain)
+
+		adminReply(c, auditLogger, action, c.Param("name"), nil, err)
+	}
+
+	return fn
+}
+
+// AdminFIPResync rebuilds floating ip routes from bgp table
+func AdminFIPResync(stream *vppapi.Stream, bgpSrv *server.BgpServer, cfg config.Config, storage *imdb.Storage, auditLogger *audit.Logger) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		result, err := service.ResyncFIPs(c.Request.Context(), stream, bgpSrv, cfg, storage)
+
+		adminReply(c, auditLogger, "floating ip resync", "", result, err)
+	}
+
+	return fn
+}
+
+func adminReply(c *gin.Context, auditLogger *audit.Logger, action, target string, result any, err error) {
+	auditLogger.Log(c.GetString(ActorKey), c.ClientIP(), action, target, err)
+
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case result != nil:
+		c.JSON(http.StatusOK, gin.H{"result": result})
+	default:
+		c.JSON(http.StatusOK, gin.H{"result": "ok"})
+	}
+}
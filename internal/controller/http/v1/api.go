@@ -0,0 +1,1034 @@
+package v1
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/osrg/gobgp/v3/pkg/server"
+	vppapi "go.fd.io/govpp/api"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/events"
+	"git.crptech.ru/cloud/cloudgw/internal/ha"
+	"git.crptech.ru/cloud/cloudgw/internal/health"
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp/dryrun"
+	"git.crptech.ru/cloud/cloudgw/internal/service"
+	"git.crptech.ru/cloud/cloudgw/pkg/audit"
+	"git.crptech.ru/cloud/cloudgw/pkg/openapi"
+)
+
+const (
+	APIPrefix = "/api/v1"
+
+	apiDefaultLimit = 100
+	apiMaxLimit     = 1000
+	bearerAuth      = "bearerAuth"
+)
+
+// APIDeps are dependencies of /api/v1 handlers
+type APIDeps struct {
+	Cfg         config.Config
+	Storage     *imdb.Storage
+	Stream      vppapi.Stream
+	BGPSrv      *server.BgpServer
+	Health      *health.Checker
+	AuditLogger *audit.Logger
+	Reload      func() (applied, restartRequired []string, err error) // re-reads config file and applies reloadable settings
+	DryRun      *dryrun.Stream                                        // nil if vpp is connected
+	Events      *events.Hub                                           // nil if state change events are disabled
+	HA          *ha.Node                                              // nil if ha pair is disabled
+	Drain       *service.GatewayDrain
+}
+
+// apiRoute is a route of /api/v1 with its openapi description
+type apiRoute struct {
+	method   string
+	path     string
+	id       string
+	summary  string
+	tag      string
+	params   []openapi.Parameter
+	response any
+	errors   []int
+	isAdmin  bool
+	isDryRun bool   // registered in vpp dry-run mode only
+	isEvents bool   // registered if state change events are enabled
+	isHA     bool   // registered if ha pair is enabled
+	isDrain  bool   // registered if the gateway drain is set
+	content  string // content type of the response, json if empty
+	handler  gin.HandlerFunc
+}
+
+// RegisterAPI registers /api/v1 routes: read routes to the read group and administrative routes to the admin group (skipped
+// if nil). Generated openapi document is served at /api/v1/openapi.json on the public group.
+func RegisterAPI(public, read, admin *gin.RouterGroup, deps APIDeps, isReadAuth bool) {
+	doc := openapi.New("cloudgw", "v1")
+
+	doc.AddBearerAuth(bearerAuth)
+
+	for _, route := range apiRoutes(deps) {
+		if (route.isAdmin && admin == nil) || (route.id == "reload" && deps.Reload == nil) || (route.isDryRun && deps.DryRun == nil) ||
+			(route.isEvents && deps.Events == nil) || (route.isHA && deps.HA == nil) ||
+			(route.isDrain && deps.Drain == nil) {
+			continue
+		}
+
+		op := openapi.Operation{
+			OperationID: route.id,
+			Summary:     route.summary,
+			Tags:        []string{route.tag},
+			Parameters:  route.params,
+			Responses:   map[string]openapi.Response{"200": doc.JSONResponse("OK", route.response)},
+		}
+
+		if route.content != "" {
+			op.Responses["200"] = doc.ContentResponse("OK", route.content, route.response)
+		}
+
+		for _, status := range route.errors {
+			op.Responses[strconv.Itoa(status)] = doc.JSONResponse(http.StatusText(status), APIError{})
+		}
+
+		if route.isAdmin || isReadAuth {
+			op.Responses[strconv.Itoa(http.StatusUnauthorized)] = doc.JSONResponse(http.StatusText(http.StatusUnauthorized), APIError{})
+			op.Responses[strconv.Itoa(http.StatusForbidden)] = doc.JSONResponse(http.StatusText(http.StatusForbidden), APIError{})
+			op.Security = []map[string][]string{{bearerAuth: {}}}
+		}
+
+		doc.Add(route.method, APIPrefix+route.path, op)
+
+		if route.isAdmin {
+			admin.Handle(route.method, APIPrefix+route.path, route.handler)
+		} else {
+			read.Handle(route.method, APIPrefix+route.path, route.handler)
+		}
+	}
+
+	public.GET(APIPrefix+"/openapi.json", func(c *gin.Context) { c.JSON(http.StatusOK, doc) })
+}
+
+func apiRoutes(deps APIDeps) []apiRoute {
+	paging := []openapi.Parameter{
+		queryParam("offset", "integer", "number of items to skip"),
+		queryParam("limit", "integer", fmt.Sprintf("max number of items (default %d, max %d)", apiDefaultLimit, apiMaxLimit)),
+	}
+
+	ribParams := append([]openapi.Parameter{
+		queryParam("prefix", "string", "address or prefix, paths to it and its more specifics are returned"),
+		queryParam("vrf", "string", "vrf name, paths with rd/rt of the vrf are returned"),
+	}, paging...)
+
+	adjRIBParams := append([]openapi.Parameter{
+		queryParam("policy", "string", "pre or post policy view (pre for adj-in and post for adj-out by default)", "pre", "post"),
+	}, ribParams...)
+
+	return []apiRoute{
+		{
+			method: http.MethodGet, path: "/summary", id: "getSummary", tag: "summary",
+			summary:  "Numbers of bgp peers, floating ip routes and udp tunnels in memory and vpp",
+			response: SummaryStatus{},
+			handler:  apiSummary(deps),
+		},
+		{
+			method: http.MethodGet, path: "/health/live", id: "getHealthLive", tag: "health",
+			summary:  "Liveness check",
+			response: health.Report{},
+			errors:   []int{http.StatusServiceUnavailable},
+			handler:  apiHealth(deps, false),
+		},
+		{
+			method: http.MethodGet, path: "/health/ready", id: "getHealthReady", tag: "health",
+			summary:  "Readiness check",
+			response: health.Report{},
+			errors:   []int{http.StatusServiceUnavailable},
+			handler:  apiHealth(deps, true),
+		},
+		{
+			method: http.MethodGet, path: "/bgp/peers", id: "listBGPPeers", tag: "bgp",
+			summary: "BGP peers sorted by address",
+			params: append([]openapi.Parameter{
+				queryParam("type", "string", "peer type", PeerTypeTF, PeerTypePhyNet),
+				queryParam("state", "string", "bgp session state, e.g. established"),
+				queryParam("vrf", "string", "vrf name of physical network peers"),
+			}, paging...),
+			response: Page[BGPPeer]{},
+			errors:   []int{http.StatusBadRequest},
+			handler:  apiBGPPeers(deps),
+		},
+		{
+			method: http.MethodGet, path: "/bgp/peers/:ip", id: "getBGPPeer", tag: "bgp",
+			summary:  "BGP peer",
+			response: BGPPeer{},
+			errors:   []int{http.StatusNotFound},
+			handler:  apiBGPPeer(deps),
+		},
+		{
+			method: http.MethodGet, path: "/bgp/peers/:ip/adj-in", id: "listBGPPeerAdjRIBIn", tag: "bgp",
+			summary:  "Paths received from the peer",
+			params:   adjRIBParams,
+			response: Page[service.RIBPath]{},
+			errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+			handler:  apiBGPPeerAdjRIB(deps, true),
+		},
+		{
+			method: http.MethodGet, path: "/bgp/peers/:ip/adj-out", id: "listBGPPeerAdjRIBOut", tag: "bgp",
+			summary:  "Paths sent to the peer",
+			params:   adjRIBParams,
+			response: Page[service.RIBPath]{},
+			errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+			handler:  apiBGPPeerAdjRIB(deps, false),
+		},
+		{
+			method: http.MethodGet, path: "/bgp/rib/vpnv4", id: "listBGPVPNv4RIB", tag: "bgp",
+			summary:  "Global vpnv4 table",
+			params:   ribParams,
+			response: Page[service.RIBPath]{},
+			errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+			handler:  apiBGPVPNv4RIB(deps),
+		},
+		{
+			method: http.MethodGet, path: "/bgp/vrfs", id: "listBGPVRFs", tag: "bgp",
+			summary:  "GoBGP vrfs sorted by id",
+			params:   paging,
+			response: Page[BGPVRF]{},
+			errors:   []int{http.StatusBadRequest},
+			handler:  apiBGPVRFs(deps),
+		},
+		{
+			method: http.MethodGet, path: "/bgp/vrfs/:name", id: "getBGPVRF", tag: "bgp",
+			summary:  "GoBGP vrf",
+			response: BGPVRF{},
+			errors:   []int{http.StatusNotFound},
+			handler:  apiBGPVRF(deps),
+		},
+		{
+			method: http.MethodGet, path: "/bgp/vrfs/:name/rib", id: "listBGPVRFRIB", tag: "bgp",
+			summary:  "GoBGP vrf table",
+			params:   append([]openapi.Parameter{ribParams[0]}, paging...),
+			response: Page[service.RIBPath]{},
+			errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+			handler:  apiBGPVRFRIB(deps),
+		},
+		{
+			method: http.MethodGet, path: "/bgp/bmp", id: "listBMPStations", tag: "bgp",
+			summary:  "BMP stations sorted by address with connection state",
+			response: []service.BMPStation{},
+			errors:   []int{http.StatusInternalServerError},
+			handler:  apiBMPStations(deps),
+		},
+		{
+			method: http.MethodGet, path: "/vpp/vrfs", id: "listVPPVRFs", tag: "vpp",
+			summary:  "VPP vrfs sorted by id",
+			params:   paging,
+			response: Page[VPPVRF]{},
+			errors:   []int{http.StatusBadRequest},
+			handler:  apiVPPVRFs(deps),
+		},
+		{
+			method: http.MethodGet, path: "/vpp/vrfs/:name", id: "getVPPVRF", tag: "vpp",
+			summary:  "VPP vrf",
+			response: VPPVRF{},
+			errors:   []int{http.StatusNotFound},
+			handler:  apiVPPVRF(deps),
+		},
+		{
+			method: http.MethodGet, path: "/vpp/fips", id: "listFIPRoutes", tag: "vpp",
+			summary: "Floating ip routes sorted by prefix",
+			params: append([]openapi.Parameter{
+				queryParam("vrf", "string", "vrf name"),
+				queryParam("prefix", "string", "address or prefix, floating ips within it are returned"),
+				queryParam("nexthop", "string", "vrouter address"),
+				queryParam("tunnel", "integer", "udp tunnel id"),
+			}, paging...),
+			response: Page[FIPRoute]{},
+			errors:   []int{http.StatusBadRequest},
+			handler:  apiFIPRoutes(deps),
+		},
+		{
+			method: http.MethodGet, path: "/vpp/fips/:ip", id: "traceFIP", tag: "vpp",
+			summary:  "Floating ip trace through bgp, memory storage and vpp",
+			response: service.FIPTrace{},
+			errors:   []int{http.StatusBadRequest},
+			handler:  apiFIPTrace(deps),
+		},
+		{
+			method: http.MethodGet, path: "/vpp/tunnels", id: "listUDPTunnels", tag: "vpp",
+			summary: "UDP tunnels sorted by id",
+			params: append([]openapi.Parameter{
+				queryParam("dst", "string", "vrouter address"),
+				queryParam("reachable", "boolean", "vrouter reachability"),
+				queryParam("idle", "boolean", "no floating ip served through the tunnel"),
+			}, paging...),
+			response: Page[UDPTunnel]{},
+			errors:   []int{http.StatusBadRequest},
+			handler:  apiUDPTunnels(deps),
+		},
+		{
+			method: http.MethodGet, path: "/dryrun/fib", id: "getDryRunFIB", tag: "vpp", isDryRun: true,
+			summary:  "Intended vpp fib built from recorded requests (dry-run mode)",
+			response: dryrun.FIB{},
+			handler:  apiDryRunFIB(deps),
+		},
+		{
+			method: http.MethodGet, path: "/dryrun/records", id: "listDryRunRecords", tag: "vpp", isDryRun: true,
+			summary:  "Recorded vpp requests, the oldest first (dry-run mode)",
+			params:   paging,
+			response: Page[dryrun.Record]{},
+			errors:   []int{http.StatusBadRequest},
+			handler:  apiDryRunRecords(deps),
+		},
+		{
+			method: http.MethodGet, path: "/events", id: "streamEvents", tag: "events", isEvents: true,
+			summary: "State change events as server-sent events (id is the event sequence), resumed after Last-Event-ID " +
+				"header or since query, new events only if neither is set",
+			params: []openapi.Parameter{
+				queryParam("since", "integer", "sequence of the last received event"),
+				queryParam("type", "string", "comma separated event types, all types if empty"),
+			},
+			response: events.Event{},
+			content:  "text/event-stream",
+			errors:   []int{http.StatusBadRequest, http.StatusGone},
+			handler:  apiEvents(deps),
+		},
+		{
+			method: http.MethodGet, path: "/ha", id: "getHA", tag: "ha", isHA: true,
+			summary:  "Role of the instance in ha pair and the peer state from its last heartbeat",
+			response: ha.Status{},
+			handler:  apiHA(deps),
+		},
+		{
+			method: http.MethodGet, path: "/drain", id: "getGatewayDrain", tag: "drain", isDrain: true,
+			summary:  "Gateway drain phase and traffic of vrf sub-interfaces",
+			response: service.GatewayDrainStatus{},
+			handler:  apiGatewayDrain(deps),
+		},
+		{
+			method: http.MethodPost, path: "/admin/bgp/peers/:ip/reset", id: "resetBGPPeer", tag: "admin", isAdmin: true,
+			summary: "Reset bgp session",
+			params: []openapi.Parameter{
+				queryParam("mode", "string", "reset mode (soft by default)",
+					service.BGPPeerResetHard, service.BGPPeerResetSoft, service.BGPPeerResetSoftIn, service.BGPPeerResetSoftOut),
+			},
+			response: AdminResult{},
+			errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+			handler:  apiAdminBGPPeerReset(deps),
+		},
+		{
+			method: http.MethodPost, path: "/admin/bgp/peers/:ip/disable", id: "disableBGPPeer", tag: "admin", isAdmin: true,
+			summary:  "Shut down bgp session",
+			response: AdminResult{},
+			errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+			handler:  apiAdminBGPPeerAdminState(deps, false),
+		},
+		{
+			method: http.MethodPost, path: "/admin/bgp/peers/:ip/enable", id: "enableBGPPeer", tag: "admin", isAdmin: true,
+			summary:  "Bring up bgp session",
+			response: AdminResult{},
+			errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+			handler:  apiAdminBGPPeerAdminState(deps, true),
+		},
+		{
+			method: http.MethodPost, path: "/admin/vrfs/:name/drain", id: "drainVRF", tag: "admin", isAdmin: true,
+			summary:  "Withdraw aggregated floating ip prefixes of the vrf",
+			response: AdminResult{},
+			errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+			handler:  apiAdminVRFDrain(deps, true),
+		},
+		{
+			method: http.MethodPost, path: "/admin/vrfs/:name/undrain", id: "undrainVRF", tag: "admin", isAdmin: true,
+			summary:  "Advertise aggregated floating ip prefixes of the vrf back",
+			response: AdminResult{},
+			errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+			handler:  apiAdminVRFDrain(deps, false),
+		},
+		{
+			method: http.MethodPost, path: "/admin/drain", id: "drainGateway", tag: "admin", isAdmin: true, isDrain: true,
+			summary: "Start the gateway drain: aggregated floating ip prefixes are advertised with Drain.Methods attributes, " +
+				"then withdrawn when traffic falls",
+			response: AdminResult{},
+			handler:  apiAdminGatewayDrain(deps, true),
+		},
+		{
+			method: http.MethodPost, path: "/admin/undrain", id: "undrainGateway", tag: "admin", isAdmin: true, isDrain: true,
+			summary:  "Cancel the gateway drain and advertise aggregated floating ip prefixes with normal attributes",
+			response: AdminResult{},
+			handler:  apiAdminGatewayDrain(deps, false),
+		},
+		{
+			method: http.MethodPost, path: "/admin/reload", id: "reload", tag: "admin", isAdmin: true,
+			summary:  "Re-read config file and apply log level, http api tokens and tls certificate",
+			response: ReloadResult{},
+			errors:   []int{http.StatusBadRequest},
+			handler:  apiAdminReload(deps),
+		},
+		{
+			method: http.MethodPost, path: "/admin/fips/resync", id: "resyncFIPs", tag: "admin", isAdmin: true,
+			summary:  "Rebuild floating ip routes from bgp table",
+			response: AdminResult{},
+			errors:   []int{http.StatusBadRequest},
+			handler:  apiAdminFIPResync(deps),
+		},
+	}
+}
+
+// AbortWithAPIError aborts the request with /api/v1 error envelope
+func AbortWithAPIError(c *gin.Context, status int, message string) {
+	c.AbortWithStatusJSON(status, APIError{Error: APIErrorBody{Code: apiErrorCode(status), Message: message}})
+}
+
+// APINoRoute replies with /api/v1 error envelope to unknown /api/v1 urls
+func APINoRoute(c *gin.Context) {
+	if strings.HasPrefix(c.Request.URL.Path, APIPrefix+"/") {
+		AbortWithAPIError(c, http.StatusNotFound, "unknown api path "+c.Request.URL.Path)
+
+		return
+	}
+
+	c.String(http.StatusNotFound, "404 page not found")
+}
+
+func apiErrorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "bad_request"
+	case http.StatusUnauthorized:
+		return "unauthorized"
+	case http.StatusForbidden:
+		return "forbidden"
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusGone:
+		return "gone"
+	case http.StatusServiceUnavailable:
+		return "unavailable"
+	default:
+		return "internal"
+	}
+}
+
+// apiServiceError replies 404 for not found objects and 400 for other service errors
+func apiServiceError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrNotFound) {
+		AbortWithAPIError(c, http.StatusNotFound, err.Error())
+
+		return
+	}
+
+	AbortWithAPIError(c, http.StatusBadRequest, err.Error())
+}
+
+func queryParam(name, schemaType, description string, enum ...string) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "query", Description: description, Schema: &openapi.Schema{Type: schemaType, Enum: enum}}
+}
+
+func pageQuery(c *gin.Context) (offset, limit int, err error) {
+	limit = apiDefaultLimit
+
+	if value := c.Query("offset"); value != "" {
+		if offset, err = strconv.Atoi(value); err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("wrong offset %q", value)
+		}
+	}
+
+	if value := c.Query("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 || limit > apiMaxLimit {
+			return 0, 0, fmt.Errorf("wrong limit %q, expected 1..%d", value, apiMaxLimit)
+		}
+	}
+
+	return offset, limit, nil
+}
+
+// replyPage replies with the page of sorted items, query: offset, limit
+func replyPage[T any](c *gin.Context, items []T) {
+	offset, limit, err := pageQuery(c)
+	if err != nil {
+		AbortWithAPIError(c, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	page := Page[T]{Total: len(items), Offset: offset, Limit: limit, Items: []T{}}
+
+	if offset < len(items) {
+		page.Items = items[offset:min(offset+limit, len(items))]
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+func replyRIBPage(c *gin.Context, page service.RIBPage, err error) {
+	if err != nil {
+		apiServiceError(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, Page[service.RIBPath]{Total: page.Total, Offset: page.Offset, Limit: page.Limit, Items: page.Paths})
+}
+
+func apiSummary(deps APIDeps) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.JSON(http.StatusOK, newSummary(*deps.Storage, deps.Stream))
+	}
+
+	return fn
+}
+
+func apiHealth(deps APIDeps, isReady bool) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		var report health.Report
+
+		if isReady {
+			report = deps.Health.Ready(c.Request.Context())
+		} else {
+			report = deps.Health.Live(c.Request.Context())
+		}
+
+		c.JSON(healthHTTPStatus(report), report)
+	}
+
+	return fn
+}
+
+// apiBGPPeers returns bgp peers, query: type, state, vrf, offset, limit
+func apiBGPPeers(deps APIDeps) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		peerType, state, vrf := c.Query("type"), strings.ToLower(c.Query("state")), c.Query("vrf")
+
+		if peerType != "" && peerType != PeerTypeTF && peerType != PeerTypePhyNet {
+			AbortWithAPIError(c, http.StatusBadRequest, fmt.Sprintf("wrong type %q, expected %s or %s", peerType, PeerTypeTF, PeerTypePhyNet))
+
+			return
+		}
+
+		bfdStates := bfdSessionStates()
+
+		peers := make([]BGPPeer, 0)
+
+		for _, peer := range deps.Storage.BGPPeerStorage.GetBGPPeers() {
+			dto := newBGPPeer(peer, bfdStates)
+
+			if (peerType != "" && dto.Type != peerType) || (state != "" && dto.State != state) || (vrf != "" && dto.VRF != vrf) {
+				continue
+			}
+
+			peers = append(peers, dto)
+		}
+
+		slices.SortFunc(peers, func(a, b BGPPeer) int { return compareAddrs(a.Address, b.Address) })
+
+		replyPage(c, peers)
+	}
+
+	return fn
+}
+
+func apiBGPPeer(deps APIDeps) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		peer := deps.Storage.BGPPeerStorage.GetBGPPeer(c.Param("ip"))
+		if peer == nil {
+			AbortWithAPIError(c, http.StatusNotFound, fmt.Sprintf("bgp peer %s: %s", c.Param("ip"), service.ErrNotFound))
+
+			return
+		}
+
+		c.JSON(http.StatusOK, newBGPPeer(peer, bfdSessionStates()))
+	}
+
+	return fn
+}
+
+func apiBGPPeerAdjRIB(deps APIDeps, isIn bool) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		query, err := ribQuery(c)
+		if err != nil {
+			AbortWithAPIError(c, http.StatusBadRequest, err.Error())
+
+			return
+		}
+
+		if query.PostPolicy, err = ribPolicy(c, isIn); err != nil {
+			AbortWithAPIError(c, http.StatusBadRequest, err.Error())
+
+			return
+		}
+
+		page, err := service.ListAdjRIB(c.Request.Context(), deps.BGPSrv, deps.Storage, c.Param("ip"), isIn, query)
+
+		replyRIBPage(c, page, err)
+	}
+
+	return fn
+}
+
+func apiBGPVPNv4RIB(deps APIDeps) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		query, err := ribQuery(c)
+		if err != nil {
+			AbortWithAPIError(c, http.StatusBadRequest, err.Error())
+
+			return
+		}
+
+		page, err := service.ListVPNv4RIB(c.Request.Context(), deps.BGPSrv, deps.Storage, query)
+
+		replyRIBPage(c, page, err)
+	}
+
+	return fn
+}
+
+func apiBGPVRFRIB(deps APIDeps) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		query, err := ribQuery(c)
+		if err != nil {
+			AbortWithAPIError(c, http.StatusBadRequest, err.Error())
+
+			return
+		}
+
+		page, err := service.ListVRFRIB(c.Request.Context(), deps.BGPSrv, deps.Storage, c.Param("name"), query)
+
+		replyRIBPage(c, page, err)
+	}
+
+	return fn
+}
+
+func apiBGPVRFs(deps APIDeps) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		vrfs := make([]BGPVRF, 0)
+
+		for _, vrf := range deps.Storage.BGPVRFStorage.GetVRFs() {
+			vrfs = append(vrfs, newBGPVRF(vrf))
+		}
+
+		slices.SortFunc(vrfs, func(a, b BGPVRF) int { return cmp.Compare(a.ID, b.ID) })
+
+		replyPage(c, vrfs)
+	}
+
+	return fn
+}
+
+func apiBGPVRF(deps APIDeps) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		for _, vrf := range deps.Storage.BGPVRFStorage.GetVRFs() {
+			if vrf.Name == c.Param("name") {
+				c.JSON(http.StatusOK, newBGPVRF(vrf))
+
+				return
+			}
+		}
+
+		AbortWithAPIError(c, http.StatusNotFound, fmt.Sprintf("bgp vrf %s: %s", c.Param("name"), service.ErrNotFound))
+	}
+
+	return fn
+}
+
+func apiVPPVRFs(deps APIDeps) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		vrfs := make([]VPPVRF, 0)
+
+		for _, vrf := range deps.Storage.VPPVRFStorage.GetVRFs() {
+			vrfs = append(vrfs, newVPPVRF(vrf))
+		}
+
+		slices.SortFunc(vrfs, func(a, b VPPVRF) int { return cmp.Compare(a.ID, b.ID) })
+
+		replyPage(c, vrfs)
+	}
+
+	return fn
+}
+
+func apiVPPVRF(deps APIDeps) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if vrf := deps.Storage.VPPVRFStorage.GetVRFByName(c.Param("name")); vrf != nil {
+			c.JSON(http.StatusOK, newVPPVRF(vrf))
+
+			return
+		}
+
+		AbortWithAPIError(c, http.StatusNotFound, fmt.Sprintf("vpp vrf %s: %s", c.Param("name"), service.ErrNotFound))
+	}
+
+	return fn
+}
+
+// apiFIPRoutes returns floating ip routes, query: vrf, prefix, nexthop, tunnel, offset, limit
+func apiFIPRoutes(deps APIDeps) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		vrfName, nextHop := c.Query("vrf"), c.Query("nexthop")
+
+		var tunnelID *uint32
+
+		if value := c.Query("tunnel"); value != "" {
+			id, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				AbortWithAPIError(c, http.StatusBadRequest, fmt.Sprintf("wrong tunnel %q, expected tunnel id", value))
+
+				return
+			}
+
+			value := uint32(id)
+			tunnelID = &value
+		}
+
+		var prefixFilter netip.Prefix
+
+		if value := c.Query("prefix"); value != "" {
+			var err error
+
+			if prefixFilter, err = parsePrefix(value); err != nil {
+				AbortWithAPIError(c, http.StatusBadRequest, err.Error())
+
+				return
+			}
+		}
+
+		vrfNames := make(map[uint32]string)
+
+		for _, vrf := range deps.Storage.VPPVRFStorage.GetVRFs() {
+			vrfNames[vrf.ID] = vrf.Name
+		}
+
+		tunnels := make(map[uint32]*model.VPPUDPTunnel)
+
+		for _, tunnel := range deps.Storage.VPPUDPTunnelStorage.GetUDPTunnels() {
+			tunnels[tunnel.TunnelID] = tunnel
+		}
+
+		// the most selective filter is looked up by the storage index, the others are checked for each route
+
+		var storedRoutes []*model.VPPIPRoute
+
+		switch {
+		case tunnelID != nil:
+			storedRoutes = deps.Storage.VPPFIPRouteStorage.GetFIPRoutesByTunnelID(*tunnelID)
+		case nextHop != "":
+			storedRoutes = deps.Storage.VPPFIPRouteStorage.GetFIPRoutesByNextHop(nextHop)
+		case vrfName != "":
+			if vrf := deps.Storage.VPPVRFStorage.GetVRFByName(vrfName); vrf != nil {
+				storedRoutes = deps.Storage.VPPFIPRouteStorage.GetFIPRoutesByVRF(vrf.ID)
+			}
+		default:
+			storedRoutes = deps.Storage.VPPFIPRouteStorage.GetFIPRoutes()
+		}
+
+		routes := make([]FIPRoute, 0)
+
+		for _, route := range storedRoutes {
+			if vrfName != "" && vrfNames[route.VRFID] != vrfName {
+				continue
+			}
+
+			if nextHop != "" && !slices.Contains(route.NextHops, nextHop) {
+				continue
+			}
+
+			if prefixFilter.IsValid() {
+				routePrefix, err := parsePrefix(route.Prefix)
+				if err != nil || !prefixFilter.Contains(routePrefix.Addr()) || routePrefix.Bits() < prefixFilter.Bits() {
+					continue
+				}
+			}
+
+			routes = append(routes, newFIPRoute(route, vrfNames[route.VRFID], tunnels))
+		}
+
+		slices.SortFunc(routes, func(a, b FIPRoute) int { return compareAddrs(a.Prefix, b.Prefix) })
+
+		replyPage(c, routes)
+	}
+
+	return fn
+}
+
+func apiFIPTrace(deps APIDeps) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		trace, err := service.TraceFIP(c.Request.Context(), deps.BGPSrv, deps.Stream, deps.Cfg, deps.Storage, c.Param("ip"))
+		if err != nil {
+			AbortWithAPIError(c, http.StatusBadRequest, err.Error())
+
+			return
+		}
+
+		c.JSON(http.StatusOK, trace)
+	}
+
+	return fn
+}
+
+// apiUDPTunnels returns udp tunnels, query: dst, reachable, idle, offset, limit
+func apiUDPTunnels(deps APIDeps) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		dst := c.Query("dst")
+
+		var reachable, idle *bool
+
+		if value := c.Query("reachable"); value != "" {
+			isReachable, err := strconv.ParseBool(value)
+			if err != nil {
+				AbortWithAPIError(c, http.StatusBadRequest, fmt.Sprintf("wrong reachable %q, expected true or false", value))
+
+				return
+			}
+
+			reachable = &isReachable
+		}
+
+		if value := c.Query("idle"); value != "" {
+			isIdle, err := strconv.ParseBool(value)
+			if err != nil {
+				AbortWithAPIError(c, http.StatusBadRequest, fmt.Sprintf("wrong idle %q, expected true or false", value))
+
+				return
+			}
+
+			idle = &isIdle
+		}
+
+		var storedTunnels []*model.VPPUDPTunnel
+
+		switch {
+		case dst != "":
+			if tunnel := deps.Storage.VPPUDPTunnelStorage.GetUDPTunnel(dst); tunnel != nil {
+				storedTunnels = []*model.VPPUDPTunnel{tunnel}
+			}
+		case idle != nil && *idle:
+			storedTunnels = deps.Storage.VPPUDPTunnelStorage.GetIdleUDPTunnels()
+		default:
+			storedTunnels = deps.Storage.VPPUDPTunnelStorage.GetUDPTunnels()
+		}
+
+		tunnels := make([]UDPTunnel, 0)
+
+		for _, tunnel := range storedTunnels {
+			if (reachable != nil && tunnel.Reachable != *reachable) || (idle != nil && (tunnel.FIPServed == 0) != *idle) {
+				continue
+			}
+
+			tunnels = append(tunnels, newUDPTunnel(tunnel))
+		}
+
+		slices.SortFunc(tunnels, func(a, b UDPTunnel) int { return cmp.Compare(a.ID, b.ID) })
+
+		replyPage(c, tunnels)
+	}
+
+	return fn
+}
+
+func apiDryRunFIB(deps APIDeps) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.JSON(http.StatusOK, deps.DryRun.FIB())
+	}
+
+	return fn
+}
+
+func apiHA(deps APIDeps) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.JSON(http.StatusOK, deps.HA.Status())
+	}
+
+	return fn
+}
+
+func apiBMPStations(deps APIDeps) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		stations, err := service.BMPStations(c.Request.Context(), deps.BGPSrv)
+		if err != nil {
+			AbortWithAPIError(c, http.StatusInternalServerError, err.Error())
+
+			return
+		}
+
+		c.JSON(http.StatusOK, stations)
+	}
+
+	return fn
+}
+
+// apiDryRunRecords returns recorded vpp requests, query: offset, limit
+func apiDryRunRecords(deps APIDeps) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		replyPage(c, deps.DryRun.Records())
+	}
+
+	return fn
+}
+
+func apiAdminBGPPeerReset(deps APIDeps) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		mode := c.DefaultQuery("mode", service.BGPPeerResetSoft)
+
+		err := service.ResetBGPPeer(c.Request.Context(), deps.BGPSrv, deps.Storage, c.Param("ip"), mode)
+
+		apiAdminReply(c, deps.AuditLogger, AdminResult{Action: "bgp peer reset " + mode, Target: c.Param("ip")}, err)
+	}
+
+	return fn
+}
+
+func apiAdminBGPPeerAdminState(deps APIDeps, isEnable bool) gin.HandlerFunc {
+	action := "bgp peer disable"
+
+	if isEnable {
+		action = "bgp peer enable"
+	}
+
+	fn := func(c *gin.Context) {
+		err := service.SetBGPPeerAdminState(c.Request.Context(), deps.BGPSrv, deps.Storage, c.Param("ip"), isEnable)
+
+		apiAdminReply(c, deps.AuditLogger, AdminResult{Action: action, Target: c.Param("ip")}, err)
+	}
+
+	return fn
+}
+
+func apiAdminVRFDrain(deps APIDeps, isDrain bool) gin.HandlerFunc {
+	action := "vrf undrain"
+
+	if isDrain {
+		action = "vrf drain"
+	}
+
+	fn := func(c *gin.Context) {
+		err := service.DrainVRF(c.Request.Context(), deps.BGPSrv, deps.Cfg, deps.Storage, c.Param("name"), isDrain)
+
+		apiAdminReply(c, deps.AuditLogger, AdminResult{Action: action, Target: c.Param("name")}, err)
+	}
+
+	return fn
+}
+
+func apiGatewayDrain(deps APIDeps) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.JSON(http.StatusOK, deps.Drain.Status())
+	}
+
+	return fn
+}
+
+func apiAdminGatewayDrain(deps APIDeps, isDrain bool) gin.HandlerFunc {
+	action := "gateway undrain"
+
+	if isDrain {
+		action = "gateway drain"
+	}
+
+	fn := func(c *gin.Context) {
+		var status service.GatewayDrainStatus
+
+		if isDrain {
+			status = deps.Drain.Start()
+		} else {
+			status = deps.Drain.Stop(c.Request.Context())
+		}
+
+		apiAdminReply(c, deps.AuditLogger, AdminResult{Action: action, Drain: &status}, nil)
+	}
+
+	return fn
+}
+
+func apiAdminFIPResync(deps APIDeps) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		result, err := service.ResyncFIPs(c.Request.Context(), &deps.Stream, deps.BGPSrv, deps.Cfg, deps.Storage)
+
+		apiAdminReply(c, deps.AuditLogger, AdminResult{Action: "floating ip resync", Resync: &result}, err)
+	}
+
+	return fn
+}
+
+func apiAdminReload(deps APIDeps) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		applied, restartRequired, err := deps.Reload()
+
+		deps.AuditLogger.Log(c.GetString(ActorKey), c.ClientIP(), "config reload", "", err)
+
+		if err != nil {
+			AbortWithAPIError(c, http.StatusBadRequest, err.Error())
+
+			return
+		}
+
+		c.JSON(http.StatusOK, ReloadResult{Applied: nonNil(applied), RestartRequired: nonNil(restartRequired)})
+	}
+
+	return fn
+}
+
+func apiAdminReply(c *gin.Context, auditLogger *audit.Logger, result AdminResult, err error) {
+	auditLogger.Log(c.GetString(ActorKey), c.ClientIP(), result.Action, result.Target, err)
+
+	if err != nil {
+		apiServiceError(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+
+	return values
+}
+
+func bfdSessionStates() map[string]string {
+	states := make(map[string]string)
+
+	for _, state := range service.BFDSessionStates() {
+		states[state.RemoteIP] = state.State
+	}
+
+	return states
+}
+
+// parsePrefix parses a prefix or an address (as host prefix)
+func parsePrefix(value string) (netip.Prefix, error) {
+	if prefix, err := netip.ParsePrefix(value); err == nil {
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("wrong prefix %q", value)
+	}
+
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// compareAddrs compares addresses or prefixes numerically (strings are compared if any of them can not be parsed)
+func compareAddrs(a, b string) int {
+	prefixA, errA := parsePrefix(a)
+	prefixB, errB := parsePrefix(b)
+
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b)
+	}
+
+	if c := prefixA.Addr().Compare(prefixB.Addr()); c != 0 {
+		return c
+	}
+
+	return cmp.Compare(prefixA.Bits(), prefixB.Bits())
+}
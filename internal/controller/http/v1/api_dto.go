@@ -0,0 +1,310 @@
+package v1
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	bgpapi "github.com/osrg/gobgp/v3/api"
+	"github.com/osrg/gobgp/v3/pkg/apiutil"
+	"github.com/osrg/gobgp/v3/pkg/packet/bgp"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/service"
+	"git.crptech.ru/cloud/cloudgw/pkg/exporter/vppexporter"
+)
+
+// data transfer objects of /api/v1, they are stable and do not follow changes of internal models
+
+const (
+	PeerTypeTF     = "tf"
+	PeerTypePhyNet = "phynet"
+)
+
+// APIError is an error envelope of /api/v1
+type APIError struct {
+	Error APIErrorBody `json:"Error"`
+}
+
+type APIErrorBody struct {
+	Code    string `json:"Code" enum:"bad_request,unauthorized,forbidden,not_found,internal,unavailable"`
+	Message string `json:"Message"`
+}
+
+// Page is a page of a list sorted by a key of the items
+type Page[T any] struct {
+	Total  int `json:"Total"`
+	Offset int `json:"Offset"`
+	Limit  int `json:"Limit"`
+	Items  []T `json:"Items"`
+}
+
+type BGPPeer struct {
+	Type             string    `json:"Type" enum:"tf,phynet"`
+	Address          string    `json:"Address"`
+	Port             uint32    `json:"Port"`
+	ASN              uint32    `json:"ASN"`
+	VRF              string    `json:"VRF,omitempty"`
+	Family           string    `json:"Family"`
+	State            string    `json:"State" enum:"unknown,idle,connect,active,opensent,openconfirm,established"`
+	PrevState        string    `json:"PrevState" enum:"unknown,idle,connect,active,opensent,openconfirm,established"`
+	LastActivity     time.Time `json:"LastActivity"`
+	EBGPMultihop     bool      `json:"EBGPMultihop"`
+	EBGPMultihopTTL  uint32    `json:"EBGPMultihopTTL"`
+	KeepAlive        uint64    `json:"KeepAlive"`
+	HoldTime         uint64    `json:"HoldTime"`
+	MD5PasswordSet   bool      `json:"MD5PasswordSet"` // the password itself is never returned
+	EndOfRIBReceived bool      `json:"EndOfRIBReceived"`
+	AdminDisabled    bool      `json:"AdminDisabled"`
+	BFD              *BFDPeer  `json:"BFD,omitempty"`
+}
+
+type BFDPeer struct {
+	Enabled      bool   `json:"Enabled"`
+	PeerIP       string `json:"PeerIP"`
+	LocalIP      string `json:"LocalIP"`
+	TxRate       int    `json:"TxRate"`
+	RxMin        int    `json:"RxMin"`
+	Multiplier   int    `json:"Multiplier"`
+	EchoInterval int    `json:"EchoInterval"`
+	State        string `json:"State,omitempty"` // bfd session state, empty if there is no session
+}
+
+type BGPVRF struct {
+	Name      string   `json:"Name"`
+	ID        uint32   `json:"ID"`
+	LocalASN  uint32   `json:"LocalASN"`
+	PeerASN   uint32   `json:"PeerASN"`
+	RD        string   `json:"RD"`
+	ImportRTs []string `json:"ImportRTs"`
+	ExportRTs []string `json:"ExportRTs"`
+}
+
+type VPPVRF struct {
+	Name            string   `json:"Name"`
+	ID              uint32   `json:"ID"`
+	MainInterfaceID uint32   `json:"MainInterfaceID"`
+	SubInterfaceID  *uint32  `json:"SubInterfaceID,omitempty"` // absent if sub-interface is not created
+	VLAN            uint32   `json:"VLAN"`
+	LocalAddress    string   `json:"LocalAddress"` // e.g. "203.0.113.1/24"
+	NextHop         string   `json:"NextHop"`
+	MPLSLocalLabel  uint32   `json:"MPLSLocalLabel"`
+	FIPPrefixes     []string `json:"FIPPrefixes"`
+	FIPServed       uint32   `json:"FIPServed"`
+	LinkUp          bool     `json:"LinkUp"`
+	Drained         bool     `json:"Drained"`
+}
+
+type FIPRoute struct {
+	Prefix   string           `json:"Prefix"`
+	VRF      string           `json:"VRF"`
+	VRFID    uint32           `json:"VRFID"`
+	Paths    []FIPPath        `json:"Paths"`
+	Counters *TrafficCounters `json:"Counters,omitempty"` // traffic to the floating ip through all paths
+}
+
+type FIPPath struct {
+	NextHop        string           `json:"NextHop"` // vrouter address
+	TunnelID       *uint32          `json:"TunnelID,omitempty"`
+	Label          uint32           `json:"Label"`
+	Reachable      bool             `json:"Reachable"`
+	TunnelCounters *TrafficCounters `json:"TunnelCounters,omitempty"` // traffic of all floating ips through the tunnel
+}
+
+// TrafficCounters is vpp counters, omitted if VPP.TrafficCounters is disabled or the counters are not read yet
+type TrafficCounters struct {
+	Packets uint64 `json:"Packets"`
+	Bytes   uint64 `json:"Bytes"`
+}
+
+type UDPTunnel struct {
+	ID        uint32 `json:"ID"`
+	SrcIP     string `json:"SrcIP"`
+	DstIP     string `json:"DstIP"`
+	SrcPort   uint16 `json:"SrcPort"`
+	DstPort   uint16 `json:"DstPort"`
+	FIPServed uint32 `json:"FIPServed"`
+	Reachable bool   `json:"Reachable"`
+}
+
+// AdminResult is a result of administrative action
+type AdminResult struct {
+	Action string                      `json:"Action"`
+	Target string                      `json:"Target,omitempty"`
+	Resync *service.FIPResyncResult    `json:"Resync,omitempty"`
+	Drain  *service.GatewayDrainStatus `json:"Drain,omitempty"`
+}
+
+// ReloadResult lists changed config settings applied by reload and the ones applied on restart only
+type ReloadResult struct {
+	Applied         []string `json:"Applied"`
+	RestartRequired []string `json:"RestartRequired"`
+}
+
+func newBGPPeer(peer *model.BGPPeer, bfdStates map[string]string) BGPPeer {
+	dto := BGPPeer{
+		Type:             peerTypeName(peer.PeerType),
+		Address:          peer.PeerAddress,
+		Port:             peer.PeerPort,
+		ASN:              peer.PeerASN,
+		VRF:              peer.VRFName,
+		Family:           bgp.AfiSafiToRouteFamily(uint16(peer.AFI), uint8(peer.SAFI)).String(),
+		State:            peerStateName(peer.BGPPeerState),
+		PrevState:        peerStateName(peer.BGPPeerPrevState),
+		LastActivity:     peer.BGPPeerLastActivity,
+		EBGPMultihop:     peer.EbgpMultiHop,
+		EBGPMultihopTTL:  peer.EbgpMultiHopTTL,
+		KeepAlive:        peer.KeepAliveTimer,
+		HoldTime:         peer.HoldTimer,
+		MD5PasswordSet:   peer.Md5Password != "",
+		EndOfRIBReceived: peer.EndOfRIBReceived,
+		AdminDisabled:    peer.AdminDisabled,
+	}
+
+	if peer.BFDPeering != nil {
+		dto.BFD = &BFDPeer{
+			Enabled:      peer.BFDPeering.BFDEnabled,
+			PeerIP:       peer.BFDPeering.BFDPeerIP,
+			LocalIP:      peer.BFDPeering.BFDLocalIP,
+			TxRate:       peer.BFDPeering.BFDTxRate,
+			RxMin:        peer.BFDPeering.BFDRxMin,
+			Multiplier:   peer.BFDPeering.BFDMultiplier,
+			EchoInterval: peer.BFDPeering.BFDEchoInterval,
+			State:        bfdStates[peer.BFDPeering.BFDPeerIP],
+		}
+	}
+
+	return dto
+}
+
+func newBGPVRF(vrf *model.BGPVRFTable) BGPVRF {
+	return BGPVRF{
+		Name:      vrf.Name,
+		ID:        vrf.ID,
+		LocalASN:  vrf.LocalASN,
+		PeerASN:   vrf.PeerASN,
+		RD:        rdString(vrf.RD),
+		ImportRTs: rtStrings(vrf.ImportRT),
+		ExportRTs: rtStrings(vrf.ExportRT),
+	}
+}
+
+func newVPPVRF(vrf *model.VPPVRFTable) VPPVRF {
+	dto := VPPVRF{
+		Name:            vrf.Name,
+		ID:              vrf.ID,
+		MainInterfaceID: uint32(vrf.MainInterfaceID),
+		VLAN:            vrf.VLAN,
+		LocalAddress:    fmt.Sprintf("%s/%d", vrf.LocalAddr, vrf.LocalAddrLen),
+		NextHop:         vrf.NextHop,
+		MPLSLocalLabel:  vrf.MPLSLocalLabel,
+		FIPPrefixes:     vrf.FIPPrefixes,
+		FIPServed:       vrf.FIPServed,
+		LinkUp:          vrf.LinkUp,
+		Drained:         vrf.Drained,
+	}
+
+	if vrf.SubInterfaceID != model.UndefinedSubIf {
+		subInterfaceID := uint32(vrf.SubInterfaceID)
+		dto.SubInterfaceID = &subInterfaceID
+	}
+
+	if dto.FIPPrefixes == nil {
+		dto.FIPPrefixes = []string{}
+	}
+
+	return dto
+}
+
+func newFIPRoute(route *model.VPPIPRoute, vrfName string, tunnels map[uint32]*model.VPPUDPTunnel) FIPRoute {
+	dto := FIPRoute{
+		Prefix: route.Prefix,
+		VRF:    vrfName,
+		VRFID:  route.VRFID,
+		Paths:  make([]FIPPath, 0, len(route.NextHops)),
+	}
+
+	if counter, ok := vppexporter.VPPTrafficMetrics.FIP(vppexporter.VPPFIPKey{VRFID: route.VRFID, Prefix: route.Prefix}); ok {
+		dto.Counters = newTrafficCounters(counter)
+	}
+
+	for i, nextHop := range route.NextHops {
+		path := FIPPath{NextHop: nextHop, Reachable: true}
+
+		if i < len(route.FIPMPLSLabels) {
+			path.Label = route.FIPMPLSLabels[i]
+		}
+
+		if i < len(route.TunnelIDs) && route.TunnelIDs[i] != model.UndefinedTunnelID {
+			tunnelID := route.TunnelIDs[i]
+			path.TunnelID = &tunnelID
+
+			if tunnel, ok := tunnels[tunnelID]; ok {
+				path.Reachable = tunnel.Reachable
+			}
+
+			if counter, ok := vppexporter.VPPTrafficMetrics.Tunnel(tunnelID); ok {
+				path.TunnelCounters = newTrafficCounters(counter)
+			}
+		}
+
+		dto.Paths = append(dto.Paths, path)
+	}
+
+	return dto
+}
+
+func newTrafficCounters(counter vppexporter.VPPTrafficCounter) *TrafficCounters {
+	return &TrafficCounters{Packets: counter.Packets, Bytes: counter.Bytes}
+}
+
+func newUDPTunnel(tunnel *model.VPPUDPTunnel) UDPTunnel {
+	return UDPTunnel{
+		ID:        tunnel.TunnelID,
+		SrcIP:     tunnel.SrcIP,
+		DstIP:     tunnel.DstIP,
+		SrcPort:   tunnel.SrcPort,
+		DstPort:   tunnel.DstPort,
+		FIPServed: tunnel.FIPServed,
+		Reachable: tunnel.Reachable,
+	}
+}
+
+func peerTypeName(peerType int) string {
+	if peerType == model.TF {
+		return PeerTypeTF
+	}
+
+	return PeerTypePhyNet
+}
+
+// peerStateName returns lower case bgp session state, e.g. "established"
+func peerStateName(state bgpapi.PeerState_SessionState) string {
+	return strings.ToLower(state.String())
+}
+
+func rdString(rd *anypb.Any) string {
+	if rd == nil {
+		return ""
+	}
+
+	decodedRD, err := apiutil.UnmarshalRD(rd)
+	if err != nil {
+		return ""
+	}
+
+	return decodedRD.String()
+}
+
+func rtStrings(rts []*anypb.Any) []string {
+	values := make([]string, 0, len(rts))
+
+	for _, rt := range rts {
+		if decodedRT, err := apiutil.UnmarshalRT(rt); err == nil {
+			values = append(values, decodedRT.String())
+		}
+	}
+
+	return values
+}
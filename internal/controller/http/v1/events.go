@@ -0,0 +1,146 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"git.crptech.ru/cloud/cloudgw/internal/events"
+)
+
+// eventsKeepAlive is the interval of comments sent to idle event streams, so proxies do not close them
+const eventsKeepAlive = 15 * time.Second
+
+// apiEvents streams state change events as server-sent events, query: since, type. The stream ends if the client
+// falls behind the events buffer, the client resumes it after the last received event or reloads the state on 410.
+func apiEvents(deps APIDeps) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		types, err := parseEventTypes(c.Query("type"))
+		if err != nil {
+			AbortWithAPIError(c, http.StatusBadRequest, err.Error())
+
+			return
+		}
+
+		seq := deps.Events.Last()
+
+		// browsers resend the url with Last-Event-ID header on reconnect, so the header takes precedence over the query
+
+		value := c.GetHeader("Last-Event-ID")
+		if value == "" {
+			value = c.Query("since")
+		}
+
+		if value != "" {
+			if seq, err = strconv.ParseUint(value, 10, 64); err != nil {
+				AbortWithAPIError(c, http.StatusBadRequest, fmt.Sprintf("wrong event sequence %q", value))
+
+				return
+			}
+		}
+
+		batch, notify, err := deps.Events.Since(seq)
+
+		switch {
+		case errors.Is(err, events.ErrStopped):
+			AbortWithAPIError(c, http.StatusServiceUnavailable, err.Error())
+
+			return
+		case err != nil:
+			AbortWithAPIError(c, http.StatusGone, fmt.Sprintf("failed to resume events after %d: %s", seq, err))
+
+			return
+		}
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("X-Accel-Buffering", "no") // nginx
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+
+		ticker := time.NewTicker(eventsKeepAlive)
+		defer ticker.Stop()
+
+		for {
+			for _, event := range batch {
+				seq = event.Seq
+
+				if len(types) != 0 && !slices.Contains(types, event.Type) {
+					continue
+				}
+
+				if err = writeEvent(c, event); err != nil {
+					return
+				}
+			}
+
+			c.Writer.Flush()
+
+			if !waitEvents(c, notify, ticker) {
+				return
+			}
+
+			if batch, notify, err = deps.Events.Since(seq); err != nil {
+				return
+			}
+		}
+	}
+
+	return fn
+}
+
+// waitEvents waits for new events sending keepalive comments, false is returned if the client is gone
+func waitEvents(c *gin.Context, notify <-chan struct{}, ticker *time.Ticker) bool {
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-notify:
+			return true
+		case <-ticker.C:
+			if _, err := fmt.Fprint(c.Writer, ": keepalive\n\n"); err != nil {
+				return false
+			}
+
+			c.Writer.Flush()
+		}
+	}
+}
+
+func writeEvent(c *gin.Context, event events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq, event.Type, data)
+
+	return err
+}
+
+func parseEventTypes(value string) ([]events.Type, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var types []events.Type
+
+	for _, name := range strings.Split(value, ",") {
+		eventType := events.Type(strings.TrimSpace(name))
+
+		if !slices.Contains(events.Types, eventType) {
+			return nil, fmt.Errorf("wrong event type %q, expected one of %v", name, events.Types)
+		}
+
+		types = append(types, eventType)
+	}
+
+	return types, nil
+}
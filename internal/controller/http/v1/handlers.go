@@ -1,16 +1,23 @@
 package v1
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	bgpapi "github.com/osrg/gobgp/v3/api"
+	"github.com/osrg/gobgp/v3/pkg/server"
 	vppapi "go.fd.io/govpp/api"
 	"go.fd.io/govpp/binapi/ip"
 
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/health"
+	"git.crptech.ru/cloud/cloudgw/internal/model"
 	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
 	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp"
+	"git.crptech.ru/cloud/cloudgw/internal/service"
 )
 
 type SummaryStatus struct {
@@ -25,65 +32,70 @@ type SummaryStatus struct {
 
 func Summary(storage imdb.Storage, stream vppapi.Stream) gin.HandlerFunc {
 	fn := func(c *gin.Context) {
-		var summaryStatus SummaryStatus
+		c.JSON(http.StatusOK, gin.H{"summary": newSummary(storage, stream)})
+	}
 
-		// bgp peers (total and active)
+	return fn
+}
 
-		bgpPeers := storage.BGPPeerStorage.GetBGPPeers()
+// newSummary counts bgp peers and floating ip routes and udp tunnels in memory and vpp
+func newSummary(storage imdb.Storage, stream vppapi.Stream) SummaryStatus {
+	var summaryStatus SummaryStatus
 
-		if len(bgpPeers) == 0 {
-			summaryStatus.Errors = append(summaryStatus.Errors, fmt.Errorf("no bgp peers found").Error())
-		}
-
-		summaryStatus.BGPPeerTotal = len(bgpPeers)
-		summaryStatus.BGPPeerActive = 0
+	// bgp peers (total and active)
 
-		for _, peer := range bgpPeers {
-			if peer.BGPPeerState == bgpapi.PeerState_ESTABLISHED {
-				summaryStatus.BGPPeerActive++
-			}
-		}
+	bgpPeers := storage.BGPPeerStorage.GetBGPPeers()
 
-		// in-memory vpp floating ip routes
+	if len(bgpPeers) == 0 {
+		summaryStatus.Errors = append(summaryStatus.Errors, fmt.Errorf("no bgp peers found").Error())
+	}
 
-		fips := storage.VPPFIPRouteStorage.GetFIPRoutes()
+	summaryStatus.BGPPeerTotal = len(bgpPeers)
+	summaryStatus.BGPPeerActive = 0
 
-		summaryStatus.MemVPPFIPRouteTotal = len(fips)
+	for _, peer := range bgpPeers {
+		if peer.BGPPeerState == bgpapi.PeerState_ESTABLISHED {
+			summaryStatus.BGPPeerActive++
+		}
+	}
 
-		// in-memory vpp udp tunnels
+	// in-memory vpp floating ip routes
 
-		tunnels := storage.VPPUDPTunnelStorage.GetUDPTunnels()
+	fips := storage.VPPFIPRouteStorage.GetFIPRoutes()
 
-		summaryStatus.MemVPPUDPTunnelTotal = len(tunnels)
+	summaryStatus.MemVPPFIPRouteTotal = len(fips)
 
-		// vpp floating ip routes
+	// in-memory vpp udp tunnels
 
-		summaryStatus.VPPFIPRouteTotal = 0
+	tunnels := storage.VPPUDPTunnelStorage.GetUDPTunnels()
 
-		vppVRFs := storage.VPPVRFStorage.GetVRFs()
+	summaryStatus.MemVPPUDPTunnelTotal = len(tunnels)
 
-		for i := 1; i < len(vppVRFs); i++ { // id=0 as vrf where are no floating ips
-			_, fips, err := vpp.CountRoutesPerTable(stream, ip.IPTable{TableID: uint32(i)})
-			if err != nil {
-				summaryStatus.Errors = append(summaryStatus.Errors, err.Error())
-			}
+	// vpp floating ip routes
 
-			summaryStatus.VPPFIPRouteTotal += int(fips)
-		}
+	summaryStatus.VPPFIPRouteTotal = 0
 
-		// vpp udp tunnels
+	vppVRFs := storage.VPPVRFStorage.GetVRFs()
 
-		udpCount, err := vpp.CountUDPTunnels(stream)
+	for i := 1; i < len(vppVRFs); i++ { // id=0 as vrf where are no floating ips
+		_, fips, err := vpp.CountRoutesPerTable(stream, ip.IPTable{TableID: uint32(i)})
 		if err != nil {
 			summaryStatus.Errors = append(summaryStatus.Errors, err.Error())
 		}
 
-		summaryStatus.VPPUDPTunnelTotal = int(udpCount)
+		summaryStatus.VPPFIPRouteTotal += int(fips)
+	}
+
+	// vpp udp tunnels
 
-		c.JSON(http.StatusOK, gin.H{"summary": summaryStatus})
+	udpCount, err := vpp.CountUDPTunnels(stream)
+	if err != nil {
+		summaryStatus.Errors = append(summaryStatus.Errors, err.Error())
 	}
 
-	return fn
+	summaryStatus.VPPUDPTunnelTotal = int(udpCount)
+
+	return summaryStatus
 }
 
 func BGPPeers(bgpPeerStorage *imdb.BGPPeerStorage) gin.HandlerFunc {
@@ -95,12 +107,132 @@ func BGPPeers(bgpPeerStorage *imdb.BGPPeerStorage) gin.HandlerFunc {
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"bgp peers": bgpPeers})
+		redactedPeers := make([]model.BGPPeer, 0, len(bgpPeers))
+
+		for _, peer := range bgpPeers {
+			redactedPeers = append(redactedPeers, peer.Redacted())
+		}
+
+		c.JSON(http.StatusOK, gin.H{"bgp peers": redactedPeers})
 	}
 
 	return fn
 }
 
+// BGPPeerAdjRIB returns adj-rib-in (isIn) or adj-rib-out of the peer, query: policy=pre|post (pre for adj-rib-in and
+// post for adj-rib-out by default), prefix, vrf, offset, limit
+func BGPPeerAdjRIB(bgpSrv *server.BgpServer, storage *imdb.Storage, isIn bool) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		query, err := ribQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+			return
+		}
+
+		if query.PostPolicy, err = ribPolicy(c, isIn); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+			return
+		}
+
+		page, err := service.ListAdjRIB(c.Request.Context(), bgpSrv, storage, c.Param("ip"), isIn, query)
+
+		ribReply(c, page, err)
+	}
+
+	return fn
+}
+
+// BGPVPNv4RIB returns global vpnv4 table, query: prefix, vrf, offset, limit
+func BGPVPNv4RIB(bgpSrv *server.BgpServer, storage *imdb.Storage) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		query, err := ribQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+			return
+		}
+
+		page, err := service.ListVPNv4RIB(c.Request.Context(), bgpSrv, storage, query)
+
+		ribReply(c, page, err)
+	}
+
+	return fn
+}
+
+// BGPVRFRIB returns gobgp vrf table, query: prefix, offset, limit
+func BGPVRFRIB(bgpSrv *server.BgpServer, storage *imdb.Storage) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		query, err := ribQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+			return
+		}
+
+		page, err := service.ListVRFRIB(c.Request.Context(), bgpSrv, storage, c.Param("name"), query)
+
+		ribReply(c, page, err)
+	}
+
+	return fn
+}
+
+func ribQuery(c *gin.Context) (service.RIBQuery, error) {
+	query := service.RIBQuery{
+		Prefix: c.Query("prefix"),
+		VRF:    c.Query("vrf"),
+		Limit:  service.RIBDefaultLimit,
+	}
+
+	var err error
+
+	if offset := c.Query("offset"); offset != "" {
+		if query.Offset, err = strconv.Atoi(offset); err != nil || query.Offset < 0 {
+			return query, fmt.Errorf("wrong offset %q", offset)
+		}
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil || query.Limit <= 0 || query.Limit > service.RIBMaxLimit {
+			return query, fmt.Errorf("wrong limit %q, expected 1..%d", limit, service.RIBMaxLimit)
+		}
+	}
+
+	return query, nil
+}
+
+// ribPolicy returns true for post-policy view (policy=post), policy is pre for adj-rib-in and post for adj-rib-out by default
+func ribPolicy(c *gin.Context, isIn bool) (bool, error) {
+	defaultPolicy := "post"
+
+	if isIn {
+		defaultPolicy = "pre"
+	}
+
+	switch c.DefaultQuery("policy", defaultPolicy) {
+	case "pre":
+		return false, nil
+	case "post":
+		return true, nil
+	default:
+		return false, errors.New("wrong policy, expected pre or post")
+	}
+}
+
+func ribReply(c *gin.Context, page service.RIBPage, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"bgp rib": page})
+	}
+}
+
 func BGPVRFs(bgpVRFStorage *imdb.BGPVRFStorage) gin.HandlerFunc {
 	fn := func(c *gin.Context) {
 		bgpVRFs := bgpVRFStorage.GetVRFs()
@@ -128,6 +260,21 @@ func VPPFIPRoutes(vppFIPRouteStorage *imdb.VPPFIPRouteStorage) gin.HandlerFunc {
 	return fn
 }
 
+func VPPFIPTrace(bgpSrv *server.BgpServer, stream vppapi.Stream, cfg config.Config, storage *imdb.Storage) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		trace, err := service.TraceFIP(c.Request.Context(), bgpSrv, stream, cfg, storage, c.Param("ip"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"vpp fip trace": trace})
+	}
+
+	return fn
+}
+
 func UDPTunnels(vppUDPTunnelStorage *imdb.VPPUDPTunnelStorage) gin.HandlerFunc {
 	fn := func(c *gin.Context) {
 		tunnels := vppUDPTunnelStorage.GetUDPTunnels()
@@ -136,3 +283,31 @@ func UDPTunnels(vppUDPTunnelStorage *imdb.VPPUDPTunnelStorage) gin.HandlerFunc {
 
 	return fn
 }
+
+func HealthLive(checker *health.Checker) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		report := checker.Live(c.Request.Context())
+
+		c.JSON(healthHTTPStatus(report), gin.H{"health": report})
+	}
+
+	return fn
+}
+
+func HealthReady(checker *health.Checker) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		report := checker.Ready(c.Request.Context())
+
+		c.JSON(healthHTTPStatus(report), gin.H{"health": report})
+	}
+
+	return fn
+}
+
+func healthHTTPStatus(report health.Report) int {
+	if !report.OK {
+		return http.StatusServiceUnavailable
+	}
+
+	return http.StatusOK
+}
@@ -0,0 +1,150 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	bgpapi "github.com/osrg/gobgp/v3/api"
+	"github.com/osrg/gobgp/v3/pkg/server"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/gobgp"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/internal/service"
+)
+
+func TestRIBHandlers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bgpSrv := server.NewBgpServer()
+
+	go bgpSrv.Serve()
+
+	defer bgpSrv.Stop()
+
+	require.NoError(t, bgpSrv.StartBgp(ctx, &bgpapi.StartBgpRequest{
+		Global: &bgpapi.Global{Asn: 65000, RouterId: "192.0.2.1", ListenPort: -1},
+	}))
+
+	cfg := config.Config{
+		TFController: config.TFController{BGPPeerASN: 64512},
+		GoBGP:        config.GoBGP{BGPLocalASN: 65000},
+	}
+
+	storage := imdb.NewStorage()
+
+	// aggregated floating ip prefixes of vrf1 in vpnv4 and vrf tables, tungsten fabric peer without session
+
+	bgpVRF := model.NewBGPVRFTable("vrf1", 1, 65000, 64512, model.RD("192.0.2.1", 1),
+		[]*anypb.Any{model.RT(65000, 1)}, []*anypb.Any{model.RT(64512, 1)})
+	vppVRF := &model.VPPVRFTable{Name: "vrf1", ID: 1, LocalAddr: "10.0.1.1", FIPPrefixes: []string{"172.16.1.0/24", "172.16.2.0/24"}}
+	peer := model.NewBGPPeer(model.TF, 64512, "10.0.0.10", 179, "", true, 2, "", 10, 30)
+
+	require.NoError(t, storage.BGPVRFStorage.AddVRF(&bgpVRF))
+	require.NoError(t, storage.BGPPeerStorage.AddBGPPeer(&peer))
+	require.NoError(t, gobgp.AddGoBGPVRF(ctx, bgpSrv, &bgpVRF))
+	require.NoError(t, gobgp.AddBGPPeer(ctx, bgpSrv, &peer))
+	require.NoError(t, service.AdvWdrawFIPAggrPrefixes(ctx, bgpSrv, cfg, service.ADVERTISE, vppVRF, &bgpVRF))
+
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	engine.GET("/bgp/peers/:ip/adj-in", BGPPeerAdjRIB(bgpSrv, storage, true))
+	engine.GET("/bgp/rib/vpnv4", BGPVPNv4RIB(bgpSrv, storage))
+	engine.GET("/bgp/vrfs/:name/rib", BGPVRFRIB(bgpSrv, storage))
+
+	tests := []struct {
+		name         string
+		url          string
+		wantCode     int
+		wantTotal    int
+		wantPrefixes []string
+	}{
+		{
+			name:         "vpnv4",
+			url:          "/bgp/rib/vpnv4",
+			wantCode:     http.StatusOK,
+			wantTotal:    2,
+			wantPrefixes: []string{"172.16.1.0/24", "172.16.2.0/24"},
+		},
+		{
+			name:         "vpnv4 page",
+			url:          "/bgp/rib/vpnv4?offset=1&limit=1",
+			wantCode:     http.StatusOK,
+			wantTotal:    2,
+			wantPrefixes: []string{"172.16.2.0/24"},
+		},
+		{
+			name:         "vpnv4 prefix",
+			url:          "/bgp/rib/vpnv4?prefix=172.16.1.0/24&vrf=vrf1",
+			wantCode:     http.StatusOK,
+			wantTotal:    1,
+			wantPrefixes: []string{"172.16.1.0/24"},
+		},
+		{name: "wrong offset", url: "/bgp/rib/vpnv4?offset=-1", wantCode: http.StatusBadRequest},
+		{name: "wrong limit", url: "/bgp/rib/vpnv4?limit=0", wantCode: http.StatusBadRequest},
+		{name: "limit above maximum", url: "/bgp/rib/vpnv4?limit=1001", wantCode: http.StatusBadRequest},
+		{name: "wrong prefix", url: "/bgp/rib/vpnv4?prefix=172.16", wantCode: http.StatusBadRequest},
+		{name: "unknown vrf filter", url: "/bgp/rib/vpnv4?vrf=vrf9", wantCode: http.StatusNotFound},
+		{
+			name:         "vrf",
+			url:          "/bgp/vrfs/vrf1/rib?prefix=172.16.2.1",
+			wantCode:     http.StatusOK,
+			wantTotal:    0,
+			wantPrefixes: []string{},
+		},
+		{
+			name:         "vrf more specifics",
+			url:          "/bgp/vrfs/vrf1/rib?prefix=172.16.0.0/16",
+			wantCode:     http.StatusOK,
+			wantTotal:    2,
+			wantPrefixes: []string{"172.16.1.0/24", "172.16.2.0/24"},
+		},
+		{name: "unknown vrf", url: "/bgp/vrfs/vrf9/rib", wantCode: http.StatusNotFound},
+		{
+			name:         "adj-in without session",
+			url:          "/bgp/peers/10.0.0.10/adj-in?policy=post",
+			wantCode:     http.StatusOK,
+			wantPrefixes: []string{},
+		},
+		{name: "wrong policy", url: "/bgp/peers/10.0.0.10/adj-in?policy=any", wantCode: http.StatusBadRequest},
+		{name: "unknown peer", url: "/bgp/peers/10.0.0.99/adj-in", wantCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			require.Equal(t, tt.wantCode, w.Code, w.Body.String())
+
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var reply struct {
+				Page service.RIBPage `json:"bgp rib"`
+			}
+
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &reply))
+			require.Equal(t, tt.wantTotal, reply.Page.Total)
+
+			prefixes := make([]string, 0, len(reply.Page.Paths))
+
+			for _, path := range reply.Page.Paths {
+				prefixes = append(prefixes, path.Prefix)
+			}
+
+			require.Equal(t, tt.wantPrefixes, prefixes)
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package events
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	bgpapi "github.com/osrg/gobgp/v3/api"
+
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+)
+
+// state is the part of storage dump events are produced from
+type state struct {
+	paths      map[pathKey]path
+	tunnels    map[string]uint32    // tunnel id by vrouter address
+	aggregates map[string]aggregate // by vrf name
+	peers      map[string]peer      // by address
+}
+
+type pathKey struct {
+	prefix  string
+	nextHop string
+}
+
+type path struct {
+	vrf      string
+	tunnelID *uint32
+	label    uint32
+}
+
+type aggregate struct {
+	isAdvertised bool
+	prefixes     []string
+}
+
+type peer struct {
+	vrf      string
+	state    bgpapi.PeerState_SessionState
+	bfdState string // empty if there is no bfd session
+}
+
+func newState(dump imdb.Dump) state {
+	s := state{
+		paths:      make(map[pathKey]path),
+		tunnels:    make(map[string]uint32, len(dump.UDPTunnels)),
+		aggregates: make(map[string]aggregate, len(dump.VPPVRFs)),
+		peers:      make(map[string]peer, len(dump.BGPPeers)),
+	}
+
+	vrfNames := make(map[uint32]string, len(dump.VPPVRFs))
+
+	// aggregated floating ip prefixes are advertised while the vrf serves floating ips, its link is up and it is not
+	// drained (see service.ChangeFIPRoute, service.HandleVRFLinkState and service.DrainVRF)
+
+	for _, vrf := range dump.VPPVRFs {
+		vrfNames[vrf.ID] = vrf.Name
+		s.aggregates[vrf.Name] = aggregate{
+			isAdvertised: vrf.FIPServed != 0 && vrf.LinkUp && !vrf.Drained,
+			prefixes:     vrf.FIPPrefixes,
+		}
+	}
+
+	for _, tunnel := range dump.UDPTunnels {
+		s.tunnels[tunnel.DstIP] = tunnel.TunnelID
+	}
+
+	for _, route := range dump.FIPRoutes {
+		for i, nh := range route.NextHops {
+			p := path{vrf: vrfNames[route.VRFID]}
+
+			if i < len(route.TunnelIDs) {
+				tunnelID := route.TunnelIDs[i]
+				p.tunnelID = &tunnelID
+			}
+
+			if i < len(route.FIPMPLSLabels) {
+				p.label = route.FIPMPLSLabels[i]
+			}
+
+			s.paths[pathKey{prefix: route.Prefix, nextHop: nh}] = p
+		}
+	}
+
+	for _, bgpPeer := range dump.BGPPeers {
+		p := peer{vrf: bgpPeer.VRFName, state: bgpPeer.BGPPeerState}
+
+		if bgpPeer.BFDPeering != nil && bgpPeer.BFDPeering.BFDEnabled {
+			p.bfdState = upDown(bgpPeer.BFDPeering.BFDPeerEstablished)
+		}
+
+		s.peers[bgpPeer.PeerAddress] = p
+	}
+
+	return s
+}
+
+// diff returns events changing prev state to cur: peer events first, then tunnels created, floating ip paths
+// removed and added, tunnels deleted and aggregates advertised or withdrawn (the order the changes are made in)
+func diff(prev, cur state) []Event {
+	var events []Event
+
+	for _, address := range sortedKeys(cur.peers) {
+		curPeer, prevPeer := cur.peers[address], prev.peers[address]
+
+		if curPeer.state != prevPeer.state {
+			events = append(events, Event{
+				Type: BGPPeerState, Peer: address, VRF: curPeer.vrf, State: peerStateName(curPeer.state),
+				PrevState: peerStateName(prevPeer.state),
+			})
+		}
+
+		if curPeer.bfdState != prevPeer.bfdState && curPeer.bfdState != "" {
+			events = append(events, Event{
+				Type: BFDState, Peer: address, VRF: curPeer.vrf, State: curPeer.bfdState, PrevState: prevPeer.bfdState,
+			})
+		}
+	}
+
+	for _, dst := range sortedKeys(cur.tunnels) {
+		if prevID, ok := prev.tunnels[dst]; !ok || prevID != cur.tunnels[dst] {
+			tunnelID := cur.tunnels[dst]
+			events = append(events, Event{Type: TunnelCreated, NextHop: dst, TunnelID: &tunnelID})
+		}
+	}
+
+	for _, key := range sortedPathKeys(prev.paths) {
+		if curPath, ok := cur.paths[key]; !ok || !curPath.equal(prev.paths[key]) {
+			events = append(events, prev.paths[key].event(FIPPathRemoved, key))
+		}
+	}
+
+	for _, key := range sortedPathKeys(cur.paths) {
+		if prevPath, ok := prev.paths[key]; !ok || !prevPath.equal(cur.paths[key]) {
+			events = append(events, cur.paths[key].event(FIPPathAdded, key))
+		}
+	}
+
+	for _, dst := range sortedKeys(prev.tunnels) {
+		if curID, ok := cur.tunnels[dst]; !ok || curID != prev.tunnels[dst] {
+			tunnelID := prev.tunnels[dst]
+			events = append(events, Event{Type: TunnelDeleted, NextHop: dst, TunnelID: &tunnelID})
+		}
+	}
+
+	for _, vrf := range sortedKeys(cur.aggregates) {
+		curAggr, prevAggr := cur.aggregates[vrf], prev.aggregates[vrf]
+
+		switch {
+		case curAggr.isAdvertised && !prevAggr.isAdvertised:
+			events = append(events, Event{Type: AggregateAdvertised, VRF: vrf, Prefixes: curAggr.prefixes})
+		case !curAggr.isAdvertised && prevAggr.isAdvertised:
+			events = append(events, Event{Type: AggregateWithdrawn, VRF: vrf, Prefixes: curAggr.prefixes})
+		}
+	}
+
+	return events
+}
+
+func (p path) equal(other path) bool {
+	return p.vrf == other.vrf && p.label == other.label &&
+		(p.tunnelID == nil) == (other.tunnelID == nil) && (p.tunnelID == nil || *p.tunnelID == *other.tunnelID)
+}
+
+func (p path) event(eventType Type, key pathKey) Event {
+	return Event{Type: eventType, VRF: p.vrf, Prefix: key.prefix, NextHop: key.nextHop, TunnelID: p.tunnelID, Label: p.label}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
+
+func sortedPathKeys(m map[pathKey]path) []pathKey {
+	keys := make([]pathKey, 0, len(m))
+
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	slices.SortFunc(keys, func(a, b pathKey) int {
+		return cmp.Or(cmp.Compare(a.prefix, b.prefix), cmp.Compare(a.nextHop, b.nextHop))
+	})
+
+	return keys
+}
+
+// peerStateName returns lower case bgp session state, e.g. "established"
+func peerStateName(state bgpapi.PeerState_SessionState) string {
+	return strings.ToLower(state.String())
+}
+
+func upDown(isUp bool) string {
+	if isUp {
+		return "up"
+	}
+
+	return "down"
+}
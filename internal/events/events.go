@@ -0,0 +1,168 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-memdb"
+
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/pkg/logger"
+)
+
+// Type is a type of state change event
+type Type string
+
+const (
+	FIPPathAdded        Type = "fip_path_added"
+	FIPPathRemoved      Type = "fip_path_removed"
+	TunnelCreated       Type = "tunnel_created"
+	TunnelDeleted       Type = "tunnel_deleted"
+	AggregateAdvertised Type = "aggregate_advertised"
+	AggregateWithdrawn  Type = "aggregate_withdrawn"
+	BGPPeerState        Type = "bgp_peer_state"
+	BFDState            Type = "bfd_state"
+)
+
+// Types are all event types
+var Types = []Type{
+	FIPPathAdded, FIPPathRemoved, TunnelCreated, TunnelDeleted, AggregateAdvertised, AggregateWithdrawn, BGPPeerState, BFDState,
+}
+
+var (
+	ErrSequenceExpired = errors.New("events after the sequence are not kept anymore")
+	ErrSequenceUnknown = errors.New("sequence is not issued yet (cloudgw restarted?)")
+	ErrStopped         = errors.New("event hub is stopped")
+)
+
+// Event is a change of cloudgw state. Seq is increased by one for each event since cloudgw start, fields not related
+// to the event type are omitted.
+type Event struct {
+	Seq       uint64    `json:"Seq"`
+	Time      time.Time `json:"Time"`
+	Type      Type      `json:"Type"`
+	VRF       string    `json:"VRF,omitempty"`       // vrf name of floating ip path, aggregate and physical network peer
+	Prefix    string    `json:"Prefix,omitempty"`    // floating ip
+	Prefixes  []string  `json:"Prefixes,omitempty"`  // aggregated floating ip prefixes
+	NextHop   string    `json:"NextHop,omitempty"`   // vrouter address of floating ip path and tunnel
+	TunnelID  *uint32   `json:"TunnelID,omitempty"`  // udp tunnel id of floating ip path and tunnel
+	Label     uint32    `json:"Label,omitempty"`     // mpls label of floating ip path
+	Peer      string    `json:"Peer,omitempty"`      // bgp peer address
+	State     string    `json:"State,omitempty"`     // bgp session state (e.g. established) or bfd session state (up, down)
+	PrevState string    `json:"PrevState,omitempty"` // state before the change
+}
+
+// Hub keeps the last events for subscribers, events are produced by comparing storage dumps taken on each change of
+// storage tables (memdb watch channels). Changes committed while the previous dump is compared are seen together, so a
+// change undone before the next dump (e.g. a tunnel created and deleted at once) is not reported.
+type Hub struct {
+	storage *imdb.Storage
+	size    int
+	prev    state
+	ws      memdb.WatchSet
+
+	mu      sync.Mutex
+	events  []Event // the oldest first
+	lastSeq uint64
+	notify  chan struct{} // closed and replaced when events are added
+	stopped bool
+}
+
+// NewHub returns the hub keeping size last events, changes made after NewHub are reported once Run is started
+func NewHub(storage *imdb.Storage, size int) *Hub {
+	dump, ws := storage.WatchDump()
+
+	return &Hub{storage: storage, size: max(size, 1), prev: newState(dump), ws: ws, notify: make(chan struct{})}
+}
+
+// Run produces events on storage changes until the context is canceled
+func (h *Hub) Run(ctx context.Context) {
+	logger.Info("state change events started", "buffer", h.size)
+
+	for {
+		if err := h.ws.WatchCtx(ctx); err != nil {
+			h.stop()
+
+			return
+		}
+
+		dump, ws := h.storage.WatchDump()
+		cur := newState(dump)
+
+		h.publish(time.Now(), diff(h.prev, cur))
+
+		h.prev, h.ws = cur, ws
+	}
+}
+
+// Last returns sequence of the last event (0 if no events yet)
+func (h *Hub) Last() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.lastSeq
+}
+
+// Since returns events after the sequence and the channel closed when new events are added. Error is returned if
+// some events after the sequence are dropped from the buffer or the sequence is greater than the last one.
+func (h *Hub) Since(seq uint64) ([]Event, <-chan struct{}, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.stopped {
+		return nil, nil, ErrStopped
+	}
+
+	if seq > h.lastSeq {
+		return nil, nil, ErrSequenceUnknown
+	}
+
+	firstSeq := h.lastSeq + 1
+	if len(h.events) != 0 {
+		firstSeq = h.events[0].Seq
+	}
+
+	if seq+1 < firstSeq {
+		return nil, nil, ErrSequenceExpired
+	}
+
+	return slices.Clone(h.events[seq+1-firstSeq:]), h.notify, nil
+}
+
+func (h *Hub) publish(t time.Time, events []Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, event := range events {
+		h.lastSeq++
+
+		event.Seq, event.Time = h.lastSeq, t
+
+		h.events = append(h.events, event)
+	}
+
+	// the buffer is trimmed to its size when it is twice as large, so events are not copied on each publish
+
+	if len(h.events) >= 2*h.size {
+		h.events = slices.Clone(h.events[len(h.events)-h.size:])
+	}
+
+	close(h.notify)
+	h.notify = make(chan struct{})
+}
+
+func (h *Hub) stop() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.stopped = true
+
+	close(h.notify)
+}
@@ -0,0 +1,150 @@
+package events_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	bgpapi "github.com/osrg/gobgp/v3/api"
+	"github.com/stretchr/testify/require"
+
+	"git.crptech.ru/cloud/cloudgw/internal/events"
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+)
+
+func newStorage(t *testing.T) *imdb.Storage {
+	storage := imdb.NewStorage()
+
+	peer := model.NewBGPPeer(model.PHYNET, 64555, "10.0.1.254", 179, "", true, 1, "vrf1", 3, 9)
+	peer.BFDPeering = &model.BFDPeer{BFDEnabled: true}
+
+	require.NoError(t, storage.BGPPeerStorage.AddBGPPeer(&peer))
+	require.NoError(t, storage.VPPVRFStorage.AddVRF(&model.VPPVRFTable{
+		Name: "vrf1", ID: 1, FIPPrefixes: []string{"172.16.1.0/24"}, LinkUp: true,
+	}))
+
+	return storage
+}
+
+// waitEvents returns events after the sequence, at least n of them
+func waitEvents(t *testing.T, hub *events.Hub, seq uint64, n int) []events.Event {
+	var received []events.Event
+
+	for len(received) < n {
+		batch, notify, err := hub.Since(seq)
+		require.NoError(t, err)
+
+		received = append(received, batch...)
+
+		if len(batch) != 0 {
+			seq = batch[len(batch)-1].Seq
+
+			continue
+		}
+
+		select {
+		case <-notify:
+		case <-time.After(time.Second):
+			require.Failf(t, "no events", "%d events received, %d expected", len(received), n)
+		}
+	}
+
+	return received
+}
+
+func TestHub(t *testing.T) {
+	storage := newStorage(t)
+	hub := events.NewHub(storage, 100)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// changes made after NewHub are reported
+
+	require.NoError(t, storage.BGPPeerStorage.UpdateBGPPeerState("10.0.1.254", bgpapi.PeerState_ACTIVE, bgpapi.PeerState_ESTABLISHED))
+	storage.BGPPeerStorage.UpdateBFDPeerState("10.0.1.254", true)
+
+	go hub.Run(ctx)
+
+	received := waitEvents(t, hub, 0, 2)
+	require.Equal(t, events.Event{
+		Seq: 1, Time: received[0].Time, Type: events.BGPPeerState, Peer: "10.0.1.254", VRF: "vrf1", State: "established",
+		PrevState: "unknown",
+	}, received[0])
+	require.Equal(t, events.BFDState, received[1].Type)
+	require.Equal(t, "up", received[1].State)
+	require.Equal(t, "down", received[1].PrevState)
+
+	// floating ip route with a new tunnel, the first floating ip of the vrf advertises the aggregate
+
+	txn := storage.Txn()
+	require.NoError(t, txn.AddUDPTunnel(&model.VPPUDPTunnel{TunnelID: 7, DstIP: "10.0.0.1"}))
+	require.NoError(t, txn.AddFIPRoute(&model.VPPIPRoute{
+		VRFID: 1, Prefix: "172.16.1.10/32", NextHops: []string{"10.0.0.1"}, TunnelIDs: []uint32{7}, FIPMPLSLabels: []uint32{25},
+	}))
+	_, err := txn.IncVRFFIPServed(1)
+	require.NoError(t, err)
+	txn.Commit()
+
+	tunnelID := uint32(7)
+
+	received = waitEvents(t, hub, 2, 3)
+	require.Equal(t, []events.Event{
+		{Seq: 3, Time: received[0].Time, Type: events.TunnelCreated, NextHop: "10.0.0.1", TunnelID: &tunnelID},
+		{
+			Seq: 4, Time: received[0].Time, Type: events.FIPPathAdded, VRF: "vrf1", Prefix: "172.16.1.10/32", NextHop: "10.0.0.1",
+			TunnelID: &tunnelID, Label: 25,
+		},
+		{Seq: 5, Time: received[0].Time, Type: events.AggregateAdvertised, VRF: "vrf1", Prefixes: []string{"172.16.1.0/24"}},
+	}, received)
+
+	// drain withdraws the aggregate
+
+	storage.VPPVRFStorage.SetDrained(1, true)
+
+	received = waitEvents(t, hub, 5, 1)
+	require.Equal(t, events.AggregateWithdrawn, received[0].Type)
+	require.Equal(t, uint64(6), hub.Last())
+
+	// the stream ends with the hub
+
+	cancel()
+
+	require.Eventually(t, func() bool {
+		_, _, err := hub.Since(6)
+
+		return errors.Is(err, events.ErrStopped)
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestHubSince(t *testing.T) {
+	storage := newStorage(t)
+	hub := events.NewHub(storage, 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go hub.Run(ctx)
+
+	_, _, err := hub.Since(1)
+	require.ErrorIs(t, err, events.ErrSequenceUnknown)
+
+	// the buffer keeps from size to twice the size of the last events
+
+	for i, isUp := range []bool{true, false, true, false, true} {
+		storage.BGPPeerStorage.UpdateBFDPeerState("10.0.1.254", isUp)
+
+		require.Eventually(t, func() bool { return hub.Last() == uint64(i+1) }, time.Second, 10*time.Millisecond)
+	}
+
+	_, _, err = hub.Since(1)
+	require.ErrorIs(t, err, events.ErrSequenceExpired)
+
+	received, _, err := hub.Since(2)
+	require.NoError(t, err)
+	require.Len(t, received, 3)
+	require.Equal(t, uint64(3), received[0].Seq)
+	require.Equal(t, "up", received[2].State)
+}
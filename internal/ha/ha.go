@@ -0,0 +1,395 @@
+package ha
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"sync"
+	"time"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/pkg/logger"
+)
+
+// Role is a role of the instance in ha pair
+type Role string
+
+const (
+	RoleActive  Role = "active"
+	RoleStandby Role = "standby"
+)
+
+// Local is the state of the local instance sent to the peer in heartbeats
+type Local struct {
+	Healthy    bool // bgp, bfd and dataplane health (readiness)
+	FIPRoutes  int
+	UDPTunnels int
+}
+
+// heartbeat is sent to the peer every HeartbeatInterval, signed by hmac-sha256 of the key (put before json) if it is set
+type heartbeat struct {
+	NodeID     string    `json:"NodeID"`
+	Priority   int       `json:"Priority"`
+	Role       Role      `json:"Role"`
+	Healthy    bool      `json:"Healthy"`
+	FIPRoutes  int       `json:"FIPRoutes"`
+	UDPTunnels int       `json:"UDPTunnels"`
+	Boot       int64     `json:"Boot"` // start time (unix ns), with the sequence rejects replayed heartbeats
+	Seq        uint64    `json:"Seq"`
+	Time       time.Time `json:"Time"`
+}
+
+// Status is the state of ha pair as seen by the local instance
+type Status struct {
+	NodeID        string      `json:"NodeID"`
+	Role          Role        `json:"Role"`
+	Priority      int         `json:"Priority"`
+	Healthy       bool        `json:"Healthy"`
+	FIPRoutes     int         `json:"FIPRoutes"`
+	UDPTunnels    int         `json:"UDPTunnels"`
+	RoleChangedAt time.Time   `json:"RoleChangedAt"`
+	Peer          *PeerStatus `json:"Peer"` // nil if no heartbeat is received
+}
+
+// PeerStatus is the state of the other instance from its last heartbeat
+type PeerStatus struct {
+	NodeID     string    `json:"NodeID"`
+	Address    string    `json:"Address"`
+	Alive      bool      `json:"Alive"` // the last heartbeat is received less than DeadInterval ago
+	Role       Role      `json:"Role"`
+	Priority   int       `json:"Priority"`
+	Healthy    bool      `json:"Healthy"`
+	FIPRoutes  int       `json:"FIPRoutes"`
+	UDPTunnels int       `json:"UDPTunnels"`
+	LastSeen   time.Time `json:"LastSeen"`
+}
+
+// Node is the local instance of ha pair. It starts as standby and takes a role once the peer heartbeat is received or
+// DeadInterval passes without it.
+type Node struct {
+	cfg          config.HA
+	conn         net.PacketConn
+	peerAddr     netip.AddrPort
+	local        func(ctx context.Context) Local
+	onRoleChange func(ctx context.Context, role Role)
+
+	heartbeatInterval time.Duration
+	deadInterval      time.Duration
+	boot              int64
+
+	mu            sync.RWMutex
+	self          heartbeat
+	isDecided     bool
+	roleChangedAt time.Time
+	peer          heartbeat
+	peerSeenAt    time.Time // zero if no heartbeat is received
+}
+
+// New creates the local instance of ha pair receiving heartbeats on conn. Local is called every HeartbeatInterval apart
+// from heartbeats, onRoleChange is called after the role change, never concurrently and with the latest role only.
+func New(
+	cfg config.HA,
+	conn net.PacketConn,
+	local func(ctx context.Context) Local,
+	onRoleChange func(ctx context.Context, role Role),
+) (*Node, error) {
+	peerAddr, err := netip.ParseAddrPort(cfg.Peer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse peer address %q: %w", cfg.Peer, err)
+	}
+
+	if cfg.NodeID == "" {
+		if cfg.NodeID, err = os.Hostname(); err != nil {
+			return nil, fmt.Errorf("failed to get hostname as node id: %w", err)
+		}
+	}
+
+	boot := time.Now().UnixNano()
+
+	return &Node{
+		cfg:               cfg,
+		conn:              conn,
+		peerAddr:          peerAddr,
+		local:             local,
+		onRoleChange:      onRoleChange,
+		heartbeatInterval: time.Duration(cfg.HeartbeatInterval) * time.Millisecond,
+		deadInterval:      time.Duration(cfg.DeadInterval) * time.Millisecond,
+		boot:              boot,
+		self:              heartbeat{NodeID: cfg.NodeID, Priority: cfg.Priority, Role: RoleStandby, Boot: boot},
+	}, nil
+}
+
+// Run sends and receives heartbeats and changes the role until the context is canceled
+func (n *Node) Run(ctx context.Context) {
+	roles := make(chan Role, 1)
+
+	go n.receive(ctx)
+	go n.checkLocal(ctx)
+	go n.applyRoles(ctx, roles)
+
+	startedAt := time.Now()
+
+	ticker := time.NewTicker(n.heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if role, ok := n.decide(time.Now(), startedAt); ok {
+			// only the latest role is applied, a pending one is replaced
+
+			select {
+			case <-roles:
+			default:
+			}
+
+			roles <- role
+		}
+
+		if err := n.send(); err != nil {
+			logger.Debug("failed to send ha heartbeat", "peer", n.peerAddr, "error", err)
+		}
+	}
+}
+
+// Status returns the state of ha pair
+func (n *Node) Status() Status {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	status := Status{
+		NodeID:        n.self.NodeID,
+		Role:          n.self.Role,
+		Priority:      n.self.Priority,
+		Healthy:       n.self.Healthy,
+		FIPRoutes:     n.self.FIPRoutes,
+		UDPTunnels:    n.self.UDPTunnels,
+		RoleChangedAt: n.roleChangedAt,
+	}
+
+	if !n.peerSeenAt.IsZero() {
+		status.Peer = &PeerStatus{
+			NodeID:     n.peer.NodeID,
+			Address:    n.peerAddr.String(),
+			Alive:      time.Since(n.peerSeenAt) < n.deadInterval,
+			Role:       n.peer.Role,
+			Priority:   n.peer.Priority,
+			Healthy:    n.peer.Healthy,
+			FIPRoutes:  n.peer.FIPRoutes,
+			UDPTunnels: n.peer.UDPTunnels,
+			LastSeen:   n.peerSeenAt,
+		}
+	}
+
+	return status
+}
+
+// decide takes the role by the local and peer state, true is returned if the role is changed
+func (n *Node) decide(now, startedAt time.Time) (Role, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	isPeerAlive := !n.peerSeenAt.IsZero() && now.Sub(n.peerSeenAt) < n.deadInterval
+
+	if !n.isDecided {
+		if !isPeerAlive && now.Sub(startedAt) < n.deadInterval {
+			return n.self.Role, false
+		}
+
+		n.isDecided = true
+	}
+
+	role := decide(n.self, n.peer, isPeerAlive, n.cfg.Preempt)
+	if role == n.self.Role {
+		return role, false
+	}
+
+	logger.Warn("ha role changed",
+		"node id", n.self.NodeID,
+		"role", role,
+		"prev role", n.self.Role,
+		"healthy", n.self.Healthy,
+		"peer alive", isPeerAlive,
+		"peer healthy", n.peer.Healthy,
+	)
+
+	n.self.Role = role
+	n.roleChangedAt = now
+
+	return role, true
+}
+
+// decide returns the role of the local instance: the only alive or the only healthy instance is active, without
+// preemption the active instance stays active, otherwise the instance with higher priority (lower node id) is active
+func decide(self, peer heartbeat, isPeerAlive, preempt bool) Role {
+	isActive := false
+
+	switch {
+	case !isPeerAlive:
+		isActive = true
+	case self.Healthy != peer.Healthy:
+		isActive = self.Healthy
+	case !preempt && (self.Role == RoleActive) != (peer.Role == RoleActive):
+		isActive = self.Role == RoleActive
+	case self.Priority != peer.Priority:
+		isActive = self.Priority > peer.Priority
+	default:
+		isActive = self.NodeID < peer.NodeID
+	}
+
+	if isActive {
+		return RoleActive
+	}
+
+	return RoleStandby
+}
+
+// checkLocal updates the local state every HeartbeatInterval, apart from heartbeats as health checks may take longer
+func (n *Node) checkLocal(ctx context.Context) {
+	for {
+		local := n.local(ctx)
+
+		n.mu.Lock()
+		n.self.Healthy = local.Healthy
+		n.self.FIPRoutes = local.FIPRoutes
+		n.self.UDPTunnels = local.UDPTunnels
+		n.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(n.heartbeatInterval):
+		}
+	}
+}
+
+func (n *Node) applyRoles(ctx context.Context, roles <-chan Role) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case role := <-roles:
+			n.onRoleChange(ctx, role)
+		}
+	}
+}
+
+func (n *Node) send() error {
+	n.mu.Lock()
+	n.self.Seq++
+	n.self.Time = time.Now()
+	hb := n.self
+	n.mu.Unlock()
+
+	data, err := json.Marshal(hb)
+	if err != nil {
+		return err
+	}
+
+	_, err = n.conn.WriteTo(n.sign(data), net.UDPAddrFromAddrPort(n.peerAddr))
+
+	return err
+}
+
+// receive reads heartbeats of the peer until the context is canceled
+func (n *Node) receive(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		_ = n.conn.SetReadDeadline(time.Now())
+	}()
+
+	buf := make([]byte, 64*1024)
+
+	for {
+		size, addr, err := n.conn.ReadFrom(buf)
+
+		switch {
+		case ctx.Err() != nil:
+			return
+		case errors.Is(err, net.ErrClosed):
+			return
+		case err != nil:
+			logger.Debug("failed to read ha heartbeat", "error", err)
+
+			continue
+		}
+
+		if err = n.handle(addr, buf[:size]); err != nil {
+			logger.Warn("ha heartbeat rejected", "from", addr, "error", err)
+		}
+	}
+}
+
+func (n *Node) handle(addr net.Addr, packet []byte) error {
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if !ok || udpAddr.AddrPort().Addr().Unmap() != n.peerAddr.Addr().Unmap() {
+		return fmt.Errorf("not the peer address %s", n.peerAddr.Addr())
+	}
+
+	data, err := n.verify(packet)
+	if err != nil {
+		return err
+	}
+
+	var hb heartbeat
+
+	if err = json.Unmarshal(data, &hb); err != nil {
+		return fmt.Errorf("failed to parse heartbeat: %w", err)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if hb.NodeID == n.self.NodeID {
+		return fmt.Errorf("peer node id %q is the same as local", hb.NodeID)
+	}
+
+	if !n.peerSeenAt.IsZero() && (hb.Boot < n.peer.Boot || hb.Boot == n.peer.Boot && hb.Seq <= n.peer.Seq) {
+		return nil // reordered or replayed
+	}
+
+	n.peer = hb
+	n.peerSeenAt = time.Now()
+
+	return nil
+}
+
+func (n *Node) sign(data []byte) []byte {
+	if n.cfg.Key == "" {
+		return data
+	}
+
+	mac := hmac.New(sha256.New, []byte(n.cfg.Key))
+	mac.Write(data)
+
+	return append(mac.Sum(nil), data...)
+}
+
+func (n *Node) verify(packet []byte) ([]byte, error) {
+	if n.cfg.Key == "" {
+		return packet, nil
+	}
+
+	if len(packet) < sha256.Size {
+		return nil, errors.New("heartbeat is not signed")
+	}
+
+	mac := hmac.New(sha256.New, []byte(n.cfg.Key))
+	mac.Write(packet[sha256.Size:])
+
+	if !hmac.Equal(mac.Sum(nil), packet[:sha256.Size]) {
+		return nil, errors.New("wrong heartbeat signature")
+	}
+
+	return packet[sha256.Size:], nil
+}
@@ -0,0 +1,211 @@
+package ha
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+)
+
+func TestDecide(t *testing.T) {
+	healthy := func(nodeID string, priority int, role Role) heartbeat {
+		return heartbeat{NodeID: nodeID, Priority: priority, Role: role, Healthy: true}
+	}
+
+	tests := []struct {
+		name        string
+		self        heartbeat
+		peer        heartbeat
+		isPeerAlive bool
+		preempt     bool
+		want        Role
+	}{
+		{
+			name: "peer is dead",
+			self: heartbeat{NodeID: "b", Priority: 50, Role: RoleStandby},
+			peer: healthy("a", 100, RoleActive),
+			want: RoleActive,
+		},
+		{
+			name:        "peer is unhealthy",
+			self:        healthy("b", 50, RoleStandby),
+			peer:        heartbeat{NodeID: "a", Priority: 100, Role: RoleActive},
+			isPeerAlive: true,
+			want:        RoleActive,
+		},
+		{
+			name:        "self is unhealthy",
+			self:        heartbeat{NodeID: "a", Priority: 100, Role: RoleActive},
+			peer:        healthy("b", 50, RoleStandby),
+			isPeerAlive: true,
+			want:        RoleStandby,
+		},
+		{
+			name:        "higher priority",
+			self:        healthy("b", 100, RoleStandby),
+			peer:        healthy("a", 50, RoleStandby),
+			isPeerAlive: true,
+			want:        RoleActive,
+		},
+		{
+			name:        "same priority, lower node id",
+			self:        healthy("a", 100, RoleStandby),
+			peer:        healthy("b", 100, RoleStandby),
+			isPeerAlive: true,
+			want:        RoleActive,
+		},
+		{
+			name:        "active is kept without preemption",
+			self:        healthy("b", 100, RoleStandby),
+			peer:        healthy("a", 50, RoleActive),
+			isPeerAlive: true,
+			want:        RoleStandby,
+		},
+		{
+			name:        "higher priority preempts",
+			self:        healthy("b", 100, RoleStandby),
+			peer:        healthy("a", 50, RoleActive),
+			isPeerAlive: true,
+			preempt:     true,
+			want:        RoleActive,
+		},
+		{
+			name:        "both active after partition",
+			self:        healthy("b", 100, RoleActive),
+			peer:        healthy("a", 50, RoleActive),
+			isPeerAlive: true,
+			want:        RoleActive,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, decide(tt.self, tt.peer, tt.isPeerAlive, tt.preempt))
+		})
+	}
+}
+
+// testNode is ha node on loopback with a fake dataplane health
+type testNode struct {
+	node    *Node
+	conn    net.PacketConn
+	healthy atomic.Bool
+	cancel  context.CancelFunc
+
+	mu    sync.Mutex
+	roles []Role
+}
+
+func (n *testNode) lastRole() Role {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if len(n.roles) == 0 {
+		return ""
+	}
+
+	return n.roles[len(n.roles)-1]
+}
+
+func newTestPair(t *testing.T, key, peerKey string) (*testNode, *testNode) {
+	connA, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	connB, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	a := &testNode{conn: connA}
+	b := &testNode{conn: connB}
+
+	for _, n := range []struct {
+		node     *testNode
+		cfg      config.HA
+		peerConn net.PacketConn
+	}{
+		{node: a, cfg: config.HA{NodeID: "a", Priority: 100, Key: key}, peerConn: connB},
+		{node: b, cfg: config.HA{NodeID: "b", Priority: 50, Key: peerKey}, peerConn: connA},
+	} {
+		tn := n.node
+		tn.healthy.Store(true)
+
+		n.cfg.Peer = n.peerConn.LocalAddr().String()
+		n.cfg.HeartbeatInterval = 20
+		n.cfg.DeadInterval = 100
+
+		tn.node, err = New(n.cfg, tn.conn, func(context.Context) Local {
+			return Local{Healthy: tn.healthy.Load(), FIPRoutes: 10}
+		}, func(_ context.Context, role Role) {
+			tn.mu.Lock()
+			defer tn.mu.Unlock()
+
+			tn.roles = append(tn.roles, role)
+		})
+		require.NoError(t, err)
+
+		var ctx context.Context
+
+		ctx, tn.cancel = context.WithCancel(context.Background())
+
+		go tn.node.Run(ctx)
+
+		t.Cleanup(func() {
+			tn.cancel()
+			_ = tn.conn.Close()
+		})
+	}
+
+	return a, b
+}
+
+func TestPair(t *testing.T) {
+	a, b := newTestPair(t, "secret", "secret")
+
+	// the higher priority instance is active, the standby takes no role change before the decision
+
+	require.Eventually(t, func() bool {
+		return a.lastRole() == RoleActive && b.node.Status().Peer != nil && b.node.Status().Peer.Role == RoleActive
+	}, time.Second, 10*time.Millisecond)
+	require.Empty(t, b.lastRole())
+
+	status := b.node.Status()
+	require.Equal(t, RoleStandby, status.Role)
+	require.Equal(t, "a", status.Peer.NodeID)
+	require.True(t, status.Peer.Alive)
+	require.Equal(t, 10, status.Peer.FIPRoutes)
+
+	// the active dataplane fails
+
+	a.healthy.Store(false)
+
+	require.Eventually(t, func() bool { return b.lastRole() == RoleActive && a.lastRole() == RoleStandby }, time.Second, 10*time.Millisecond)
+
+	// the active is kept without preemption when the failed instance is healthy again
+
+	a.healthy.Store(true)
+
+	time.Sleep(200 * time.Millisecond)
+	require.Equal(t, RoleActive, b.node.Status().Role)
+	require.Equal(t, RoleStandby, a.node.Status().Role)
+
+	// heartbeats are lost
+
+	b.cancel()
+
+	require.Eventually(t, func() bool { return a.lastRole() == RoleActive }, time.Second, 10*time.Millisecond)
+	require.False(t, a.node.Status().Peer.Alive)
+}
+
+func TestPairWrongKey(t *testing.T) {
+	a, b := newTestPair(t, "secret", "other")
+
+	// heartbeats are rejected, so both instances are active
+
+	require.Eventually(t, func() bool { return a.lastRole() == RoleActive && b.lastRole() == RoleActive }, time.Second, 10*time.Millisecond)
+	require.Nil(t, a.node.Status().Peer)
+}
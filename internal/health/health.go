@@ -0,0 +1,441 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket/layers"
+	bgpapi "github.com/osrg/gobgp/v3/api"
+	"github.com/osrg/gobgp/v3/pkg/server"
+	vppapi "go.fd.io/govpp/api"
+	"go.fd.io/govpp/binapi/ip"
+	"go.fd.io/govpp/core"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/gobgp"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp"
+	"git.crptech.ru/cloud/cloudgw/internal/service"
+	"git.crptech.ru/cloud/cloudgw/pkg/logger"
+)
+
+const (
+	StatusUp       = "up"
+	StatusDown     = "down"
+	StatusDisabled = "disabled"
+
+	StatusReady    = "ready"
+	StatusNotReady = "not ready"
+	StatusLive     = "live"
+	StatusNotLive  = "not live"
+
+	liveCheckTimeout = 2 * time.Second
+)
+
+// Component is a result of a component health check. Critical components in status "down" make cloudgw not ready (not live).
+type Component struct {
+	Name     string `json:"Name"`
+	Status   string `json:"Status"`
+	Critical bool   `json:"Critical"`
+	Details  string `json:"Details,omitempty"`
+}
+
+// Report is a result of liveness or readiness check
+type Report struct {
+	Status     string      `json:"Status"`
+	Components []Component `json:"Components"`
+	OK         bool        `json:"-"`
+}
+
+// drift is a result of the last dataplane drift check
+type drift struct {
+	checked       bool
+	checkedAt     time.Time
+	memFIPRoutes  int
+	vppFIPRoutes  int
+	memUDPTunnels int
+	vppUDPTunnels int
+	err           error
+}
+
+// Checker checks health of cloudgw components (vpp connections, bgp and bfd peers, initial sync and dataplane drift)
+type Checker struct {
+	cfg      config.Health
+	storage  *imdb.Storage
+	bgpSrv   *server.BgpServer
+	vppConn  vppapi.Connection // nil in dry-run mode, the drift is not checked
+	vppStats *core.StatsConnection
+
+	mu           sync.RWMutex
+	vppConnState core.ConnectionState
+	drift        drift
+}
+
+func NewChecker(
+	cfg config.Health,
+	storage *imdb.Storage,
+	bgpSrv *server.BgpServer,
+	vppConn vppapi.Connection,
+	vppStats *core.StatsConnection,
+) *Checker {
+	return &Checker{
+		cfg:          cfg,
+		storage:      storage,
+		bgpSrv:       bgpSrv,
+		vppConn:      vppConn,
+		vppStats:     vppStats,
+		vppConnState: core.Connected, // the app is started after vpp connection established
+	}
+}
+
+// SetVPPConnState updates state of vpp binary api connection
+func (c *Checker) SetVPPConnState(state core.ConnectionState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.vppConnState = state
+}
+
+// Live checks if cloudgw is alive (vpp binary api connection is not failed and gobgp server responds)
+func (c *Checker) Live(ctx context.Context) Report {
+	components := []Component{
+		c.checkVPPBinAPI(),
+		c.checkGoBGP(ctx),
+	}
+
+	return newReport(components, StatusLive, StatusNotLive)
+}
+
+// Ready checks if cloudgw is ready to forward traffic
+func (c *Checker) Ready(ctx context.Context) Report {
+	components := []Component{
+		c.checkVPPBinAPI(),
+		c.checkVPPStats(),
+		c.checkGoBGP(ctx),
+	}
+
+	components = append(components, c.checkBGPPeers()...)
+	components = append(components, c.checkBFDSessions()...)
+	components = append(components, c.checkEndOfRIB(), c.checkDrift())
+
+	return newReport(components, StatusReady, StatusNotReady)
+}
+
+// RunDriftCheck compares floating ip routes and udp tunnels in memory and vpp every DriftCheckInterval seconds
+func (c *Checker) RunDriftCheck(ctx context.Context) {
+	if c.vppConn == nil {
+		logger.Info("dataplane drift check is disabled as vpp is not connected (dry-run mode)")
+
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(c.cfg.DriftCheckInterval) * time.Second)
+
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("dataplane drift check stopped")
+
+			return
+		case <-ticker.C:
+			result := c.countDrift(ctx)
+
+			if result.err != nil {
+				logger.Error("failed to check dataplane drift", "error", result.err)
+			}
+
+			c.mu.Lock()
+			c.drift = result
+			c.mu.Unlock()
+		}
+	}
+}
+
+// countDrift dumps vpp on a dedicated stream, as replies to dumps on the shared stream interleave with replies to
+// requests of other goroutines
+func (c *Checker) countDrift(ctx context.Context) drift {
+	result := drift{
+		checked:       true,
+		checkedAt:     time.Now(),
+		memFIPRoutes:  len(c.storage.VPPFIPRouteStorage.GetFIPRoutes()),
+		memUDPTunnels: len(c.storage.VPPUDPTunnelStorage.GetUDPTunnels()),
+	}
+
+	stream, err := c.vppConn.NewStream(ctx)
+	if err != nil {
+		result.err = fmt.Errorf("failed to create vpp stream for drift check: %w", err)
+
+		return result
+	}
+
+	defer stream.Close()
+
+	for _, vrf := range c.storage.VPPVRFStorage.GetVRFs() {
+		if vrf.ID == 0 { // no floating ips in grt
+			continue
+		}
+
+		_, fipRouteCount, err := vpp.CountRoutesPerTable(stream, ip.IPTable{TableID: vrf.ID})
+		if err != nil {
+			result.err = fmt.Errorf("failed to count floating ip routes in vrf %d: %w", vrf.ID, err)
+
+			return result
+		}
+
+		result.vppFIPRoutes += int(fipRouteCount)
+	}
+
+	udpTunnelCount, err := vpp.CountUDPTunnels(stream)
+	if err != nil {
+		result.err = fmt.Errorf("failed to count udp tunnels: %w", err)
+
+		return result
+	}
+
+	result.vppUDPTunnels = int(udpTunnelCount)
+
+	return result
+}
+
+func (c *Checker) checkVPPBinAPI() Component {
+	c.mu.RLock()
+	state := c.vppConnState
+	c.mu.RUnlock()
+
+	component := Component{Name: "vpp binapi", Status: StatusUp, Critical: true, Details: state.String()}
+
+	if state != core.Connected {
+		component.Status = StatusDown
+	}
+
+	return component
+}
+
+func (c *Checker) checkVPPStats() Component {
+	component := Component{Name: "vpp stats", Status: StatusUp}
+
+	if c.vppStats == nil {
+		component.Status = StatusDown
+		component.Details = "not connected"
+
+		return component
+	}
+
+	if err := c.vppStats.GetSystemStats(&vppapi.SystemStats{}); err != nil {
+		component.Status = StatusDown
+		component.Details = err.Error()
+	}
+
+	return component
+}
+
+func (c *Checker) checkGoBGP(ctx context.Context) Component {
+	component := Component{Name: "gobgp", Status: StatusUp, Critical: true}
+
+	ctx, cancel := context.WithTimeout(ctx, liveCheckTimeout)
+	defer cancel()
+
+	if _, err := gobgp.GetGoBGPLocalConfig(ctx, c.bgpSrv); err != nil {
+		component.Status = StatusDown
+		component.Details = err.Error()
+	}
+
+	return component
+}
+
+// checkBGPPeers reports each peer state and number of established peers of each type compared to configured minimums
+func (c *Checker) checkBGPPeers() []Component {
+	var (
+		components             []Component
+		tfEstablished, tfTotal int
+		pnEstablished, pnTotal int
+	)
+
+	for _, peer := range c.storage.BGPPeerStorage.GetBGPPeers() {
+		component := Component{
+			Name:    fmt.Sprintf("bgp peer %s (%s)", peer.PeerAddress, peerTypeName(peer.PeerType)),
+			Status:  StatusDown,
+			Details: peer.BGPPeerState.String(),
+		}
+
+		isEstablished := peer.BGPPeerState == bgpapi.PeerState_ESTABLISHED
+
+		if isEstablished {
+			component.Status = StatusUp
+		}
+
+		switch peer.PeerType {
+		case model.TF:
+			tfTotal++
+
+			if isEstablished {
+				tfEstablished++
+			}
+		case model.PHYNET:
+			pnTotal++
+
+			if isEstablished {
+				pnEstablished++
+			}
+		}
+
+		components = append(components, component)
+	}
+
+	components = append(components,
+		minPeersComponent("tungsten fabric peers", tfEstablished, tfTotal, c.cfg.TFPeersMin),
+		minPeersComponent("physical network peers", pnEstablished, pnTotal, c.cfg.PhyNetPeersMin),
+	)
+
+	return components
+}
+
+// checkBFDSessions reports bfd session of each physical network peer with established bgp session and bfd enabled
+func (c *Checker) checkBFDSessions() []Component {
+	sessionStates := make(map[string]string)
+
+	for _, state := range service.BFDSessionStates() {
+		sessionStates[state.RemoteIP] = state.State
+	}
+
+	var components []Component
+
+	for _, peer := range c.storage.BGPPeerStorage.GetBGPPeers() {
+		if peer.PeerType != model.PHYNET || peer.BFDPeering == nil || !peer.BFDPeering.BFDEnabled {
+			continue
+		}
+
+		component := Component{
+			Name:     "bfd session " + peer.BFDPeering.BFDPeerIP,
+			Status:   StatusDown,
+			Critical: !c.cfg.BFDIgnore,
+		}
+
+		if peer.BGPPeerState != bgpapi.PeerState_ESTABLISHED {
+			component.Status = StatusDisabled
+			component.Critical = false
+			component.Details = "bgp session is not established"
+
+			components = append(components, component)
+
+			continue
+		}
+
+		state, ok := sessionStates[peer.BFDPeering.BFDPeerIP]
+		if !ok {
+			component.Details = "no bfd session"
+
+			components = append(components, component)
+
+			continue
+		}
+
+		component.Details = state
+
+		if state == layers.BFDStateUp.String() {
+			component.Status = StatusUp
+		}
+
+		components = append(components, component)
+	}
+
+	return components
+}
+
+// checkEndOfRIB checks if initial routing table is received at least from one established tungsten fabric peer
+func (c *Checker) checkEndOfRIB() Component {
+	component := Component{Name: "tungsten fabric end-of-rib", Status: StatusDown, Critical: !c.cfg.EndOfRIBIgnore}
+
+	var received []string
+
+	for _, peer := range c.storage.BGPPeerStorage.GetBGPPeers() {
+		if peer.PeerType == model.TF && peer.EndOfRIBReceived {
+			received = append(received, peer.PeerAddress)
+		}
+	}
+
+	if len(received) > 0 {
+		component.Status = StatusUp
+		component.Details = fmt.Sprintf("received from %v", received)
+	} else {
+		component.Details = "not received from any peer"
+	}
+
+	return component
+}
+
+func (c *Checker) checkDrift() Component {
+	c.mu.RLock()
+	result := c.drift
+	c.mu.RUnlock()
+
+	component := Component{Name: "dataplane drift", Status: StatusUp, Critical: true}
+
+	switch {
+	case !result.checked:
+		component.Details = "not checked yet"
+	case result.err != nil:
+		component.Status = StatusDown
+		component.Details = result.err.Error()
+	default:
+		fipDrift := int(math.Abs(float64(result.memFIPRoutes - result.vppFIPRoutes)))
+		tunnelDrift := int(math.Abs(float64(result.memUDPTunnels - result.vppUDPTunnels)))
+
+		component.Details = fmt.Sprintf(
+			"floating ip routes %d/%d, udp tunnels %d/%d (memory/vpp), checked at %s",
+			result.memFIPRoutes, result.vppFIPRoutes,
+			result.memUDPTunnels, result.vppUDPTunnels,
+			result.checkedAt.Format(time.RFC3339),
+		)
+
+		if fipDrift+tunnelDrift > c.cfg.DriftMax {
+			component.Status = StatusDown
+		}
+	}
+
+	return component
+}
+
+func minPeersComponent(name string, established, total, minEstablished int) Component {
+	component := Component{
+		Name:     name,
+		Status:   StatusUp,
+		Critical: true,
+		Details:  fmt.Sprintf("%d/%d established (min %d)", established, total, minEstablished),
+	}
+
+	if established < minEstablished {
+		component.Status = StatusDown
+	}
+
+	return component
+}
+
+func newReport(components []Component, okStatus, failedStatus string) Report {
+	report := Report{Status: okStatus, Components: components, OK: true}
+
+	for _, component := range components {
+		if component.Critical && component.Status == StatusDown {
+			report.Status = failedStatus
+			report.OK = false
+
+			break
+		}
+	}
+
+	return report
+}
+
+func peerTypeName(peerType int) string {
+	if peerType == model.TF {
+		return "tf"
+	}
+
+	return "phynet"
+}
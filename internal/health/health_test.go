@@ -0,0 +1,233 @@
+package health
+
+import (
+	"context"
+	"testing"
+
+	bgpapi "github.com/osrg/gobgp/v3/api"
+	"github.com/osrg/gobgp/v3/pkg/server"
+	"github.com/stretchr/testify/require"
+	vppapi "go.fd.io/govpp/api"
+	"go.fd.io/govpp/core"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp/dryrun"
+)
+
+// fakeConn is vpp connection creating streams of the dry-run vpp
+type fakeConn struct {
+	vppapi.Connection
+
+	stream  vppapi.Stream
+	streams int
+}
+
+func (c *fakeConn) NewStream(context.Context, ...vppapi.StreamOption) (vppapi.Stream, error) {
+	c.streams++
+
+	return c.stream, nil
+}
+
+func TestChecker(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bgpSrv := server.NewBgpServer()
+
+	go bgpSrv.Serve()
+
+	defer bgpSrv.Stop()
+
+	require.NoError(t, bgpSrv.StartBgp(ctx, &bgpapi.StartBgpRequest{
+		Global: &bgpapi.Global{Asn: 65000, RouterId: "192.0.2.1", ListenPort: -1},
+	}))
+
+	// vpp has floating ip route 172.16.1.10/32 and udp tunnel to vrouter 10.1.1.1
+
+	stream := dryrun.NewStream(ctx)
+
+	vrf := &model.VPPVRFTable{Name: "vrf1", ID: 1}
+	tunnel := &model.VPPUDPTunnel{SrcIP: "192.0.0.1", DstIP: "10.1.1.1", SrcPort: 50000, DstPort: 6635}
+	route := model.NewVPPIPRoute(1, 1, model.UndefinedSubIf, "172.16.1.10/32", []string{"10.1.1.1"}, []uint32{0}, []uint32{25})
+
+	require.NoError(t, vpp.AddDelVRF(stream, true, *vrf))
+	require.NoError(t, vpp.AddUDPTunnel(stream, tunnel))
+	require.NoError(t, vpp.AddDelFIPRoute(stream, true, &route))
+
+	established := func(peer model.BGPPeer) model.BGPPeer {
+		peer.BGPPeerState = bgpapi.PeerState_ESTABLISHED
+
+		return peer
+	}
+
+	tfPeer := model.BGPPeer{PeerType: model.TF, PeerAddress: "10.0.0.10", EndOfRIBReceived: true}
+	phyNetPeer := model.BGPPeer{PeerType: model.PHYNET, PeerAddress: "10.12.0.3"}
+	bfdPeer := model.BGPPeer{
+		PeerType: model.PHYNET, PeerAddress: "10.12.0.3",
+		BFDPeering: &model.BFDPeer{BFDEnabled: true, BFDPeerIP: "10.12.0.3", BFDLocalIP: "10.12.0.1"},
+	}
+
+	tests := []struct {
+		name       string
+		cfg        config.Health
+		peers      []model.BGPPeer
+		extraFIP   bool // floating ip route in memory only
+		noDrift    bool // drift is not checked yet
+		connState  core.ConnectionState
+		wantReady  bool
+		wantLive   bool
+		wantFailed []string // critical components down
+	}{
+		{
+			name:      "ready",
+			cfg:       config.Health{TFPeersMin: 1, PhyNetPeersMin: 1},
+			peers:     []model.BGPPeer{established(tfPeer), established(phyNetPeer)},
+			wantReady: true,
+			wantLive:  true,
+		},
+		{
+			name:       "tungsten fabric peers below minimum",
+			cfg:        config.Health{TFPeersMin: 2},
+			peers:      []model.BGPPeer{established(tfPeer)},
+			wantLive:   true,
+			wantFailed: []string{"tungsten fabric peers"},
+		},
+		{
+			name:       "physical network peers below minimum",
+			cfg:        config.Health{TFPeersMin: 1, PhyNetPeersMin: 1},
+			peers:      []model.BGPPeer{established(tfPeer), phyNetPeer},
+			wantLive:   true,
+			wantFailed: []string{"physical network peers"},
+		},
+		{
+			name:       "no end-of-rib",
+			cfg:        config.Health{},
+			peers:      []model.BGPPeer{{PeerType: model.TF, PeerAddress: "10.0.0.10"}},
+			wantLive:   true,
+			wantFailed: []string{"tungsten fabric end-of-rib"},
+		},
+		{
+			name:      "no end-of-rib ignored",
+			cfg:       config.Health{EndOfRIBIgnore: true},
+			peers:     []model.BGPPeer{{PeerType: model.TF, PeerAddress: "10.0.0.10"}},
+			wantReady: true,
+			wantLive:  true,
+		},
+		{
+			name:       "bfd session down",
+			cfg:        config.Health{TFPeersMin: 1},
+			peers:      []model.BGPPeer{established(tfPeer), established(bfdPeer)},
+			wantLive:   true,
+			wantFailed: []string{"bfd session 10.12.0.3"},
+		},
+		{
+			name:      "bfd session down ignored",
+			cfg:       config.Health{TFPeersMin: 1, BFDIgnore: true},
+			peers:     []model.BGPPeer{established(tfPeer), established(bfdPeer)},
+			wantReady: true,
+			wantLive:  true,
+		},
+		{
+			name:      "bfd session of not established peer",
+			cfg:       config.Health{},
+			peers:     []model.BGPPeer{established(tfPeer), bfdPeer},
+			wantReady: true,
+			wantLive:  true,
+		},
+		{
+			name:       "drift exceeds maximum",
+			cfg:        config.Health{},
+			peers:      []model.BGPPeer{established(tfPeer)},
+			extraFIP:   true,
+			wantLive:   true,
+			wantFailed: []string{"dataplane drift"},
+		},
+		{
+			name:      "drift within maximum",
+			cfg:       config.Health{DriftMax: 1},
+			peers:     []model.BGPPeer{established(tfPeer)},
+			extraFIP:  true,
+			wantReady: true,
+			wantLive:  true,
+		},
+		{
+			name:      "drift not checked yet",
+			cfg:       config.Health{},
+			peers:     []model.BGPPeer{established(tfPeer)},
+			extraFIP:  true,
+			noDrift:   true,
+			wantReady: true,
+			wantLive:  true,
+		},
+		{
+			name:       "vpp binapi disconnected",
+			cfg:        config.Health{},
+			peers:      []model.BGPPeer{established(tfPeer)},
+			connState:  core.Disconnected,
+			wantFailed: []string{"vpp binapi"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := imdb.NewStorage()
+
+			require.NoError(t, storage.VPPVRFStorage.AddVRF(vrf))
+			require.NoError(t, storage.VPPUDPTunnelStorage.AddUDPTunnel(tunnel))
+			require.NoError(t, storage.VPPFIPRouteStorage.AddFIPRoute(&route))
+
+			if tt.extraFIP {
+				extra := model.NewVPPIPRoute(1, 1, model.UndefinedSubIf, "172.16.1.11/32", []string{"10.1.1.1"}, []uint32{0}, []uint32{26})
+
+				require.NoError(t, storage.VPPFIPRouteStorage.AddFIPRoute(&extra))
+			}
+
+			for _, peer := range tt.peers {
+				require.NoError(t, storage.BGPPeerStorage.AddBGPPeer(&peer))
+			}
+
+			conn := &fakeConn{stream: stream}
+			checker := NewChecker(tt.cfg, storage, bgpSrv, conn, nil)
+
+			checker.SetVPPConnState(tt.connState)
+
+			if !tt.noDrift {
+				checker.drift = checker.countDrift(ctx)
+
+				require.NoError(t, checker.drift.err)
+				require.Equal(t, 1, conn.streams) // dumped on a dedicated stream
+			}
+
+			ready := checker.Ready(ctx)
+
+			require.Equal(t, tt.wantReady, ready.OK)
+			require.Equal(t, tt.wantFailed, failedComponents(ready))
+
+			if tt.wantReady {
+				require.Equal(t, StatusReady, ready.Status)
+			} else {
+				require.Equal(t, StatusNotReady, ready.Status)
+			}
+
+			live := checker.Live(ctx)
+
+			require.Equal(t, tt.wantLive, live.OK)
+		})
+	}
+}
+
+// failedComponents returns names of critical components down
+func failedComponents(report Report) []string {
+	var names []string
+
+	for _, component := range report.Components {
+		if component.Critical && component.Status == StatusDown {
+			names = append(names, component.Name)
+		}
+	}
+
+	return names
+}
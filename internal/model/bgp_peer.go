@@ -9,6 +9,8 @@ import (
 const (
 	TF     int = 0
 	PHYNET int = 1
+
+	RedactedSecret = "******" // replaces secrets in http api replies
 )
 
 type BGPPeer struct {
@@ -27,6 +29,8 @@ type BGPPeer struct {
 	BGPPeerState        bgpapi.PeerState_SessionState
 	BGPPeerPrevState    bgpapi.PeerState_SessionState
 	BGPPeerLastActivity time.Time
+	EndOfRIBReceived    bool     // initial routing table received (reset when bgp session goes down)
+	AdminDisabled       bool     // bgp session shut down by administrator (not restored by vpp interface monitoring)
 	BFDPeering          *BFDPeer // nil for tungsten fabric controllers
 }
 
@@ -38,6 +42,7 @@ type BFDPeer struct {
 	BFDTxRate          int
 	BFDRxMin           int
 	BFDMultiplier      int
+	BFDEchoInterval    int // 0 - echo function disabled
 }
 
 func NewBGPPeer(
@@ -98,6 +103,15 @@ func NewBGPPeer(
 	return peer
 }
 
+// Redacted returns a copy of the peer with md5 password replaced by RedactedSecret
+func (p BGPPeer) Redacted() BGPPeer {
+	if p.Md5Password != "" {
+		p.Md5Password = RedactedSecret
+	}
+
+	return p
+}
+
 func NewBFDPeer(
 	bfdEnabled bool,
 	bfdPeerIP string,
@@ -105,6 +119,7 @@ func NewBFDPeer(
 	bfdTxRate int,
 	bfdRxMin int,
 	bfdMultiplier int,
+	bfdEchoInterval int,
 ) BFDPeer {
 	return BFDPeer{
 		BFDEnabled:         bfdEnabled,
@@ -114,5 +129,6 @@ func NewBFDPeer(
 		BFDTxRate:          bfdTxRate,
 		BFDRxMin:           bfdRxMin,
 		BFDMultiplier:      bfdMultiplier,
+		BFDEchoInterval:    bfdEchoInterval,
 	}
 }
@@ -0,0 +1,23 @@
+package model_test
+
+import (
+	"testing"
+
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedacted(t *testing.T) {
+	peer := model.NewBGPPeer(model.PHYNET, 65001, "203.0.113.1", 179, "secret", true, 10, "vrf1", 30, 90)
+
+	redacted := peer.Redacted()
+
+	require.Equal(t, model.RedactedSecret, redacted.Md5Password)
+	require.Equal(t, "secret", peer.Md5Password)
+	require.Equal(t, peer.PeerAddress, redacted.PeerAddress)
+
+	peer = model.NewBGPPeer(model.TF, 65001, "192.0.2.11", 179, "", true, 10, "", 1, 3)
+
+	require.Empty(t, peer.Redacted().Md5Password)
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"slices"
 	"strconv"
 	"strings"
 
@@ -38,6 +39,16 @@ func NewVPPIPRoute(
 	}
 }
 
+// Clone returns a copy of the route with copied paths (routes from storage must not be changed in place)
+func (r *VPPIPRoute) Clone() VPPIPRoute {
+	route := *r
+	route.NextHops = slices.Clone(r.NextHops)
+	route.TunnelIDs = slices.Clone(r.TunnelIDs)
+	route.FIPMPLSLabels = slices.Clone(r.FIPMPLSLabels)
+
+	return route
+}
+
 func (r *VPPIPRoute) MPLSLabels() string {
 	if len(r.FIPMPLSLabels) == 0 {
 		return ""
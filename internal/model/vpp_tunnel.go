@@ -13,6 +13,7 @@ type VPPUDPTunnel struct {
 	SrcPort        uint16 // random 49152 to 65535 (https://datatracker.ietf.org/doc/rfc7510/)
 	DstPort        uint16 // 6635 by rfc7510
 	FIPServed      uint32
+	Reachable      bool // vrouter replies to probes (always true if tunnel probing disabled)
 }
 
 const (
@@ -37,5 +38,6 @@ func NewVPPUDPTunnel(
 		SrcPort:        srcPort,
 		DstPort:        uint16(6635),
 		FIPServed:      0,
+		Reachable:      true,
 	}
 }
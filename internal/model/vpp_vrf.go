@@ -21,6 +21,8 @@ type VPPVRFTable struct {
 	MPLSLocalLabel  uint32
 	FIPPrefixes     []string
 	FIPServed       uint32
+	LinkUp          bool // vpp main interface and sub-interface are up (aggregated floating ip prefixes may be advertised)
+	Drained         bool // aggregated floating ip prefixes are withdrawn by administrator, floating ips stay installed
 }
 
 const (
@@ -53,5 +55,6 @@ func NewVPPVRFTable(
 		MPLSLocalLabel:  mplsLocalLabel,
 		FIPPrefixes:     fipPrefixes,
 		FIPServed:       0,
+		LinkUp:          true,
 	}
 }
@@ -10,7 +10,8 @@ import (
 )
 
 // VPPConnStatus checks if Bin API VPP connection status changed from "Connected" to "Disconnected"/"Failed".
-func VPPConnStatus(ctx context.Context, vppEvt chan core.ConnectionEvent, wg *sync.WaitGroup) {
+// Every state change is passed to onStateChange (if set).
+func VPPConnStatus(ctx context.Context, vppEvt chan core.ConnectionEvent, onStateChange func(core.ConnectionState), wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	for {
@@ -20,6 +21,10 @@ func VPPConnStatus(ctx context.Context, vppEvt chan core.ConnectionEvent, wg *sy
 
 			return
 		case currState := <-vppEvt:
+			if onStateChange != nil {
+				onStateChange(currState.State)
+			}
+
 			if currState.State.String() == "Disconnected" || currState.State.String() == "Failed" {
 				logger.Error("vpp api connection status changed", "current state", currState.State)
 
@@ -2,94 +2,157 @@ package monitor
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/osrg/gobgp/v3/pkg/server"
+	"go.fd.io/govpp/api"
+	interfaces "go.fd.io/govpp/binapi/interface"
+	"go.fd.io/govpp/binapi/interface_types"
 
 	"git.crptech.ru/cloud/cloudgw/internal/config"
-	"git.crptech.ru/cloud/cloudgw/internal/model"
-	"git.crptech.ru/cloud/cloudgw/internal/repository/gobgp"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp"
+	"git.crptech.ru/cloud/cloudgw/internal/service"
 	"git.crptech.ru/cloud/cloudgw/pkg/logger"
-	"git.crptech.ru/cloud/cloudgw/pkg/netutils"
 )
 
-func VPPInterfaceStatus(ctx context.Context, cfg *config.Config, bgpPeers []*model.BGPPeer, bgpSrv *server.BgpServer) error {
-	if cfg.VPP.InterfaceMonitorEnable {
-		monitoredAddress := []string{netutils.Addr(cfg.VPP.TunLocalIP)}
+// vppInterfaceMonitor keeps link states of vpp main interface and vrf sub-interfaces
+type vppInterfaceMonitor struct {
+	cfg     config.Config
+	bgpSrv  *server.BgpServer
+	storage *imdb.Storage
 
-		go ProbeVPPInterface(ctx, bgpSrv, bgpPeers, monitoredAddress, 2, 10)
+	mainInterfaceID interface_types.InterfaceIndex
+	mainUp          bool
+	subUp           map[interface_types.InterfaceIndex]bool
+	subToVRF        map[interface_types.InterfaceIndex]uint32
+}
+
+// VPPInterfaceStatus subscribes to vpp interface events (link/admin state) of main interface and vrf sub-interfaces.
+// Sub-interface down withdraws aggregated floating ip prefixes of the vrf and shuts down the bgp peer to physical network,
+// main interface down withdraws aggregated floating ip prefixes of all vrfs. All is restored when the interfaces are up.
+func VPPInterfaceStatus(
+	ctx context.Context,
+	cfg *config.Config,
+	vppConn api.Connection,
+	vppStream *api.Stream,
+	bgpSrv *server.BgpServer,
+	storage *imdb.Storage,
+) error {
+	if !cfg.VPP.InterfaceMonitorEnable {
+		return nil
+	}
+
+	m := &vppInterfaceMonitor{
+		cfg:             *cfg,
+		bgpSrv:          bgpSrv,
+		storage:         storage,
+		mainInterfaceID: interface_types.InterfaceIndex(cfg.VPP.MainInterfaceID),
+		mainUp:          true,
+		subUp:           make(map[interface_types.InterfaceIndex]bool),
+		subToVRF:        make(map[interface_types.InterfaceIndex]uint32),
+	}
+
+	for _, vrf := range storage.VPPVRFStorage.GetVRFs() {
+		if vrf.ID == 0 {
+			continue
+		}
+
+		m.subUp[vrf.SubInterfaceID] = true
+		m.subToVRF[vrf.SubInterfaceID] = vrf.ID
+	}
 
-		logger.Info("vpp main interface monitoring started", "monitored addresses", monitoredAddress)
+	// subscribe before enabling the events to not miss any of them
+
+	watcher, err := vppConn.WatchEvent(ctx, &interfaces.SwInterfaceEvent{})
+	if err != nil {
+		return fmt.Errorf("failed to watch vpp interface events: %w", err)
+	}
+
+	if err = vpp.WantInterfaceEvents(*vppStream, true); err != nil {
+		watcher.Close()
+
+		return fmt.Errorf("failed to enable vpp interface events: %w", err)
 	}
 
+	// apply current link states as the interfaces could go down before the subscription
+
+	linkStates, err := vpp.DumpInterfaceLinkStates(*vppStream)
+	if err != nil {
+		logger.Error("failed to dump vpp interface link states", "error", err)
+	}
+
+	for swIfIndex, isUp := range linkStates {
+		m.handleLinkState(ctx, swIfIndex, isUp)
+	}
+
+	go m.watch(ctx, watcher)
+
+	logger.Info("vpp interface monitoring started", "main interface id", m.mainInterfaceID, "sub-interfaces", len(m.subToVRF))
+
 	return nil
 }
 
-// ProbeVPPInterface checks vpp interface(s) availability and exit from the program if any ip address of vpp are not available
-func ProbeVPPInterface(
-	ctx context.Context,
-	bgpSrv *server.BgpServer,
-	bgpPeers []*model.BGPPeer,
-	monitoredIPAddress []string,
-	pingDuration uint,
-	maxFailCount int,
-) {
+func (m *vppInterfaceMonitor) watch(ctx context.Context, watcher api.Watcher) {
+	defer watcher.Close()
+
 	for {
-		for _, address := range monitoredIPAddress {
-			ok, err := netutils.IsAddressAlive(address, pingDuration)
-			if err != nil {
-				logger.Error("failed to probe ip address", "address", address, "error", err)
+		select {
+		case <-ctx.Done():
+			logger.Info("closed context detected, stopping vpp interface monitoring")
 
-				break
+			return
+		case msg, ok := <-watcher.Events():
+			if !ok {
+				logger.Error("vpp interface events channel closed, stopping vpp interface monitoring")
+
+				return
 			}
 
-			if !ok { //nolint:nestif
-				failCount := 1
-				// counting failed attempts
-				for i := 0; i < maxFailCount; i++ {
-					ok, err := netutils.IsAddressAlive(address, pingDuration)
-					if err != nil {
-						logger.Error("failed to check is address alive", "address", address, "error", err)
-
-						break
-					}
-
-					if ok {
-						logger.Error(
-							"ip address is dead less then deadline interval, skip and continue",
-							"address", address,
-							"fail count", failCount,
-							"deadline time", int(pingDuration)*maxFailCount,
-						)
-
-						break
-					}
-
-					failCount++
-				}
-
-				// stop app if limit exceeded
-
-				if failCount >= maxFailCount {
-					logger.Error(
-						"ip address is dead more then deadline interval",
-						"address", address,
-						"fail count", failCount,
-						"deadline time", int(pingDuration)*maxFailCount,
-					)
-
-					for _, peer := range bgpPeers {
-						if err := gobgp.DelBGPPeer(ctx, bgpSrv, peer); err != nil {
-							logger.Info(
-								"failed to delete bgp peer",
-								"peer address", peer.PeerAddress,
-								"error", err,
-							)
-						}
-					}
-
-					logger.Fatal("exit from app due to vpp interface monitoring failed")
-				}
+			event, ok := msg.(*interfaces.SwInterfaceEvent)
+			if !ok {
+				continue
 			}
+
+			logger.Debug("got vpp interface event", "interface id", event.SwIfIndex, "flags", event.Flags, "deleted", event.Deleted)
+
+			m.handleLinkState(ctx, event.SwIfIndex, !event.Deleted && vpp.IsInterfaceUp(event.Flags))
 		}
 	}
 }
+
+// handleLinkState applies link state of main interface or vrf sub-interface if the state changed
+func (m *vppInterfaceMonitor) handleLinkState(ctx context.Context, swIfIndex interface_types.InterfaceIndex, isUp bool) {
+	switch {
+	case swIfIndex == m.mainInterfaceID:
+		if m.mainUp == isUp {
+			return
+		}
+
+		m.mainUp = isUp
+
+		logger.Info("vpp main interface link state changed", "interface id", swIfIndex, "up", isUp)
+
+		for subIfIndex, vrfID := range m.subToVRF {
+			service.HandleVRFLinkState(ctx, m.bgpSrv, m.cfg, m.storage, vrfID, m.mainUp && m.subUp[subIfIndex])
+		}
+
+	default:
+		vrfID, ok := m.subToVRF[swIfIndex]
+		if !ok || m.subUp[swIfIndex] == isUp {
+			return
+		}
+
+		m.subUp[swIfIndex] = isUp
+
+		vrf := m.storage.VPPVRFStorage.GetVRF(vrfID)
+		if vrf == nil {
+			return
+		}
+
+		logger.Info("vpp sub-interface link state changed", "interface id", swIfIndex, "vrf", vrf.Name, "up", isUp)
+
+		service.HandleVRFLinkState(ctx, m.bgpSrv, m.cfg, m.storage, vrfID, m.mainUp && isUp)
+		service.HandlePhyNetLinkState(ctx, m.bgpSrv, m.storage, vrf.Name, isUp)
+	}
+}
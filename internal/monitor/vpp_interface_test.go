@@ -0,0 +1,139 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	bgpapi "github.com/osrg/gobgp/v3/api"
+	"github.com/osrg/gobgp/v3/pkg/server"
+	"github.com/stretchr/testify/require"
+	"go.fd.io/govpp/binapi/interface_types"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/gobgp"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/internal/service"
+)
+
+func TestHandleLinkState(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bgpSrv := server.NewBgpServer()
+
+	go bgpSrv.Serve()
+
+	defer bgpSrv.Stop()
+
+	require.NoError(t, bgpSrv.StartBgp(ctx, &bgpapi.StartBgpRequest{
+		Global: &bgpapi.Global{Asn: 65000, RouterId: "192.0.2.1", ListenPort: -1},
+	}))
+
+	cfg := config.Config{
+		TFController: config.TFController{BGPPeerASN: 64512},
+		GoBGP:        config.GoBGP{BGPLocalASN: 65000},
+	}
+
+	storage := imdb.NewStorage()
+
+	// vrf1 on sub-interface 11 with a peer to physical network, vrf2 on sub-interface 12, main interface 1
+
+	for _, vrfID := range []uint32{1, 2} {
+		bgpVRF := model.NewBGPVRFTable(fmt.Sprintf("vrf%d", vrfID), vrfID, 65000, 65100, model.RD("192.0.2.1", vrfID),
+			[]*anypb.Any{model.RT(65000, vrfID)}, []*anypb.Any{model.RT(64512, vrfID)})
+		require.NoError(t, storage.BGPVRFStorage.AddVRF(&bgpVRF))
+
+		vppVRF := &model.VPPVRFTable{
+			Name: bgpVRF.Name, ID: vrfID, SubInterfaceID: interface_types.InterfaceIndex(10 + vrfID), LocalAddr: fmt.Sprintf("10.0.%d.1", vrfID),
+			FIPPrefixes: []string{fmt.Sprintf("172.16.%d.0/24", vrfID)}, FIPServed: 1, LinkUp: true,
+		}
+		require.NoError(t, storage.VPPVRFStorage.AddVRF(vppVRF))
+		require.NoError(t, service.AdvWdrawFIPAggrPrefixes(ctx, bgpSrv, cfg, service.ADVERTISE, vppVRF, &bgpVRF))
+	}
+
+	peer := &model.BGPPeer{PeerType: model.PHYNET, PeerASN: 65010, PeerAddress: "10.0.1.2", VRFName: "vrf1"}
+
+	require.NoError(t, storage.BGPPeerStorage.AddBGPPeer(peer))
+	require.NoError(t, bgpSrv.AddPeer(ctx, &bgpapi.AddPeerRequest{Peer: &bgpapi.Peer{
+		Conf:      &bgpapi.PeerConf{NeighborAddress: peer.PeerAddress, PeerAsn: peer.PeerASN},
+		Transport: &bgpapi.Transport{PassiveMode: true},
+	}}))
+
+	m := &vppInterfaceMonitor{
+		cfg:             cfg,
+		bgpSrv:          bgpSrv,
+		storage:         storage,
+		mainInterfaceID: 1,
+		mainUp:          true,
+		subUp:           map[interface_types.InterfaceIndex]bool{11: true, 12: true},
+		subToVRF:        map[interface_types.InterfaceIndex]uint32{11: 1, 12: 2},
+	}
+
+	steps := []struct {
+		name       string
+		swIfIndex  interface_types.InterfaceIndex
+		isUp       bool
+		advertised []string
+		peerUp     bool
+	}{
+		{name: "unknown interface", swIfIndex: 5, advertised: []string{"172.16.1.0/24", "172.16.2.0/24"}, peerUp: true},
+		{name: "sub-interface down", swIfIndex: 11, advertised: []string{"172.16.2.0/24"}},
+		{name: "sub-interface down again", swIfIndex: 11, advertised: []string{"172.16.2.0/24"}},
+		{name: "main interface down", swIfIndex: 1},
+		// the peer is enabled with the sub-interface, the prefixes wait for the main interface
+		{name: "sub-interface up", swIfIndex: 11, isUp: true, peerUp: true},
+		{name: "main interface up", swIfIndex: 1, isUp: true, advertised: []string{"172.16.1.0/24", "172.16.2.0/24"}, peerUp: true},
+	}
+
+	for _, step := range steps {
+		m.handleLinkState(ctx, step.swIfIndex, step.isUp)
+
+		require.ElementsMatch(t, step.advertised, advertisedPrefixes(t, bgpSrv), step.name)
+
+		// gobgp applies the peer admin state asynchronously
+
+		require.Eventually(t, func() bool {
+			return isPeerEnabled(t, bgpSrv, peer.PeerAddress) == step.peerUp
+		}, time.Second, 10*time.Millisecond, step.name)
+
+		for _, vrfID := range []uint32{1, 2} {
+			vrf := storage.VPPVRFStorage.GetVRF(vrfID)
+
+			require.Equal(t, slices.Contains(step.advertised, vrf.FIPPrefixes[0]), storage.VPPVRFStorage.IsLinkUp(vrfID), step.name)
+		}
+	}
+}
+
+// advertisedPrefixes returns the aggregated floating ip prefixes of the global vpnv4 rib
+func advertisedPrefixes(t *testing.T, bgpSrv *server.BgpServer) []string {
+	paths, err := gobgp.ListPaths(context.Background(), bgpSrv, bgpapi.TableType_GLOBAL, bgpapi.Family_AFI_IP,
+		bgpapi.Family_SAFI_MPLS_VPN, "", nil, false)
+	require.NoError(t, err)
+
+	prefixes := make([]string, 0, len(paths))
+
+	for _, path := range paths {
+		var nlri bgpapi.LabeledVPNIPAddressPrefix
+
+		require.NoError(t, path.Nlri.UnmarshalTo(&nlri))
+
+		prefixes = append(prefixes, fmt.Sprintf("%s/%d", nlri.Prefix, nlri.PrefixLen))
+	}
+
+	return prefixes
+}
+
+func isPeerEnabled(t *testing.T, bgpSrv *server.BgpServer, address string) bool {
+	var adminState bgpapi.PeerState_AdminState
+
+	require.NoError(t, bgpSrv.ListPeer(context.Background(), &bgpapi.ListPeerRequest{Address: address}, func(peer *bgpapi.Peer) {
+		adminState = peer.State.AdminState
+	}))
+
+	return adminState == bgpapi.PeerState_UP
+}
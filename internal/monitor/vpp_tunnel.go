@@ -0,0 +1,281 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.fd.io/govpp/api"
+	"go.fd.io/govpp/binapi/ping"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp"
+	"git.crptech.ru/cloud/cloudgw/internal/service"
+	"git.crptech.ru/cloud/cloudgw/pkg/exporter/vppexporter"
+	"git.crptech.ru/cloud/cloudgw/pkg/logger"
+)
+
+const tunnelProbePacketInterval = 100 * time.Millisecond
+
+// tunnelProbeCounter counts consecutive failed and successful probes of vrouter
+type tunnelProbeCounter struct {
+	failed    int
+	succeeded int
+}
+
+// VPPTunnelStatus probes vrouters (udp tunnel destinations) by icmp from the vpp. Paths through unreachable vrouter are
+// pruned from multipath floating ip routes in vpp and restored when the vrouter replies again.
+func VPPTunnelStatus(
+	ctx context.Context,
+	cfg *config.Config,
+	vppConn api.Connection,
+	storage *imdb.Storage,
+) error {
+	if !cfg.VPP.TunnelProbe.Enable {
+		return nil
+	}
+
+	// dedicated stream as paths are replaced on it, the shared stream is used by other goroutines
+
+	probeStream, err := vppConn.NewStream(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create vpp stream for tunnel probing: %w", err)
+	}
+
+	vppPingers := make([]*vppPinger, cfg.VPP.TunnelProbe.Workers)
+	pingers := make([]vrouterPinger, cfg.VPP.TunnelProbe.Workers)
+
+	for i := range vppPingers {
+		vppPingers[i] = &vppPinger{sockAddr: cfg.VPP.BinAPISock, count: cfg.VPP.TunnelProbe.Count}
+		pingers[i] = vppPingers[i]
+	}
+
+	go func() {
+		defer func() {
+			for _, pinger := range vppPingers {
+				pinger.close()
+			}
+
+			_ = probeStream.Close()
+		}()
+
+		probeVRouters(ctx, cfg.VPP.TunnelProbe, probeStream, pingers, storage)
+	}()
+
+	logger.Info("vpp tunnel probing started", "interval", cfg.VPP.TunnelProbe.Interval, "threshold", cfg.VPP.TunnelProbe.Threshold,
+		"workers", cfg.VPP.TunnelProbe.Workers)
+
+	return nil
+}
+
+// vrouterPinger pings a vrouter and returns the number of sent and received icmp echo packets
+type vrouterPinger interface {
+	ping(ctx context.Context, dstIP string) (uint32, uint32, error)
+}
+
+// vppPinger pings vrouters from the vpp on its own vpp api connection. The ping finished event carries no address and
+// is sent to the api client of the ping only, so with one ping in progress per connection the event is the result of
+// that ping. The connection is replaced after a failed ping to drop the late event of it.
+type vppPinger struct {
+	sockAddr string
+	count    uint32
+
+	disconnect func()
+	stream     api.Stream
+	watcher    api.Watcher
+}
+
+func (p *vppPinger) connect(ctx context.Context) error {
+	conn, disconnect, err := vpp.ConnectToVPPAPI(p.sockAddr)
+	if err != nil {
+		return err
+	}
+
+	stream, err := conn.NewStream(ctx)
+	if err != nil {
+		disconnect()
+
+		return fmt.Errorf("failed to create vpp stream for tunnel probing: %w", err)
+	}
+
+	watcher, err := conn.WatchEvent(ctx, &ping.PingFinishedEvent{})
+	if err != nil {
+		_ = stream.Close()
+
+		disconnect()
+
+		return fmt.Errorf("failed to watch vpp ping finished events: %w", err)
+	}
+
+	p.disconnect, p.stream, p.watcher = disconnect, stream, watcher
+
+	return nil
+}
+
+func (p *vppPinger) close() {
+	if p.disconnect == nil {
+		return
+	}
+
+	p.watcher.Close()
+	_ = p.stream.Close()
+	p.disconnect()
+
+	p.disconnect, p.stream, p.watcher = nil, nil, nil
+}
+
+func (p *vppPinger) ping(ctx context.Context, dstIP string) (uint32, uint32, error) {
+	if p.disconnect == nil {
+		if err := p.connect(ctx); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	sent, received, err := vpp.PingAddress(p.stream, p.watcher, dstIP, p.count, tunnelProbePacketInterval)
+	if err != nil {
+		p.close()
+	}
+
+	return sent, received, err
+}
+
+// vrouterProbe is the result of a vrouter ping
+type vrouterProbe struct {
+	sent     uint32
+	received uint32
+	err      error
+}
+
+// pingVRouters pings vrouters concurrently, one ping in progress per pinger. Vrouters not pinged as the context is
+// closed have no result.
+func pingVRouters(ctx context.Context, pingers []vrouterPinger, dstIPs []string) map[string]vrouterProbe {
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		probes = make(map[string]vrouterProbe, len(dstIPs))
+		next   = make(chan string)
+	)
+
+	for _, pinger := range pingers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for dstIP := range next {
+				sent, received, err := pinger.ping(ctx, dstIP)
+
+				mu.Lock()
+				probes[dstIP] = vrouterProbe{sent: sent, received: received, err: err}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, dstIP := range dstIPs {
+		if ctx.Err() != nil {
+			break
+		}
+
+		next <- dstIP
+	}
+
+	close(next)
+	wg.Wait()
+
+	return probes
+}
+
+func probeVRouters(
+	ctx context.Context,
+	probeCfg config.TunnelProbe,
+	probeStream api.Stream,
+	pingers []vrouterPinger,
+	storage *imdb.Storage,
+) {
+	counters := make(map[string]*tunnelProbeCounter)
+
+	ticker := time.NewTicker(time.Duration(probeCfg.Interval) * time.Millisecond)
+
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("closed context detected, stopping vpp tunnel probing")
+
+			return
+		case <-ticker.C:
+		}
+
+		tunnels := storage.VPPUDPTunnelStorage.GetUDPTunnels()
+
+		// forget deleted tunnels
+
+		existed := make(map[string]struct{}, len(tunnels))
+		dstIPs := make([]string, 0, len(tunnels))
+
+		for _, tunnel := range tunnels {
+			if _, ok := existed[tunnel.DstIP]; !ok {
+				existed[tunnel.DstIP] = struct{}{}
+				dstIPs = append(dstIPs, tunnel.DstIP)
+			}
+		}
+
+		for dstIP := range counters {
+			if _, ok := existed[dstIP]; !ok {
+				delete(counters, dstIP)
+				vppexporter.VPPUDPTunnelProbeMetrics.Del(dstIP)
+			}
+		}
+
+		probes := pingVRouters(ctx, pingers, dstIPs)
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		for _, dstIP := range dstIPs {
+			probe := probes[dstIP]
+
+			counter, ok := counters[dstIP]
+			if !ok {
+				counter = &tunnelProbeCounter{}
+				counters[dstIP] = counter
+			}
+
+			if probe.err != nil {
+				logger.Debug("failed to probe vrouter", "vrouter", dstIP, "error", probe.err)
+			}
+
+			failed := probe.err != nil || probe.received == 0
+
+			if failed {
+				counter.failed++
+				counter.succeeded = 0
+			} else {
+				counter.succeeded++
+				counter.failed = 0
+			}
+
+			logger.Debug("vrouter probed", "vrouter", dstIP, "sent", probe.sent, "received", probe.received)
+
+			isReachable := storage.VPPUDPTunnelStorage.IsReachable(dstIP)
+
+			switch {
+			case isReachable && counter.failed >= probeCfg.Threshold:
+				service.HandleUDPTunnelReachability(probeStream, storage, dstIP, false)
+
+				isReachable = false
+			case !isReachable && counter.succeeded >= probeCfg.Threshold:
+				service.HandleUDPTunnelReachability(probeStream, storage, dstIP, true)
+
+				isReachable = true
+			}
+
+			vppexporter.VPPUDPTunnelProbeMetrics.SetProbeResult(dstIP, isReachable, failed)
+		}
+	}
+}
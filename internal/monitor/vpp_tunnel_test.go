@@ -0,0 +1,124 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakePinger replies to the pings of vrouters from replies, the other vrouters time out
+type fakePinger struct {
+	replies    map[string]uint32
+	inProgress *atomic.Int32
+	maxPings   *atomic.Int32
+
+	mu     sync.Mutex
+	pinged []string
+}
+
+func (p *fakePinger) ping(ctx context.Context, dstIP string) (uint32, uint32, error) {
+	n := p.inProgress.Add(1)
+	defer p.inProgress.Add(-1)
+
+	for {
+		prev := p.maxPings.Load()
+		if n <= prev || p.maxPings.CompareAndSwap(prev, n) {
+			break
+		}
+	}
+
+	p.mu.Lock()
+	p.pinged = append(p.pinged, dstIP)
+	p.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return 0, 0, ctx.Err()
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	received, ok := p.replies[dstIP]
+	if !ok {
+		return 3, 0, errors.New("timed out")
+	}
+
+	return 3, received, nil
+}
+
+func TestPingVRouters(t *testing.T) {
+	var inProgress, maxPings atomic.Int32
+
+	replies := make(map[string]uint32)
+	dstIPs := make([]string, 0, 20)
+
+	for i := range 20 {
+		dstIP := fmt.Sprintf("10.1.1.%d", i+1)
+		dstIPs = append(dstIPs, dstIP)
+
+		if i%4 != 0 {
+			replies[dstIP] = uint32(i % 4)
+		}
+	}
+
+	newPingers := func(n int) ([]*fakePinger, []vrouterPinger) {
+		fakes := make([]*fakePinger, n)
+		pingers := make([]vrouterPinger, n)
+
+		for i := range fakes {
+			fakes[i] = &fakePinger{replies: replies, inProgress: &inProgress, maxPings: &maxPings}
+			pingers[i] = fakes[i]
+		}
+
+		return fakes, pingers
+	}
+
+	t.Run("concurrently", func(t *testing.T) {
+		fakes, pingers := newPingers(4)
+
+		probes := pingVRouters(context.Background(), pingers, dstIPs)
+
+		// every vrouter is pinged once, at most one ping in progress per pinger
+
+		require.Len(t, probes, len(dstIPs))
+		require.Equal(t, int32(4), maxPings.Load())
+
+		var pinged int
+
+		for _, fake := range fakes {
+			require.NotEmpty(t, fake.pinged)
+
+			pinged += len(fake.pinged)
+		}
+
+		require.Equal(t, len(dstIPs), pinged)
+
+		for _, dstIP := range dstIPs {
+			probe := probes[dstIP]
+
+			if received, ok := replies[dstIP]; ok {
+				require.NoError(t, probe.err, dstIP)
+				require.Equal(t, received, probe.received, dstIP)
+			} else {
+				require.Error(t, probe.err, dstIP)
+				require.Zero(t, probe.received, dstIP)
+			}
+		}
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, pingers := newPingers(2)
+
+		probes := pingVRouters(ctx, pingers, dstIPs)
+
+		require.Empty(t, probes)
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/md5" //nolint:gosec
 	"encoding/hex"
 	"fmt"
+	"slices"
 	"strings"
 
 	bgpapi "github.com/osrg/gobgp/v3/api"
@@ -138,6 +139,44 @@ func DelBGPPeer(ctx context.Context, srv *server.BgpServer, peer *model.BGPPeer)
 	return nil
 }
 
+// DisableBGPPeer shuts down a BGP Peer on local GoBGP server (the peer stays configured)
+func DisableBGPPeer(ctx context.Context, srv *server.BgpServer, peer *model.BGPPeer, communication string) error {
+	if err := srv.DisablePeer(ctx, &bgpapi.DisablePeerRequest{
+		Address:       peer.PeerAddress,
+		Communication: communication,
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// EnableBGPPeer brings up a BGP Peer disabled by DisableBGPPeer on local GoBGP server
+func EnableBGPPeer(ctx context.Context, srv *server.BgpServer, peer *model.BGPPeer) error {
+	if err := srv.EnablePeer(ctx, &bgpapi.EnablePeerRequest{
+		Address: peer.PeerAddress,
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ResetBGPPeer resets BGP session on local GoBGP server: hard reset (session is closed) or soft reset (routes are
+// re-sent to the peer (out) and/or re-evaluated by import policy (in) without session closing)
+func ResetBGPPeer(ctx context.Context, srv *server.BgpServer, peer *model.BGPPeer, soft bool, direction bgpapi.ResetPeerRequest_SoftResetDirection, communication string) error {
+	if err := srv.ResetPeer(ctx, &bgpapi.ResetPeerRequest{
+		Address:       peer.PeerAddress,
+		Communication: communication,
+		Soft:          soft,
+		Direction:     direction,
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // AdvWdrawIPv4Prefix advertises/withdraws IPv4/Unicast prefix on local GoBGP server in GRT
 func AdvWdrawIPv4Prefix(ctx context.Context, srv *server.BgpServer, isAdvertise bool, ipv4BGPNLRIAttrs gobgpapi.BGPNLRIAttrs) error {
 	nlri, _ := anypb.New(&bgpapi.IPAddressPrefix{
@@ -190,8 +229,28 @@ func AdvWdrawIPv4Prefix(ctx context.Context, srv *server.BgpServer, isAdvertise
 	return nil
 }
 
-// AdvWdrawVpnv4Prefix advertises/withdraws VPNv4 prefix on local GoBGP server
-func AdvWdrawVpnv4Prefix(ctx context.Context, srv *server.BgpServer, isAdvertise bool, bgpNLRIAttrs gobgpapi.BGPNLRIAttrs, sourceASN uint32) error {
+// PathPreference are attributes of advertised path making it less (or more) preferred by receivers
+type PathPreference struct {
+	LocalPref   uint32   // 100 if 0
+	Prepend     []uint32 // asns put before the source asn in as path
+	MED         uint32
+	Communities []uint32 // standard communities, e.g. GracefulShutdownCommunity
+}
+
+// GracefulShutdownCommunity is well-known GRACEFUL_SHUTDOWN community 65535:0 (rfc8326), receivers lower local
+// preference of paths with it
+const GracefulShutdownCommunity uint32 = 0xFFFF0000
+
+// AdvWdrawVpnv4Prefix advertises/withdraws VPNv4 prefix on local GoBGP server, advertising the prefix again replaces its
+// path attributes
+func AdvWdrawVpnv4Prefix(
+	ctx context.Context,
+	srv *server.BgpServer,
+	isAdvertise bool,
+	bgpNLRIAttrs gobgpapi.BGPNLRIAttrs,
+	sourceASN uint32,
+	pref PathPreference,
+) error {
 	nlri, _ := anypb.New(&bgpapi.LabeledVPNIPAddressPrefix{
 		Labels:    bgpNLRIAttrs.MPLSLabel,
 		Rd:        bgpNLRIAttrs.RD,
@@ -204,11 +263,15 @@ func AdvWdrawVpnv4Prefix(ctx context.Context, srv *server.BgpServer, isAdvertise
 	})
 
 	med, _ := anypb.New(&bgpapi.MultiExitDiscAttribute{
-		Med: 0,
+		Med: pref.MED,
 	})
 
+	if pref.LocalPref == 0 {
+		pref.LocalPref = 100
+	}
+
 	localPref, _ := anypb.New(&bgpapi.LocalPrefAttribute{
-		LocalPref: 100,
+		LocalPref: pref.LocalPref,
 	})
 
 	rt := bgpNLRIAttrs.RT
@@ -237,13 +300,21 @@ func AdvWdrawVpnv4Prefix(ctx context.Context, srv *server.BgpServer, isAdvertise
 		Segments: []*bgpapi.AsSegment{
 			{
 				Type:    2,
-				Numbers: []uint32{sourceASN},
+				Numbers: append(slices.Clone(pref.Prepend), sourceASN),
 			},
 		},
 	})
 
 	pAttrs := []*anypb.Any{origin, med, localPref, communities, nlriAttr, asnPath}
 
+	if len(pref.Communities) != 0 {
+		stdCommunities, _ := anypb.New(&bgpapi.CommunitiesAttribute{
+			Communities: pref.Communities,
+		})
+
+		pAttrs = append(pAttrs, stdCommunities)
+	}
+
 	if isAdvertise {
 		// Advertise the prefix
 		if _, err := srv.AddPath(ctx, &bgpapi.AddPathRequest{
@@ -464,3 +535,101 @@ func UpdateGoBGPPerPeerMetrics(ctx context.Context, srv *server.BgpServer, table
 
 	return float64(result.NumDestination), nil
 }
+
+// ListPaths returns paths of global or vrf table (name is vrf name) or paths received from (adj-in) or sent to (adj-out)
+// specific peer (name is peer address), all paths of the family if prefixes are not set. If enableFiltered is set, paths
+// rejected by import/export policy are returned too (marked as Filtered).
+func ListPaths(
+	ctx context.Context,
+	srv *server.BgpServer,
+	tableType bgpapi.TableType,
+	afi bgpapi.Family_Afi,
+	safi bgpapi.Family_Safi,
+	name string,
+	prefixes []string,
+	enableFiltered bool,
+) ([]*bgpapi.Path, error) {
+	req := &bgpapi.ListPathRequest{
+		TableType: tableType,
+		Family: &bgpapi.Family{
+			Afi:  afi,
+			Safi: safi,
+		},
+		Name:           name,
+		EnableFiltered: enableFiltered,
+	}
+
+	for _, prefix := range prefixes {
+		req.Prefixes = append(req.Prefixes, &bgpapi.TableLookupPrefix{Prefix: prefix})
+	}
+
+	var paths []*bgpapi.Path
+
+	if err := srv.ListPath(ctx, req, func(d *bgpapi.Destination) {
+		paths = append(paths, d.Paths...)
+	}); err != nil {
+		return nil, err
+	}
+
+	return paths, nil
+}
+
+// AddBMPStation starts streaming peer states, statistics (every statisticsInterval sec, 0 disables) and routes selected
+// by policy to BMP station on local GoBGP server, the station is reconnected by GoBGP until it is deleted
+func AddBMPStation(
+	ctx context.Context,
+	srv *server.BgpServer,
+	address string,
+	port uint32,
+	policy bgpapi.AddBmpRequest_MonitoringPolicy,
+	statisticsInterval int32,
+	sysName string,
+) error {
+	if err := srv.AddBmp(ctx, &bgpapi.AddBmpRequest{
+		Address:           address,
+		Port:              port,
+		Policy:            policy,
+		StatisticsTimeout: statisticsInterval,
+		SysName:           sysName,
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ListBMPStations returns BMP stations of local GoBGP server with their last connection and disconnection time
+func ListBMPStations(ctx context.Context, srv *server.BgpServer) ([]*bgpapi.ListBmpResponse_BmpStation, error) {
+	var stations []*bgpapi.ListBmpResponse_BmpStation
+
+	if err := srv.ListBmp(ctx, &bgpapi.ListBmpRequest{}, func(station *bgpapi.ListBmpResponse_BmpStation) {
+		stations = append(stations, station)
+	}); err != nil {
+		return nil, err
+	}
+
+	return stations, nil
+}
+
+// EnableMRT starts dumping to MRT file on local GoBGP server: received updates (UPDATES) or global rib (TABLE) every
+// dumpInterval sec. The filename is time layout if rotationInterval is set, a new file is started every
+// rotationInterval sec (TABLE dumps are written to a new file each).
+func EnableMRT(
+	ctx context.Context,
+	srv *server.BgpServer,
+	dumpType bgpapi.EnableMrtRequest_DumpType,
+	filename string,
+	dumpInterval uint64,
+	rotationInterval uint64,
+) error {
+	if err := srv.EnableMrt(ctx, &bgpapi.EnableMrtRequest{
+		Type:             dumpType,
+		Filename:         filename,
+		DumpInterval:     dumpInterval,
+		RotationInterval: rotationInterval,
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
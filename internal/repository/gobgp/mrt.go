@@ -0,0 +1,156 @@
+package gobgp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"time"
+
+	bgpapi "github.com/osrg/gobgp/v3/api"
+	"github.com/osrg/gobgp/v3/pkg/apiutil"
+	"github.com/osrg/gobgp/v3/pkg/packet/bgp"
+	"github.com/osrg/gobgp/v3/pkg/packet/mrt"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const mrtMaxRecordLen = 1 << 20 // bgp messages are up to 64k (rfc 8654)
+
+// MRTUpdate is the bgp update of mrt BGP4MP record
+type MRTUpdate struct {
+	Time    time.Time
+	PeerIP  string
+	PeerASN uint32
+	Paths   []*bgpapi.Path // announced and withdrawn paths as GoBGP passes them to watchers
+}
+
+// ReadMRTUpdates calls fn for bgp updates of mrt file (rfc 6396) in the recorded order. Other records (table dumps,
+// state changes, keepalives) and end-of-rib markers are skipped.
+func ReadMRTUpdates(r io.Reader, fn func(MRTUpdate) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), mrtMaxRecordLen)
+	scanner.Split(mrt.SplitMrt)
+
+	for scanner.Scan() {
+		record := scanner.Bytes()
+
+		header := &mrt.MRTHeader{}
+		if err := header.DecodeFromBytes(record[:mrt.MRT_COMMON_HEADER_LEN]); err != nil {
+			return fmt.Errorf("failed to decode mrt header: %w", err)
+		}
+
+		if header.Type != mrt.BGP4MP {
+			continue
+		}
+
+		msg, err := mrt.ParseMRTBody(header, record[mrt.MRT_COMMON_HEADER_LEN:])
+		if err != nil {
+			return fmt.Errorf("failed to parse mrt record of %s: %w", header.GetTime(), err)
+		}
+
+		body, ok := msg.Body.(*mrt.BGP4MPMessage)
+		if !ok || body.BGPMessage.Header.Type != bgp.BGP_MSG_UPDATE {
+			continue
+		}
+
+		update := body.BGPMessage.Body.(*bgp.BGPUpdate)
+
+		if isEOR, _ := update.IsEndOfRib(); isEOR {
+			continue
+		}
+
+		at := header.GetTime().UTC()
+
+		paths, err := updatePaths(update, body.PeerIpAddress.String(), body.PeerAS, at)
+		if err != nil {
+			return fmt.Errorf("failed to convert bgp update of %s from %s: %w", at, body.PeerIpAddress, err)
+		}
+
+		if err = fn(MRTUpdate{Time: at, PeerIP: body.PeerIpAddress.String(), PeerASN: body.PeerAS, Paths: paths}); err != nil {
+			return err
+		}
+	}
+
+	// the truncated last record of the file being written is not returned by the scanner
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read mrt file: %w", err)
+	}
+
+	return nil
+}
+
+// updatePaths splits the update into paths the way GoBGP does on receiving: announced paths share attributes in the
+// received order (mp_reach_nlri is the last one), withdrawn paths have no attributes
+func updatePaths(update *bgp.BGPUpdate, peerIP string, peerASN uint32, age time.Time) ([]*bgpapi.Path, error) {
+	var (
+		attrs []bgp.PathAttributeInterface
+		reach *bgp.PathAttributeMpReachNLRI
+		dels  []bgp.AddrPrefixInterface
+	)
+
+	for _, nlri := range update.WithdrawnRoutes {
+		dels = append(dels, nlri)
+	}
+
+	for _, attr := range update.PathAttributes {
+		switch a := attr.(type) {
+		case *bgp.PathAttributeMpReachNLRI:
+			reach = a
+		case *bgp.PathAttributeMpUnreachNLRI:
+			dels = append(dels, a.Value...)
+		default:
+			attrs = append(attrs, attr)
+		}
+	}
+
+	paths := make([]*bgpapi.Path, 0, len(update.NLRI)+len(dels))
+
+	newPath := func(nlri bgp.AddrPrefixInterface, isWithdraw bool, attrs []bgp.PathAttributeInterface) error {
+		anyNLRI, err := apiutil.MarshalNLRI(nlri)
+		if err != nil {
+			return err
+		}
+
+		anyAttrs, err := apiutil.MarshalPathAttributes(attrs)
+		if err != nil {
+			return err
+		}
+
+		paths = append(paths, &bgpapi.Path{
+			Nlri:       anyNLRI,
+			Pattrs:     anyAttrs,
+			Age:        timestamppb.New(age),
+			IsWithdraw: isWithdraw,
+			Family:     apiutil.ToApiFamily(nlri.AFI(), nlri.SAFI()),
+			Identifier: nlri.PathIdentifier(),
+			NeighborIp: peerIP,
+			SourceAsn:  peerASN,
+		})
+
+		return nil
+	}
+
+	for _, nlri := range update.NLRI {
+		if err := newPath(nlri, false, attrs); err != nil {
+			return nil, err
+		}
+	}
+
+	if reach != nil {
+		reachAttrs := append(append(make([]bgp.PathAttributeInterface, 0, len(attrs)+1), attrs...), reach)
+
+		for _, nlri := range reach.Value {
+			if err := newPath(nlri, false, reachAttrs); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, nlri := range dels {
+		if err := newPath(nlri, true, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return paths, nil
+}
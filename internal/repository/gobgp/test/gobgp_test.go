@@ -188,6 +188,7 @@ func TestGoBGPServer(t *testing.T) {
 			RT:        []*anypb.Any{model.RT(cfg.TFController.BGPPeerASN, 1)},
 		},
 		65001,
+		gobgp.PathPreference{},
 	)
 
 	require.NoError(t, err)
@@ -206,6 +207,7 @@ func TestGoBGPServer(t *testing.T) {
 			RT:        []*anypb.Any{model.RT(cfg.TFController.BGPPeerASN, 1)},
 		},
 		65001,
+		gobgp.PathPreference{},
 	)
 
 	require.NoError(t, err)
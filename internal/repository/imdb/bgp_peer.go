@@ -5,7 +5,6 @@ import (
 	bgpapi "github.com/osrg/gobgp/v3/api"
 
 	"git.crptech.ru/cloud/cloudgw/internal/model"
-	"git.crptech.ru/cloud/cloudgw/pkg/logger"
 )
 
 var BGPPeerTableName = "bgp_peer"
@@ -15,27 +14,20 @@ type BGPPeerStorage struct {
 }
 
 func NewBGPPeerStorage() *BGPPeerStorage {
-	schema := &memdb.DBSchema{
-		Tables: map[string]*memdb.TableSchema{
-			BGPPeerTableName: {
-				Name: BGPPeerTableName,
-				Indexes: map[string]*memdb.IndexSchema{
-					"id": {
-						Name:    "id",
-						Unique:  true,
-						Indexer: &memdb.StringFieldIndex{Field: "PeerAddress"},
-					},
-				},
+	return &BGPPeerStorage{db: newMemDB(bgpPeerTable())}
+}
+
+func bgpPeerTable() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: BGPPeerTableName,
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": {
+				Name:    "id",
+				Unique:  true,
+				Indexer: &memdb.StringFieldIndex{Field: "PeerAddress"},
 			},
 		},
 	}
-
-	db, err := memdb.NewMemDB(schema)
-	if err != nil {
-		logger.Fatal("failed to create bgp peer storage", "error", err)
-	}
-
-	return &BGPPeerStorage{db: db}
 }
 
 func (s *BGPPeerStorage) AddBGPPeer(bgpPeer *model.BGPPeer) error {
@@ -212,10 +204,13 @@ func (s *BGPPeerStorage) UpdateBGPPeerState(peerIP string, prevState, currentSta
 		return ErrNoBGPPeerFoundInStorage
 	}
 
-	peer.BGPPeerPrevState = prevState
-	peer.BGPPeerState = currentState
+	// the stored peer is read by other goroutines (e.g. state dumps), so it is replaced by the updated copy
 
-	if err = txn.Insert(BGPPeerTableName, peer); err != nil {
+	updated := *peer
+	updated.BGPPeerPrevState = prevState
+	updated.BGPPeerState = currentState
+
+	if err = txn.Insert(BGPPeerTableName, &updated); err != nil {
 		return err
 	}
 
@@ -237,9 +232,62 @@ func (s *BGPPeerStorage) UpdateBFDPeerState(peerIP string, isBFDEstablished bool
 		return
 	}
 
-	peer.BFDPeering.BFDPeerEstablished = isBFDEstablished
+	if peer.BFDPeering == nil {
+		return
+	}
+
+	updated, bfdPeering := *peer, *peer.BFDPeering
+	bfdPeering.BFDPeerEstablished = isBFDEstablished
+	updated.BFDPeering = &bfdPeering
+
+	if err := txn.Insert(BGPPeerTableName, &updated); err != nil {
+		return
+	}
+}
+
+func (s *BGPPeerStorage) UpdateEndOfRIB(peerIP string, isReceived bool) {
+	txn := s.db.Txn(true)
+
+	defer txn.Commit()
+
+	raw, err := txn.First(BGPPeerTableName, "id", peerIP)
+	if err != nil {
+		return
+	}
+
+	peer, ok := raw.(*model.BGPPeer)
+	if !ok {
+		return
+	}
+
+	updated := *peer
+	updated.EndOfRIBReceived = isReceived
+
+	if err := txn.Insert(BGPPeerTableName, &updated); err != nil {
+		return
+	}
+}
+
+// SetAdminDisabled marks bgp peer as shut down (brought up) by administrator
+func (s *BGPPeerStorage) SetAdminDisabled(peerIP string, isDisabled bool) {
+	txn := s.db.Txn(true)
+
+	defer txn.Commit()
+
+	raw, err := txn.First(BGPPeerTableName, "id", peerIP)
+	if err != nil {
+		return
+	}
+
+	peer, ok := raw.(*model.BGPPeer)
+	if !ok {
+		return
+	}
+
+	updated := *peer
+	updated.AdminDisabled = isDisabled
 
-	if err := txn.Insert(BGPPeerTableName, peer); err != nil {
+	if err := txn.Insert(BGPPeerTableName, &updated); err != nil {
 		return
 	}
 }
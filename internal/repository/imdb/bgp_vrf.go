@@ -4,7 +4,6 @@ import (
 	"github.com/hashicorp/go-memdb"
 
 	"git.crptech.ru/cloud/cloudgw/internal/model"
-	"git.crptech.ru/cloud/cloudgw/pkg/logger"
 )
 
 var BGPVRFTableName = "bgp_vrf"
@@ -14,33 +13,26 @@ type BGPVRFStorage struct {
 }
 
 func NewBGPVRFStorage() *BGPVRFStorage {
-	schema := &memdb.DBSchema{
-		Tables: map[string]*memdb.TableSchema{
-			BGPVRFTableName: {
-				Name: BGPVRFTableName,
-				Indexes: map[string]*memdb.IndexSchema{
-					"id": {
-						Name:    "id",
-						Unique:  true,
-						Indexer: &memdb.UintFieldIndex{Field: "ID"},
-					},
-
-					"name": {
-						Name:    "name",
-						Unique:  true,
-						Indexer: &memdb.StringFieldIndex{Field: "Name"},
-					},
-				},
+	return &BGPVRFStorage{db: newMemDB(bgpVRFTable())}
+}
+
+func bgpVRFTable() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: BGPVRFTableName,
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": {
+				Name:    "id",
+				Unique:  true,
+				Indexer: &memdb.UintFieldIndex{Field: "ID"},
 			},
-		},
-	}
 
-	db, err := memdb.NewMemDB(schema)
-	if err != nil {
-		logger.Fatal("failed to create bgp vrf storage", "error", err)
+			"name": {
+				Name:    "name",
+				Unique:  true,
+				Indexer: &memdb.StringFieldIndex{Field: "Name"},
+			},
+		},
 	}
-
-	return &BGPVRFStorage{db: db}
 }
 
 func (s *BGPVRFStorage) AddVRF(vrf *model.BGPVRFTable) error {
@@ -0,0 +1,57 @@
+package imdb
+
+import (
+	"github.com/hashicorp/go-memdb"
+
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+)
+
+// Dump is a copy of storage objects read in one transaction, so objects of different tables match each other (e.g.
+// floating ips served by a tunnel and floating ip routes through it)
+type Dump struct {
+	BGPPeers   []model.BGPPeer
+	VPPVRFs    []model.VPPVRFTable
+	UDPTunnels []model.VPPUDPTunnel
+	FIPRoutes  []model.VPPIPRoute
+}
+
+func (s *Storage) Dump() Dump {
+	dump, _ := s.WatchDump()
+
+	return dump
+}
+
+// WatchDump returns the dump and the watch set fired when any dumped table is changed after the dump
+func (s *Storage) WatchDump() (Dump, memdb.WatchSet) {
+	txn := s.db.Txn(false)
+	defer txn.Abort()
+
+	ws := memdb.NewWatchSet()
+
+	return Dump{
+		BGPPeers:   dumpTable[model.BGPPeer](txn, ws, BGPPeerTableName),
+		VPPVRFs:    dumpTable[model.VPPVRFTable](txn, ws, VPPVRFTableName),
+		UDPTunnels: dumpTable[model.VPPUDPTunnel](txn, ws, VPPUDPTunnelTableName),
+		FIPRoutes:  dumpTable[model.VPPIPRoute](txn, ws, VPPFIPRouteTableName),
+	}, ws
+}
+
+// dumpTable returns copies of table objects in id index order
+func dumpTable[T any](txn *memdb.Txn, ws memdb.WatchSet, table string) []T {
+	it, err := txn.Get(table, "id")
+	if err != nil {
+		return nil
+	}
+
+	ws.Add(it.WatchCh())
+
+	var objs []T
+
+	for raw := it.Next(); raw != nil; raw = it.Next() {
+		if obj, ok := raw.(*T); ok {
+			objs = append(objs, *obj)
+		}
+	}
+
+	return objs
+}
@@ -4,7 +4,6 @@ import (
 	"github.com/hashicorp/go-memdb"
 
 	"git.crptech.ru/cloud/cloudgw/internal/model"
-	"git.crptech.ru/cloud/cloudgw/pkg/logger"
 )
 
 var VPPFIPRouteTableName = "fip_route"
@@ -14,29 +13,34 @@ type VPPFIPRouteStorage struct {
 }
 
 func NewVPPFIPRouteStorage() *VPPFIPRouteStorage {
-	schema := &memdb.DBSchema{
-		Tables: map[string]*memdb.TableSchema{
-			VPPFIPRouteTableName: {
-				Name: VPPFIPRouteTableName,
-				Indexes: map[string]*memdb.IndexSchema{
-					"id": {
-						Name:    "id",
-						Unique:  true,
-						Indexer: &memdb.StringFieldIndex{Field: "Prefix"},
-					},
-				},
+	return &VPPFIPRouteStorage{db: newMemDB(fipRouteTable())}
+}
+
+func fipRouteTable() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: VPPFIPRouteTableName,
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": {
+				Name:    "id",
+				Unique:  true,
+				Indexer: &memdb.StringFieldIndex{Field: "Prefix"},
+			},
+			"next_hop": {
+				Name:         "next_hop",
+				AllowMissing: true,
+				Indexer:      &memdb.StringSliceFieldIndex{Field: "NextHops"},
+			},
+			"vrf_id": {
+				Name:    "vrf_id",
+				Indexer: &memdb.UintFieldIndex{Field: "VRFID"},
+			},
+			"tunnel_id": {
+				Name:         "tunnel_id",
+				AllowMissing: true,
+				Indexer:      &uint32SliceFieldIndex{Field: "TunnelIDs"},
 			},
 		},
 	}
-
-	db, err := memdb.NewMemDB(schema)
-	if err != nil {
-		logger.Fatal("failed to create vpp floating ip route storage", "error", err)
-	}
-
-	return &VPPFIPRouteStorage{
-		db: db,
-	}
 }
 
 func (s *VPPFIPRouteStorage) AddFIPRoute(fipRoute *model.VPPIPRoute) error {
@@ -99,11 +103,31 @@ func (s *VPPFIPRouteStorage) GetFIPRoute(fipPrefix string) *model.VPPIPRoute {
 }
 
 func (s *VPPFIPRouteStorage) GetFIPRoutes() []*model.VPPIPRoute {
+	return s.getFIPRoutes("id_prefix", "")
+}
+
+// GetFIPRoutesByNextHop returns floating ip routes with a path through the vrouter
+func (s *VPPFIPRouteStorage) GetFIPRoutesByNextHop(nextHop string) []*model.VPPIPRoute {
+	return s.getFIPRoutes("next_hop", nextHop)
+}
+
+// GetFIPRoutesByVRF returns floating ip routes of the vrf
+func (s *VPPFIPRouteStorage) GetFIPRoutesByVRF(vrfID uint32) []*model.VPPIPRoute {
+	return s.getFIPRoutes("vrf_id", vrfID)
+}
+
+// GetFIPRoutesByTunnelID returns floating ip routes with a path through the udp tunnel
+func (s *VPPFIPRouteStorage) GetFIPRoutesByTunnelID(tunnelID uint32) []*model.VPPIPRoute {
+	return s.getFIPRoutes("tunnel_id", tunnelID)
+}
+
+// getFIPRoutes returns floating ip routes found by the index or nil if no route found
+func (s *VPPFIPRouteStorage) getFIPRoutes(index string, args ...any) []*model.VPPIPRoute {
 	txn := s.db.Txn(false)
 
 	defer txn.Abort()
 
-	raws, err := txn.Get(VPPFIPRouteTableName, "id_prefix", "")
+	raws, err := txn.Get(VPPFIPRouteTableName, index, args...)
 	if err != nil {
 		return nil
 	}
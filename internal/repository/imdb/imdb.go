@@ -1,9 +1,54 @@
 package imdb
 
+import (
+	"github.com/hashicorp/go-memdb"
+
+	"git.crptech.ru/cloud/cloudgw/pkg/logger"
+)
+
+// Storage is the memory storage of cloudgw. All tables are in one memdb, so changes of several tables made in one
+// transaction (see Txn) are committed or aborted together.
 type Storage struct {
 	*BGPPeerStorage
 	*BGPVRFStorage
 	*VPPVRFStorage
 	*VPPFIPRouteStorage
 	*VPPUDPTunnelStorage
+
+	db *memdb.MemDB
+}
+
+func NewStorage() *Storage {
+	db := newMemDB(bgpPeerTable(), bgpVRFTable(), vppVRFTable(), fipRouteTable(), udpTunnelTable())
+
+	return &Storage{
+		BGPPeerStorage:      &BGPPeerStorage{db: db},
+		BGPVRFStorage:       &BGPVRFStorage{db: db},
+		VPPVRFStorage:       &VPPVRFStorage{db: db},
+		VPPFIPRouteStorage:  &VPPFIPRouteStorage{db: db},
+		VPPUDPTunnelStorage: &VPPUDPTunnelStorage{db: db},
+		db:                  db,
+	}
+}
+
+// Txn starts a write transaction over all tables. Other writers are blocked until the transaction is committed or
+// aborted, readers see the state before the transaction.
+func (s *Storage) Txn() *Txn {
+	return newTxn(s.db)
+}
+
+// newMemDB creates memdb with the tables (the schema is static, so an error is a bug)
+func newMemDB(tables ...*memdb.TableSchema) *memdb.MemDB {
+	schema := &memdb.DBSchema{Tables: make(map[string]*memdb.TableSchema, len(tables))}
+
+	for _, table := range tables {
+		schema.Tables[table.Name] = table
+	}
+
+	db, err := memdb.NewMemDB(schema)
+	if err != nil {
+		logger.Fatal("failed to create memory storage", "error", err)
+	}
+
+	return db
 }
@@ -0,0 +1,52 @@
+package imdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+)
+
+// uint32SliceFieldIndex indexes an object by each value of the []uint32 field (e.g. tunnel ids of floating ip route
+// paths), memdb has slice indexers for strings only
+type uint32SliceFieldIndex struct {
+	Field string
+}
+
+func (u *uint32SliceFieldIndex) FromObject(obj any) (bool, [][]byte, error) {
+	v := reflect.Indirect(reflect.ValueOf(obj))
+
+	fv := v.FieldByName(u.Field)
+	if !fv.IsValid() {
+		return false, nil, fmt.Errorf("field '%s' for %#v is invalid", u.Field, obj)
+	}
+
+	values, ok := fv.Interface().([]uint32)
+	if !ok {
+		return false, nil, fmt.Errorf("field '%s' is not []uint32", u.Field)
+	}
+
+	if len(values) == 0 {
+		return false, nil, nil
+	}
+
+	keys := make([][]byte, 0, len(values))
+
+	for _, value := range values {
+		keys = append(keys, binary.BigEndian.AppendUint32(nil, value))
+	}
+
+	return true, keys, nil
+}
+
+func (u *uint32SliceFieldIndex) FromArgs(args ...any) ([]byte, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("must provide only a single argument")
+	}
+
+	value, ok := args[0].(uint32)
+	if !ok {
+		return nil, fmt.Errorf("argument must be a uint32: %#v", args[0])
+	}
+
+	return binary.BigEndian.AppendUint32(nil, value), nil
+}
@@ -112,3 +112,39 @@ func (s *IMDBStorageSuite) TestUpdateBFDPeerState() {
 	s.bgpPeerStorage.UpdateBFDPeerState("", true)
 	s.bgpPeerStorage.UpdateBFDPeerState("", false)
 }
+
+func (s *IMDBStorageSuite) TestUpdateEndOfRIB() {
+	peer := s.bgpPeerStorage.GetBGPPeer("10.0.0.1")
+	s.Require().False(peer.EndOfRIBReceived)
+
+	s.bgpPeerStorage.UpdateEndOfRIB("10.0.0.1", true)
+
+	peer = s.bgpPeerStorage.GetBGPPeer("10.0.0.1")
+	s.Require().True(peer.EndOfRIBReceived)
+
+	s.bgpPeerStorage.UpdateEndOfRIB("10.0.0.1", false)
+
+	peer = s.bgpPeerStorage.GetBGPPeer("10.0.0.1")
+	s.Require().False(peer.EndOfRIBReceived)
+
+	// Check no panic
+	s.bgpPeerStorage.UpdateEndOfRIB("", true)
+}
+
+func (s *IMDBStorageSuite) TestSetAdminDisabled() {
+	peer := s.bgpPeerStorage.GetBGPPeer("10.0.0.1")
+	s.Require().False(peer.AdminDisabled)
+
+	s.bgpPeerStorage.SetAdminDisabled("10.0.0.1", true)
+
+	peer = s.bgpPeerStorage.GetBGPPeer("10.0.0.1")
+	s.Require().True(peer.AdminDisabled)
+
+	s.bgpPeerStorage.SetAdminDisabled("10.0.0.1", false)
+
+	peer = s.bgpPeerStorage.GetBGPPeer("10.0.0.1")
+	s.Require().False(peer.AdminDisabled)
+
+	// Check no panic
+	s.bgpPeerStorage.SetAdminDisabled("", true)
+}
@@ -196,3 +196,40 @@ func BenchmarkIMDBStorage(_ *testing.B) {
 		}
 	}
 }
+
+func (s *IMDBStorageSuite) TestGetFIPRoutesByNextHop() {
+	fips := s.fipRouteStorage.GetFIPRoutesByNextHop("10.0.0.1")
+	s.Require().Len(fips, 2)
+	s.Require().Equal("10.11.64.1/32", fips[0].Prefix)
+	s.Require().Equal("10.11.64.5/32", fips[1].Prefix)
+
+	fips = s.fipRouteStorage.GetFIPRoutesByNextHop("10.0.0.2")
+	s.Require().Len(fips, 3)
+
+	s.Require().Nil(s.fipRouteStorage.GetFIPRoutesByNextHop("10.0.0.10"))
+	s.Require().Nil(s.fipRouteStorage.GetFIPRoutesByNextHop(""))
+
+	// the index follows the stored route
+
+	err := s.fipRouteStorage.DelFIPRoute("10.11.64.4/32")
+	s.Require().NoError(err)
+	s.Require().Nil(s.fipRouteStorage.GetFIPRoutesByNextHop("10.0.0.3"))
+}
+
+func (s *IMDBStorageSuite) TestGetFIPRoutesByVRF() {
+	fips := s.fipRouteStorage.GetFIPRoutesByVRF(1)
+	s.Require().Len(fips, len(fipFixtures))
+
+	s.Require().Nil(s.fipRouteStorage.GetFIPRoutesByVRF(3))
+}
+
+func (s *IMDBStorageSuite) TestGetFIPRoutesByTunnelID() {
+	fips := s.fipRouteStorage.GetFIPRoutesByTunnelID(2)
+	s.Require().Len(fips, 4)
+
+	fips = s.fipRouteStorage.GetFIPRoutesByTunnelID(4)
+	s.Require().Len(fips, 1)
+	s.Require().Equal("10.11.64.5/32", fips[0].Prefix)
+
+	s.Require().Nil(s.fipRouteStorage.GetFIPRoutesByTunnelID(5))
+}
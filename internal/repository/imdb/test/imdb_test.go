@@ -20,11 +20,11 @@ var (
 		{VRFID: 1, Prefix: "10.11.64.5/32", NextHops: []string{"10.0.0.1", "10.0.0.2, 10.0.0.4"}, MainInterfaceID: 1, SubInterfaceID: 2, TunnelIDs: []uint32{1, 2, 4}, FIPMPLSLabels: []uint32{10, 20, 50}},
 	}
 	udpTunnelFixtures = []*model.VPPUDPTunnel{
-		{RoutingTableID: 1, TunnelID: 1, SrcIP: "10.0.0.1", DstIP: "10.10.10.1", SrcPort: 50000, DstPort: 6635, FIPServed: 0},
-		{RoutingTableID: 1, TunnelID: 2, SrcIP: "10.0.0.1", DstIP: "10.10.10.2", SrcPort: 50002, DstPort: 6635, FIPServed: 10},
-		{RoutingTableID: 1, TunnelID: 3, SrcIP: "10.0.0.1", DstIP: "10.10.10.3", SrcPort: 50003, DstPort: 6635, FIPServed: 20},
-		{RoutingTableID: 1, TunnelID: 4, SrcIP: "10.0.0.1", DstIP: "10.10.10.4", SrcPort: 50004, DstPort: 6635, FIPServed: 30},
-		{RoutingTableID: 1, TunnelID: 5, SrcIP: "10.0.0.1", DstIP: "10.10.10.5", SrcPort: 50005, DstPort: 6635, FIPServed: 40},
+		{RoutingTableID: 1, TunnelID: 1, SrcIP: "10.0.0.1", DstIP: "10.10.10.1", SrcPort: 50000, DstPort: 6635, FIPServed: 0, Reachable: true},
+		{RoutingTableID: 1, TunnelID: 2, SrcIP: "10.0.0.1", DstIP: "10.10.10.2", SrcPort: 50002, DstPort: 6635, FIPServed: 10, Reachable: true},
+		{RoutingTableID: 1, TunnelID: 3, SrcIP: "10.0.0.1", DstIP: "10.10.10.3", SrcPort: 50003, DstPort: 6635, FIPServed: 20, Reachable: true},
+		{RoutingTableID: 1, TunnelID: 4, SrcIP: "10.0.0.1", DstIP: "10.10.10.4", SrcPort: 50004, DstPort: 6635, FIPServed: 30, Reachable: true},
+		{RoutingTableID: 1, TunnelID: 5, SrcIP: "10.0.0.1", DstIP: "10.10.10.5", SrcPort: 50005, DstPort: 6635, FIPServed: 40, Reachable: true},
 	}
 	bgpPeerFixtures = []*model.BGPPeer{
 		{PeerType: model.TF, PeerASN: 65000, PeerAddress: "10.0.0.1", PeerPort: 169, Md5Password: "", EbgpMultiHop: false, EbgpMultiHopTTL: 1, VRFName: "", AFI: bgpapi.Family_AFI_IP, SAFI: bgpapi.Family_SAFI_MPLS_VPN, KeepAliveTimer: 3, HoldTimer: 9, BGPPeerState: bgpapi.PeerState_UNKNOWN, BGPPeerPrevState: bgpapi.PeerState_UNKNOWN, BGPPeerLastActivity: time.Now(), BFDPeering: &model.BFDPeer{BFDPeerEstablished: false}},
@@ -39,9 +39,9 @@ var (
 		{Name: "test03", ID: 3, LocalASN: 65000, PeerASN: 64555, RD: nil, ExportRT: nil, ImportRT: nil},
 	}
 	vppVRFFixtures = []*model.VPPVRFTable{
-		{Name: "test01", ID: 0, MainInterfaceID: 1, SubInterfaceID: 2, VLAN: 100, LocalAddr: "10.0.1.1", LocalAddrLen: 24, NextHop: "10.0.1.254", MPLSLocalLabel: 1000, FIPPrefixes: []string{"192.1.0.0/24", "192.1.1.0/24"}, FIPServed: 0},
-		{Name: "test02", ID: 1, MainInterfaceID: 1, SubInterfaceID: 3, VLAN: 200, LocalAddr: "10.0.2.1", LocalAddrLen: 24, NextHop: "10.0.2.254", MPLSLocalLabel: 2000, FIPPrefixes: []string{"192.2.0.0/24", "192.2.1.0/24"}, FIPServed: 0},
-		{Name: "test03", ID: 2, MainInterfaceID: 1, SubInterfaceID: 4, VLAN: 300, LocalAddr: "10.0.3.1", LocalAddrLen: 24, NextHop: "10.0.3.254", MPLSLocalLabel: 3000, FIPPrefixes: []string{"192.3.0.0/24", "192.3.1.0/24"}, FIPServed: 0},
+		{Name: "test01", ID: 0, MainInterfaceID: 1, SubInterfaceID: 2, VLAN: 100, LocalAddr: "10.0.1.1", LocalAddrLen: 24, NextHop: "10.0.1.254", MPLSLocalLabel: 1000, FIPPrefixes: []string{"192.1.0.0/24", "192.1.1.0/24"}, FIPServed: 0, LinkUp: true},
+		{Name: "test02", ID: 1, MainInterfaceID: 1, SubInterfaceID: 3, VLAN: 200, LocalAddr: "10.0.2.1", LocalAddrLen: 24, NextHop: "10.0.2.254", MPLSLocalLabel: 2000, FIPPrefixes: []string{"192.2.0.0/24", "192.2.1.0/24"}, FIPServed: 0, LinkUp: true},
+		{Name: "test03", ID: 2, MainInterfaceID: 1, SubInterfaceID: 4, VLAN: 300, LocalAddr: "10.0.3.1", LocalAddrLen: 24, NextHop: "10.0.3.254", MPLSLocalLabel: 3000, FIPPrefixes: []string{"192.3.0.0/24", "192.3.1.0/24"}, FIPServed: 0, LinkUp: true},
 	}
 )
 
@@ -0,0 +1,71 @@
+package test_test
+
+import (
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+)
+
+func newTxnStorage(s *IMDBStorageSuite) *imdb.Storage {
+	storage := imdb.NewStorage()
+
+	s.Require().NoError(storage.VPPVRFStorage.AddVRF(&model.VPPVRFTable{Name: "test02", ID: 1, FIPServed: 1}))
+	s.Require().NoError(storage.VPPUDPTunnelStorage.AddUDPTunnel(&model.VPPUDPTunnel{TunnelID: 1, DstIP: "10.10.10.1", FIPServed: 1}))
+	s.Require().NoError(storage.VPPFIPRouteStorage.AddFIPRoute(&model.VPPIPRoute{VRFID: 1, Prefix: "10.11.64.1/32", NextHops: []string{"10.10.10.1"}}))
+
+	return storage
+}
+
+func (s *IMDBStorageSuite) TestTxnCommit() {
+	storage := newTxnStorage(s)
+
+	txn := storage.Txn()
+	s.Require().NoError(txn.DelFIPRoute("10.11.64.1/32"))
+	s.Require().NoError(txn.AddUDPTunnel(&model.VPPUDPTunnel{TunnelID: 2, DstIP: "10.10.10.2"}))
+
+	served, err := txn.DecVRFFIPServed(1)
+	s.Require().NoError(err)
+	s.Require().Equal(uint32(0), served)
+
+	served, err = txn.DecUDPTunnelFIPServed("10.10.10.1")
+	s.Require().NoError(err)
+	s.Require().Equal(uint32(0), served)
+
+	// readers see the state before the transaction until commit
+
+	s.Require().NotNil(storage.VPPFIPRouteStorage.GetFIPRoute("10.11.64.1/32"))
+	s.Require().Equal(uint32(1), storage.VPPVRFStorage.GetFIPServed(1))
+
+	txn.Commit()
+
+	s.Require().Nil(storage.VPPFIPRouteStorage.GetFIPRoute("10.11.64.1/32"))
+	s.Require().NotNil(storage.VPPUDPTunnelStorage.GetUDPTunnel("10.10.10.2"))
+	s.Require().Equal(uint32(0), storage.VPPVRFStorage.GetFIPServed(1))
+	s.Require().Equal(uint32(0), storage.VPPUDPTunnelStorage.GetFIPServed("10.10.10.1"))
+}
+
+func (s *IMDBStorageSuite) TestTxnAbort() {
+	storage := newTxnStorage(s)
+	tunnel := storage.VPPUDPTunnelStorage.GetUDPTunnel("10.10.10.1")
+
+	txn := storage.Txn()
+	s.Require().NoError(txn.DelFIPRoute("10.11.64.1/32"))
+	s.Require().ErrorIs(txn.DelFIPRoute("10.11.64.1/32"), imdb.ErrNoVPPFIPFoundInStorage)
+
+	_, err := txn.IncVRFFIPServed(1)
+	s.Require().NoError(err)
+
+	_, err = txn.IncUDPTunnelFIPServed("10.10.10.1")
+	s.Require().NoError(err)
+
+	_, err = txn.IncUDPTunnelFIPServed("10.10.10.2")
+	s.Require().ErrorIs(err, imdb.ErrNoVPPUDPTunnelFoundInStorage)
+
+	txn.Abort()
+
+	// objects got before the transaction are not changed
+
+	s.Require().Equal(uint32(1), tunnel.FIPServed)
+	s.Require().Equal(uint32(1), storage.VPPUDPTunnelStorage.GetFIPServed("10.10.10.1"))
+	s.Require().Equal(uint32(1), storage.VPPVRFStorage.GetFIPServed(1))
+	s.Require().NotNil(storage.VPPFIPRouteStorage.GetFIPRoute("10.11.64.1/32"))
+}
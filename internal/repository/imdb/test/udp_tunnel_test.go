@@ -83,3 +83,44 @@ func (s *IMDBStorageSuite) TestIsUDPTunnelExist() {
 	isExist = s.udpTunnelStorage.IsUDPTunnelExist("")
 	s.Require().False(isExist)
 }
+
+func (s *IMDBStorageSuite) TestSetReachable() {
+	s.Require().True(s.udpTunnelStorage.IsReachable("10.10.10.1"))
+
+	s.udpTunnelStorage.SetReachable("10.10.10.1", false)
+	s.Require().False(s.udpTunnelStorage.IsReachable("10.10.10.1"))
+	s.Require().True(s.udpTunnelStorage.IsReachable("10.10.10.2"))
+
+	s.udpTunnelStorage.SetReachable("10.10.10.1", true)
+	s.Require().True(s.udpTunnelStorage.IsReachable("10.10.10.1"))
+
+	// unknown vrouter is reachable, check no panic
+	s.udpTunnelStorage.SetReachable("1.1.1.1", false)
+	s.Require().True(s.udpTunnelStorage.IsReachable("1.1.1.1"))
+}
+
+func (s *IMDBStorageSuite) TestGetUDPTunnelByID() {
+	tunnel := s.udpTunnelStorage.GetUDPTunnelByID(3)
+	s.Require().NotNil(tunnel)
+	s.Require().Equal("10.10.10.3", tunnel.DstIP)
+
+	s.Require().Nil(s.udpTunnelStorage.GetUDPTunnelByID(10))
+}
+
+func (s *IMDBStorageSuite) TestGetIdleUDPTunnels() {
+	tunnels := s.udpTunnelStorage.GetIdleUDPTunnels()
+	s.Require().Len(tunnels, 1)
+	s.Require().Equal("10.10.10.1", tunnels[0].DstIP)
+
+	// the idle index is updated by served floating ip counters
+
+	s.udpTunnelStorage.IncFIPServed("10.10.10.1")
+	s.Require().Nil(s.udpTunnelStorage.GetIdleUDPTunnels())
+
+	s.udpTunnelStorage.DecFIPServed("10.10.10.1")
+	s.udpTunnelStorage.SetReachable("10.10.10.1", false)
+
+	tunnels = s.udpTunnelStorage.GetIdleUDPTunnels()
+	s.Require().Len(tunnels, 1)
+	s.Require().False(tunnels[0].Reachable)
+}
@@ -29,6 +29,36 @@ func (s *IMDBStorageSuite) TestIncDecVPPFIPServed() {
 	s.vppVRFStorage.IncFIPServed(100)
 }
 
+func (s *IMDBStorageSuite) TestSetLinkUp() {
+	s.Require().True(s.vppVRFStorage.IsLinkUp(1))
+
+	s.vppVRFStorage.SetLinkUp(1, false)
+	s.Require().False(s.vppVRFStorage.IsLinkUp(1))
+	s.Require().True(s.vppVRFStorage.IsLinkUp(2))
+
+	s.vppVRFStorage.SetLinkUp(1, true)
+	s.Require().True(s.vppVRFStorage.IsLinkUp(1))
+
+	// Check no panic
+	s.vppVRFStorage.SetLinkUp(100, false)
+	s.Require().False(s.vppVRFStorage.IsLinkUp(100))
+}
+
+func (s *IMDBStorageSuite) TestSetDrained() {
+	s.Require().False(s.vppVRFStorage.IsDrained(1))
+
+	s.vppVRFStorage.SetDrained(1, true)
+	s.Require().True(s.vppVRFStorage.IsDrained(1))
+	s.Require().False(s.vppVRFStorage.IsDrained(2))
+
+	s.vppVRFStorage.SetDrained(1, false)
+	s.Require().False(s.vppVRFStorage.IsDrained(1))
+
+	// Check no panic
+	s.vppVRFStorage.SetDrained(100, true)
+	s.Require().False(s.vppVRFStorage.IsDrained(100))
+}
+
 func (s *IMDBStorageSuite) TestGetVRF() {
 	vrf := s.vppVRFStorage.GetVRF(0)
 	s.Require().NotNil(vrf)
@@ -65,3 +95,12 @@ func (s *IMDBStorageSuite) TestCreateVRFIDToNextHopMap() {
 		s.Require().Equal(vrf.NextHop, vrfMap[vppVRFFixtures[i].ID])
 	}
 }
+
+func (s *IMDBStorageSuite) TestGetVRFByName() {
+	vrf := s.vppVRFStorage.GetVRFByName("test02")
+	s.Require().NotNil(vrf)
+	s.Require().Equal(uint32(1), vrf.ID)
+
+	s.Require().Nil(s.vppVRFStorage.GetVRFByName("test04"))
+	s.Require().Nil(s.vppVRFStorage.GetVRFByName(""))
+}
@@ -0,0 +1,168 @@
+package imdb
+
+import (
+	"github.com/hashicorp/go-memdb"
+
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+)
+
+// Txn is a write transaction over all tables of the storage. Changed objects are copied before update, so objects got
+// from the storage are not changed if the transaction is aborted.
+type Txn struct {
+	txn *memdb.Txn
+}
+
+func newTxn(db *memdb.MemDB) *Txn {
+	return &Txn{txn: db.Txn(true)}
+}
+
+func (t *Txn) Commit() {
+	t.txn.Commit()
+}
+
+// Abort discards all changes of the transaction, it is no-op after commit
+func (t *Txn) Abort() {
+	t.txn.Abort()
+}
+
+// floating ip routes
+
+func (t *Txn) GetFIPRoute(fipPrefix string) *model.VPPIPRoute {
+	raw, err := t.txn.First(VPPFIPRouteTableName, "id", fipPrefix)
+	if err != nil {
+		return nil
+	}
+
+	route, ok := raw.(*model.VPPIPRoute)
+	if !ok {
+		return nil
+	}
+
+	return route
+}
+
+func (t *Txn) AddFIPRoute(fipRoute *model.VPPIPRoute) error {
+	return t.txn.Insert(VPPFIPRouteTableName, fipRoute)
+}
+
+func (t *Txn) DelFIPRoute(fipPrefix string) error {
+	deleted, err := t.txn.DeleteAll(VPPFIPRouteTableName, "id", fipPrefix)
+	if err != nil {
+		return err
+	}
+
+	if deleted == 0 {
+		return ErrNoVPPFIPFoundInStorage
+	}
+
+	return nil
+}
+
+// udp tunnels
+
+func (t *Txn) GetUDPTunnel(dstIP string) *model.VPPUDPTunnel {
+	raw, err := t.txn.First(VPPUDPTunnelTableName, "id", dstIP)
+	if err != nil {
+		return nil
+	}
+
+	tunnel, ok := raw.(*model.VPPUDPTunnel)
+	if !ok {
+		return nil
+	}
+
+	return tunnel
+}
+
+func (t *Txn) AddUDPTunnel(tunnel *model.VPPUDPTunnel) error {
+	return t.txn.Insert(VPPUDPTunnelTableName, tunnel)
+}
+
+func (t *Txn) DelUDPTunnel(dstIP string) error {
+	deleted, err := t.txn.DeleteAll(VPPUDPTunnelTableName, "id", dstIP)
+	if err != nil {
+		return err
+	}
+
+	if deleted == 0 {
+		return ErrNoVPPUDPTunnelFoundInStorage
+	}
+
+	return nil
+}
+
+// IncUDPTunnelFIPServed increments number of floating ips served by the vrouter and returns the new number
+func (t *Txn) IncUDPTunnelFIPServed(dstIP string) (uint32, error) {
+	tunnel := t.GetUDPTunnel(dstIP)
+	if tunnel == nil {
+		return 0, ErrNoVPPUDPTunnelFoundInStorage
+	}
+
+	updated := *tunnel
+	updated.FIPServed++
+
+	return updated.FIPServed, t.txn.Insert(VPPUDPTunnelTableName, &updated)
+}
+
+// DecUDPTunnelFIPServed decrements number of floating ips served by the vrouter (not below zero) and returns the new number
+func (t *Txn) DecUDPTunnelFIPServed(dstIP string) (uint32, error) {
+	tunnel := t.GetUDPTunnel(dstIP)
+	if tunnel == nil {
+		return 0, ErrNoVPPUDPTunnelFoundInStorage
+	}
+
+	if tunnel.FIPServed == 0 {
+		return 0, nil
+	}
+
+	updated := *tunnel
+	updated.FIPServed--
+
+	return updated.FIPServed, t.txn.Insert(VPPUDPTunnelTableName, &updated)
+}
+
+// vpp vrfs
+
+func (t *Txn) GetVRF(vrfID uint32) *model.VPPVRFTable {
+	raw, err := t.txn.First(VPPVRFTableName, "id", vrfID)
+	if err != nil {
+		return nil
+	}
+
+	vrf, ok := raw.(*model.VPPVRFTable)
+	if !ok {
+		return nil
+	}
+
+	return vrf
+}
+
+// IncVRFFIPServed increments number of floating ips served by the vrf and returns the new number
+func (t *Txn) IncVRFFIPServed(vrfID uint32) (uint32, error) {
+	vrf := t.GetVRF(vrfID)
+	if vrf == nil {
+		return 0, ErrNoVPPVRFsFoundInStorage
+	}
+
+	updated := *vrf
+	updated.FIPServed++
+
+	return updated.FIPServed, t.txn.Insert(VPPVRFTableName, &updated)
+}
+
+// DecVRFFIPServed decrements number of floating ips served by the vrf (not below zero) and returns the new number
+func (t *Txn) DecVRFFIPServed(vrfID uint32) (uint32, error) {
+	vrf := t.GetVRF(vrfID)
+	if vrf == nil {
+		return 0, ErrNoVPPVRFsFoundInStorage
+	}
+
+	if vrf.FIPServed == 0 {
+		return 0, nil
+	}
+
+	updated := *vrf
+	updated.FIPServed--
+
+	return updated.FIPServed, t.txn.Insert(VPPVRFTableName, &updated)
+}
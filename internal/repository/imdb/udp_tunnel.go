@@ -4,7 +4,6 @@ import (
 	"github.com/hashicorp/go-memdb"
 
 	"git.crptech.ru/cloud/cloudgw/internal/model"
-	"git.crptech.ru/cloud/cloudgw/pkg/logger"
 )
 
 var VPPUDPTunnelTableName = "udp_tunnel"
@@ -14,28 +13,31 @@ type VPPUDPTunnelStorage struct {
 }
 
 func NewVPPUDPTunnelStorage() *VPPUDPTunnelStorage {
-	schema := &memdb.DBSchema{
-		Tables: map[string]*memdb.TableSchema{
-			VPPUDPTunnelTableName: {
-				Name: VPPUDPTunnelTableName,
-				Indexes: map[string]*memdb.IndexSchema{
-					"id": {
-						Name:    "id",
-						Unique:  true,
-						Indexer: &memdb.StringFieldIndex{Field: "DstIP"},
-					},
-				},
-			},
-		},
-	}
+	return &VPPUDPTunnelStorage{db: newMemDB(udpTunnelTable())}
+}
 
-	db, err := memdb.NewMemDB(schema)
-	if err != nil {
-		logger.Fatal("failed to create vpp udp tunnel storage", "error", err)
-	}
+func udpTunnelTable() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: VPPUDPTunnelTableName,
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": {
+				Name:    "id",
+				Unique:  true,
+				Indexer: &memdb.StringFieldIndex{Field: "DstIP"},
+			},
+			"tunnel_id": {
+				Name:    "tunnel_id",
+				Indexer: &memdb.UintFieldIndex{Field: "TunnelID"},
+			},
+			"idle": {
+				Name: "idle",
+				Indexer: &memdb.ConditionalIndex{Conditional: func(obj any) (bool, error) {
+					tunnel, ok := obj.(*model.VPPUDPTunnel)
 
-	return &VPPUDPTunnelStorage{
-		db: db,
+					return ok && tunnel.FIPServed == 0, nil
+				}},
+			},
+		},
 	}
 }
 
@@ -99,11 +101,40 @@ func (s *VPPUDPTunnelStorage) GetUDPTunnel(dstIP string) *model.VPPUDPTunnel {
 }
 
 func (s *VPPUDPTunnelStorage) GetUDPTunnels() []*model.VPPUDPTunnel {
+	return s.getUDPTunnels("id_prefix", "")
+}
+
+// GetUDPTunnelByID returns udp tunnel with the vpp tunnel id or nil if not found
+func (s *VPPUDPTunnelStorage) GetUDPTunnelByID(tunnelID uint32) *model.VPPUDPTunnel {
+	txn := s.db.Txn(false)
+
+	defer txn.Abort()
+
+	raw, err := txn.First(VPPUDPTunnelTableName, "tunnel_id", tunnelID)
+	if err != nil {
+		return nil
+	}
+
+	tunnel, ok := raw.(*model.VPPUDPTunnel)
+	if !ok {
+		return nil
+	}
+
+	return tunnel
+}
+
+// GetIdleUDPTunnels returns udp tunnels without served floating ips
+func (s *VPPUDPTunnelStorage) GetIdleUDPTunnels() []*model.VPPUDPTunnel {
+	return s.getUDPTunnels("idle", true)
+}
+
+// getUDPTunnels returns udp tunnels found by the index or nil if no tunnel found
+func (s *VPPUDPTunnelStorage) getUDPTunnels(index string, args ...any) []*model.VPPUDPTunnel {
 	txn := s.db.Txn(false)
 
 	defer txn.Abort()
 
-	raws, err := txn.Get(VPPUDPTunnelTableName, "id_prefix", "")
+	raws, err := txn.Get(VPPUDPTunnelTableName, index, args...)
 	if err != nil {
 		return nil
 	}
@@ -129,28 +160,56 @@ func (s *VPPUDPTunnelStorage) GetUDPTunnels() []*model.VPPUDPTunnel {
 }
 
 func (s *VPPUDPTunnelStorage) IncFIPServed(dstIP string) {
-	txn := s.db.Txn(true)
+	txn := newTxn(s.db)
+
+	defer txn.Commit()
+
+	_, _ = txn.IncUDPTunnelFIPServed(dstIP)
+}
+
+func (s *VPPUDPTunnelStorage) DecFIPServed(dstIP string) {
+	txn := newTxn(s.db)
 
 	defer txn.Commit()
 
+	_, _ = txn.DecUDPTunnelFIPServed(dstIP)
+}
+
+func (s *VPPUDPTunnelStorage) GetFIPServed(dstIP string) uint32 {
+	txn := s.db.Txn(false)
+
+	defer txn.Abort()
+
 	raw, err := txn.First(VPPUDPTunnelTableName, "id", dstIP)
 	if err != nil {
-		return
+		return 0
 	}
 
 	tunnel, ok := raw.(*model.VPPUDPTunnel)
 	if !ok {
-		return
+		return 0
 	}
 
-	tunnel.FIPServed++
+	return tunnel.FIPServed
+}
 
-	if err := txn.Insert(VPPUDPTunnelTableName, tunnel); err != nil {
-		return
+func (s *VPPUDPTunnelStorage) IsUDPTunnelExist(dstIP string) bool {
+	txn := s.db.Txn(false)
+
+	defer txn.Abort()
+
+	raw, err := txn.First(VPPUDPTunnelTableName, "id", dstIP)
+	if err != nil {
+		return false
 	}
+
+	_, ok := raw.(*model.VPPUDPTunnel)
+
+	return ok
 }
 
-func (s *VPPUDPTunnelStorage) DecFIPServed(dstIP string) {
+// SetReachable updates reachability of vrouter (tunnel destination) detected by tunnel probing
+func (s *VPPUDPTunnelStorage) SetReachable(dstIP string, isReachable bool) {
 	txn := s.db.Txn(true)
 
 	defer txn.Commit()
@@ -165,46 +224,31 @@ func (s *VPPUDPTunnelStorage) DecFIPServed(dstIP string) {
 		return
 	}
 
-	if tunnel.FIPServed == 0 {
-		return
-	}
+	// objects are not changed in place as memdb removes old index entries using the stored object
 
-	tunnel.FIPServed--
+	updated := *tunnel
+	updated.Reachable = isReachable
 
-	if err := txn.Insert(VPPUDPTunnelTableName, tunnel); err != nil {
+	if err := txn.Insert(VPPUDPTunnelTableName, &updated); err != nil {
 		return
 	}
 }
 
-func (s *VPPUDPTunnelStorage) GetFIPServed(dstIP string) uint32 {
+// IsReachable checks if vrouter (tunnel destination) is reachable, unknown vrouters are considered reachable
+func (s *VPPUDPTunnelStorage) IsReachable(dstIP string) bool {
 	txn := s.db.Txn(false)
 
 	defer txn.Abort()
 
 	raw, err := txn.First(VPPUDPTunnelTableName, "id", dstIP)
 	if err != nil {
-		return 0
+		return true
 	}
 
 	tunnel, ok := raw.(*model.VPPUDPTunnel)
 	if !ok {
-		return 0
+		return true
 	}
 
-	return tunnel.FIPServed
-}
-
-func (s *VPPUDPTunnelStorage) IsUDPTunnelExist(dstIP string) bool {
-	txn := s.db.Txn(false)
-
-	defer txn.Abort()
-
-	raw, err := txn.First(VPPUDPTunnelTableName, "id", dstIP)
-	if err != nil {
-		return false
-	}
-
-	_, ok := raw.(*model.VPPUDPTunnel)
-
-	return ok
+	return tunnel.Reachable
 }
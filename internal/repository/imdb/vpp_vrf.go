@@ -4,7 +4,6 @@ import (
 	"github.com/hashicorp/go-memdb"
 
 	"git.crptech.ru/cloud/cloudgw/internal/model"
-	"git.crptech.ru/cloud/cloudgw/pkg/logger"
 )
 
 var VPPVRFTableName = "vpp_vrf"
@@ -14,34 +13,25 @@ type VPPVRFStorage struct {
 }
 
 func NewVPPVRFStorage() *VPPVRFStorage {
-	schema := &memdb.DBSchema{
-		Tables: map[string]*memdb.TableSchema{
-			VPPVRFTableName: {
-				Name: VPPVRFTableName,
-				Indexes: map[string]*memdb.IndexSchema{
-					"id": {
-						Name:    "id",
-						Unique:  true,
-						Indexer: &memdb.UintFieldIndex{Field: "ID"},
-					},
-					"name": {
-						Name:    "name",
-						Unique:  true,
-						Indexer: &memdb.StringFieldIndex{Field: "Name"},
-					},
-				},
+	return &VPPVRFStorage{db: newMemDB(vppVRFTable())}
+}
+
+func vppVRFTable() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: VPPVRFTableName,
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": {
+				Name:    "id",
+				Unique:  true,
+				Indexer: &memdb.UintFieldIndex{Field: "ID"},
+			},
+			"name": {
+				Name:    "name",
+				Unique:  true,
+				Indexer: &memdb.StringFieldIndex{Field: "Name"},
 			},
 		},
 	}
-
-	db, err := memdb.NewMemDB(schema)
-	if err != nil {
-		logger.Fatal("failed to create vpp vrf storage", "error", err)
-	}
-
-	return &VPPVRFStorage{
-		db: db,
-	}
 }
 
 func (s *VPPVRFStorage) AddVRF(vrf *model.VPPVRFTable) error {
@@ -86,6 +76,25 @@ func (s *VPPVRFStorage) GetVRF(vrfID uint32) *model.VPPVRFTable {
 	return vrf
 }
 
+// GetVRFByName returns vrf with the name or nil if not found
+func (s *VPPVRFStorage) GetVRFByName(name string) *model.VPPVRFTable {
+	txn := s.db.Txn(false)
+
+	defer txn.Abort()
+
+	raw, err := txn.First(VPPVRFTableName, "name", name)
+	if err != nil {
+		return nil
+	}
+
+	vrf, ok := raw.(*model.VPPVRFTable)
+	if !ok {
+		return nil
+	}
+
+	return vrf
+}
+
 func (s *VPPVRFStorage) GetVRFs() []*model.VPPVRFTable {
 	txn := s.db.Txn(false)
 
@@ -117,10 +126,45 @@ func (s *VPPVRFStorage) GetVRFs() []*model.VPPVRFTable {
 }
 
 func (s *VPPVRFStorage) IncFIPServed(vrfID uint32) {
+	txn := newTxn(s.db)
+
+	defer txn.Commit()
+
+	_, _ = txn.IncVRFFIPServed(vrfID)
+}
+
+func (s *VPPVRFStorage) DecFIPServed(vrfID uint32) {
+	txn := newTxn(s.db)
+
+	defer txn.Commit()
+
+	_, _ = txn.DecVRFFIPServed(vrfID)
+}
+
+func (s *VPPVRFStorage) GetFIPServed(vrfID uint32) uint32 {
 	txn := s.db.Txn(false)
 
 	defer txn.Abort()
 
+	raw, err := txn.First(VPPVRFTableName, "id", vrfID)
+	if err != nil {
+		return 0
+	}
+
+	vrf, ok := raw.(*model.VPPVRFTable)
+	if !ok {
+		return 0
+	}
+
+	return vrf.FIPServed
+}
+
+// SetLinkUp updates link state of vrf (state of vpp main interface and sub-interface to physical network)
+func (s *VPPVRFStorage) SetLinkUp(vrfID uint32, isUp bool) {
+	txn := s.db.Txn(true)
+
+	defer txn.Commit()
+
 	raw, err := txn.First(VPPVRFTableName, "id", vrfID)
 	if err != nil {
 		return
@@ -131,55 +175,72 @@ func (s *VPPVRFStorage) IncFIPServed(vrfID uint32) {
 		return
 	}
 
-	vrf.FIPServed++
+	updated := *vrf
+	updated.LinkUp = isUp
 
-	if err := txn.Insert(VPPVRFTableName, vrf); err != nil {
+	if err := txn.Insert(VPPVRFTableName, &updated); err != nil {
 		return
 	}
 }
 
-func (s *VPPVRFStorage) DecFIPServed(vrfID uint32) {
+func (s *VPPVRFStorage) IsLinkUp(vrfID uint32) bool {
 	txn := s.db.Txn(false)
 
 	defer txn.Abort()
 
 	raw, err := txn.First(VPPVRFTableName, "id", vrfID)
 	if err != nil {
-		return
+		return false
 	}
 
 	vrf, ok := raw.(*model.VPPVRFTable)
 	if !ok {
+		return false
+	}
+
+	return vrf.LinkUp
+}
+
+// SetDrained marks vrf as drained (aggregated floating ip prefixes withdrawn by administrator) or undrained
+func (s *VPPVRFStorage) SetDrained(vrfID uint32, isDrained bool) {
+	txn := s.db.Txn(true)
+
+	defer txn.Commit()
+
+	raw, err := txn.First(VPPVRFTableName, "id", vrfID)
+	if err != nil {
 		return
 	}
 
-	if vrf.FIPServed == 0 {
+	vrf, ok := raw.(*model.VPPVRFTable)
+	if !ok {
 		return
 	}
 
-	vrf.FIPServed--
+	updated := *vrf
+	updated.Drained = isDrained
 
-	if err := txn.Insert(VPPVRFTableName, vrf); err != nil {
+	if err := txn.Insert(VPPVRFTableName, &updated); err != nil {
 		return
 	}
 }
 
-func (s *VPPVRFStorage) GetFIPServed(vrfID uint32) uint32 {
+func (s *VPPVRFStorage) IsDrained(vrfID uint32) bool {
 	txn := s.db.Txn(false)
 
 	defer txn.Abort()
 
 	raw, err := txn.First(VPPVRFTableName, "id", vrfID)
 	if err != nil {
-		return 0
+		return false
 	}
 
 	vrf, ok := raw.(*model.VPPVRFTable)
 	if !ok {
-		return 0
+		return false
 	}
 
-	return vrf.FIPServed
+	return vrf.Drained
 }
 
 func (s *VPPVRFStorage) IsVRFExist(vrfID uint32) bool {
@@ -0,0 +1,213 @@
+package dryrun
+
+import (
+	"fmt"
+	"math"
+	"net/netip"
+	"strings"
+
+	"go.fd.io/govpp/api"
+	"go.fd.io/govpp/binapi/fib_types"
+	interfaces "go.fd.io/govpp/binapi/interface"
+	"go.fd.io/govpp/binapi/ip"
+	"go.fd.io/govpp/binapi/ip_types"
+	"go.fd.io/govpp/binapi/mpls"
+	"go.fd.io/govpp/binapi/udp"
+)
+
+const nullInterface = math.MaxUint32
+
+// FIB is the intended fib: udp tunnels and ipv4 routes vpp would have if requests were sent
+type FIB struct {
+	Tables  []Table  `json:"Tables"`
+	Tunnels []Tunnel `json:"Tunnels"`
+	Routes  []Route  `json:"Routes"`
+}
+
+type Table struct {
+	ID   uint32 `json:"ID"`
+	Name string `json:"Name"`
+}
+
+type Tunnel struct {
+	ID      uint32 `json:"ID"`
+	SrcIP   string `json:"SrcIP"`
+	DstIP   string `json:"DstIP"`
+	SrcPort uint16 `json:"SrcPort"`
+	DstPort uint16 `json:"DstPort"`
+}
+
+type Route struct {
+	TableID uint32 `json:"TableID"`
+	Prefix  string `json:"Prefix"`
+	Paths   []Path `json:"Paths"`
+}
+
+type Path struct {
+	Type      string  `json:"Type"`                // normal, drop, udp_encap, ...
+	NextHop   string  `json:"NextHop,omitempty"`   // absent for drop paths
+	TunnelID  *uint32 `json:"TunnelID,omitempty"`  // udp_encap paths only
+	Label     uint32  `json:"Label,omitempty"`     // out mpls label
+	Interface *uint32 `json:"Interface,omitempty"` // absent for drop paths
+}
+
+// FIB returns the intended fib sorted by table id and prefix
+func (s *Stream) FIB() FIB {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var fib FIB
+
+	for _, msg := range s.dumpTables() {
+		table := msg.(*ip.IPTableDetails).Table
+		fib.Tables = append(fib.Tables, Table{ID: table.TableID, Name: table.Name})
+	}
+
+	for _, msg := range s.dumpTunnels() {
+		tunnel := msg.(*udp.UDPEncapDetails).UDPEncap
+
+		fib.Tunnels = append(fib.Tunnels, Tunnel{
+			ID:      tunnel.ID,
+			SrcIP:   tunnel.SrcIP.String(),
+			DstIP:   tunnel.DstIP.String(),
+			SrcPort: tunnel.SrcPort,
+			DstPort: tunnel.DstPort,
+		})
+	}
+
+	for _, key := range s.sortedRouteKeys() {
+		route := Route{TableID: key.tableID, Prefix: key.prefix.String()}
+
+		for _, p := range s.routes[key].Paths {
+			route.Paths = append(route.Paths, fibPath(p))
+		}
+
+		fib.Routes = append(fib.Routes, route)
+	}
+
+	return fib
+}
+
+func fibPath(p fib_types.FibPath) Path {
+	path := Path{Type: strings.ToLower(strings.TrimPrefix(p.Type.String(), "FIB_API_PATH_TYPE_"))}
+
+	if nh := p.Nh.Address.GetIP4(); !netip.AddrFrom4(nh).IsUnspecified() {
+		path.NextHop = nh.String()
+	}
+
+	if p.SwIfIndex != nullInterface {
+		swIfIndex := p.SwIfIndex
+		path.Interface = &swIfIndex
+	}
+
+	// blackhole routes are added as normal paths via the null interface (see vpp.AddBlackHoleIPRoute)
+
+	if p.Type == fib_types.FIB_API_PATH_TYPE_NORMAL && path.NextHop == "" && path.Interface == nil {
+		path.Type = "drop"
+	}
+
+	if p.Type == fib_types.FIB_API_PATH_TYPE_UDP_ENCAP {
+		tunnelID := p.Nh.ObjID
+		path.TunnelID = &tunnelID
+	}
+
+	if p.NLabels != 0 {
+		path.Label = p.LabelStack[0].Label
+	}
+
+	return path
+}
+
+func routePrefix(prefix ip_types.Prefix) (netip.Prefix, bool) {
+	p, err := netip.ParsePrefix(prefix.String())
+	if err != nil || !p.Addr().Is4() {
+		return netip.Prefix{}, false
+	}
+
+	return p.Masked(), true
+}
+
+// summary returns vpp cli like text of the request, e.g. "ip route add table 1 192.0.2.1/32 via 10.0.0.1 udp-encap 3
+// out-labels 25"
+func summary(msg api.Message) string {
+	switch req := msg.(type) {
+	case *udp.UDPEncapAdd:
+		return fmt.Sprintf("udp encap add %s %s %d %d table-id %d",
+			req.UDPEncap.SrcIP, req.UDPEncap.DstIP, req.UDPEncap.SrcPort, req.UDPEncap.DstPort, req.UDPEncap.TableID)
+
+	case *udp.UDPEncapDel:
+		return fmt.Sprintf("udp encap del index %d", req.ID)
+
+	case *ip.IPTableAddDel:
+		return fmt.Sprintf("ip table %s %d", addDel(req.IsAdd), req.Table.TableID)
+
+	case *ip.IPRouteAddDelV2:
+		paths := make([]string, 0, len(req.Route.Paths))
+
+		for _, p := range req.Route.Paths {
+			paths = append(paths, pathSummary(p))
+		}
+
+		return fmt.Sprintf("ip route %s table %d %s %s",
+			addDel(req.IsAdd), req.Route.TableID, req.Route.Prefix, strings.Join(paths, ", "))
+
+	case *mpls.MplsRouteAddDel:
+		paths := make([]string, 0, len(req.MrRoute.MrPaths))
+
+		for _, p := range req.MrRoute.MrPaths {
+			paths = append(paths, pathSummary(p))
+		}
+
+		return fmt.Sprintf("mpls local-label %s %d eos %s",
+			addDel(req.MrIsAdd), req.MrRoute.MrLabel, strings.Join(paths, ", "))
+
+	case *interfaces.CreateVlanSubif:
+		return fmt.Sprintf("create sub-interfaces %d %d", req.SwIfIndex, req.VlanID)
+	}
+
+	return msg.GetMessageName()
+}
+
+func pathSummary(p fib_types.FibPath) string {
+	path := fibPath(p)
+
+	switch path.Type {
+	case "drop":
+		return "via drop"
+
+	case "udp_encap":
+		return fmt.Sprintf("via %s udp-encap %d out-labels %d", path.NextHop, *path.TunnelID, path.Label)
+	}
+
+	text := "via " + valueOr(path.NextHop, "0.0.0.0")
+
+	if path.Interface != nil {
+		text += fmt.Sprintf(" sw_if_index %d", *path.Interface)
+	}
+
+	if p.TableID != 0 {
+		text += fmt.Sprintf(" ip4-lookup-in-table %d", p.TableID)
+	}
+
+	if p.NLabels != 0 {
+		text += fmt.Sprintf(" out-labels %d", path.Label)
+	}
+
+	return text
+}
+
+func addDel(isAdd bool) string {
+	if isAdd {
+		return "add"
+	}
+
+	return "del"
+}
+
+func valueOr(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+
+	return value
+}
@@ -0,0 +1,420 @@
+package dryrun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.fd.io/govpp/api"
+	"go.fd.io/govpp/binapi/fib_types"
+	interfaces "go.fd.io/govpp/binapi/interface"
+	"go.fd.io/govpp/binapi/interface_types"
+	"go.fd.io/govpp/binapi/ip"
+	"go.fd.io/govpp/binapi/memclnt"
+	"go.fd.io/govpp/binapi/udp"
+	"go.fd.io/govpp/binapi/vpe"
+)
+
+const (
+	Version = "dry-run"
+
+	maxRecords       = 10000 // oldest records are dropped
+	firstSwIfIndex   = 1000  // sw_if_index of the first created sub-interface
+	udpEncapObjShift = 24    // vpp replies udp encap id of a path shifted (see vpp.DumpFIPRoutes)
+)
+
+var errNoReply = errors.New("no reply, request was not sent")
+
+// Stream is a vpp binary api stream that records requests instead of sending them to vpp. Replies are generated as
+// vpp would reply, udp tunnels and ip routes are applied to the intended fib to reply dumps and lookups.
+type Stream struct {
+	ctx context.Context
+
+	mu            sync.Mutex
+	replies       []api.Message // replies to sent requests
+	records       []Record
+	recordTotal   int
+	tunnels       map[uint32]udp.UDPEncap
+	nextTunnelID  uint32
+	tables        map[uint32]string // ipv4 table id -> name
+	routes        map[routeKey]ip.IPRouteV2
+	nextSwIfIndex uint32
+	nextStatsIdx  uint32 // stats index of the next added route, vpp replies the load balance index of the route
+}
+
+type routeKey struct {
+	tableID uint32
+	prefix  netip.Prefix
+}
+
+// Record is a request that would be sent to vpp
+type Record struct {
+	Seq     int       `json:"Seq"`
+	Time    time.Time `json:"Time"`
+	Message string    `json:"Message"` // vpp api message name, e.g. "ip_route_add_del_v2"
+	Summary string    `json:"Summary"` // vpp cli like summary of the request
+}
+
+func NewStream(ctx context.Context) *Stream {
+	return &Stream{
+		ctx:           ctx,
+		tunnels:       make(map[uint32]udp.UDPEncap),
+		tables:        map[uint32]string{0: "ipv4-VRF:0"},
+		routes:        make(map[routeKey]ip.IPRouteV2),
+		nextSwIfIndex: firstSwIfIndex,
+	}
+}
+
+func (s *Stream) Context() context.Context {
+	return s.ctx
+}
+
+// SendMsg records the request and prepares replies
+func (s *Stream) SendMsg(msg api.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := msg.(*memclnt.ControlPing); !ok {
+		s.record(msg)
+	}
+
+	replies, err := s.handle(msg)
+	if err != nil {
+		return err
+	}
+
+	s.replies = append(s.replies, replies...)
+
+	return nil
+}
+
+// RecvMsg returns the next reply, it does not block as all replies are prepared on send
+func (s *Stream) RecvMsg() (api.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.replies) == 0 {
+		return nil, errNoReply
+	}
+
+	reply := s.replies[0]
+	s.replies = s.replies[1:]
+
+	return reply, nil
+}
+
+func (s *Stream) Close() error {
+	return nil
+}
+
+// Records returns recorded requests, the oldest first
+func (s *Stream) Records() []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.records)
+}
+
+func (s *Stream) record(msg api.Message) {
+	s.recordTotal++
+
+	s.records = append(s.records, Record{
+		Seq:     s.recordTotal,
+		Time:    time.Now(),
+		Message: msg.GetMessageName(),
+		Summary: summary(msg),
+	})
+
+	if len(s.records) > maxRecords {
+		s.records = slices.Delete(s.records, 0, len(s.records)-maxRecords)
+	}
+}
+
+// handle applies the request to the intended fib and returns replies to it
+func (s *Stream) handle(msg api.Message) ([]api.Message, error) {
+	switch req := msg.(type) {
+	case *memclnt.ControlPing:
+		return []api.Message{&memclnt.ControlPingReply{}}, nil
+
+	case *vpe.ShowVersion:
+		return []api.Message{&vpe.ShowVersionReply{Program: "vpe", Version: Version}}, nil
+
+	case *interfaces.CreateVlanSubif:
+		s.nextSwIfIndex++
+
+		return []api.Message{&interfaces.CreateVlanSubifReply{SwIfIndex: interface_types.InterfaceIndex(s.nextSwIfIndex - 1)}}, nil
+
+	case *udp.UDPEncapAdd:
+		tunnel := req.UDPEncap
+		tunnel.ID = s.nextTunnelID
+		s.tunnels[tunnel.ID] = tunnel
+		s.nextTunnelID++
+
+		return []api.Message{&udp.UDPEncapAddReply{ID: tunnel.ID}}, nil
+
+	case *udp.UDPEncapDel:
+		if _, ok := s.tunnels[req.ID]; !ok {
+			return []api.Message{&udp.UDPEncapDelReply{Retval: int32(api.NO_SUCH_ENTRY)}}, nil
+		}
+
+		delete(s.tunnels, req.ID)
+
+		return []api.Message{&udp.UDPEncapDelReply{}}, nil
+
+	case *udp.UDPEncapDump:
+		return s.dumpTunnels(), nil
+
+	case *ip.IPTableAddDel:
+		if !req.Table.IsIP6 {
+			if req.IsAdd {
+				s.tables[req.Table.TableID] = req.Table.Name
+			} else if req.Table.TableID != 0 {
+				s.deleteTable(req.Table.TableID)
+			}
+		}
+
+		return []api.Message{&ip.IPTableAddDelReply{}}, nil
+
+	case *ip.IPTableDump:
+		return s.dumpTables(), nil
+
+	case *ip.IPRouteAddDelV2:
+		retval, statsIndex := s.addDelRoute(req)
+
+		return []api.Message{&ip.IPRouteAddDelV2Reply{Retval: retval, StatsIndex: statsIndex}}, nil
+
+	case *ip.IPRouteV2Dump:
+		return s.dumpRoutes(req.Table.TableID), nil
+
+	case *ip.IPRouteLookupV2:
+		return []api.Message{s.lookupRoute(req)}, nil
+	}
+
+	// other requests (interfaces, mpls, decap) are only recorded, dumps reply with nothing
+
+	return genericReply(msg)
+}
+
+func (s *Stream) addDelRoute(req *ip.IPRouteAddDelV2) (retval int32, statsIndex uint32) {
+	prefix, ok := routePrefix(req.Route.Prefix)
+	if !ok {
+		return int32(api.INVALID_VALUE), 0
+	}
+
+	key := routeKey{tableID: req.Route.TableID, prefix: prefix}
+
+	if _, ok = s.tables[key.tableID]; !ok {
+		return int32(api.NO_SUCH_FIB), 0
+	}
+
+	route, isExist := s.routes[key]
+
+	switch {
+	case req.IsAdd && req.IsMultipath && isExist:
+		// multipath add appends paths to the existing route
+		for _, path := range req.Route.Paths {
+			if !slices.ContainsFunc(route.Paths, func(p fib_types.FibPath) bool { return samePath(p, path) }) {
+				route.Paths = append(route.Paths, path)
+			}
+		}
+
+	case req.IsAdd:
+		// not multipath add replaces all paths of the route
+		index := route.StatsIndex
+
+		if !isExist {
+			index = s.nextStatsIdx
+			s.nextStatsIdx++
+		}
+
+		route = req.Route
+		route.Paths = slices.Clone(req.Route.Paths)
+		route.StatsIndex = index
+
+	case !isExist:
+		return int32(api.NO_SUCH_ENTRY), 0
+
+	case req.IsMultipath && len(req.Route.Paths) != 0:
+		// multipath delete removes the paths, the route is removed with the last path
+		route.Paths = slices.DeleteFunc(route.Paths, func(p fib_types.FibPath) bool {
+			return slices.ContainsFunc(req.Route.Paths, func(path fib_types.FibPath) bool { return samePath(p, path) })
+		})
+
+	default:
+		route.Paths = nil
+	}
+
+	if len(route.Paths) == 0 {
+		delete(s.routes, key)
+
+		return 0, route.StatsIndex
+	}
+
+	route.NPaths = uint8(len(route.Paths))
+	s.routes[key] = route
+
+	return 0, route.StatsIndex
+}
+
+func (s *Stream) deleteTable(tableID uint32) {
+	delete(s.tables, tableID)
+
+	for key := range s.routes {
+		if key.tableID == tableID {
+			delete(s.routes, key)
+		}
+	}
+}
+
+func (s *Stream) dumpTunnels() []api.Message {
+	ids := make([]uint32, 0, len(s.tunnels))
+
+	for id := range s.tunnels {
+		ids = append(ids, id)
+	}
+
+	slices.Sort(ids)
+
+	replies := make([]api.Message, 0, len(ids))
+
+	for _, id := range ids {
+		replies = append(replies, &udp.UDPEncapDetails{UDPEncap: s.tunnels[id]})
+	}
+
+	return replies
+}
+
+func (s *Stream) dumpTables() []api.Message {
+	ids := make([]uint32, 0, len(s.tables))
+
+	for id := range s.tables {
+		ids = append(ids, id)
+	}
+
+	slices.Sort(ids)
+
+	replies := make([]api.Message, 0, len(ids))
+
+	for _, id := range ids {
+		replies = append(replies, &ip.IPTableDetails{Table: ip.IPTable{TableID: id, Name: s.tables[id]}})
+	}
+
+	return replies
+}
+
+func (s *Stream) dumpRoutes(tableID uint32) []api.Message {
+	var replies []api.Message
+
+	for _, key := range s.sortedRouteKeys() {
+		if key.tableID == tableID {
+			replies = append(replies, &ip.IPRouteV2Details{Route: replyRoute(s.routes[key])})
+		}
+	}
+
+	return replies
+}
+
+// lookupRoute finds the exact or the longest prefix match route
+func (s *Stream) lookupRoute(req *ip.IPRouteLookupV2) api.Message {
+	prefix, ok := routePrefix(req.Prefix)
+	if !ok {
+		return &ip.IPRouteLookupV2Reply{Retval: int32(api.INVALID_VALUE)}
+	}
+
+	var (
+		found   ip.IPRouteV2
+		isFound bool
+		bestLen = -1
+	)
+
+	for key, route := range s.routes {
+		if key.tableID != req.TableID || key.prefix.Bits() <= bestLen || !key.prefix.Contains(prefix.Addr()) {
+			continue
+		}
+
+		if req.Exact != 0 && key.prefix != prefix {
+			continue
+		}
+
+		found, isFound, bestLen = route, true, key.prefix.Bits()
+	}
+
+	if !isFound {
+		return &ip.IPRouteLookupV2Reply{Retval: int32(api.NO_SUCH_ENTRY)}
+	}
+
+	return &ip.IPRouteLookupV2Reply{Route: replyRoute(found)}
+}
+
+func (s *Stream) sortedRouteKeys() []routeKey {
+	keys := make([]routeKey, 0, len(s.routes))
+
+	for key := range s.routes {
+		keys = append(keys, key)
+	}
+
+	slices.SortFunc(keys, func(a, b routeKey) int {
+		if a.tableID != b.tableID {
+			return int(a.tableID) - int(b.tableID)
+		}
+
+		if c := a.prefix.Addr().Compare(b.prefix.Addr()); c != 0 {
+			return c
+		}
+
+		return a.prefix.Bits() - b.prefix.Bits()
+	})
+
+	return keys
+}
+
+// replyRoute returns the route as vpp replies it: udp encap ids of paths are shifted
+func replyRoute(route ip.IPRouteV2) ip.IPRouteV2 {
+	route.Paths = slices.Clone(route.Paths)
+
+	for i := range route.Paths {
+		if route.Paths[i].Type == fib_types.FIB_API_PATH_TYPE_UDP_ENCAP {
+			route.Paths[i].Nh.ObjID <<= udpEncapObjShift
+		}
+	}
+
+	return route
+}
+
+func samePath(a, b fib_types.FibPath) bool {
+	return a.Type == b.Type && a.SwIfIndex == b.SwIfIndex && a.Nh == b.Nh && a.LabelStack[0].Label == b.LabelStack[0].Label
+}
+
+// genericReply returns empty reply of the request (retval 0) and no replies to dumps
+func genericReply(msg api.Message) ([]api.Message, error) {
+	name := msg.GetMessageName()
+
+	if strings.HasSuffix(name, "_dump") {
+		return nil, nil
+	}
+
+	replyType, ok := messageTypes()[name+"_reply"]
+	if !ok {
+		return nil, fmt.Errorf("unknown reply of vpp api message %s", name)
+	}
+
+	return []api.Message{reflect.New(replyType).Interface().(api.Message)}, nil
+}
+
+var messageTypes = sync.OnceValue(func() map[string]reflect.Type {
+	types := make(map[string]reflect.Type)
+
+	for _, messages := range api.GetRegisteredMessages() {
+		for _, msg := range messages {
+			types[msg.GetMessageName()] = reflect.TypeOf(msg).Elem()
+		}
+	}
+
+	return types
+})
@@ -0,0 +1,99 @@
+package dryrun_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp/dryrun"
+)
+
+func TestStreamFIPRoutes(t *testing.T) {
+	stream := dryrun.NewStream(context.Background())
+
+	version, err := vpp.GetVPPVersion(stream)
+	require.NoError(t, err)
+	require.Equal(t, dryrun.Version, version)
+
+	vrf := model.VPPVRFTable{ID: 1, Name: "vrf1"}
+	require.NoError(t, vpp.AddDelVRF(stream, true, vrf))
+
+	// two vrouters serve the same floating ip
+
+	tunnels := []*model.VPPUDPTunnel{
+		{SrcIP: "192.0.0.1", DstIP: "10.0.0.1", SrcPort: 50001, DstPort: 6635},
+		{SrcIP: "192.0.0.1", DstIP: "10.0.0.2", SrcPort: 50002, DstPort: 6635},
+	}
+
+	for _, tunnel := range tunnels {
+		require.NoError(t, vpp.AddUDPTunnel(stream, tunnel))
+	}
+
+	require.Equal(t, uint32(0), tunnels[0].TunnelID)
+	require.Equal(t, uint32(1), tunnels[1].TunnelID)
+
+	count, err := vpp.CountUDPTunnels(stream)
+	require.NoError(t, err)
+	require.Equal(t, float64(2), count)
+
+	route := model.NewVPPIPRoute(1, 1, model.UndefinedSubIf, "172.16.1.10/32",
+		[]string{"10.0.0.1", "10.0.0.2"}, []uint32{0, 1}, []uint32{25, 26})
+	require.NoError(t, vpp.AddDelFIPRoute(stream, true, &route))
+
+	routes, err := vpp.DumpFIPRoutes(stream)
+	require.NoError(t, err)
+	require.Len(t, routes, 1)
+	require.Equal(t, "172.16.1.10/32", routes[0].Prefix)
+	require.Equal(t, []uint32{0, 1}, routes[0].TunnelIDs)
+	require.Equal(t, []uint32{25, 26}, routes[0].FIPMPLSLabels)
+
+	lookup := model.VPPIPRoute{VRFID: 1, Prefix: "172.16.1.10/32"}
+
+	isFound, err := vpp.LookupFIPRoute(stream, &lookup)
+	require.NoError(t, err)
+	require.True(t, isFound)
+	require.Equal(t, []uint32{0, 1}, lookup.TunnelIDs)
+
+	// one vrouter withdraws the floating ip
+
+	withdrawn := model.NewVPPIPRoute(1, 1, model.UndefinedSubIf, "172.16.1.10/32",
+		[]string{"10.0.0.2"}, []uint32{1}, []uint32{26})
+	require.NoError(t, vpp.ReplaceFIPRoutePaths(stream, &withdrawn))
+	require.NoError(t, vpp.DelUDPTunnel(stream, 0))
+
+	fib := stream.FIB()
+	require.Equal(t, []dryrun.Tunnel{{ID: 1, SrcIP: "192.0.0.1", DstIP: "10.0.0.2", SrcPort: 50002, DstPort: 6635}}, fib.Tunnels)
+	require.Len(t, fib.Routes, 1)
+	require.Equal(t, "172.16.1.10/32", fib.Routes[0].Prefix)
+	require.Len(t, fib.Routes[0].Paths, 1)
+	require.Equal(t, "udp_encap", fib.Routes[0].Paths[0].Type)
+	require.Equal(t, "10.0.0.2", fib.Routes[0].Paths[0].NextHop)
+	require.Equal(t, uint32(1), *fib.Routes[0].Paths[0].TunnelID)
+
+	require.NoError(t, vpp.AddDelFIPRoute(stream, false, &withdrawn))
+	require.Empty(t, stream.FIB().Routes)
+
+	err = vpp.AddDelFIPRoute(stream, false, &withdrawn)
+	require.Error(t, err)
+
+	// dumps and lookups are recorded, control pings are not
+
+	var summaries []string
+
+	for _, record := range stream.Records() {
+		if record.Message == "ip_route_add_del_v2" || record.Message == "udp_encap_del" {
+			summaries = append(summaries, record.Summary)
+		}
+	}
+
+	require.Equal(t, []string{
+		"ip route add table 1 172.16.1.10/32 via 10.0.0.1 udp-encap 0 out-labels 25, via 10.0.0.2 udp-encap 1 out-labels 26",
+		"ip route add table 1 172.16.1.10/32 via 10.0.0.2 udp-encap 1 out-labels 26",
+		"udp encap del index 0",
+		"ip route del table 1 172.16.1.10/32 via 10.0.0.2 udp-encap 1 out-labels 26",
+		"ip route del table 1 172.16.1.10/32 via 10.0.0.2 udp-encap 1 out-labels 26",
+	}, summaries)
+}
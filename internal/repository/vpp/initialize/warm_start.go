@@ -0,0 +1,78 @@
+package initialize
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"go.fd.io/govpp/api"
+
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp"
+	"git.crptech.ru/cloud/cloudgw/pkg/logger"
+)
+
+const maxFillerTunnels = 1024
+
+// RestoreUDPTunnels re-creates udp tunnels of the previous run (cleared from vpp on start) with the same source ports
+// and adds them to storage without served floating ips, so floating ip routes received from tungsten fabric reuse them.
+// The tunnels are created in the order of previous ids; when vpp assigns a lower id (a gap in previous ids) a filler
+// tunnel takes it and is deleted after all tunnels are created. Tunnels from another local address are skipped.
+// Returns the number of restored tunnels and the number of them with a changed id.
+func RestoreUDPTunnels(
+	stream api.Stream,
+	tunnels []model.VPPUDPTunnel,
+	tunLocalIP string,
+	udpTunnelStorage *imdb.VPPUDPTunnelStorage,
+) (int, int, error) {
+	tunnels = slices.Clone(tunnels)
+	slices.SortFunc(tunnels, func(a, b model.VPPUDPTunnel) int { return cmp.Compare(a.TunnelID, b.TunnelID) })
+
+	var (
+		restored, changed int
+		fillers           []uint32
+	)
+
+	defer func() {
+		for _, id := range fillers {
+			if err := vpp.DelUDPTunnel(stream, id); err != nil {
+				logger.Error("failed to delete filler udp tunnel from vpp", "tunnel id", id, "error", err)
+			}
+		}
+	}()
+
+	for _, prev := range tunnels {
+		if prev.SrcIP != tunLocalIP || udpTunnelStorage.IsUDPTunnelExist(prev.DstIP) {
+			continue
+		}
+
+		tunnel := model.NewVPPUDPTunnel(model.UndefinedTunnelID, prev.SrcIP, prev.DstIP, prev.SrcPort)
+
+		for {
+			if err := vpp.AddUDPTunnel(stream, &tunnel); err != nil {
+				return restored, changed, fmt.Errorf("failed to create udp tunnel to %s in vpp: %w", prev.DstIP, err)
+			}
+
+			if tunnel.TunnelID >= prev.TunnelID || len(fillers) >= maxFillerTunnels {
+				break
+			}
+
+			fillers = append(fillers, tunnel.TunnelID)
+		}
+
+		if tunnel.TunnelID != prev.TunnelID {
+			changed++
+
+			logger.Debug("udp tunnel id changed since previous run", "vrouter", prev.DstIP, "previous id", prev.TunnelID, "id", tunnel.TunnelID)
+		}
+
+		if err := udpTunnelStorage.AddUDPTunnel(&tunnel); err != nil {
+			return restored, changed, fmt.Errorf("failed to add udp tunnel to %s to memory storage: %w", prev.DstIP, err)
+		}
+
+		restored++
+	}
+
+	return restored, changed, nil
+}
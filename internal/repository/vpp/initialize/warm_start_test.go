@@ -0,0 +1,43 @@
+package initialize_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp/dryrun"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp/initialize"
+)
+
+func TestRestoreUDPTunnels(t *testing.T) {
+	stream := dryrun.NewStream(context.Background())
+	storage := imdb.NewVPPUDPTunnelStorage()
+
+	// ids 1 and 2 are the gap in previous ids, the tunnel from another local address is skipped
+
+	tunnels := []model.VPPUDPTunnel{
+		{TunnelID: 3, SrcIP: "192.0.0.1", DstIP: "10.0.0.2", SrcPort: 50002, FIPServed: 5},
+		{TunnelID: 0, SrcIP: "192.0.0.1", DstIP: "10.0.0.1", SrcPort: 50001, FIPServed: 1},
+		{TunnelID: 4, SrcIP: "192.0.0.2", DstIP: "10.0.0.3", SrcPort: 50003},
+	}
+
+	restored, changed, err := initialize.RestoreUDPTunnels(stream, tunnels, "192.0.0.1", storage)
+	require.NoError(t, err)
+	require.Equal(t, 2, restored)
+	require.Equal(t, 0, changed)
+
+	fib := stream.FIB()
+	require.Len(t, fib.Tunnels, 2)
+	require.Equal(t, uint32(0), fib.Tunnels[0].ID)
+	require.Equal(t, uint32(3), fib.Tunnels[1].ID)
+	require.Equal(t, uint16(50002), fib.Tunnels[1].SrcPort)
+
+	tunnel := storage.GetUDPTunnel("10.0.0.2")
+	require.Equal(t, uint32(3), tunnel.TunnelID)
+	require.Equal(t, uint16(50002), tunnel.SrcPort)
+	require.Equal(t, uint32(0), tunnel.FIPServed) // counted again by floating ip routes
+	require.Nil(t, storage.GetUDPTunnel("10.0.0.3"))
+}
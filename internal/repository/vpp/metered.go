@@ -0,0 +1,84 @@
+package vpp
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"go.fd.io/govpp/api"
+	"go.fd.io/govpp/binapi/memclnt"
+)
+
+// callObserver is passed the latency and the error of every vpp binary api call made by the repository functions
+var callObserver atomic.Pointer[func(msg string, latency time.Duration, err error)]
+
+// SetCallObserver sets the function the latency of every vpp binary api call is passed to. The latency of a call is
+// the time from the request sent to its reply received (to the control ping reply for dumps) or to the failure of
+// the call (the request not sent, the reply not received or unexpected), the error is passed then. Calls are timed by
+// the repository functions, so the calls of goroutines sharing a stream are timed separately.
+func SetCallObserver(observe func(msg string, latency time.Duration, err error)) {
+	callObserver.Store(&observe)
+}
+
+// vppCall is a vpp binary api call being timed
+type vppCall struct {
+	msg       string
+	startedAt time.Time
+}
+
+func startCall(req api.Message) vppCall {
+	return vppCall{msg: req.GetMessageName(), startedAt: time.Now()}
+}
+
+func (c vppCall) done(err error) {
+	if observe := callObserver.Load(); observe != nil && *observe != nil {
+		(*observe)(c.msg, time.Since(c.startedAt), err)
+	}
+}
+
+// requestReply sends the request and receives its reply, the call is timed
+func requestReply(stream api.Stream, req api.Message) (msg api.Message, err error) {
+	call := startCall(req)
+
+	defer func() { call.done(err) }()
+
+	if err := stream.SendMsg(req); err != nil {
+		return nil, err
+	}
+
+	return stream.RecvMsg()
+}
+
+// dump sends the dump request followed by the control ping and passes the details replies to handle until the control
+// ping reply, the call is timed
+func dump[D api.Message](stream api.Stream, req api.Message, handle func(details D)) (err error) {
+	call := startCall(req)
+
+	defer func() { call.done(err) }()
+
+	if err := stream.SendMsg(req); err != nil {
+		return err
+	}
+
+	if err := stream.SendMsg(&memclnt.ControlPing{}); err != nil {
+		return err
+	}
+
+	for {
+		msg, err := stream.RecvMsg()
+		if err != nil {
+			return err
+		}
+
+		switch reply := msg.(type) {
+		case D:
+			handle(reply)
+
+		case *memclnt.ControlPingReply:
+			return nil
+
+		default:
+			return fmt.Errorf("unexpected message type: %T", msg)
+		}
+	}
+}
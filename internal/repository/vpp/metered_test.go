@@ -0,0 +1,82 @@
+package vpp_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.fd.io/govpp/api"
+	"go.fd.io/govpp/binapi/udp"
+
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp/dryrun"
+)
+
+// replyStream replies to every request with the reply or the error
+type replyStream struct {
+	api.Stream
+	reply api.Message
+	err   error
+}
+
+func (s replyStream) RecvMsg() (api.Message, error) {
+	return s.reply, s.err
+}
+
+func TestCallObserver(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls = make(map[string]int)
+	)
+
+	vpp.SetCallObserver(func(msg string, latency time.Duration, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		require.GreaterOrEqual(t, latency, time.Duration(0))
+
+		if err != nil {
+			msg += " failed"
+		}
+
+		calls[msg]++
+	})
+
+	defer vpp.SetCallObserver(nil)
+
+	stream := dryrun.NewStream(context.Background())
+
+	tunnel := model.NewVPPUDPTunnel(model.UndefinedTunnelID, "192.0.0.1", "10.1.1.1", 50000)
+
+	require.NoError(t, vpp.AddUDPTunnel(stream, &tunnel))
+
+	// calls of goroutines sharing the stream are timed separately, the dump with control ping is one call
+
+	var wg sync.WaitGroup
+
+	for range 10 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, _ = vpp.CountUDPTunnels(stream)
+		}()
+	}
+
+	wg.Wait()
+
+	// failed calls are timed too: the reply not received and the unexpected reply
+
+	_, err := vpp.CountUDPTunnels(replyStream{Stream: stream, err: errors.New("no reply received within the timeout period")})
+	require.Error(t, err)
+
+	_, err = vpp.DumpUDPTunnels(replyStream{Stream: stream, reply: &udp.UDPEncapAddReply{}})
+	require.ErrorContains(t, err, "unexpected message type")
+
+	require.Equal(t, map[string]int{"udp_encap_add": 1, "udp_encap_dump": 10, "udp_encap_dump failed": 2}, calls)
+}
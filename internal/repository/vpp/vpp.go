@@ -2,8 +2,10 @@ package vpp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
+	"os"
 	"strconv"
 	"time"
 
@@ -15,8 +17,8 @@ import (
 	"go.fd.io/govpp/binapi/interface_types"
 	"go.fd.io/govpp/binapi/ip"
 	"go.fd.io/govpp/binapi/ip_types"
-	"go.fd.io/govpp/binapi/memclnt"
 	"go.fd.io/govpp/binapi/mpls"
+	"go.fd.io/govpp/binapi/ping"
 	"go.fd.io/govpp/binapi/udp"
 	"go.fd.io/govpp/binapi/vpe"
 	"go.fd.io/govpp/core"
@@ -26,37 +28,40 @@ import (
 	"git.crptech.ru/cloud/cloudgw/pkg/netutils"
 )
 
-// ConnectToVPPAPIAsync connects to VPP asynchronously
-func ConnectToVPPAPIAsync(ctx context.Context, sockAddr string) (api.Stream, func(), chan core.ConnectionEvent, error) {
+// ErrPingTimeout is returned by PingAddress when the ping finished event is not received in time
+var ErrPingTimeout = errors.New("ping finished event not received in time")
+
+// ConnectToVPPAPIAsync connects to VPP asynchronously. The connection is returned to subscribe to VPP events.
+func ConnectToVPPAPIAsync(ctx context.Context, sockAddr string) (api.Stream, api.Connection, func(), chan core.ConnectionEvent, error) {
 	if sockAddr == "" {
 		sockAddr = socketclient.DefaultSocketName
 	}
 
 	conn, connEvent, err := govpp.AsyncConnect(sockAddr, core.DefaultMaxReconnectAttempts, core.DefaultReconnectInterval)
 	if err != nil {
-		return nil, nil, connEvent, fmt.Errorf("failed to initialize connection to vpp: %w", err)
+		return nil, nil, nil, connEvent, fmt.Errorf("failed to initialize connection to vpp: %w", err)
 	}
 
 	// wait for connected event
 
 	event := <-connEvent
 	if event.State != core.Connected {
-		return nil, nil, connEvent, fmt.Errorf("failed to connect to vpp: %w", err)
+		return nil, nil, nil, connEvent, fmt.Errorf("failed to connect to vpp: %w", err)
 	}
 
 	// check compatibility of used messages
 
 	ch, err := conn.NewAPIChannel()
 	if err != nil {
-		return nil, nil, connEvent, fmt.Errorf("failed to create new api channel: %w", err)
+		return nil, nil, nil, connEvent, fmt.Errorf("failed to create new api channel: %w", err)
 	}
 
 	if err := ch.CheckCompatiblity(vpe.AllMessages()...); err != nil {
-		return nil, nil, connEvent, fmt.Errorf("vpp channel compatibility check failed: %w", err)
+		return nil, nil, nil, connEvent, fmt.Errorf("vpp channel compatibility check failed: %w", err)
 	}
 
 	if err := ch.CheckCompatiblity(interfaces.AllMessages()...); err != nil {
-		return nil, nil, connEvent, fmt.Errorf("vpp channel compatibility check failed: %w", err)
+		return nil, nil, nil, connEvent, fmt.Errorf("vpp channel compatibility check failed: %w", err)
 	}
 
 	stream, err := conn.NewStream(
@@ -65,21 +70,32 @@ func ConnectToVPPAPIAsync(ctx context.Context, sockAddr string) (api.Stream, fun
 		core.WithReplySize(50),
 		core.WithReplyTimeout(2*time.Second))
 	if err != nil {
-		return nil, nil, connEvent, fmt.Errorf("failed to create new stream: %w", err)
+		return nil, nil, nil, connEvent, fmt.Errorf("failed to create new stream: %w", err)
+	}
+
+	return stream, conn, conn.Disconnect, connEvent, nil
+}
+
+// ConnectToVPPAPI connects to VPP synchronously as a separate api client. VPP sends some events (e.g. ping finished
+// events) to the client that requested them only, so the client of the connection identifies the event.
+func ConnectToVPPAPI(sockAddr string) (api.Connection, func(), error) {
+	if sockAddr == "" {
+		sockAddr = socketclient.DefaultSocketName
+	}
+
+	conn, err := govpp.Connect(sockAddr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to vpp: %w", err)
 	}
 
-	return stream, conn.Disconnect, connEvent, nil
+	return conn, conn.Disconnect, nil
 }
 
 // GetVPPVersion gets and returns VPP version
 func GetVPPVersion(stream api.Stream) (string, error) {
 	req := &vpe.ShowVersion{}
 
-	if err := stream.SendMsg(req); err != nil {
-		return "", err
-	}
-
-	msg, err := stream.RecvMsg()
+	msg, err := requestReply(stream, req)
 	if err != nil {
 		return "", err
 	}
@@ -103,11 +119,7 @@ func AddDelMPLSTable(stream api.Stream, isAdd bool) error {
 		},
 	}
 
-	if err := stream.SendMsg(req); err != nil {
-		return err
-	}
-
-	msg, err := stream.RecvMsg()
+	msg, err := requestReply(stream, req)
 	if err != nil {
 		return err
 	}
@@ -125,33 +137,12 @@ func AddDelMPLSTable(stream api.Stream, isAdd bool) error {
 func GetInterfaceIDs(stream api.Stream) ([]interface_types.InterfaceIndex, error) {
 	req := &interfaces.SwInterfaceDump{}
 
-	if err := stream.SendMsg(req); err != nil {
-		return nil, err
-	}
-
-	if err := stream.SendMsg(&memclnt.ControlPing{}); err != nil {
-		return nil, err
-	}
-
 	var interfaceIDs []interface_types.InterfaceIndex
 
-Loop:
-	for {
-		msg, err := stream.RecvMsg()
-		if err != nil {
-			return nil, err
-		}
-
-		switch reply := msg.(type) {
-		case *interfaces.SwInterfaceDetails:
-			interfaceIDs = append(interfaceIDs, reply.SwIfIndex)
-
-		case *memclnt.ControlPingReply:
-			break Loop
-
-		default:
-			return nil, fmt.Errorf("unexpected message type: %T", msg)
-		}
+	if err := dump(stream, req, func(reply *interfaces.SwInterfaceDetails) {
+		interfaceIDs = append(interfaceIDs, reply.SwIfIndex)
+	}); err != nil {
+		return nil, err
 	}
 
 	return interfaceIDs, nil
@@ -177,11 +168,7 @@ func SetupMainInterface(stream api.Stream, vppVRFTable model.VPPVRFTable) error
 			Prefix:    interfacePrefix,
 		}
 
-		if err := stream.SendMsg(req); err != nil {
-			return err
-		}
-
-		msg, err := stream.RecvMsg()
+		msg, err := requestReply(stream, req)
 		if err != nil {
 			return err
 		}
@@ -203,11 +190,7 @@ func SetupMainInterface(stream api.Stream, vppVRFTable model.VPPVRFTable) error
 			Flags:     interface_types.IF_STATUS_API_FLAG_ADMIN_UP,
 		}
 
-		if err := stream.SendMsg(req); err != nil {
-			return err
-		}
-
-		msg, err := stream.RecvMsg()
+		msg, err := requestReply(stream, req)
 		if err != nil {
 			return err
 		}
@@ -229,11 +212,7 @@ func SetupMainInterface(stream api.Stream, vppVRFTable model.VPPVRFTable) error
 			Enable:    true,
 		}
 
-		if err := stream.SendMsg(req); err != nil {
-			return err
-		}
-
-		msg, err := stream.RecvMsg()
+		msg, err := requestReply(stream, req)
 		if err != nil {
 			return err
 		}
@@ -264,11 +243,7 @@ func ResetMainInterface(stream api.Stream, mainInterfaceID uint32) error {
 			DelAll:    true,
 		}
 
-		if err := stream.SendMsg(req); err != nil {
-			return err
-		}
-
-		msg, err := stream.RecvMsg()
+		msg, err := requestReply(stream, req)
 		if err != nil {
 			return err
 		}
@@ -290,11 +265,7 @@ func ResetMainInterface(stream api.Stream, mainInterfaceID uint32) error {
 			Flags:     math.MaxUint32,
 		}
 
-		if err := stream.SendMsg(req); err != nil {
-			return err
-		}
-
-		msg, err := stream.RecvMsg()
+		msg, err := requestReply(stream, req)
 		if err != nil {
 			return err
 		}
@@ -322,11 +293,7 @@ func AddDelVRF(stream api.Stream, isAdd bool, vppVRFTable model.VPPVRFTable) err
 		},
 	}
 
-	if err := stream.SendMsg(req); err != nil {
-		return err
-	}
-
-	msg, err := stream.RecvMsg()
+	msg, err := requestReply(stream, req)
 	if err != nil {
 		return err
 	}
@@ -354,11 +321,7 @@ func AddSubInterface(stream api.Stream, vppVRFTable *model.VPPVRFTable) (interfa
 			VlanID:    vppVRFTable.VLAN,
 		}
 
-		if err := stream.SendMsg(req); err != nil {
-			return model.UndefinedSubIf, err
-		}
-
-		msg, err := stream.RecvMsg()
+		msg, err := requestReply(stream, req)
 		if err != nil {
 			return model.UndefinedSubIf, err
 		}
@@ -383,11 +346,7 @@ func AddSubInterface(stream api.Stream, vppVRFTable *model.VPPVRFTable) (interfa
 			VrfID:     vppVRFTable.ID,
 		}
 
-		if err := stream.SendMsg(req); err != nil {
-			return model.UndefinedSubIf, err
-		}
-
-		msg, err := stream.RecvMsg()
+		msg, err := requestReply(stream, req)
 		if err != nil {
 			return model.UndefinedSubIf, err
 		}
@@ -414,11 +373,7 @@ func AddSubInterface(stream api.Stream, vppVRFTable *model.VPPVRFTable) (interfa
 			Prefix:    interfaceAddr,
 		}
 
-		if err := stream.SendMsg(req); err != nil {
-			return model.UndefinedSubIf, err
-		}
-
-		msg, err := stream.RecvMsg()
+		msg, err := requestReply(stream, req)
 		if err != nil {
 			return model.UndefinedSubIf, err
 		}
@@ -440,11 +395,7 @@ func AddSubInterface(stream api.Stream, vppVRFTable *model.VPPVRFTable) (interfa
 			Flags:     interface_types.IF_STATUS_API_FLAG_ADMIN_UP,
 		}
 
-		if err := stream.SendMsg(req); err != nil {
-			return model.UndefinedSubIf, err
-		}
-
-		msg, err := stream.RecvMsg()
+		msg, err := requestReply(stream, req)
 		if err != nil {
 			return model.UndefinedSubIf, err
 		}
@@ -469,33 +420,12 @@ func DelSubInterfaces(stream api.Stream, mainInterfaceID uint32) (uint32, error)
 
 	req := &interfaces.SwInterfaceDump{}
 
-	if err := stream.SendMsg(req); err != nil {
-		return 0, err
-	}
-
-	if err := stream.SendMsg(&memclnt.ControlPing{}); err != nil {
-		return 0, err
-	}
-
-Loop:
-	for {
-		msg, err := stream.RecvMsg()
-		if err != nil {
-			return 0, err
-		}
-
-		switch reply := msg.(type) {
-		case *interfaces.SwInterfaceDetails:
-			if reply.SwIfIndex > interface_types.InterfaceIndex(mainInterfaceID) {
-				subInterfaceIDs = append(subInterfaceIDs, reply.SwIfIndex)
-			}
-
-		case *memclnt.ControlPingReply:
-			break Loop
-
-		default:
-			return removedSubInterfaces, fmt.Errorf("unexpected message type: %T", msg)
+	if err := dump(stream, req, func(reply *interfaces.SwInterfaceDetails) {
+		if reply.SwIfIndex > interface_types.InterfaceIndex(mainInterfaceID) {
+			subInterfaceIDs = append(subInterfaceIDs, reply.SwIfIndex)
 		}
+	}); err != nil {
+		return 0, err
 	}
 
 	if len(subInterfaceIDs) == 0 {
@@ -509,11 +439,7 @@ Loop:
 			SwIfIndex: subInterfaceID,
 		}
 
-		if err := stream.SendMsg(req); err != nil {
-			return removedSubInterfaces, err
-		}
-
-		msg, err := stream.RecvMsg()
+		msg, err := requestReply(stream, req)
 		if err != nil {
 			return removedSubInterfaces, err
 		}
@@ -532,73 +458,131 @@ Loop:
 	return removedSubInterfaces, nil
 }
 
-// CountUDPTunnels counts all configured UDP tunnels in a VPP (used for metric expose)
-func CountUDPTunnels(stream api.Stream) (float64, error) {
-	req := &udp.UDPEncapDump{}
+// WantInterfaceEvents enables/disables sending of VPP interface events (link/admin state changes) to the process
+func WantInterfaceEvents(stream api.Stream, isEnable bool) error {
+	req := &interfaces.WantInterfaceEvents{
+		PID: uint32(os.Getpid()),
+	}
 
-	udpTunnelRecords := float64(0)
+	if isEnable {
+		req.EnableDisable = 1
+	}
 
-	if err := stream.SendMsg(req); err != nil {
-		return udpTunnelRecords, err
+	msg, err := requestReply(stream, req)
+	if err != nil {
+		return err
 	}
 
-	if err := stream.SendMsg(&memclnt.ControlPing{}); err != nil {
-		return udpTunnelRecords, err
+	reply, ok := msg.(*interfaces.WantInterfaceEventsReply)
+	if !ok {
+		return fmt.Errorf("unexpected message type: %T", msg)
 	}
 
-Loop:
-	for {
-		msg, err := stream.RecvMsg()
-		if err != nil {
-			return udpTunnelRecords, err
-		}
+	if api.RetvalToVPPApiError(reply.Retval) != nil {
+		return api.RetvalToVPPApiError(reply.Retval)
+	}
 
-		switch msg.(type) {
-		case *udp.UDPEncapDetails:
-			udpTunnelRecords++
+	return nil
+}
+
+// DumpInterfaceLinkStates gets link state of all VPP interfaces (true if the interface is admin up and link up)
+func DumpInterfaceLinkStates(stream api.Stream) (map[interface_types.InterfaceIndex]bool, error) {
+	req := &interfaces.SwInterfaceDump{
+		SwIfIndex: math.MaxUint32, // all interfaces
+	}
 
-		case *memclnt.ControlPingReply:
-			break Loop
+	linkStates := make(map[interface_types.InterfaceIndex]bool)
 
-		default:
-			return udpTunnelRecords, fmt.Errorf("unexpected message type: %T", msg)
-		}
+	if err := dump(stream, req, func(reply *interfaces.SwInterfaceDetails) {
+		linkStates[reply.SwIfIndex] = IsInterfaceUp(reply.Flags)
+	}); err != nil {
+		return nil, err
 	}
 
-	return udpTunnelRecords, nil
+	return linkStates, nil
 }
 
-// CheckUDPTunnelTableEmpty checks if UDP tunnel table is empty
-func CheckUDPTunnelTableEmpty(stream api.Stream) (bool, error) {
-	req := &udp.UDPEncapDump{}
+// IsInterfaceUp checks if VPP interface flags have both admin up and link up states
+func IsInterfaceUp(flags interface_types.IfStatusFlags) bool {
+	return flags&interface_types.IF_STATUS_API_FLAG_ADMIN_UP != 0 && flags&interface_types.IF_STATUS_API_FLAG_LINK_UP != 0
+}
 
-	udpTunnelRecords := 0
+// PingAddress sends ICMP echo requests from a VPP to the address and waits for the ping finished event (ping plugin is
+// required). The watcher must be subscribed to ping.PingFinishedEvent on the connection of the stream. The event
+// carries no address, so only one ping must be in progress per connection, and the connection must be replaced after
+// ErrPingTimeout as the late event of the timed out ping would be taken as the result of the next one.
+func PingAddress(stream api.Stream, watcher api.Watcher, address string, repeat uint32, interval time.Duration) (uint32, uint32, error) {
+	addr, err := ip_types.ParseAddress(address)
+	if err != nil {
+		return 0, 0, err
+	}
 
-	if err := stream.SendMsg(req); err != nil {
-		return false, err
+	req := &ping.WantPingFinishedEvents{
+		Address:  addr,
+		Repeat:   repeat,
+		Interval: interval.Seconds(),
 	}
 
-	if err := stream.SendMsg(&memclnt.ControlPing{}); err != nil {
-		return false, err
+	msg, err := requestReply(stream, req)
+	if err != nil {
+		return 0, 0, err
 	}
 
-Loop:
-	for {
-		msg, err := stream.RecvMsg()
-		if err != nil {
-			return false, err
-		}
+	reply, ok := msg.(*ping.WantPingFinishedEventsReply)
+	if !ok {
+		return 0, 0, fmt.Errorf("unexpected message type: %T", msg)
+	}
 
-		switch msg.(type) {
-		case *udp.UDPEncapDetails:
-			udpTunnelRecords++
+	if api.RetvalToVPPApiError(reply.Retval) != nil {
+		return 0, 0, api.RetvalToVPPApiError(reply.Retval)
+	}
+
+	timeout := time.NewTimer(time.Duration(repeat)*interval + time.Second)
+
+	defer timeout.Stop()
 
-		case *memclnt.ControlPingReply:
-			break Loop
+	select {
+	case event, ok := <-watcher.Events():
+		if !ok {
+			return 0, 0, fmt.Errorf("ping finished events channel closed")
+		}
 
-		default:
-			return false, fmt.Errorf("unexpected message type: %T", msg)
+		finished, ok := event.(*ping.PingFinishedEvent)
+		if !ok {
+			return 0, 0, fmt.Errorf("unexpected message type: %T", event)
 		}
+
+		return finished.RequestCount, finished.ReplyCount, nil
+	case <-timeout.C:
+		return repeat, 0, fmt.Errorf("failed to ping %s: %w", address, ErrPingTimeout)
+	}
+}
+
+// CountUDPTunnels counts all configured UDP tunnels in a VPP (used for metric expose)
+func CountUDPTunnels(stream api.Stream) (float64, error) {
+	req := &udp.UDPEncapDump{}
+
+	udpTunnelRecords := float64(0)
+
+	if err := dump(stream, req, func(_ *udp.UDPEncapDetails) {
+		udpTunnelRecords++
+	}); err != nil {
+		return udpTunnelRecords, err
+	}
+
+	return udpTunnelRecords, nil
+}
+
+// CheckUDPTunnelTableEmpty checks if UDP tunnel table is empty
+func CheckUDPTunnelTableEmpty(stream api.Stream) (bool, error) {
+	req := &udp.UDPEncapDump{}
+
+	udpTunnelRecords := 0
+
+	if err := dump(stream, req, func(_ *udp.UDPEncapDetails) {
+		udpTunnelRecords++
+	}); err != nil {
+		return false, err
 	}
 
 	if udpTunnelRecords == 0 {
@@ -617,38 +601,17 @@ func DumpUDPTunnels(stream api.Stream) ([]model.VPPUDPTunnel, error) {
 
 	req := &udp.UDPEncapDump{}
 
-	if err := stream.SendMsg(req); err != nil {
-		return nil, err
-	}
-
-	if err := stream.SendMsg(&memclnt.ControlPing{}); err != nil {
-		return nil, err
-	}
-
-Loop:
-	for {
-		msg, err := stream.RecvMsg()
-		if err != nil {
-			return dumpedRecords, err
+	if err := dump(stream, req, func(replay *udp.UDPEncapDetails) {
+		dumpedRecord = model.VPPUDPTunnel{
+			TunnelID: replay.UDPEncap.ID,
+			SrcIP:    replay.UDPEncap.SrcIP.String(),
+			DstIP:    replay.UDPEncap.DstIP.String(),
+			SrcPort:  replay.UDPEncap.SrcPort,
 		}
 
-		switch replay := msg.(type) {
-		case *udp.UDPEncapDetails:
-			dumpedRecord = model.VPPUDPTunnel{
-				TunnelID: replay.UDPEncap.ID,
-				SrcIP:    replay.UDPEncap.SrcIP.String(),
-				DstIP:    replay.UDPEncap.DstIP.String(),
-				SrcPort:  replay.UDPEncap.SrcPort,
-			}
-
-			dumpedRecords = append(dumpedRecords, dumpedRecord)
-
-		case *memclnt.ControlPingReply:
-			break Loop
-
-		default:
-			return dumpedRecords, fmt.Errorf("unexpected message type: %T", msg)
-		}
+		dumpedRecords = append(dumpedRecords, dumpedRecord)
+	}); err != nil {
+		return dumpedRecords, err
 	}
 
 	return dumpedRecords, nil
@@ -676,11 +639,7 @@ func AddUDPTunnel(stream api.Stream, vppUDPTunnel *model.VPPUDPTunnel) error {
 		},
 	}
 
-	if err := stream.SendMsg(req); err != nil {
-		return err
-	}
-
-	msg, err := stream.RecvMsg()
+	msg, err := requestReply(stream, req)
 	if err != nil {
 		return err
 	}
@@ -702,11 +661,7 @@ func DelUDPTunnel(stream api.Stream, udpTunnelID uint32) error {
 		ID: udpTunnelID,
 	}
 
-	if err := stream.SendMsg(req); err != nil {
-		return err
-	}
-
-	msg, err := stream.RecvMsg()
+	msg, err := requestReply(stream, req)
 	if err != nil {
 		return err
 	}
@@ -731,11 +686,7 @@ func AddUDPDecap(stream api.Stream) error {
 		},
 	}
 
-	if err := stream.SendMsg(req); err != nil {
-		return err
-	}
-
-	msg, err := stream.RecvMsg()
+	msg, err := requestReply(stream, req)
 	if err != nil {
 		return err
 	}
@@ -760,33 +711,12 @@ func DumpFIPRoutes(stream api.Stream) ([]model.VPPIPRoute, error) {
 	{
 		req := &ip.IPTableDump{}
 
-		if err := stream.SendMsg(req); err != nil {
-			return nil, err
-		}
-
-		if err := stream.SendMsg(&memclnt.ControlPing{}); err != nil {
-			return nil, err
-		}
-
-	LoopTable:
-		for {
-			msg, err := stream.RecvMsg()
-			if err != nil {
-				return dumpedRouteRecords, err
-			}
-
-			switch reply := msg.(type) {
-			case *ip.IPTableDetails:
-				if !reply.Table.IsIP6 {
-					dumpedTableRecords = append(dumpedTableRecords, *reply)
-				}
-
-			case *memclnt.ControlPingReply:
-				break LoopTable
-
-			default:
-				return dumpedRouteRecords, fmt.Errorf("unexpected message type %T", msg)
+		if err := dump(stream, req, func(reply *ip.IPTableDetails) {
+			if !reply.Table.IsIP6 {
+				dumpedTableRecords = append(dumpedTableRecords, *reply)
 			}
+		}); err != nil {
+			return dumpedRouteRecords, err
 		}
 	}
 
@@ -797,62 +727,41 @@ func DumpFIPRoutes(stream api.Stream) ([]model.VPPIPRoute, error) {
 			Table: tableID.Table,
 		}
 
-		if err := stream.SendMsg(req); err != nil {
-			return nil, err
-		}
-
-		if err := stream.SendMsg(&memclnt.ControlPing{}); err != nil {
-			return nil, err
-		}
-
-	LoopRoute:
-		for {
-			msg, err := stream.RecvMsg()
-			if err != nil {
-				return dumpedRouteRecords, err
-			}
-
-			switch reply := msg.(type) {
-			case *ip.IPRouteV2Details:
-				tunnels := make([]uint32, 0)
-				nhs := make([]string, 0)
-				labels := make([]uint32, 0)
+		if err := dump(stream, req, func(reply *ip.IPRouteV2Details) {
+			tunnels := make([]uint32, 0)
+			nhs := make([]string, 0)
+			labels := make([]uint32, 0)
 
-				for _, p := range reply.Route.Paths {
-					tnl := p.Nh.ObjID / 16777216 // div 2^24
+			for _, p := range reply.Route.Paths {
+				tnl := p.Nh.ObjID / 16777216 // div 2^24
 
-					tunnels = append(tunnels, tnl)
+				tunnels = append(tunnels, tnl)
 
-					nh := p.Nh.Address.GetIP4().String()
+				nh := p.Nh.Address.GetIP4().String()
 
-					nhs = append(nhs, nh)
+				nhs = append(nhs, nh)
 
-					lbl := p.LabelStack[0].Label
+				lbl := p.LabelStack[0].Label
 
-					labels = append(labels, lbl)
-				}
-
-				// if first element is tunnel, then other are tunnels also
-				if reply.Route.Paths[0].Type.String() == "FIB_API_PATH_TYPE_UDP_ENCAP" { //nolint:goconst
-					dumpedRouteRecord := model.NewVPPIPRoute(
-						reply.Route.TableID,
-						interface_types.InterfaceIndex(reply.Route.Paths[0].SwIfIndex),
-						model.UndefinedSubIf,
-						reply.Route.Prefix.String(),
-						nhs,
-						tunnels,
-						labels,
-					)
-
-					dumpedRouteRecords = append(dumpedRouteRecords, dumpedRouteRecord)
-				}
-
-			case *memclnt.ControlPingReply:
-				break LoopRoute
+				labels = append(labels, lbl)
+			}
 
-			default:
-				return dumpedRouteRecords, fmt.Errorf("unexpected message type %T", msg)
+			// if first element is tunnel, then other are tunnels also
+			if reply.Route.Paths[0].Type.String() == "FIB_API_PATH_TYPE_UDP_ENCAP" { //nolint:goconst
+				dumpedRouteRecord := model.NewVPPIPRoute(
+					reply.Route.TableID,
+					interface_types.InterfaceIndex(reply.Route.Paths[0].SwIfIndex),
+					model.UndefinedSubIf,
+					reply.Route.Prefix.String(),
+					nhs,
+					tunnels,
+					labels,
+				)
+
+				dumpedRouteRecords = append(dumpedRouteRecords, dumpedRouteRecord)
 			}
+		}); err != nil {
+			return dumpedRouteRecords, err
 		}
 	}
 
@@ -874,11 +783,7 @@ func LookupFIPRoute(stream api.Stream, vppIPRoute *model.VPPIPRoute) (bool, erro
 		Prefix:  floatingIPPrefix,
 	}
 
-	if err = stream.SendMsg(req); err != nil {
-		return false, err
-	}
-
-	msg, err := stream.RecvMsg()
+	msg, err := requestReply(stream, req)
 	if err != nil {
 		return false, err
 	}
@@ -903,7 +808,7 @@ func LookupFIPRoute(stream api.Stream, vppIPRoute *model.VPPIPRoute) (bool, erro
 		labels = append(labels, label)
 	}
 
-	if reply.Route.Paths[0].Type.String() == "FIB_API_PATH_TYPE_UDP_ENCAP" { //nolint:goconst
+	if len(reply.Route.Paths) != 0 && reply.Route.Paths[0].Type.String() == "FIB_API_PATH_TYPE_UDP_ENCAP" { //nolint:goconst
 		isFound = true
 		vppIPRoute.TunnelIDs = tunnels
 		vppIPRoute.FIPMPLSLabels = labels
@@ -916,15 +821,92 @@ func LookupFIPRoute(stream api.Stream, vppIPRoute *model.VPPIPRoute) (bool, erro
 // (ip route add|del <fip>/32 via <vrouter> udp-encap <id> mpls-lookup-in-table 0 out-labels <mpls_label>) and
 // (ip route add|del <fip>/32 via <vrouter> <vpp_main_interface_name> udp-encap <id> <vpp_main_interface_name> out-labels <mpls_label>)
 func AddDelFIPRoute(stream api.Stream, isAdd bool, vppIPRoute *model.VPPIPRoute) error {
+	floatingPrefix, paths, err := fipRoutePaths(vppIPRoute)
+	if err != nil {
+		return err
+	}
+
+	isMultipath := false
+
+	if len(vppIPRoute.NextHops) > 1 {
+		isMultipath = true
+	}
+
+	req := &ip.IPRouteAddDelV2{
+		IsAdd:       isAdd,
+		IsMultipath: isMultipath,
+		Route: ip.IPRouteV2{
+			TableID: vppIPRoute.VRFID,
+			Prefix:  floatingPrefix,
+			NPaths:  uint8(len(vppIPRoute.NextHops)),
+			Paths:   paths,
+		},
+	}
+
+	msg, err := requestReply(stream, req)
+	if err != nil {
+		return err
+	}
+
+	reply, ok := msg.(*ip.IPRouteAddDelV2Reply)
+	if !ok {
+		return fmt.Errorf("unexpected message type: %T", msg)
+	}
+
+	if api.RetvalToVPPApiError(reply.Retval) != nil {
+		return api.RetvalToVPPApiError(reply.Retval)
+	}
+
+	return nil
+}
+
+// ReplaceFIPRoutePaths replaces all paths of existing floating ip route in a VPP with paths of vppIPRoute (used for
+// pruning/restoring paths through unreachable vRouters)
+func ReplaceFIPRoutePaths(stream api.Stream, vppIPRoute *model.VPPIPRoute) error {
+	floatingPrefix, paths, err := fipRoutePaths(vppIPRoute)
+	if err != nil {
+		return err
+	}
+
+	req := &ip.IPRouteAddDelV2{
+		IsAdd:       true,
+		IsMultipath: false, // not multipath update replaces all paths of the route
+		Route: ip.IPRouteV2{
+			TableID: vppIPRoute.VRFID,
+			Prefix:  floatingPrefix,
+			NPaths:  uint8(len(vppIPRoute.NextHops)),
+			Paths:   paths,
+		},
+	}
+
+	msg, err := requestReply(stream, req)
+	if err != nil {
+		return err
+	}
+
+	reply, ok := msg.(*ip.IPRouteAddDelV2Reply)
+	if !ok {
+		return fmt.Errorf("unexpected message type: %T", msg)
+	}
+
+	if api.RetvalToVPPApiError(reply.Retval) != nil {
+		return api.RetvalToVPPApiError(reply.Retval)
+	}
+
+	return nil
+}
+
+// fipRoutePaths creates prefix and udp encap paths (one per vRouter) of floating ip route
+func fipRoutePaths(vppIPRoute *model.VPPIPRoute) (ip_types.Prefix, []fib_types.FibPath, error) {
 	for _, p := range vppIPRoute.TunnelIDs {
 		if p == model.UndefinedTunnelID {
-			return fmt.Errorf("wrong udp tunnel id %d", p)
+			return ip_types.Prefix{}, nil, fmt.Errorf("wrong udp tunnel id %d", p)
 		}
 	}
 
 	floatingPrefix, err := ip_types.ParsePrefix(vppIPRoute.Prefix)
 	if err != nil {
-		return err
+		return ip_types.Prefix{}, nil, err
 	}
 
 	nextHops := make([]ip_types.IP4Address, len(vppIPRoute.NextHops))
@@ -932,7 +914,7 @@ func AddDelFIPRoute(stream api.Stream, isAdd bool, vppIPRoute *model.VPPIPRoute)
 	for i, nh := range vppIPRoute.NextHops {
 		nhIP, err := ip_types.ParseIP4Address(nh)
 		if err != nil {
-			return err
+			return ip_types.Prefix{}, nil, err
 		}
 
 		nextHops[i] = nhIP
@@ -961,39 +943,7 @@ func AddDelFIPRoute(stream api.Stream, isAdd bool, vppIPRoute *model.VPPIPRoute)
 		}
 	}
 
-	isMultipath := false
-
-	if len(vppIPRoute.NextHops) > 1 {
-		isMultipath = true
-	}
-
-	req := &ip.IPRouteAddDelV2{
-		IsAdd:       isAdd,
-		IsMultipath: isMultipath,
-		Route: ip.IPRouteV2{
-			TableID: vppIPRoute.VRFID,
-			Prefix:  floatingPrefix,
-			NPaths:  uint8(len(vppIPRoute.NextHops)),
-			Paths:   paths,
-		},
-	}
-
-	if err := stream.SendMsg(req); err != nil {
-		return err
-	}
-
-	msg, err := stream.RecvMsg()
-	if err != nil {
-		return err
-	}
-
-	reply := msg.(*ip.IPRouteAddDelV2Reply)
-
-	if api.RetvalToVPPApiError(reply.Retval) != nil {
-		return api.RetvalToVPPApiError(reply.Retval)
-	}
-
-	return nil
+	return floatingPrefix, paths, nil
 }
 
 // AddDelMPLSLocalLabelRoute adds/deletes MPLS local-label route to accept labeled traffic from vRouters and send it to physical network
@@ -1030,11 +980,7 @@ func AddDelMPLSLocalLabelRoute(stream api.Stream, isAdd bool, vppVRFTable model.
 		},
 	}
 
-	if err = stream.SendMsg(req); err != nil {
-		return err
-	}
-
-	msg, err := stream.RecvMsg()
+	msg, err := requestReply(stream, req)
 	if err != nil {
 		return err
 	}
@@ -1058,33 +1004,12 @@ func DumpMPLSLocalLabelRoute(stream api.Stream) ([]mpls.MplsRouteDetails, error)
 		},
 	}
 
-	if err := stream.SendMsg(req); err != nil {
-		return nil, err
-	}
-
-	if err := stream.SendMsg(&memclnt.ControlPing{}); err != nil {
+	if err := dump(stream, req, func(replay *mpls.MplsRouteDetails) {
+		dumpedMplsRoutes = append(dumpedMplsRoutes, *replay)
+	}); err != nil {
 		return nil, err
 	}
 
-Loop:
-	for {
-		msg, err := stream.RecvMsg()
-		if err != nil {
-			return nil, err
-		}
-
-		switch replay := msg.(type) {
-		case *mpls.MplsRouteDetails:
-			dumpedMplsRoutes = append(dumpedMplsRoutes, *replay)
-
-		case *memclnt.ControlPingReply:
-			break Loop
-
-		default:
-			return nil, fmt.Errorf("unexpected message type received: %T", msg)
-		}
-	}
-
 	return dumpedMplsRoutes, nil
 }
 
@@ -1094,33 +1019,12 @@ func GetRoutingTables(stream api.Stream) ([]ip.IPTableDetails, error) {
 
 	req := &ip.IPTableDump{}
 
-	if err := stream.SendMsg(req); err != nil {
-		return nil, err
-	}
-
-	if err := stream.SendMsg(&memclnt.ControlPing{}); err != nil {
-		return nil, err
-	}
-
-LoopTable:
-	for {
-		msg, err := stream.RecvMsg()
-		if err != nil {
-			return nil, err
-		}
-
-		switch replay := msg.(type) {
-		case *ip.IPTableDetails:
-			if !replay.Table.IsIP6 {
-				dumpedTables = append(dumpedTables, *replay)
-			}
-
-		case *memclnt.ControlPingReply:
-			break LoopTable
-
-		default:
-			return nil, fmt.Errorf("unexpected message type received: %T", msg)
+	if err := dump(stream, req, func(replay *ip.IPTableDetails) {
+		if !replay.Table.IsIP6 {
+			dumpedTables = append(dumpedTables, *replay)
 		}
+	}); err != nil {
+		return nil, err
 	}
 
 	return dumpedTables, nil
@@ -1139,33 +1043,12 @@ func DumpIPRoutes(stream api.Stream) ([]model.VPPIPRoute, error) {
 	{
 		req := &ip.IPTableDump{}
 
-		if err := stream.SendMsg(req); err != nil {
-			return nil, err
-		}
-
-		if err := stream.SendMsg(&memclnt.ControlPing{}); err != nil {
-			return nil, err
-		}
-
-	LoopTable:
-		for {
-			msg, err := stream.RecvMsg()
-			if err != nil {
-				return nil, err
-			}
-
-			switch replay := msg.(type) {
-			case *ip.IPTableDetails:
-				if !replay.Table.IsIP6 {
-					dumpedTables = append(dumpedTables, *replay)
-				}
-
-			case *memclnt.ControlPingReply:
-				break LoopTable
-
-			default:
-				return nil, fmt.Errorf("unexpected message type received: %T", msg)
+		if err := dump(stream, req, func(replay *ip.IPTableDetails) {
+			if !replay.Table.IsIP6 {
+				dumpedTables = append(dumpedTables, *replay)
 			}
+		}); err != nil {
+			return nil, err
 		}
 	}
 
@@ -1177,54 +1060,33 @@ func DumpIPRoutes(stream api.Stream) ([]model.VPPIPRoute, error) {
 				Table: table.Table,
 			}
 
-			if err := stream.SendMsg(req); err != nil {
-				return nil, err
-			}
-
-			if err := stream.SendMsg(&memclnt.ControlPing{}); err != nil {
-				return nil, err
-			}
-
-		LoopRoute:
-			for {
-				msg, err := stream.RecvMsg()
-				if err != nil {
-					return nil, err
-				}
-
-				switch replay := msg.(type) {
-				case *ip.IPRouteV2Details:
-					// Get only external IPv4 route by type and specific attributes:
-					if replay.Route.Paths[0].Type.String() == "FIB_API_PATH_TYPE_NORMAL" && //nolint:goconst
-						// exclude connected interface
-						replay.Route.Paths[0].Nh.Address.GetIP4().String() != "0.0.0.0" &&
-						// exclude local interface address
-						netutils.Addr(replay.Route.Prefix.String()) != replay.Route.Paths[0].Nh.Address.GetIP4().String() &&
-						// exclude all /32 for vRouter's UDP tunnels
-						netutils.MaskLen(replay.Route.Prefix.String()) != 32 {
-
-						dumpedRoute.VRFID = replay.Route.TableID
-						dumpedRoute.Prefix = replay.Route.Prefix.String()
-
-						if dumpedRoute.VRFID == 0 {
-							dumpedRoute.MainInterfaceID = interface_types.InterfaceIndex(replay.Route.Paths[0].SwIfIndex)
-						} else {
-							dumpedRoute.SubInterfaceID = interface_types.InterfaceIndex(replay.Route.Paths[0].SwIfIndex)
-						}
-
-						for _, nhIP := range replay.Route.Paths {
-							dumpedRoute.NextHops = append(dumpedRoute.NextHops, nhIP.Nh.Address.GetIP4().String())
-						}
-
-						dumpedRoutes = append(dumpedRoutes, dumpedRoute)
+			if err := dump(stream, req, func(replay *ip.IPRouteV2Details) {
+				// Get only external IPv4 route by type and specific attributes:
+				if replay.Route.Paths[0].Type.String() == "FIB_API_PATH_TYPE_NORMAL" && //nolint:goconst
+					// exclude connected interface
+					replay.Route.Paths[0].Nh.Address.GetIP4().String() != "0.0.0.0" &&
+					// exclude local interface address
+					netutils.Addr(replay.Route.Prefix.String()) != replay.Route.Paths[0].Nh.Address.GetIP4().String() &&
+					// exclude all /32 for vRouter's UDP tunnels
+					netutils.MaskLen(replay.Route.Prefix.String()) != 32 {
+
+					dumpedRoute.VRFID = replay.Route.TableID
+					dumpedRoute.Prefix = replay.Route.Prefix.String()
+
+					if dumpedRoute.VRFID == 0 {
+						dumpedRoute.MainInterfaceID = interface_types.InterfaceIndex(replay.Route.Paths[0].SwIfIndex)
+					} else {
+						dumpedRoute.SubInterfaceID = interface_types.InterfaceIndex(replay.Route.Paths[0].SwIfIndex)
 					}
 
-				case *memclnt.ControlPingReply:
-					break LoopRoute
+					for _, nhIP := range replay.Route.Paths {
+						dumpedRoute.NextHops = append(dumpedRoute.NextHops, nhIP.Nh.Address.GetIP4().String())
+					}
 
-				default:
-					return nil, fmt.Errorf("unexpected message type received: %T", msg)
+					dumpedRoutes = append(dumpedRoutes, dumpedRoute)
 				}
+			}); err != nil {
+				return nil, err
 			}
 		}
 	}
@@ -1240,44 +1102,41 @@ func CountRoutesPerTable(stream api.Stream, tableID ip.IPTable) (
 		Table: tableID,
 	}
 
-	if err = stream.SendMsg(req); err != nil {
-		return 0, 0, err
-	}
+	if err = dump(stream, req, func(replay *ip.IPRouteV2Details) {
+		if replay.Route.Paths[0].Type.String() == "FIB_API_PATH_TYPE_NORMAL" {
+			ipRouteCount++
 
-	if err = stream.SendMsg(&memclnt.ControlPing{}); err != nil {
-		return 0, 0, err
-	}
-
-Loop:
-	for {
-		msg, err := stream.RecvMsg()
-		if err != nil {
-			return 0, 0, err
+			return
 		}
 
-		switch replay := msg.(type) {
-		case *ip.IPRouteV2Details:
-			if replay.Route.Paths[0].Type.String() == "FIB_API_PATH_TYPE_NORMAL" {
-				ipRouteCount++
-
-				continue
-			}
+		if replay.Route.Paths[0].Type.String() == "FIB_API_PATH_TYPE_UDP_ENCAP" {
+			fipRouteCount++
+		}
+	}); err != nil {
+		return 0, 0, err
+	}
 
-			if replay.Route.Paths[0].Type.String() == "FIB_API_PATH_TYPE_UDP_ENCAP" {
-				fipRouteCount++
+	return ipRouteCount, fipRouteCount, nil
+}
 
-				continue
-			}
+// DumpFIPRouteStatsIndexes returns stats indexes of floating IP routes of specific VRF (Table) ID by prefix, the index is
+// the route counter index in the /net/route/to stats vector (used for Metric Exporter)
+func DumpFIPRouteStatsIndexes(stream api.Stream, tableID uint32) (map[string]uint32, error) {
+	req := &ip.IPRouteV2Dump{
+		Table: ip.IPTable{TableID: tableID},
+	}
 
-		case *memclnt.ControlPingReply:
-			break Loop
+	statsIndexes := make(map[string]uint32)
 
-		default:
-			return 0, 0, fmt.Errorf("unexpected message type received: %T", msg)
+	if err := dump(stream, req, func(reply *ip.IPRouteV2Details) {
+		if len(reply.Route.Paths) != 0 && reply.Route.Paths[0].Type == fib_types.FIB_API_PATH_TYPE_UDP_ENCAP {
+			statsIndexes[reply.Route.Prefix.String()] = reply.Route.StatsIndex
 		}
+	}); err != nil {
+		return nil, err
 	}
 
-	return ipRouteCount, fipRouteCount, nil
+	return statsIndexes, nil
 }
 
 // AddDelIPRoute adds/deletes an IPv4 route from VPP (route to Internet/External Networks or to vRouters)
@@ -1332,11 +1191,7 @@ func AddDelIPRoute(stream api.Stream, isAdd bool, vppIPRoute model.VPPIPRoute) e
 		},
 	}
 
-	if err = stream.SendMsg(req); err != nil {
-		return err
-	}
-
-	msg, err := stream.RecvMsg()
+	msg, err := requestReply(stream, req)
 	if err != nil {
 		return err
 	}
@@ -1376,11 +1231,7 @@ func AddBlackHoleIPRoute(stream api.Stream, vppIPRoute model.VPPIPRoute) error {
 		},
 	}
 
-	if err = stream.SendMsg(req); err != nil {
-		return err
-	}
-
-	msg, err := stream.RecvMsg()
+	msg, err := requestReply(stream, req)
 	if err != nil {
 		return err
 	}
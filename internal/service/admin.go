@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	bgpapi "github.com/osrg/gobgp/v3/api"
+	"github.com/osrg/gobgp/v3/pkg/server"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/gobgp"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/pkg/logger"
+)
+
+const (
+	BGPPeerResetHard    = "hard"
+	BGPPeerResetSoft    = "soft"
+	BGPPeerResetSoftIn  = "soft-in"
+	BGPPeerResetSoftOut = "soft-out"
+
+	adminCommunication = "administratively reset/disabled by cloudgw"
+)
+
+// ResetBGPPeer resets bgp session with the peer: hard (session is closed), soft, soft-in or soft-out
+func ResetBGPPeer(ctx context.Context, bgpSrv *server.BgpServer, storage *imdb.Storage, peerIP string, mode string) error {
+	peer := storage.BGPPeerStorage.GetBGPPeer(peerIP)
+	if peer == nil {
+		return fmt.Errorf("bgp peer %s: %w", peerIP, ErrNotFound)
+	}
+
+	var (
+		soft      = true
+		direction bgpapi.ResetPeerRequest_SoftResetDirection
+	)
+
+	switch mode {
+	case BGPPeerResetHard:
+		soft = false
+	case BGPPeerResetSoft:
+		direction = bgpapi.ResetPeerRequest_BOTH
+	case BGPPeerResetSoftIn:
+		direction = bgpapi.ResetPeerRequest_IN
+	case BGPPeerResetSoftOut:
+		direction = bgpapi.ResetPeerRequest_OUT
+	default:
+		return fmt.Errorf("wrong reset mode %q, expected %s, %s, %s or %s",
+			mode, BGPPeerResetHard, BGPPeerResetSoft, BGPPeerResetSoftIn, BGPPeerResetSoftOut)
+	}
+
+	if err := gobgp.ResetBGPPeer(ctx, bgpSrv, peer, soft, direction, adminCommunication); err != nil {
+		return fmt.Errorf("failed to reset bgp peer %s: %w", peerIP, err)
+	}
+
+	logger.Info("bgp peer reset by administrator", "peer address", peerIP, "mode", mode)
+
+	return nil
+}
+
+// SetBGPPeerAdminState shuts down (brings up) bgp session with the peer. Disabled peer is not brought up by vpp interface monitoring.
+func SetBGPPeerAdminState(ctx context.Context, bgpSrv *server.BgpServer, storage *imdb.Storage, peerIP string, isEnable bool) error {
+	// serialized with vrf sub-interface link state changes enabling and disabling the peers too
+
+	fipMu.Lock()
+	defer fipMu.Unlock()
+
+	peer := storage.BGPPeerStorage.GetBGPPeer(peerIP)
+	if peer == nil {
+		return fmt.Errorf("bgp peer %s: %w", peerIP, ErrNotFound)
+	}
+
+	if isEnable {
+		if err := gobgp.EnableBGPPeer(ctx, bgpSrv, peer); err != nil {
+			return fmt.Errorf("failed to enable bgp peer %s: %w", peerIP, err)
+		}
+	} else {
+		if err := gobgp.DisableBGPPeer(ctx, bgpSrv, peer, adminCommunication); err != nil {
+			return fmt.Errorf("failed to disable bgp peer %s: %w", peerIP, err)
+		}
+	}
+
+	storage.BGPPeerStorage.SetAdminDisabled(peerIP, !isEnable)
+
+	logger.Info("bgp peer admin state changed by administrator", "peer address", peerIP, "enabled", isEnable)
+
+	return nil
+}
+
+// DrainVRF withdraws aggregated floating ip prefixes of the vrf from physical network keeping floating ips installed in
+// vpp (isDrain) or advertises them back (if the vrf link is up and any floating ip is served). fipMu serializes the
+// advertisement with floating ip changes, link state changes, gateway drain and ha role changes.
+func DrainVRF(ctx context.Context, bgpSrv *server.BgpServer, cfg config.Config, storage *imdb.Storage, vrfName string, isDrain bool) error {
+	fipMu.Lock()
+	defer fipMu.Unlock()
+
+	bgpVRF := findBGPVRF(storage, vrfName)
+	if bgpVRF == nil {
+		return fmt.Errorf("vrf %s: %w", vrfName, ErrNotFound)
+	}
+
+	vppVRF := storage.VPPVRFStorage.GetVRF(bgpVRF.ID)
+	if vppVRF == nil {
+		return fmt.Errorf("vpp vrf %s: %w", vrfName, ErrNotFound)
+	}
+
+	if vppVRF.ID == 0 {
+		return fmt.Errorf("global routing table can not be drained")
+	}
+
+	storage.VPPVRFStorage.SetDrained(vppVRF.ID, isDrain)
+
+	switch {
+	case isDrain:
+		_ = AdvWdrawFIPAggrPrefixes(ctx, bgpSrv, cfg, WITHDRAW, vppVRF, bgpVRF)
+
+		logger.Info("vrf drained by administrator, aggregated floating ip prefixes withdrawn", "vrf", vrfName)
+	case storage.VPPVRFStorage.IsLinkUp(vppVRF.ID) && storage.VPPVRFStorage.GetFIPServed(vppVRF.ID) != 0:
+		_ = AdvWdrawFIPAggrPrefixes(ctx, bgpSrv, cfg, ADVERTISE, vppVRF, bgpVRF)
+
+		logger.Info("vrf undrained by administrator, aggregated floating ip prefixes advertised", "vrf", vrfName)
+	default:
+		logger.Info("vrf undrained by administrator, vrf link is down or no floating ip served", "vrf", vrfName)
+	}
+
+	return nil
+}
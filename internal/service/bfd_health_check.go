@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"strings"
+	"sync"
 	"syscall"
 
 	"git.crptech.ru/cloud/cloudgw/internal/model"
@@ -11,6 +12,26 @@ import (
 	"git.crptech.ru/cloud/cloudgw/pkg/logger"
 )
 
+// bfdControls keeps bfd controls of physical network peers to report bfd session states
+var bfdControls = struct {
+	sync.RWMutex
+	controls map[string]*bfd.Control
+}{controls: make(map[string]*bfd.Control)}
+
+// BFDSessionStates returns states of all bfd sessions with physical network peers
+func BFDSessionStates() []bfd.SessionState {
+	bfdControls.RLock()
+	defer bfdControls.RUnlock()
+
+	states := make([]bfd.SessionState, 0, len(bfdControls.controls))
+
+	for _, control := range bfdControls.controls {
+		states = append(states, control.GetSessionStates()...)
+	}
+
+	return states
+}
+
 // CheckBFDPeerStatus starts monitoring a peer by BFD, exit from program when BFD peer moved UP > DOWN
 func CheckBFDPeerStatus(ctx context.Context, bgpPeer model.BGPPeer) {
 	chBFDDone := make(chan struct{})
@@ -23,11 +44,16 @@ func CheckBFDPeerStatus(ctx context.Context, bgpPeer model.BGPPeer) {
 		bgpPeer.BFDPeering.BFDRxMin,
 		bgpPeer.BFDPeering.BFDTxRate,
 		bgpPeer.BFDPeering.BFDMultiplier,
+		bgpPeer.BFDPeering.BFDEchoInterval,
 		callbackBFDState,
 		chBFDDone,
 	)
 
-	closer.Add(func() error {
+	bfdControls.Lock()
+	bfdControls.controls[bgpPeer.BFDPeering.BFDPeerIP] = control
+	bfdControls.Unlock()
+
+	closer.Add(closer.PhaseSessions, "bfd session "+bgpPeer.BFDPeering.BFDPeerIP, func(context.Context) error {
 		logger.Info("bfd session disconnecting", "peer ip", bgpPeer.BFDPeering.BFDPeerIP)
 
 		return control.DelSession(bgpPeer.BFDPeering.BFDPeerIP)
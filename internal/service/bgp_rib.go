@@ -0,0 +1,359 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	bgpapi "github.com/osrg/gobgp/v3/api"
+	"github.com/osrg/gobgp/v3/pkg/apiutil"
+	"github.com/osrg/gobgp/v3/pkg/packet/bgp"
+	"github.com/osrg/gobgp/v3/pkg/server"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/gobgp"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+)
+
+const (
+	RIBDefaultLimit = 100
+	RIBMaxLimit     = 1000
+)
+
+// ErrNotFound is returned for unknown bgp peer or vrf
+var ErrNotFound = errors.New("not found")
+
+// RIBQuery filters and paginates bgp paths. Prefix is an address or prefix, paths to the prefix and its more specifics
+// are returned. VRF is a vrf name, paths with rd/rt of the vrf (or received from peers of the vrf) are returned.
+type RIBQuery struct {
+	Prefix     string
+	VRF        string
+	PostPolicy bool // adj-in/adj-out only: skip paths rejected by import/export policy
+	Offset     int
+	Limit      int
+}
+
+// RIBPage is a page of bgp paths sorted by prefix
+type RIBPage struct {
+	Total  int       `json:"Total"`
+	Offset int       `json:"Offset"`
+	Limit  int       `json:"Limit"`
+	Paths  []RIBPath `json:"Paths"`
+}
+
+// RIBPath is a decoded bgp path
+type RIBPath struct {
+	Prefix      string    `json:"Prefix"`
+	RD          string    `json:"RD,omitempty"`
+	RTs         []string  `json:"RTs,omitempty"`
+	Labels      []uint32  `json:"Labels,omitempty"`
+	NextHop     string    `json:"NextHop"`
+	ASPath      string    `json:"ASPath"`
+	Origin      string    `json:"Origin"`
+	LocalPref   uint32    `json:"LocalPref,omitempty"`
+	MED         uint32    `json:"MED,omitempty"`
+	Communities []string  `json:"Communities,omitempty"`
+	Neighbor    string    `json:"Neighbor"`
+	SourceASN   uint32    `json:"SourceASN"`
+	Age         time.Time `json:"Age"`
+	Best        bool      `json:"Best"`
+	Filtered    bool      `json:"Filtered"`
+}
+
+// ListAdjRIB returns paths received from (adj-rib-in) or sent to (adj-rib-out) the bgp peer. Paths rejected by policy are
+// returned as filtered (pre-policy view) unless query.PostPolicy is set.
+func ListAdjRIB(
+	ctx context.Context,
+	bgpSrv *server.BgpServer,
+	storage *imdb.Storage,
+	peerIP string,
+	isIn bool,
+	query RIBQuery,
+) (RIBPage, error) {
+	peer := storage.BGPPeerStorage.GetBGPPeer(peerIP)
+	if peer == nil {
+		return RIBPage{}, fmt.Errorf("bgp peer %s: %w", peerIP, ErrNotFound)
+	}
+
+	tableType := bgpapi.TableType_ADJ_OUT
+
+	if isIn {
+		tableType = bgpapi.TableType_ADJ_IN
+	}
+
+	paths, err := gobgp.ListPaths(ctx, bgpSrv, tableType, peer.AFI, peer.SAFI, peer.PeerAddress, nil, true)
+	if err != nil {
+		return RIBPage{}, fmt.Errorf("failed to list bgp paths of peer %s: %w", peerIP, err)
+	}
+
+	return ribPage(storage, paths, query)
+}
+
+// ListVPNv4RIB returns paths of the global vpnv4 table
+func ListVPNv4RIB(ctx context.Context, bgpSrv *server.BgpServer, storage *imdb.Storage, query RIBQuery) (RIBPage, error) {
+	paths, err := gobgp.ListPaths(ctx, bgpSrv, bgpapi.TableType_GLOBAL, bgpapi.Family_AFI_IP, bgpapi.Family_SAFI_MPLS_VPN, "", nil, false)
+	if err != nil {
+		return RIBPage{}, fmt.Errorf("failed to list bgp paths of global vpnv4 table: %w", err)
+	}
+
+	query.PostPolicy = false
+
+	return ribPage(storage, paths, query)
+}
+
+// ListVRFRIB returns paths of the gobgp vrf table
+func ListVRFRIB(ctx context.Context, bgpSrv *server.BgpServer, storage *imdb.Storage, vrfName string, query RIBQuery) (RIBPage, error) {
+	if findBGPVRF(storage, vrfName) == nil {
+		return RIBPage{}, fmt.Errorf("bgp vrf %s: %w", vrfName, ErrNotFound)
+	}
+
+	paths, err := gobgp.ListPaths(ctx, bgpSrv, bgpapi.TableType_VRF, bgpapi.Family_AFI_IP, bgpapi.Family_SAFI_UNICAST, vrfName, nil, false)
+	if err != nil {
+		return RIBPage{}, fmt.Errorf("failed to list bgp paths of vrf %s: %w", vrfName, err)
+	}
+
+	query.PostPolicy = false
+	query.VRF = ""
+
+	return ribPage(storage, paths, query)
+}
+
+// ribPage decodes, filters, sorts and paginates the paths
+func ribPage(storage *imdb.Storage, paths []*bgpapi.Path, query RIBQuery) (RIBPage, error) {
+	if query.Limit <= 0 || query.Limit > RIBMaxLimit {
+		query.Limit = RIBDefaultLimit
+	}
+
+	if query.Offset < 0 {
+		query.Offset = 0
+	}
+
+	var (
+		prefixFilter netip.Prefix
+		vrfFilter    []string // rd and rts of the vrf
+		err          error
+	)
+
+	if query.Prefix != "" {
+		if prefixFilter, err = parsePrefixFilter(query.Prefix); err != nil {
+			return RIBPage{}, err
+		}
+	}
+
+	if query.VRF != "" {
+		bgpVRF := findBGPVRF(storage, query.VRF)
+		if bgpVRF == nil {
+			return RIBPage{}, fmt.Errorf("bgp vrf %s: %w", query.VRF, ErrNotFound)
+		}
+
+		vrfFilter = vrfRDAndRTs(bgpVRF)
+	}
+
+	page := RIBPage{Offset: query.Offset, Limit: query.Limit, Paths: []RIBPath{}}
+
+	var decodedPaths []RIBPath
+
+	for _, path := range paths {
+		if path.IsWithdraw || (query.PostPolicy && path.Filtered) {
+			continue
+		}
+
+		decodedPath, pathPrefix, err := decodeRIBPath(path)
+		if err != nil {
+			continue
+		}
+
+		if prefixFilter.IsValid() && !(prefixFilter.Contains(pathPrefix.Addr()) && pathPrefix.Bits() >= prefixFilter.Bits()) {
+			continue
+		}
+
+		if query.VRF != "" && !isPathOfVRF(storage, decodedPath, query.VRF, vrfFilter) {
+			continue
+		}
+
+		decodedPaths = append(decodedPaths, decodedPath)
+	}
+
+	slices.SortStableFunc(decodedPaths, func(a, b RIBPath) int {
+		if c := strings.Compare(a.Prefix, b.Prefix); c != 0 {
+			return c
+		}
+
+		if c := strings.Compare(a.RD, b.RD); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a.Neighbor, b.Neighbor)
+	})
+
+	page.Total = len(decodedPaths)
+
+	if query.Offset < len(decodedPaths) {
+		page.Paths = decodedPaths[query.Offset:min(query.Offset+query.Limit, len(decodedPaths))]
+	}
+
+	return page, nil
+}
+
+// decodeRIBPath converts gobgp path to readable structure and returns prefix of the path
+func decodeRIBPath(path *bgpapi.Path) (RIBPath, netip.Prefix, error) {
+	decodedPath := RIBPath{
+		Neighbor:  path.NeighborIp,
+		SourceASN: path.SourceAsn,
+		Best:      path.Best,
+		Filtered:  path.Filtered,
+	}
+
+	if path.Age != nil {
+		decodedPath.Age = path.Age.AsTime()
+	}
+
+	nlri, err := apiutil.GetNativeNlri(path)
+	if err != nil {
+		return decodedPath, netip.Prefix{}, fmt.Errorf("failed to decode nlri: %w", err)
+	}
+
+	var (
+		nlriPrefix net.IP
+		nlriLength uint8
+	)
+
+	switch n := nlri.(type) {
+	case *bgp.LabeledVPNIPAddrPrefix:
+		nlriPrefix, nlriLength = n.Prefix, n.IPPrefixLen() // length of the nlri includes labels and rd
+		decodedPath.RD = n.RD.String()
+		decodedPath.Labels = n.Labels.Labels
+	case *bgp.IPAddrPrefix:
+		nlriPrefix, nlriLength = n.Prefix, n.Length
+	default:
+		return decodedPath, netip.Prefix{}, fmt.Errorf("unsupported nlri type %T", nlri)
+	}
+
+	addr, ok := netip.AddrFromSlice(nlriPrefix)
+	if !ok {
+		return decodedPath, netip.Prefix{}, fmt.Errorf("wrong nlri prefix %s", nlriPrefix)
+	}
+
+	pathPrefix := netip.PrefixFrom(addr.Unmap(), int(nlriLength))
+
+	decodedPath.Prefix = pathPrefix.String()
+
+	attrs, err := apiutil.GetNativePathAttributes(path)
+	if err != nil {
+		return decodedPath, pathPrefix, fmt.Errorf("failed to decode path attributes: %w", err)
+	}
+
+	for _, attr := range attrs {
+		switch a := attr.(type) {
+		case *bgp.PathAttributeOrigin:
+			decodedPath.Origin = originString(a.Value)
+		case *bgp.PathAttributeAsPath:
+			segments := make([]string, 0, len(a.Value))
+
+			for _, segment := range a.Value {
+				segments = append(segments, segment.String())
+			}
+
+			decodedPath.ASPath = strings.Join(segments, " ")
+		case *bgp.PathAttributeNextHop:
+			decodedPath.NextHop = a.Value.String()
+		case *bgp.PathAttributeMpReachNLRI:
+			decodedPath.NextHop = a.Nexthop.String()
+		case *bgp.PathAttributeLocalPref:
+			decodedPath.LocalPref = a.Value
+		case *bgp.PathAttributeMultiExitDisc:
+			decodedPath.MED = a.Value
+		case *bgp.PathAttributeCommunities:
+			for _, community := range a.Value {
+				decodedPath.Communities = append(decodedPath.Communities, fmt.Sprintf("%d:%d", community>>16, community&0xffff))
+			}
+		case *bgp.PathAttributeExtendedCommunities:
+			for _, community := range a.Value {
+				if _, subType := community.GetTypes(); subType == bgp.EC_SUBTYPE_ROUTE_TARGET {
+					decodedPath.RTs = append(decodedPath.RTs, community.String())
+				}
+			}
+		}
+	}
+
+	return decodedPath, pathPrefix, nil
+}
+
+// isPathOfVRF checks the path has rd/rt of the vrf, paths without rd/rt (ipv4 unicast) are checked by the peer vrf
+func isPathOfVRF(storage *imdb.Storage, path RIBPath, vrfName string, vrfRDAndRTs []string) bool {
+	if path.RD == "" && len(path.RTs) == 0 {
+		peer := storage.BGPPeerStorage.GetBGPPeer(path.Neighbor)
+
+		return peer != nil && peer.VRFName == vrfName
+	}
+
+	if slices.Contains(vrfRDAndRTs, path.RD) {
+		return true
+	}
+
+	for _, rt := range path.RTs {
+		if slices.Contains(vrfRDAndRTs, rt) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func vrfRDAndRTs(bgpVRF *model.BGPVRFTable) []string {
+	var values []string
+
+	if rd, err := apiutil.UnmarshalRD(bgpVRF.RD); err == nil {
+		values = append(values, rd.String())
+	}
+
+	for _, rts := range [][]*anypb.Any{bgpVRF.ImportRT, bgpVRF.ExportRT} {
+		for _, rt := range rts {
+			if decodedRT, err := apiutil.UnmarshalRT(rt); err == nil {
+				values = append(values, decodedRT.String())
+			}
+		}
+	}
+
+	return values
+}
+
+func findBGPVRF(storage *imdb.Storage, vrfName string) *model.BGPVRFTable {
+	for _, vrf := range storage.BGPVRFStorage.GetVRFs() {
+		if vrf.Name == vrfName {
+			return vrf
+		}
+	}
+
+	return nil
+}
+
+func parsePrefixFilter(value string) (netip.Prefix, error) {
+	if prefix, err := netip.ParsePrefix(value); err == nil {
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("wrong prefix %q", value)
+	}
+
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+func originString(origin uint8) string {
+	switch origin {
+	case bgp.BGP_ORIGIN_ATTR_TYPE_IGP:
+		return "igp"
+	case bgp.BGP_ORIGIN_ATTR_TYPE_EGP:
+		return "egp"
+	default:
+		return "incomplete"
+	}
+}
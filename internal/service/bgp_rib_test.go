@@ -0,0 +1,231 @@
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/gobgp"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/internal/service"
+	"git.crptech.ru/cloud/cloudgw/pkg/gobgpapi"
+)
+
+func TestListRIB(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := config.Config{
+		TFController: config.TFController{BGPPeerASN: 64512},
+		GoBGP:        config.GoBGP{BGPLocalASN: 65000},
+	}
+
+	peering := newTFPeering(ctx, t)
+	storage := imdb.NewStorage()
+
+	require.NoError(t, storage.BGPPeerStorage.AddBGPPeer(&peering.peer))
+
+	// vrf1 and vrf2 import the paths with rt 64512:vrfID from tungsten fabric, the aggregated prefix of vrf1 is sent back
+
+	for _, vrfID := range []uint32{1, 2} {
+		bgpVRF := model.NewBGPVRFTable(fmt.Sprintf("vrf%d", vrfID), vrfID, 65000, 64512, model.RD("192.0.2.1", vrfID),
+			[]*anypb.Any{model.RT(65000, vrfID)}, []*anypb.Any{model.RT(64512, vrfID)})
+
+		require.NoError(t, storage.BGPVRFStorage.AddVRF(&bgpVRF))
+		require.NoError(t, gobgp.AddGoBGPVRF(ctx, peering.local, &bgpVRF))
+
+		if vrfID == 1 {
+			vppVRF := &model.VPPVRFTable{Name: bgpVRF.Name, ID: vrfID, LocalAddr: "10.0.1.1", FIPPrefixes: []string{"172.16.1.0/24"}}
+
+			require.NoError(t, service.AdvWdrawFIPAggrPrefixes(ctx, peering.local, cfg, service.ADVERTISE, vppVRF, &bgpVRF))
+		}
+	}
+
+	peering.advertise(ctx, t,
+		gobgpapi.BGPNLRIAttrs{
+			Prefix: "172.16.1.11/32", NextHop: "10.1.1.2", RD: model.RD("10.1.1.2", 1),
+			RT: []*anypb.Any{model.RT(64512, 1)}, MPLSLabel: []uint32{26},
+		},
+		gobgpapi.BGPNLRIAttrs{
+			Prefix: "172.16.1.10/32", NextHop: "10.1.1.1", RD: model.RD("10.1.1.1", 1),
+			RT: []*anypb.Any{model.RT(64512, 1)}, MPLSLabel: []uint32{25},
+		},
+		gobgpapi.BGPNLRIAttrs{
+			Prefix: "172.16.2.10/32", NextHop: "10.1.1.1", RD: model.RD("10.1.1.1", 2),
+			RT: []*anypb.Any{model.RT(64512, 2)}, MPLSLabel: []uint32{27},
+		},
+	)
+
+	require.Eventually(t, func() bool {
+		page, err := service.ListAdjRIB(ctx, peering.local, storage, peering.peer.PeerAddress, false, service.RIBQuery{})
+		require.NoError(t, err)
+
+		return page.Total == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	adjIn := func(query service.RIBQuery) func() (service.RIBPage, error) {
+		return func() (service.RIBPage, error) {
+			return service.ListAdjRIB(ctx, peering.local, storage, peering.peer.PeerAddress, true, query)
+		}
+	}
+
+	tests := []struct {
+		name         string
+		list         func() (service.RIBPage, error)
+		wantTotal    int
+		wantPrefixes []string
+		wantErr      error
+	}{
+		{
+			name:         "adj-in",
+			list:         adjIn(service.RIBQuery{}),
+			wantTotal:    3,
+			wantPrefixes: []string{"172.16.1.10/32", "172.16.1.11/32", "172.16.2.10/32"},
+		},
+		{
+			name:         "adj-in more specifics of prefix",
+			list:         adjIn(service.RIBQuery{Prefix: "172.16.1.0/24"}),
+			wantTotal:    2,
+			wantPrefixes: []string{"172.16.1.10/32", "172.16.1.11/32"},
+		},
+		{
+			name:         "adj-in address",
+			list:         adjIn(service.RIBQuery{Prefix: "172.16.1.11"}),
+			wantTotal:    1,
+			wantPrefixes: []string{"172.16.1.11/32"},
+		},
+		{
+			name:         "adj-in of vrf",
+			list:         adjIn(service.RIBQuery{VRF: "vrf2"}),
+			wantTotal:    1,
+			wantPrefixes: []string{"172.16.2.10/32"},
+		},
+		{
+			name:         "adj-in page",
+			list:         adjIn(service.RIBQuery{Offset: 1, Limit: 1}),
+			wantTotal:    3,
+			wantPrefixes: []string{"172.16.1.11/32"},
+		},
+		{
+			name:         "adj-in last page",
+			list:         adjIn(service.RIBQuery{Offset: 2, Limit: 2}),
+			wantTotal:    3,
+			wantPrefixes: []string{"172.16.2.10/32"},
+		},
+		{
+			name:         "adj-in offset beyond total",
+			list:         adjIn(service.RIBQuery{Offset: 5}),
+			wantTotal:    3,
+			wantPrefixes: []string{},
+		},
+		{
+			name: "adj-out",
+			list: func() (service.RIBPage, error) {
+				return service.ListAdjRIB(ctx, peering.local, storage, peering.peer.PeerAddress, false, service.RIBQuery{PostPolicy: true})
+			},
+			wantTotal:    1,
+			wantPrefixes: []string{"172.16.1.0/24"},
+		},
+		{
+			name: "vpnv4",
+			list: func() (service.RIBPage, error) {
+				return service.ListVPNv4RIB(ctx, peering.local, storage, service.RIBQuery{})
+			},
+			wantTotal:    4,
+			wantPrefixes: []string{"172.16.1.0/24", "172.16.1.10/32", "172.16.1.11/32", "172.16.2.10/32"},
+		},
+		{
+			name: "vpnv4 of vrf",
+			list: func() (service.RIBPage, error) {
+				return service.ListVPNv4RIB(ctx, peering.local, storage, service.RIBQuery{VRF: "vrf1"})
+			},
+			wantTotal:    3,
+			wantPrefixes: []string{"172.16.1.0/24", "172.16.1.10/32", "172.16.1.11/32"},
+		},
+		{
+			name: "vrf",
+			list: func() (service.RIBPage, error) {
+				return service.ListVRFRIB(ctx, peering.local, storage, "vrf2", service.RIBQuery{})
+			},
+			wantTotal:    1,
+			wantPrefixes: []string{"172.16.2.10/32"},
+		},
+		{
+			name: "unknown peer",
+			list: func() (service.RIBPage, error) {
+				return service.ListAdjRIB(ctx, peering.local, storage, "127.0.0.3", true, service.RIBQuery{})
+			},
+			wantErr: service.ErrNotFound,
+		},
+		{
+			name: "unknown vrf",
+			list: func() (service.RIBPage, error) {
+				return service.ListVRFRIB(ctx, peering.local, storage, "vrf3", service.RIBQuery{})
+			},
+			wantErr: service.ErrNotFound,
+		},
+		{
+			name:    "filter by unknown vrf",
+			list:    adjIn(service.RIBQuery{VRF: "vrf3"}),
+			wantErr: service.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := tt.list()
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.wantTotal, page.Total)
+
+			prefixes := make([]string, 0, len(page.Paths))
+
+			for _, path := range page.Paths {
+				prefixes = append(prefixes, path.Prefix)
+			}
+
+			require.Equal(t, tt.wantPrefixes, prefixes)
+		})
+	}
+
+	// wrong prefix filter is rejected
+
+	_, err := adjIn(service.RIBQuery{Prefix: "172.16.1"})()
+	require.ErrorContains(t, err, "wrong prefix")
+
+	// attributes of the path are decoded
+
+	page, err := adjIn(service.RIBQuery{Prefix: "172.16.1.10"})()
+	require.NoError(t, err)
+	require.Len(t, page.Paths, 1)
+
+	path := page.Paths[0]
+
+	require.False(t, path.Age.IsZero())
+
+	path.Age = time.Time{}
+
+	require.Equal(t, service.RIBPath{
+		Prefix:    "172.16.1.10/32",
+		RD:        "10.1.1.1:1",
+		RTs:       []string{"64512:1"},
+		Labels:    []uint32{25},
+		NextHop:   "10.1.1.1",
+		ASPath:    "64512",
+		Origin:    "incomplete",
+		LocalPref: 100,
+		Neighbor:  peering.peer.PeerAddress,
+		SourceASN: 65000,
+	}, path)
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+	"time"
+
+	bgpapi "github.com/osrg/gobgp/v3/api"
+	"github.com/osrg/gobgp/v3/pkg/server"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/gobgp"
+	"git.crptech.ru/cloud/cloudgw/pkg/logger"
+)
+
+var bmpPolicies = map[string]bgpapi.AddBmpRequest_MonitoringPolicy{
+	config.BMPPolicyPre:   bgpapi.AddBmpRequest_PRE,
+	config.BMPPolicyPost:  bgpapi.AddBmpRequest_POST,
+	config.BMPPolicyLocal: bgpapi.AddBmpRequest_LOCAL,
+	config.BMPPolicyAll:   bgpapi.AddBmpRequest_ALL,
+}
+
+// BMPStation is the connection state of bmp station
+type BMPStation struct {
+	Address   string    `json:"Address"`
+	Port      uint32    `json:"Port"`
+	Connected bool      `json:"Connected"`
+	Uptime    time.Time `json:"Uptime"`   // last connection, zero if the station has never been connected
+	Downtime  time.Time `json:"Downtime"` // last disconnection, zero if the station has never been disconnected
+}
+
+// AddBMPStations adds BMP.Stations to gobgp server: peer up and down notifications, statistics reports and routes of
+// BMP.Policy of all peers are streamed to them, disconnected stations are reconnected by gobgp
+func AddBMPStations(ctx context.Context, bgpSrv *server.BgpServer, cfg config.BMP) error {
+	for _, station := range cfg.Stations {
+		addrPort, err := netip.ParseAddrPort(station)
+		if err != nil {
+			return fmt.Errorf("failed to parse bmp station %q: %w", station, err)
+		}
+
+		if err = gobgp.AddBMPStation(ctx, bgpSrv, addrPort.Addr().String(), uint32(addrPort.Port()), bmpPolicies[cfg.Policy],
+			int32(cfg.StatisticsInterval), cfg.SysName); err != nil {
+			return fmt.Errorf("failed to add bmp station %s: %w", station, err)
+		}
+
+		logger.Info("bmp station added", "station", station, "policy", cfg.Policy)
+	}
+
+	return nil
+}
+
+// BMPStations returns bmp stations of gobgp server sorted by address and port. Connection time is known to a second,
+// so a station disconnected in the second it was connected is reported as connected until it is reconnected.
+func BMPStations(ctx context.Context, bgpSrv *server.BgpServer) ([]BMPStation, error) {
+	stations, err := gobgp.ListBMPStations(ctx, bgpSrv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bmp stations: %w", err)
+	}
+
+	result := make([]BMPStation, 0, len(stations))
+
+	for _, station := range stations {
+		uptime, downtime := bmpTime(station.GetState().GetUptime()), bmpTime(station.GetState().GetDowntime())
+
+		result = append(result, BMPStation{
+			Address:   station.GetConf().GetAddress(),
+			Port:      station.GetConf().GetPort(),
+			Connected: !uptime.IsZero() && !uptime.Before(downtime),
+			Uptime:    uptime,
+			Downtime:  downtime,
+		})
+	}
+
+	slices.SortFunc(result, func(a, b BMPStation) int {
+		return cmp.Or(cmp.Compare(a.Address, b.Address), cmp.Compare(a.Port, b.Port))
+	})
+
+	return result, nil
+}
+
+// bmpTime returns zero time for unset (zero unix time) timestamps of gobgp
+func bmpTime(ts *timestamppb.Timestamp) time.Time {
+	if ts.GetSeconds() == 0 {
+		return time.Time{}
+	}
+
+	return ts.AsTime()
+}
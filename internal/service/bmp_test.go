@@ -0,0 +1,76 @@
+package service_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	bgpapi "github.com/osrg/gobgp/v3/api"
+	"github.com/osrg/gobgp/v3/pkg/packet/bmp"
+	"github.com/osrg/gobgp/v3/pkg/server"
+	"github.com/stretchr/testify/require"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/service"
+)
+
+func TestBMPStations(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bgpSrv := server.NewBgpServer()
+
+	go bgpSrv.Serve()
+
+	defer bgpSrv.Stop()
+
+	require.NoError(t, bgpSrv.StartBgp(ctx, &bgpapi.StartBgpRequest{
+		Global: &bgpapi.Global{Asn: 65000, RouterId: "192.0.2.1", ListenPort: -1},
+	}))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer listener.Close()
+
+	station := netip.MustParseAddrPort(listener.Addr().String())
+
+	// loc-rib of the all policy is not monitored, gobgp reads its global config racing with the server stop of the test
+
+	require.NoError(t, service.AddBMPStations(ctx, bgpSrv, config.BMP{
+		Stations: []string{station.String()}, Policy: config.BMPPolicyPre, StatisticsInterval: 60, SysName: "cloudgw",
+	}))
+
+	// the station receives the initiation message first
+
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+
+	defer conn.Close()
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	header := make([]byte, bmp.BMP_HEADER_SIZE)
+
+	_, err = io.ReadFull(conn, header)
+	require.NoError(t, err)
+	require.Equal(t, uint8(bmp.BMP_VERSION), header[0])
+	require.Equal(t, uint8(bmp.BMP_MSG_INITIATION), header[5])
+
+	require.Eventually(t, func() bool {
+		stations, err := service.BMPStations(ctx, bgpSrv)
+		require.NoError(t, err)
+		require.Len(t, stations, 1)
+		require.Equal(t, "127.0.0.1", stations[0].Address)
+		require.Equal(t, uint32(station.Port()), stations[0].Port)
+
+		return stations[0].Connected
+	}, 5*time.Second, 100*time.Millisecond)
+
+	// a station is added once
+
+	require.Error(t, service.AddBMPStations(ctx, bgpSrv, config.BMP{Stations: []string{station.String()}, Policy: config.BMPPolicyPre}))
+}
@@ -0,0 +1,341 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/osrg/gobgp/v3/pkg/server"
+	"go.fd.io/govpp/api"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp"
+	"git.crptech.ru/cloud/cloudgw/pkg/logger"
+	"git.crptech.ru/cloud/cloudgw/pkg/netutils"
+	"git.crptech.ru/cloud/cloudgw/pkg/tracing"
+)
+
+// ChangeFIPRoute replaces the stored floating ip route with the new one (stored route is nil to add the floating ip,
+// new route is nil to delete it) in vpp, memory storage and bgp aggregated prefixes of the vrf. The change is applied
+// fully or not at all: storage is changed in one transaction and, if any step fails, done vpp and bgp steps are undone
+// in the reverse order and the transaction is aborted. Routes passed to the function are not changed.
+func ChangeFIPRoute(
+	ctx context.Context,
+	stream api.Stream,
+	bgpSrv *server.BgpServer,
+	cfg config.Config,
+	storage *imdb.Storage,
+	storedRoute, newRoute *model.VPPIPRoute,
+	vppVRF *model.VPPVRFTable,
+	bgpVRF *model.BGPVRFTable,
+) error {
+	change := &fipChange{
+		ctx:     ctx,
+		stream:  stream,
+		bgpSrv:  bgpSrv,
+		cfg:     cfg,
+		storage: storage,
+		txn:     storage.Txn(),
+		vppVRF:  vppVRF,
+		bgpVRF:  bgpVRF,
+	}
+
+	if err := change.apply(storedRoute, newRoute); err != nil {
+		change.rollback()
+
+		return err
+	}
+
+	change.txn.Commit()
+
+	switch {
+	case storedRoute == nil:
+		logger.Info("floating ip route created", "prefix", newRoute.Prefix, "nh", newRoute.NextHops, "label", newRoute.MPLSLabels())
+	case newRoute == nil:
+		logger.Info("floating ip route deleted", "prefix", storedRoute.Prefix, "nh", storedRoute.NextHops)
+	default:
+		logger.Info("floating ip route updated", "prefix", newRoute.Prefix, "nh", newRoute.NextHops, "label", newRoute.MPLSLabels())
+	}
+
+	return nil
+}
+
+type fipChange struct {
+	ctx     context.Context
+	stream  api.Stream
+	bgpSrv  *server.BgpServer
+	cfg     config.Config
+	storage *imdb.Storage
+	txn     *imdb.Txn
+	vppVRF  *model.VPPVRFTable
+	bgpVRF  *model.BGPVRFTable
+	undo    []undoStep // done vpp and bgp steps
+}
+
+type undoStep struct {
+	name string
+	fn   func() error
+}
+
+func (c *fipChange) apply(storedRoute, newRoute *model.VPPIPRoute) error {
+	vrf := c.txn.GetVRF(c.vppVRF.ID)
+	if vrf == nil {
+		return fmt.Errorf("failed to fetch vrf %d from memory storage: %w", c.vppVRF.ID, imdb.ErrNoVPPVRFsFoundInStorage)
+	}
+
+	fipServedBefore := vrf.FIPServed
+
+	if storedRoute != nil {
+		if err := c.delRoute(*storedRoute); err != nil {
+			return err
+		}
+	}
+
+	if newRoute != nil {
+		if err := c.addRoute(newRoute.Clone()); err != nil {
+			return err
+		}
+	}
+
+	if err := c.advWdrawAggrPrefixes(fipServedBefore); err != nil {
+		return err
+	}
+
+	// the tunnels are deleted last as a deleted tunnel can not be restored with the same id
+
+	if storedRoute != nil {
+		c.delFreeTunnels(storedRoute.NextHops)
+	}
+
+	return nil
+}
+
+func (c *fipChange) rollback() {
+	for i := len(c.undo) - 1; i >= 0; i-- {
+		if err := c.undo[i].fn(); err != nil {
+			logger.Error("failed to undo floating ip route change, vpp or bgp may differ from memory storage", "step", c.undo[i].name, "error", err)
+		}
+	}
+
+	c.txn.Abort()
+}
+
+func (c *fipChange) done(name string, fn func() error) {
+	c.undo = append(c.undo, undoStep{name: name, fn: fn})
+}
+
+func (c *fipChange) delRoute(route model.VPPIPRoute) error {
+	if err := c.addDelFIPRoute(false, &route); err != nil {
+		return fmt.Errorf("failed to delete floating ip route %s from vpp: %w", route.Prefix, err)
+	}
+
+	c.done("re-add floating ip route "+route.Prefix, func() error {
+		liveRoute := LiveFIPRoute(route, c.storage)
+
+		return c.addDelFIPRoute(true, &liveRoute)
+	})
+
+	if err := c.txn.DelFIPRoute(route.Prefix); err != nil {
+		return fmt.Errorf("failed to delete floating ip route %s from memory storage: %w", route.Prefix, err)
+	}
+
+	if _, err := c.txn.DecVRFFIPServed(route.VRFID); err != nil {
+		return fmt.Errorf("failed to decrement floating ips served by vrf %d: %w", route.VRFID, err)
+	}
+
+	for _, nh := range route.NextHops {
+		if _, err := c.txn.DecUDPTunnelFIPServed(nh); err != nil {
+			return fmt.Errorf("failed to decrement floating ips served by vrouter %s: %w", nh, err)
+		}
+	}
+
+	return nil
+}
+
+func (c *fipChange) addRoute(route model.VPPIPRoute) error {
+	// reuse the udp tunnel to the vrouter or create a new one
+
+	for i, nh := range route.NextHops {
+		if tunnel := c.txn.GetUDPTunnel(nh); tunnel != nil {
+			route.TunnelIDs[i] = tunnel.TunnelID
+
+			continue
+		}
+
+		tunnel := model.NewVPPUDPTunnel(model.UndefinedTunnelID, netutils.Addr(c.cfg.VPP.TunLocalIP), nh, model.RandUDPTunnelSrcPort())
+
+		if err := c.addUDPTunnel(&tunnel); err != nil {
+			return fmt.Errorf("failed to create udp tunnel to %s in vpp: %w", nh, err)
+		}
+
+		c.done("delete udp tunnel to "+nh, func() error {
+			return c.delUDPTunnel(tunnel)
+		})
+
+		route.TunnelIDs[i] = tunnel.TunnelID
+
+		if err := c.txn.AddUDPTunnel(&tunnel); err != nil {
+			return fmt.Errorf("failed to create udp tunnel to %s in memory storage: %w", nh, err)
+		}
+	}
+
+	// only paths through reachable vrouters are installed in vpp
+
+	liveRoute := LiveFIPRoute(route, c.storage)
+
+	if err := c.addDelFIPRoute(true, &liveRoute); err != nil {
+		return fmt.Errorf("failed to add floating ip route %s to vpp: %w", route.Prefix, err)
+	}
+
+	c.done("delete floating ip route "+route.Prefix, func() error {
+		return c.addDelFIPRoute(false, &liveRoute)
+	})
+
+	if err := c.txn.AddFIPRoute(&route); err != nil {
+		return fmt.Errorf("failed to create floating ip route %s in memory storage: %w", route.Prefix, err)
+	}
+
+	if _, err := c.txn.IncVRFFIPServed(route.VRFID); err != nil {
+		return fmt.Errorf("failed to increment floating ips served by vrf %d: %w", route.VRFID, err)
+	}
+
+	for _, nh := range route.NextHops {
+		if _, err := c.txn.IncUDPTunnelFIPServed(nh); err != nil {
+			return fmt.Errorf("failed to increment floating ips served by vrouter %s: %w", nh, err)
+		}
+	}
+
+	return nil
+}
+
+// advWdrawAggrPrefixes advertises aggregated floating ip prefixes of the vrf with the first served floating ip and
+// withdraws them with the last one. Prefixes of the vrf with the link down or drained are not advertised (they are
+// advertised by vpp interface monitoring or by undrain).
+func (c *fipChange) advWdrawAggrPrefixes(fipServedBefore uint32) error {
+	vrf := c.txn.GetVRF(c.vppVRF.ID)
+	if vrf == nil {
+		return fmt.Errorf("failed to fetch vrf %d from memory storage: %w", c.vppVRF.ID, imdb.ErrNoVPPVRFsFoundInStorage)
+	}
+
+	if !vrf.LinkUp || vrf.Drained {
+		return nil
+	}
+
+	var isAdvertise bool
+
+	switch {
+	case fipServedBefore == 0 && vrf.FIPServed != 0:
+		isAdvertise = ADVERTISE
+	case fipServedBefore != 0 && vrf.FIPServed == 0:
+		isAdvertise = WITHDRAW
+	default:
+		return nil
+	}
+
+	if err := AdvWdrawFIPAggrPrefixes(c.ctx, c.bgpSrv, c.cfg, isAdvertise, c.vppVRF, c.bgpVRF); err != nil {
+		return fmt.Errorf("failed to advertise/withdraw aggregated floating ip prefixes of vrf %s: %w", c.bgpVRF.Name, err)
+	}
+
+	c.done("advertise/withdraw aggregated floating ip prefixes back", func() error {
+		return AdvWdrawFIPAggrPrefixes(c.ctx, c.bgpSrv, c.cfg, !isAdvertise, c.vppVRF, c.bgpVRF)
+	})
+
+	return nil
+}
+
+// addDelFIPRoute adds (deletes) the floating ip route to (from) vpp in the span of the binary api call
+func (c *fipChange) addDelFIPRoute(isAdd bool, route *model.VPPIPRoute) error {
+	_, span := tracing.Start(c.ctx, "vpp.AddDelFIPRoute",
+		tracing.VPPMessageKey.String("ip_route_add_del_v2"),
+		tracing.AddKey.Bool(isAdd),
+		tracing.PrefixKey.String(route.Prefix),
+		tracing.VRFKey.Int64(int64(route.VRFID)),
+		tracing.NextHopKey.StringSlice(route.NextHops),
+	)
+
+	err := vpp.AddDelFIPRoute(c.stream, isAdd, route)
+
+	tracing.End(span, err)
+
+	return err
+}
+
+func (c *fipChange) addUDPTunnel(tunnel *model.VPPUDPTunnel) error {
+	_, span := tracing.Start(c.ctx, "vpp.AddUDPTunnel",
+		tracing.VPPMessageKey.String("udp_encap_add"),
+		tracing.NextHopKey.String(tunnel.DstIP),
+	)
+
+	err := vpp.AddUDPTunnel(c.stream, tunnel)
+
+	tracing.End(span, err)
+
+	return err
+}
+
+func (c *fipChange) delUDPTunnel(tunnel model.VPPUDPTunnel) error {
+	_, span := tracing.Start(c.ctx, "vpp.DelUDPTunnel",
+		tracing.VPPMessageKey.String("udp_encap_del"),
+		tracing.NextHopKey.String(tunnel.DstIP),
+	)
+
+	err := vpp.DelUDPTunnel(c.stream, tunnel.TunnelID)
+
+	tracing.End(span, err)
+
+	return err
+}
+
+// delFreeTunnels deletes udp tunnels to the vrouters without served floating ips and returns the number of deleted ones.
+// A tunnel vpp fails to delete is kept in memory storage to be reused, so the change is not rolled back.
+func (c *fipChange) delFreeTunnels(nextHops []string) int {
+	var deleted int
+
+	for _, nh := range nextHops {
+		tunnel := c.txn.GetUDPTunnel(nh)
+		if tunnel == nil || tunnel.FIPServed != 0 {
+			continue
+		}
+
+		if err := c.delUDPTunnel(*tunnel); err != nil {
+			logger.Error("failed to delete udp tunnel from vpp, the tunnel is kept", "vrouter", nh, "error", err)
+
+			continue
+		}
+
+		if err := c.txn.DelUDPTunnel(nh); err != nil {
+			logger.Error("failed to delete udp tunnel from memory storage", "vrouter", nh, "error", err)
+
+			continue
+		}
+
+		deleted++
+	}
+
+	return deleted
+}
+
+// DelIdleUDPTunnels deletes udp tunnels without served floating ips, e.g. restored on warm start and not used by
+// floating ip routes from tungsten fabric, and returns the number of deleted tunnels
+func DelIdleUDPTunnels(stream api.Stream, storage *imdb.Storage) int {
+	fipMu.Lock()
+	defer fipMu.Unlock()
+
+	var nextHops []string
+
+	for _, tunnel := range storage.VPPUDPTunnelStorage.GetIdleUDPTunnels() {
+		nextHops = append(nextHops, tunnel.DstIP)
+	}
+
+	if len(nextHops) == 0 {
+		return 0
+	}
+
+	change := &fipChange{ctx: context.Background(), stream: stream, storage: storage, txn: storage.Txn()}
+	deleted := change.delFreeTunnels(nextHops)
+
+	change.txn.Commit()
+
+	return deleted
+}
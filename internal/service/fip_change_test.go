@@ -0,0 +1,83 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp/dryrun"
+	"git.crptech.ru/cloud/cloudgw/internal/service"
+)
+
+func TestChangeFIPRoute(t *testing.T) {
+	ctx := context.Background()
+	stream := dryrun.NewStream(ctx)
+	storage := imdb.NewStorage()
+	cfg := config.Config{VPP: config.VPP{TunLocalIP: "192.0.0.1/24"}}
+
+	// vrf 2 is not created in vpp, so adding a route to it fails; the vrf links are down to keep bgp out of the test
+
+	vppVRFs := []*model.VPPVRFTable{{Name: "vrf1", ID: 1}, {Name: "vrf2", ID: 2}}
+	bgpVRFs := []*model.BGPVRFTable{{Name: "vrf1", ID: 1}, {Name: "vrf2", ID: 2}}
+
+	for i := range vppVRFs {
+		require.NoError(t, storage.VPPVRFStorage.AddVRF(vppVRFs[i]))
+		require.NoError(t, storage.BGPVRFStorage.AddVRF(bgpVRFs[i]))
+	}
+
+	require.NoError(t, vpp.AddDelVRF(stream, true, *vppVRFs[0]))
+
+	newRoute := func(vrfID uint32, prefix string, nextHops ...string) *model.VPPIPRoute {
+		route := model.NewVPPIPRoute(vrfID, 1, model.UndefinedSubIf, prefix, nil, nil, nil)
+
+		for i, nh := range nextHops {
+			route.AddPath(nh, model.UndefinedTunnelID, uint32(25+i))
+		}
+
+		return &route
+	}
+
+	// add the floating ip
+
+	route := newRoute(1, "172.16.1.10/32", "10.0.0.1")
+	require.NoError(t, service.ChangeFIPRoute(ctx, stream, nil, cfg, storage, nil, route, vppVRFs[0], bgpVRFs[0]))
+
+	require.Equal(t, []uint32{model.UndefinedTunnelID}, route.TunnelIDs) // the passed route is not changed
+	require.Equal(t, uint32(1), storage.VPPVRFStorage.GetFIPServed(1))
+	require.Equal(t, uint32(1), storage.VPPUDPTunnelStorage.GetFIPServed("10.0.0.1"))
+	require.Len(t, stream.FIB().Routes, 1)
+
+	// failed change is rolled back: the created tunnel is deleted from vpp, storage is not changed
+
+	failed := newRoute(2, "172.16.2.10/32", "10.0.0.2")
+	require.Error(t, service.ChangeFIPRoute(ctx, stream, nil, cfg, storage, nil, failed, vppVRFs[1], bgpVRFs[1]))
+
+	require.Nil(t, storage.VPPFIPRouteStorage.GetFIPRoute("172.16.2.10/32"))
+	require.Nil(t, storage.VPPUDPTunnelStorage.GetUDPTunnel("10.0.0.2"))
+	require.Equal(t, uint32(0), storage.VPPVRFStorage.GetFIPServed(2))
+	require.Len(t, stream.FIB().Tunnels, 1)
+
+	// update the floating ip with the second path, then delete it
+
+	stored := storage.VPPFIPRouteStorage.GetFIPRoute("172.16.1.10/32")
+	updated := newRoute(1, "172.16.1.10/32", "10.0.0.1", "10.0.0.3")
+	require.NoError(t, service.ChangeFIPRoute(ctx, stream, nil, cfg, storage, stored, updated, vppVRFs[0], bgpVRFs[0]))
+
+	require.Equal(t, uint32(1), storage.VPPVRFStorage.GetFIPServed(1))
+	require.Len(t, stream.FIB().Tunnels, 2)
+	require.Len(t, stream.FIB().Routes[0].Paths, 2)
+
+	stored = storage.VPPFIPRouteStorage.GetFIPRoute("172.16.1.10/32")
+	require.NoError(t, service.ChangeFIPRoute(ctx, stream, nil, cfg, storage, stored, nil, vppVRFs[0], bgpVRFs[0]))
+
+	fib := stream.FIB()
+	require.Empty(t, fib.Routes)
+	require.Empty(t, fib.Tunnels)
+	require.Empty(t, storage.VPPUDPTunnelStorage.GetUDPTunnels())
+	require.Equal(t, uint32(0), storage.VPPVRFStorage.GetFIPServed(1))
+}
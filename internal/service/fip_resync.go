@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+
+	bgpapi "github.com/osrg/gobgp/v3/api"
+	"github.com/osrg/gobgp/v3/pkg/server"
+	"go.fd.io/govpp/api"
+	"go.fd.io/govpp/binapi/interface_types"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/gobgp"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp"
+	"git.crptech.ru/cloud/cloudgw/pkg/logger"
+	"git.crptech.ru/cloud/cloudgw/pkg/netutils"
+)
+
+// fipMu serializes floating ip changes by bgp updates from tungsten fabric and by floating ip resync
+var fipMu sync.Mutex
+
+// FIPResyncResult is a number of floating ip routes changed by resync
+type FIPResyncResult struct {
+	Added        int `json:"Added"`
+	Updated      int `json:"Updated"`
+	Deleted      int `json:"Deleted"`
+	Reprogrammed int `json:"Reprogrammed"` // unchanged routes re-installed in vpp
+}
+
+// ResyncFIPs rebuilds floating ip routes from best vpnv4 paths received from tungsten fabric: missed routes are added,
+// routes with changed paths are re-created, routes without paths are deleted and unchanged routes are re-installed in vpp
+func ResyncFIPs(
+	ctx context.Context,
+	vppStream *api.Stream,
+	bgpSrv *server.BgpServer,
+	cfg config.Config,
+	storage *imdb.Storage,
+) (FIPResyncResult, error) {
+	var result FIPResyncResult
+
+	fipMu.Lock()
+	defer fipMu.Unlock()
+
+	bgpPeerToPeerTypeMap, err := storage.CreateBGPPeerToTypeMap()
+	if err != nil {
+		return result, fmt.Errorf("failed to get bgp peer to type map: %w", err)
+	}
+
+	vppVRFIDToNHMap, err := storage.VPPVRFStorage.CreateVRFIDToNextHopMap()
+	if err != nil {
+		return result, fmt.Errorf("failed to get vrf id to next-hop map: %w", err)
+	}
+
+	var vppAggregatedFIPs []*net.IPNet
+
+	for _, vrf := range storage.VPPVRFStorage.GetVRFs() {
+		for _, fipPrefix := range vrf.FIPPrefixes {
+			if _, parsedFIPPrefix, err := net.ParseCIDR(fipPrefix); err == nil {
+				vppAggregatedFIPs = append(vppAggregatedFIPs, parsedFIPPrefix)
+			}
+		}
+	}
+
+	paths, err := gobgp.ListPaths(ctx, bgpSrv, bgpapi.TableType_GLOBAL, bgpapi.Family_AFI_IP, bgpapi.Family_SAFI_MPLS_VPN, "", nil, false)
+	if err != nil {
+		return result, fmt.Errorf("failed to list bgp paths of global vpnv4 table: %w", err)
+	}
+
+	// floating ip routes expected by bgp table (best path of each rd)
+
+	expectedRoutes := make(map[string]*model.VPPIPRoute)
+
+	for _, path := range paths {
+		if !path.Best || path.IsWithdraw || !storage.BGPPeerStorage.IsTF(path.NeighborIp) {
+			continue
+		}
+
+		fromTF, _, parsedBGPNLRIAttrs, err := ParseBGPUpdate(
+			path,
+			vppVRFIDToNHMap,
+			bgpPeerToPeerTypeMap,
+			cfg.TFController.BGPPeerASN,
+			cfg.GoBGP.BGPLocalASN,
+		)
+		if err != nil || !fromTF || !netutils.IsFIP(parsedBGPNLRIAttrs.Prefix, vppAggregatedFIPs) {
+			continue
+		}
+
+		vppVRF := storage.VPPVRFStorage.GetVRF(parsedBGPNLRIAttrs.VRFID)
+		if vppVRF == nil {
+			continue
+		}
+
+		expectedRoute, ok := expectedRoutes[parsedBGPNLRIAttrs.Prefix]
+		if !ok {
+			route := model.NewVPPIPRoute(
+				parsedBGPNLRIAttrs.VRFID,
+				interface_types.InterfaceIndex(cfg.VPP.MainInterfaceID),
+				vppVRF.SubInterfaceID,
+				parsedBGPNLRIAttrs.Prefix,
+				nil,
+				nil,
+				nil,
+			)
+
+			expectedRoute = &route
+			expectedRoutes[parsedBGPNLRIAttrs.Prefix] = expectedRoute
+		}
+
+		expectedRoute.AddPath(parsedBGPNLRIAttrs.NextHop, model.UndefinedTunnelID, parsedBGPNLRIAttrs.MPLSLabel[0])
+	}
+
+	// delete floating ip routes without bgp paths
+
+	for _, storedRoute := range storage.VPPFIPRouteStorage.GetFIPRoutes() {
+		if _, ok := expectedRoutes[storedRoute.Prefix]; ok {
+			continue
+		}
+
+		vppVRF, bgpVRF := storage.VPPVRFStorage.GetVRF(storedRoute.VRFID), storage.BGPVRFStorage.GetVRF(storedRoute.VRFID)
+		if vppVRF == nil || bgpVRF == nil {
+			continue
+		}
+
+		if err := ChangeFIPRoute(ctx, *vppStream, bgpSrv, cfg, storage, storedRoute, nil, vppVRF, bgpVRF); err != nil {
+			logger.Error("failed to delete floating ip route without bgp paths", "prefix", storedRoute.Prefix, "error", err)
+
+			continue
+		}
+
+		result.Deleted++
+	}
+
+	// add missed and update changed floating ip routes, re-install unchanged ones
+
+	for prefix, expectedRoute := range expectedRoutes {
+		vppVRF, bgpVRF := storage.VPPVRFStorage.GetVRF(expectedRoute.VRFID), storage.BGPVRFStorage.GetVRF(expectedRoute.VRFID)
+		if vppVRF == nil || bgpVRF == nil {
+			continue
+		}
+
+		storedRoute := storage.VPPFIPRouteStorage.GetFIPRoute(prefix)
+
+		switch {
+		case storedRoute == nil:
+			if err := ChangeFIPRoute(ctx, *vppStream, bgpSrv, cfg, storage, nil, expectedRoute, vppVRF, bgpVRF); err != nil {
+				logger.Error("failed to add missed floating ip route", "prefix", prefix, "error", err)
+
+				continue
+			}
+
+			result.Added++
+		case !isSamePaths(storedRoute, expectedRoute):
+			if err := ChangeFIPRoute(ctx, *vppStream, bgpSrv, cfg, storage, storedRoute, expectedRoute, vppVRF, bgpVRF); err != nil {
+				logger.Error("failed to update changed floating ip route", "prefix", prefix, "error", err)
+
+				continue
+			}
+
+			result.Updated++
+		default:
+			liveRoute := LiveFIPRoute(*storedRoute, storage)
+
+			if err := vpp.ReplaceFIPRoutePaths(*vppStream, &liveRoute); err != nil {
+				logger.Error("failed to re-install floating ip route in vpp", "prefix", prefix, "error", err)
+
+				continue
+			}
+
+			result.Reprogrammed++
+		}
+	}
+
+	logger.Info(
+		"floating ip routes resynced from bgp table",
+		"added", result.Added,
+		"updated", result.Updated,
+		"deleted", result.Deleted,
+		"reprogrammed", result.Reprogrammed,
+	)
+
+	return result, nil
+}
+
+// isSamePaths checks floating ip routes have the same next-hops with the same labels
+func isSamePaths(a, b *model.VPPIPRoute) bool {
+	if len(a.NextHops) != len(b.NextHops) {
+		return false
+	}
+
+	labels := make(map[string]uint32, len(a.NextHops))
+
+	for i, nh := range a.NextHops {
+		labels[nh] = a.FIPMPLSLabels[i]
+	}
+
+	for i, nh := range b.NextHops {
+		if label, ok := labels[nh]; !ok || label != b.FIPMPLSLabels[i] {
+			return false
+		}
+	}
+
+	return true
+}
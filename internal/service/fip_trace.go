@@ -0,0 +1,433 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+
+	bgpapi "github.com/osrg/gobgp/v3/api"
+	"github.com/osrg/gobgp/v3/pkg/apiutil"
+	"github.com/osrg/gobgp/v3/pkg/server"
+	"go.fd.io/govpp/api"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/gobgp"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp"
+)
+
+// FIPTrace is the full path of a floating ip through all layers: bgp paths from tungsten fabric, memory storage, vpp fib,
+// udp tunnels and the aggregated prefix advertised to physical network. Inconsistencies between the layers are listed,
+// expected states worth attention (e.g. paths pruned as vrouter is unreachable) are listed as notes.
+type FIPTrace struct {
+	FIP             string                   `json:"FIP"`
+	VRFName         string                   `json:"VRFName,omitempty"`
+	VRFID           uint32                   `json:"VRFID"`
+	VRFLinkUp       bool                     `json:"VRFLinkUp"`
+	BGPPaths        []FIPTraceBGPPath        `json:"BGPPaths"`
+	MemFIPRoute     *model.VPPIPRoute        `json:"MemFIPRoute"`
+	VPPFIPRoute     *model.VPPIPRoute        `json:"VPPFIPRoute"`
+	UDPTunnels      []FIPTraceUDPTunnel      `json:"UDPTunnels"`
+	AggrPrefixes    []FIPTraceAggrAdvertised `json:"AggrPrefixes"`
+	Inconsistencies []string                 `json:"Inconsistencies"`
+	Notes           []string                 `json:"Notes,omitempty"`
+	Errors          []string                 `json:"Errors,omitempty"`
+}
+
+// FIPTraceBGPPath is a vpnv4 path to the floating ip received from tungsten fabric controller
+type FIPTraceBGPPath struct {
+	Peer    string `json:"Peer"`
+	RD      string `json:"RD"`
+	Label   uint32 `json:"Label"`
+	NextHop string `json:"NextHop"`
+	VRFID   uint32 `json:"VRFID"` // from route target tfASN:vrfID
+	Best    bool   `json:"Best"`
+}
+
+// FIPTraceUDPTunnel is an udp tunnel to the vrouter (next-hop of the floating ip) in memory storage and vpp
+type FIPTraceUDPTunnel struct {
+	DstIP     string              `json:"DstIP"`
+	MemTunnel *model.VPPUDPTunnel `json:"MemTunnel"`
+	VPPTunnel *model.VPPUDPTunnel `json:"VPPTunnel"`
+}
+
+// FIPTraceAggrAdvertised shows if the aggregated floating ip prefix is sent to physical network peer
+type FIPTraceAggrAdvertised struct {
+	Prefix     string `json:"Prefix"`
+	Peer       string `json:"Peer"`
+	Advertised bool   `json:"Advertised"`
+}
+
+// TraceFIP collects the floating ip state from gobgp, memory storage and vpp and checks the layers are consistent
+func TraceFIP(
+	ctx context.Context,
+	bgpSrv *server.BgpServer,
+	stream api.Stream,
+	cfg config.Config,
+	storage *imdb.Storage,
+	fip string,
+) (FIPTrace, error) {
+	fipAddr, err := netip.ParseAddr(fip)
+	if err != nil || !fipAddr.Is4() {
+		return FIPTrace{}, fmt.Errorf("wrong floating ip address %q", fip)
+	}
+
+	fipPrefix := netip.PrefixFrom(fipAddr, 32).String()
+
+	trace := FIPTrace{
+		FIP:             fip,
+		BGPPaths:        []FIPTraceBGPPath{},
+		UDPTunnels:      []FIPTraceUDPTunnel{},
+		AggrPrefixes:    []FIPTraceAggrAdvertised{},
+		Inconsistencies: []string{},
+	}
+
+	// vrf where the floating ip belongs to (by aggregated floating ip prefixes)
+
+	var (
+		vppVRF       *model.VPPVRFTable
+		aggrPrefixes []string
+	)
+
+	for _, vrf := range storage.VPPVRFStorage.GetVRFs() {
+		for _, aggrPrefix := range vrf.FIPPrefixes {
+			prefix, err := netip.ParsePrefix(aggrPrefix)
+			if err != nil || !prefix.Contains(fipAddr) {
+				continue
+			}
+
+			vppVRF = vrf
+
+			aggrPrefixes = append(aggrPrefixes, aggrPrefix)
+		}
+
+		if vppVRF != nil {
+			break
+		}
+	}
+
+	if vppVRF == nil {
+		trace.inconsistent("floating ip does not belong to any aggregated floating ip prefix of the vrfs")
+	} else {
+		trace.VRFName = vppVRF.Name
+		trace.VRFID = vppVRF.ID
+		trace.VRFLinkUp = vppVRF.LinkUp
+	}
+
+	// bgp paths from tungsten fabric controllers
+
+	trace.BGPPaths = traceBGPPaths(ctx, bgpSrv, cfg, storage, fipPrefix, &trace)
+
+	for _, path := range trace.BGPPaths {
+		if vppVRF != nil && path.VRFID != vppVRF.ID {
+			trace.inconsistent(fmt.Sprintf("bgp path from %s is imported to vrf %d, expected vrf %d", path.Peer, path.VRFID, vppVRF.ID))
+		}
+	}
+
+	// floating ip route in memory storage
+
+	trace.MemFIPRoute = storage.VPPFIPRouteStorage.GetFIPRoute(fipPrefix)
+
+	switch {
+	case trace.MemFIPRoute == nil && len(trace.BGPPaths) != 0:
+		trace.inconsistent("bgp paths received, but floating ip route is not in memory storage")
+	case trace.MemFIPRoute != nil && len(trace.BGPPaths) == 0:
+		trace.inconsistent("floating ip route is in memory storage, but no bgp paths received")
+	case trace.MemFIPRoute != nil:
+		for _, path := range trace.BGPPaths {
+			i := slices.Index(trace.MemFIPRoute.NextHops, path.NextHop)
+
+			switch {
+			case i < 0:
+				trace.inconsistent(fmt.Sprintf("bgp path next-hop %s is not in memory storage route", path.NextHop))
+			case trace.MemFIPRoute.FIPMPLSLabels[i] != path.Label:
+				trace.inconsistent(fmt.Sprintf(
+					"bgp path label %d differs from memory storage route label %d (next-hop %s)",
+					path.Label, trace.MemFIPRoute.FIPMPLSLabels[i], path.NextHop,
+				))
+			}
+		}
+	}
+
+	// udp tunnels to vrouters in memory storage and vpp
+
+	vppTunnels, err := vpp.DumpUDPTunnels(stream)
+	if err != nil {
+		trace.Errors = append(trace.Errors, fmt.Sprintf("failed to dump udp tunnels from vpp: %s", err))
+	}
+
+	vppTunnelByID := make(map[uint32]model.VPPUDPTunnel, len(vppTunnels))
+
+	for _, tunnel := range vppTunnels {
+		vppTunnelByID[tunnel.TunnelID] = tunnel
+	}
+
+	if trace.MemFIPRoute != nil {
+		liveRoute := LiveFIPRoute(*trace.MemFIPRoute, storage)
+
+		for i, nh := range trace.MemFIPRoute.NextHops {
+			traceTunnel := FIPTraceUDPTunnel{DstIP: nh, MemTunnel: storage.VPPUDPTunnelStorage.GetUDPTunnel(nh)}
+
+			if vppTunnel, ok := vppTunnelByID[trace.MemFIPRoute.TunnelIDs[i]]; ok {
+				traceTunnel.VPPTunnel = &vppTunnel
+			}
+
+			switch {
+			case traceTunnel.MemTunnel == nil:
+				trace.inconsistent(fmt.Sprintf("udp tunnel to %s is not in memory storage", nh))
+			case traceTunnel.MemTunnel.TunnelID != trace.MemFIPRoute.TunnelIDs[i]:
+				trace.inconsistent(fmt.Sprintf(
+					"udp tunnel to %s has id %d in memory storage, floating ip route uses id %d",
+					nh, traceTunnel.MemTunnel.TunnelID, trace.MemFIPRoute.TunnelIDs[i],
+				))
+			case !traceTunnel.MemTunnel.Reachable && slices.Contains(liveRoute.NextHops, nh):
+				trace.note(fmt.Sprintf("vrouter %s is unreachable, the path is kept in vpp as no vrouter of the route is reachable", nh))
+			case !traceTunnel.MemTunnel.Reachable:
+				trace.note(fmt.Sprintf("vrouter %s is unreachable, the path is pruned in vpp", nh))
+			}
+
+			switch {
+			case traceTunnel.VPPTunnel == nil:
+				trace.inconsistent(fmt.Sprintf("udp tunnel %d to %s is not in vpp", trace.MemFIPRoute.TunnelIDs[i], nh))
+			case traceTunnel.VPPTunnel.DstIP != nh:
+				trace.inconsistent(fmt.Sprintf(
+					"udp tunnel %d leads to %s in vpp, expected %s",
+					trace.MemFIPRoute.TunnelIDs[i], traceTunnel.VPPTunnel.DstIP, nh,
+				))
+			}
+
+			trace.UDPTunnels = append(trace.UDPTunnels, traceTunnel)
+		}
+	}
+
+	// floating ip route in vpp
+
+	if vppVRF != nil {
+		vppRoute := model.NewVPPIPRoute(vppVRF.ID, vppVRF.MainInterfaceID, vppVRF.SubInterfaceID, fipPrefix, nil, nil, nil)
+
+		isFound, err := vpp.LookupFIPRoute(stream, &vppRoute)
+		if err != nil {
+			trace.Errors = append(trace.Errors, fmt.Sprintf("failed to lookup floating ip route in vpp: %s", err))
+		}
+
+		if isFound {
+			for _, tunnelID := range vppRoute.TunnelIDs {
+				vppRoute.NextHops = append(vppRoute.NextHops, vppTunnelByID[tunnelID].DstIP)
+			}
+
+			trace.VPPFIPRoute = &vppRoute
+		}
+
+		traceVPPRoute(&trace, storage, err == nil)
+	}
+
+	// aggregated floating ip prefixes sent to physical network
+
+	if vppVRF != nil {
+		trace.AggrPrefixes = traceAggrPrefixes(ctx, bgpSrv, cfg, storage, vppVRF, aggrPrefixes, &trace)
+	}
+
+	return trace, nil
+}
+
+// traceBGPPaths returns vpnv4 paths to the floating ip received from all tungsten fabric controllers
+func traceBGPPaths(
+	ctx context.Context,
+	bgpSrv *server.BgpServer,
+	cfg config.Config,
+	storage *imdb.Storage,
+	fipPrefix string,
+	trace *FIPTrace,
+) []FIPTraceBGPPath {
+	paths := []FIPTraceBGPPath{}
+
+	for _, peer := range storage.BGPPeerStorage.GetBGPPeers() {
+		if peer.PeerType != model.TF {
+			continue
+		}
+
+		// vpnv4 adj-in is not filtered by prefix as the lookup needs rd
+		adjInPaths, err := gobgp.ListPaths(ctx, bgpSrv, bgpapi.TableType_ADJ_IN, peer.AFI, peer.SAFI, peer.PeerAddress, nil, false)
+		if err != nil {
+			trace.Errors = append(trace.Errors, fmt.Sprintf("failed to list bgp paths from %s: %s", peer.PeerAddress, err))
+
+			continue
+		}
+
+		for _, path := range adjInPaths {
+			if path.IsWithdraw {
+				continue
+			}
+
+			nlri := &bgpapi.LabeledVPNIPAddressPrefix{}
+
+			if err := path.Nlri.UnmarshalTo(nlri); err != nil {
+				continue
+			}
+
+			if fmt.Sprintf("%s/%d", nlri.Prefix, nlri.PrefixLen) != fipPrefix {
+				continue
+			}
+
+			tracePath := FIPTraceBGPPath{Peer: peer.PeerAddress, Best: path.Best}
+
+			if rd, err := apiutil.UnmarshalRD(nlri.Rd); err == nil {
+				tracePath.RD = rd.String()
+			}
+
+			if len(nlri.Labels) != 0 {
+				tracePath.Label = nlri.Labels[0]
+			}
+
+			for _, pattr := range path.Pattrs {
+				mpReach := &bgpapi.MpReachNLRIAttribute{}
+				extCommunities := &bgpapi.ExtendedCommunitiesAttribute{}
+
+				switch {
+				case pattr.UnmarshalTo(mpReach) == nil:
+					if len(mpReach.NextHops) != 0 {
+						tracePath.NextHop = mpReach.NextHops[0]
+					}
+				case pattr.UnmarshalTo(extCommunities) == nil:
+					for _, community := range extCommunities.Communities {
+						rt := &bgpapi.TwoOctetAsSpecificExtended{}
+
+						if community.UnmarshalTo(rt) == nil && rt.Asn == cfg.TFController.BGPPeerASN {
+							tracePath.VRFID = rt.LocalAdmin
+						}
+					}
+				}
+			}
+
+			paths = append(paths, tracePath)
+		}
+	}
+
+	return paths
+}
+
+// traceVPPRoute compares floating ip route in vpp with memory storage route (paths through reachable vrouters only)
+func traceVPPRoute(trace *FIPTrace, storage *imdb.Storage, isLookedUp bool) {
+	if !isLookedUp {
+		return
+	}
+
+	switch {
+	case trace.MemFIPRoute != nil && trace.VPPFIPRoute == nil:
+		trace.inconsistent("floating ip route is in memory storage, but not in vpp")
+	case trace.MemFIPRoute == nil && trace.VPPFIPRoute != nil:
+		trace.inconsistent("floating ip route is in vpp, but not in memory storage")
+	case trace.MemFIPRoute != nil:
+		liveRoute := LiveFIPRoute(*trace.MemFIPRoute, storage)
+
+		expected := make(map[uint32]uint32, len(liveRoute.TunnelIDs))
+
+		for i, tunnelID := range liveRoute.TunnelIDs {
+			expected[tunnelID] = liveRoute.FIPMPLSLabels[i]
+		}
+
+		for i, tunnelID := range trace.VPPFIPRoute.TunnelIDs {
+			label, ok := expected[tunnelID]
+
+			memIdx := slices.Index(trace.MemFIPRoute.TunnelIDs, tunnelID)
+
+			switch {
+			case !ok && memIdx >= 0:
+				trace.inconsistent(fmt.Sprintf(
+					"vpp route still has path via unreachable vrouter %s (udp tunnel %d)",
+					trace.MemFIPRoute.NextHops[memIdx], tunnelID,
+				))
+			case !ok:
+				trace.inconsistent(fmt.Sprintf("vpp route has unexpected path via udp tunnel %d", tunnelID))
+			case label != trace.VPPFIPRoute.FIPMPLSLabels[i]:
+				trace.inconsistent(fmt.Sprintf(
+					"vpp route label %d differs from memory storage label %d (udp tunnel %d)",
+					trace.VPPFIPRoute.FIPMPLSLabels[i], label, tunnelID,
+				))
+			}
+
+			delete(expected, tunnelID)
+		}
+
+		for tunnelID := range expected {
+			trace.inconsistent(fmt.Sprintf("vpp route has no path via udp tunnel %d", tunnelID))
+		}
+	}
+}
+
+// traceAggrPrefixes checks aggregated floating ip prefixes in adj-out of physical network peers of the vrf
+func traceAggrPrefixes(
+	ctx context.Context,
+	bgpSrv *server.BgpServer,
+	cfg config.Config,
+	storage *imdb.Storage,
+	vppVRF *model.VPPVRFTable,
+	aggrPrefixes []string,
+	trace *FIPTrace,
+) []FIPTraceAggrAdvertised {
+	advertisements := []FIPTraceAggrAdvertised{}
+
+	// the standby of ha pair in withdraw mode does not advertise the prefixes
+
+	_, isAdvertisedByRole := aggrPathPreference(cfg)
+
+	shouldAdvertise := vppVRF.LinkUp && storage.VPPVRFStorage.GetFIPServed(vppVRF.ID) != 0 && !vppVRF.Drained && isAdvertisedByRole
+
+	for _, peer := range storage.BGPPeerStorage.GetBGPPeers() {
+		if peer.PeerType != model.PHYNET || peer.VRFName != vppVRF.Name {
+			continue
+		}
+
+		for _, aggrPrefix := range aggrPrefixes {
+			adjOutPaths, err := gobgp.ListPaths(
+				ctx,
+				bgpSrv,
+				bgpapi.TableType_ADJ_OUT,
+				peer.AFI,
+				peer.SAFI,
+				peer.PeerAddress,
+				[]string{aggrPrefix},
+				false,
+			)
+			if err != nil {
+				trace.Errors = append(trace.Errors, fmt.Sprintf("failed to list bgp paths to %s: %s", peer.PeerAddress, err))
+
+				continue
+			}
+
+			advertisement := FIPTraceAggrAdvertised{Prefix: aggrPrefix, Peer: peer.PeerAddress}
+
+			for _, path := range adjOutPaths {
+				if !path.IsWithdraw {
+					advertisement.Advertised = true
+				}
+			}
+
+			switch {
+			case peer.BGPPeerState != bgpapi.PeerState_ESTABLISHED:
+				trace.inconsistent(fmt.Sprintf("bgp session to physical network peer %s is %s", peer.PeerAddress, peer.BGPPeerState))
+			case shouldAdvertise && !advertisement.Advertised:
+				trace.inconsistent(fmt.Sprintf("aggregated prefix %s is not advertised to %s", aggrPrefix, peer.PeerAddress))
+			case !shouldAdvertise && advertisement.Advertised:
+				trace.inconsistent(fmt.Sprintf(
+					"aggregated prefix %s is advertised to %s, but vrf link is down, no floating ip served, vrf is drained or ha standby withdraws it",
+					aggrPrefix, peer.PeerAddress,
+				))
+			}
+
+			advertisements = append(advertisements, advertisement)
+		}
+	}
+
+	return advertisements
+}
+
+func (t *FIPTrace) inconsistent(msg string) {
+	t.Inconsistencies = append(t.Inconsistencies, msg)
+}
+
+func (t *FIPTrace) note(msg string) {
+	t.Notes = append(t.Notes, msg)
+}
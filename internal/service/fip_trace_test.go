@@ -0,0 +1,230 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	bgpapi "github.com/osrg/gobgp/v3/api"
+	"github.com/osrg/gobgp/v3/pkg/server"
+	"github.com/phayes/freeport"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/gobgp"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp/dryrun"
+	"git.crptech.ru/cloud/cloudgw/internal/service"
+	"git.crptech.ru/cloud/cloudgw/pkg/gobgpapi"
+)
+
+// tfPeering is a vpnv4 session of the local gobgp server with the gobgp server emulating tungsten fabric controller
+// on another loopback address. The session is ibgp, so the next hops (vrouters) of the advertised paths are kept.
+type tfPeering struct {
+	local *server.BgpServer
+	tf    *server.BgpServer
+	peer  model.BGPPeer // tungsten fabric controller peer of the local server
+}
+
+func newTFPeering(ctx context.Context, t *testing.T) *tfPeering {
+	t.Helper()
+
+	port, err := freeport.GetFreePort()
+	require.NoError(t, err)
+
+	p := &tfPeering{
+		local: server.NewBgpServer(),
+		tf:    server.NewBgpServer(),
+		peer:  model.NewBGPPeer(model.TF, 65000, "127.0.0.2", uint32(port), "", false, 0, "", 10, 30),
+	}
+
+	for _, srv := range []*server.BgpServer{p.local, p.tf} {
+		go srv.Serve()
+
+		t.Cleanup(srv.Stop)
+	}
+
+	require.NoError(t, p.local.StartBgp(ctx, &bgpapi.StartBgpRequest{
+		Global: &bgpapi.Global{Asn: 65000, RouterId: "192.0.2.1", ListenPort: -1},
+	}))
+	require.NoError(t, p.tf.StartBgp(ctx, &bgpapi.StartBgpRequest{
+		Global: &bgpapi.Global{Asn: 65000, RouterId: "192.0.2.2", ListenPort: int32(port), ListenAddresses: []string{p.peer.PeerAddress}},
+	}))
+
+	require.NoError(t, gobgp.AddBGPPeer(ctx, p.local, &p.peer))
+	require.NoError(t, p.tf.AddPeer(ctx, &bgpapi.AddPeerRequest{Peer: &bgpapi.Peer{
+		Conf:      &bgpapi.PeerConf{NeighborAddress: "127.0.0.1", PeerAsn: 65000},
+		Transport: &bgpapi.Transport{PassiveMode: true, LocalAddress: p.peer.PeerAddress},
+		AfiSafis: []*bgpapi.AfiSafi{{
+			Config: &bgpapi.AfiSafiConfig{Family: &bgpapi.Family{Afi: p.peer.AFI, Safi: p.peer.SAFI}},
+		}},
+	}}))
+
+	// gobgp connects after the connect retry timer (5-10s)
+
+	require.Eventually(t, func() bool {
+		var state bgpapi.PeerState_SessionState
+
+		require.NoError(t, p.local.ListPeer(ctx, &bgpapi.ListPeerRequest{Address: p.peer.PeerAddress}, func(peer *bgpapi.Peer) {
+			state = peer.State.SessionState
+		}))
+
+		return state == bgpapi.PeerState_ESTABLISHED
+	}, 30*time.Second, 100*time.Millisecond)
+
+	return p
+}
+
+// advertise sends vpnv4 paths from tungsten fabric controller and waits for them in adj-in of the local server
+func (p *tfPeering) advertise(ctx context.Context, t *testing.T, paths ...gobgpapi.BGPNLRIAttrs) {
+	t.Helper()
+
+	for _, path := range paths {
+		require.NoError(t, gobgp.AdvWdrawVpnv4Prefix(ctx, p.tf, true, path, 64512, gobgp.PathPreference{}))
+	}
+
+	require.Eventually(t, func() bool {
+		adjIn, err := gobgp.ListPaths(ctx, p.local, bgpapi.TableType_ADJ_IN, p.peer.AFI, p.peer.SAFI, p.peer.PeerAddress, nil, false)
+		require.NoError(t, err)
+
+		return len(adjIn) == len(paths)
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestTraceFIP(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := config.Config{TFController: config.TFController{BGPPeerASN: 64512}}
+
+	// floating ip 172.16.1.10 of vrf1 via vrouters 10.1.1.1 (label 25) and 10.1.1.2 (label 26)
+
+	peering := newTFPeering(ctx, t)
+
+	peering.advertise(ctx, t,
+		gobgpapi.BGPNLRIAttrs{
+			Prefix: "172.16.1.10/32", NextHop: "10.1.1.1", RD: model.RD("10.1.1.1", 1),
+			RT: []*anypb.Any{model.RT(64512, 1)}, MPLSLabel: []uint32{25},
+		},
+		gobgpapi.BGPNLRIAttrs{
+			Prefix: "172.16.1.10/32", NextHop: "10.1.1.2", RD: model.RD("10.1.1.2", 1),
+			RT: []*anypb.Any{model.RT(64512, 1)}, MPLSLabel: []uint32{26},
+		},
+	)
+
+	vrf := model.VPPVRFTable{Name: "vrf1", ID: 1, MainInterfaceID: 1, FIPPrefixes: []string{"172.16.1.0/24"}, LinkUp: true}
+	vrouters := []string{"10.1.1.1", "10.1.1.2"}
+
+	tests := []struct {
+		name                string
+		unreachable         []string
+		vppPaths            []int // indexes of vrouters with paths in vpp
+		vppLabels           []uint32
+		wantInconsistencies []string
+		wantNotes           []string
+	}{
+		{
+			name:                "consistent",
+			vppPaths:            []int{0, 1},
+			wantInconsistencies: []string{},
+		},
+		{
+			name:                "path via unreachable vrouter pruned",
+			unreachable:         []string{"10.1.1.2"},
+			vppPaths:            []int{0},
+			wantInconsistencies: []string{},
+			wantNotes:           []string{"vrouter 10.1.1.2 is unreachable, the path is pruned in vpp"},
+		},
+		{
+			name:                "path via unreachable vrouter not pruned",
+			unreachable:         []string{"10.1.1.2"},
+			vppPaths:            []int{0, 1},
+			wantInconsistencies: []string{"vpp route still has path via unreachable vrouter 10.1.1.2 (udp tunnel 2)"},
+			wantNotes:           []string{"vrouter 10.1.1.2 is unreachable, the path is pruned in vpp"},
+		},
+		{
+			name:                "all vrouters unreachable",
+			unreachable:         []string{"10.1.1.1", "10.1.1.2"},
+			vppPaths:            []int{0, 1},
+			wantInconsistencies: []string{},
+			wantNotes: []string{
+				"vrouter 10.1.1.1 is unreachable, the path is kept in vpp as no vrouter of the route is reachable",
+				"vrouter 10.1.1.2 is unreachable, the path is kept in vpp as no vrouter of the route is reachable",
+			},
+		},
+		{
+			name:                "path via reachable vrouter missing in vpp",
+			vppPaths:            []int{0},
+			wantInconsistencies: []string{"vpp route has no path via udp tunnel 2"},
+		},
+		{
+			name:                "vpp label differs",
+			vppPaths:            []int{0, 1},
+			vppLabels:           []uint32{25, 36},
+			wantInconsistencies: []string{"vpp route label 36 differs from memory storage label 26 (udp tunnel 2)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := dryrun.NewStream(ctx)
+			storage := imdb.NewStorage()
+
+			require.NoError(t, vpp.AddDelVRF(stream, true, vrf))
+			require.NoError(t, storage.VPPVRFStorage.AddVRF(&vrf))
+			require.NoError(t, storage.BGPPeerStorage.AddBGPPeer(&peering.peer))
+
+			// udp tunnel id 0 is not used by the paths, it leads to another vrouter
+
+			other := model.NewVPPUDPTunnel(model.UndefinedTunnelID, "192.0.0.1", "10.1.1.9", 50000)
+			require.NoError(t, vpp.AddUDPTunnel(stream, &other))
+
+			memRoute := model.NewVPPIPRoute(vrf.ID, vrf.MainInterfaceID, model.UndefinedSubIf, "172.16.1.10/32", nil, nil, nil)
+
+			for i, nh := range vrouters {
+				tunnel := model.NewVPPUDPTunnel(model.UndefinedTunnelID, "192.0.0.1", nh, 50000)
+
+				require.NoError(t, vpp.AddUDPTunnel(stream, &tunnel))
+				require.NoError(t, storage.VPPUDPTunnelStorage.AddUDPTunnel(&tunnel))
+
+				memRoute.AddPath(nh, tunnel.TunnelID, uint32(25+i))
+			}
+
+			require.NoError(t, storage.VPPFIPRouteStorage.AddFIPRoute(&memRoute))
+
+			for _, nh := range tt.unreachable {
+				storage.VPPUDPTunnelStorage.SetReachable(nh, false)
+			}
+
+			vppRoute := model.NewVPPIPRoute(vrf.ID, vrf.MainInterfaceID, model.UndefinedSubIf, memRoute.Prefix, nil, nil, nil)
+
+			for _, i := range tt.vppPaths {
+				label := memRoute.FIPMPLSLabels[i]
+
+				if tt.vppLabels != nil {
+					label = tt.vppLabels[i]
+				}
+
+				vppRoute.AddPath(memRoute.NextHops[i], memRoute.TunnelIDs[i], label)
+			}
+
+			require.NoError(t, vpp.AddDelFIPRoute(stream, true, &vppRoute))
+
+			trace, err := service.TraceFIP(ctx, peering.local, stream, cfg, storage, "172.16.1.10")
+			require.NoError(t, err)
+
+			require.Empty(t, trace.Errors)
+			require.Equal(t, "vrf1", trace.VRFName)
+			require.ElementsMatch(t, []service.FIPTraceBGPPath{
+				{Peer: "127.0.0.2", RD: "10.1.1.1:1", Label: 25, NextHop: "10.1.1.1", VRFID: 1},
+				{Peer: "127.0.0.2", RD: "10.1.1.2:1", Label: 26, NextHop: "10.1.1.2", VRFID: 1},
+			}, trace.BGPPaths)
+			require.Len(t, trace.UDPTunnels, 2)
+			require.Equal(t, tt.wantInconsistencies, trace.Inconsistencies)
+			require.Equal(t, tt.wantNotes, trace.Notes)
+		})
+	}
+}
@@ -0,0 +1,320 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/osrg/gobgp/v3/pkg/server"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/gobgp"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/pkg/logger"
+)
+
+// gateway drain phases
+const (
+	DrainPhaseNone        = "none"
+	DrainPhaseDepreferred = "depreferred" // prefixes are advertised with Drain.Methods attributes, traffic is waited to fall
+	DrainPhaseWithdrawn   = "withdrawn"   // prefixes are withdrawn, the gateway can be shut down
+)
+
+// ErrDrainCanceled is returned by waiting for the gateway drain canceled by undrain
+var ErrDrainCanceled = errors.New("gateway drain is canceled")
+
+// drainPhase is the gateway drain phase aggregated floating ip prefixes are advertised by (DrainPhaseNone if empty)
+var drainPhase atomic.Value
+
+// drainPathPreference adds Drain.Methods attributes to the preference while the gateway is depreferred, false is
+// returned if the gateway drain withdrew the prefixes
+func drainPathPreference(cfg config.Config, pref gobgp.PathPreference) (gobgp.PathPreference, bool) {
+	switch phase, _ := drainPhase.Load().(string); phase {
+	case DrainPhaseWithdrawn:
+		return pref, false
+	case DrainPhaseDepreferred:
+	default:
+		return pref, true
+	}
+
+	if slices.Contains(cfg.Drain.Methods, config.DrainPrepend) {
+		for range cfg.Drain.Prepend {
+			pref.Prepend = append(pref.Prepend, cfg.GoBGP.BGPLocalASN)
+		}
+	}
+
+	if slices.Contains(cfg.Drain.Methods, config.DrainMED) {
+		pref.MED = cfg.Drain.MED
+	}
+
+	if slices.Contains(cfg.Drain.Methods, config.DrainGShut) {
+		pref.Communities = append(pref.Communities, gobgp.GracefulShutdownCommunity)
+	}
+
+	return pref, true
+}
+
+// GatewayDrainStatus is the state of the gateway drain
+type GatewayDrainStatus struct {
+	Phase            string    `json:"Phase"`
+	StartedAt        time.Time `json:"StartedAt"`   // zero if the gateway is not drained
+	WithdrawnAt      time.Time `json:"WithdrawnAt"` // zero until the prefixes are withdrawn
+	TrafficPPS       uint64    `json:"TrafficPPS"`  // the last measured packet rate of vrf sub-interfaces (received and sent)
+	TrafficThreshold uint64    `json:"TrafficThreshold"`
+	TimedOut         bool      `json:"TimedOut"` // the prefixes are withdrawn on Drain.Timeout with traffic above the threshold
+}
+
+// GatewayDrain moves traffic away from the gateway before maintenance: aggregated floating ip prefixes of all vrfs
+// are advertised with Drain.Methods attributes making physical network prefer other gateways, then, when traffic of
+// vrf sub-interfaces falls below Drain.TrafficThreshold (or on Drain.Timeout), the prefixes are withdrawn. Floating
+// ip routes stay installed in vpp, so the remaining traffic is forwarded until the gateway is shut down.
+type GatewayDrain struct {
+	ctx     context.Context
+	bgpSrv  *server.BgpServer
+	cfg     config.Config
+	storage *imdb.Storage
+	packets func() (uint64, error) // packets received and sent by vrf sub-interfaces, nil if vpp stats are not connected
+
+	startMu    sync.Mutex // serializes Start, Stop and Shutdown
+	isShutdown bool       // prefixes are withdrawn by Shutdown, neither drain nor undrain changes them
+	cancel     context.CancelFunc
+	done       chan struct{} // closed when the drain goroutine is finished
+	withdrawn  chan struct{} // closed when the prefixes are withdrawn
+
+	mu     sync.Mutex
+	status GatewayDrainStatus
+}
+
+// NewGatewayDrain creates the gateway drain, the drain goroutine is stopped with the context
+func NewGatewayDrain(
+	ctx context.Context,
+	bgpSrv *server.BgpServer,
+	cfg config.Config,
+	storage *imdb.Storage,
+	packets func() (uint64, error),
+) *GatewayDrain {
+	// the gateway is not drained until the drain is started, the phase of previous drain (if any) is not kept
+
+	drainPhase.Store(DrainPhaseNone)
+
+	return &GatewayDrain{
+		ctx:     ctx,
+		bgpSrv:  bgpSrv,
+		cfg:     cfg,
+		storage: storage,
+		packets: packets,
+		status:  GatewayDrainStatus{Phase: DrainPhaseNone, TrafficThreshold: cfg.Drain.TrafficThreshold},
+	}
+}
+
+// Start depreferes the aggregated floating ip prefixes and withdraws them in background once traffic falls, the
+// started drain is not restarted
+func (d *GatewayDrain) Start() GatewayDrainStatus {
+	d.startMu.Lock()
+	defer d.startMu.Unlock()
+
+	if d.cancel != nil || d.isShutdown {
+		return d.Status()
+	}
+
+	ctx, cancel := context.WithCancel(d.ctx)
+
+	d.setPhase(ctx, DrainPhaseDepreferred)
+
+	d.mu.Lock()
+	d.cancel = cancel
+	d.done = make(chan struct{})
+	d.withdrawn = make(chan struct{})
+	d.status = GatewayDrainStatus{Phase: DrainPhaseDepreferred, StartedAt: time.Now(), TrafficThreshold: d.cfg.Drain.TrafficThreshold}
+	d.mu.Unlock()
+
+	logger.Warn("gateway drain started, aggregated floating ip prefixes depreferred", "methods", d.cfg.Drain.Methods)
+
+	go d.run(ctx, d.done, d.withdrawn)
+
+	return d.Status()
+}
+
+// Stop cancels the drain and advertises the aggregated floating ip prefixes back with normal attributes
+func (d *GatewayDrain) Stop(ctx context.Context) GatewayDrainStatus {
+	d.startMu.Lock()
+	defer d.startMu.Unlock()
+
+	if d.cancel == nil || d.isShutdown {
+		return d.Status()
+	}
+
+	d.cancel()
+	<-d.done
+
+	d.setPhase(ctx, DrainPhaseNone)
+
+	d.mu.Lock()
+	d.cancel, d.done, d.withdrawn = nil, nil, nil
+	d.status = GatewayDrainStatus{Phase: DrainPhaseNone, TrafficThreshold: d.cfg.Drain.TrafficThreshold}
+	d.mu.Unlock()
+
+	logger.Warn("gateway drain canceled, aggregated floating ip prefixes advertised")
+
+	return d.Status()
+}
+
+// Shutdown withdraws the aggregated floating ip prefixes at once without waiting for traffic, the running drain is
+// finished first. The prefixes are not advertised back, it is the first phase of the app shutdown.
+func (d *GatewayDrain) Shutdown(ctx context.Context) error {
+	d.startMu.Lock()
+	defer d.startMu.Unlock()
+
+	if d.cancel != nil {
+		d.cancel()
+		<-d.done
+	}
+
+	d.isShutdown = true
+
+	d.setPhase(ctx, DrainPhaseWithdrawn)
+
+	d.mu.Lock()
+	if d.status.Phase != DrainPhaseWithdrawn {
+		d.status.Phase, d.status.WithdrawnAt = DrainPhaseWithdrawn, time.Now()
+	}
+	d.mu.Unlock()
+
+	logger.Info("aggregated floating ip prefixes withdrawn on shutdown")
+
+	return ctx.Err()
+}
+
+// Wait waits until the aggregated floating ip prefixes are withdrawn by the started drain
+func (d *GatewayDrain) Wait(ctx context.Context) error {
+	d.mu.Lock()
+	done, withdrawn := d.done, d.withdrawn
+	d.mu.Unlock()
+
+	if done == nil {
+		return ErrDrainCanceled
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-withdrawn:
+		return nil
+	case <-done:
+		// the drain goroutine is finished by Stop or by the context before the prefixes are withdrawn
+
+		select {
+		case <-withdrawn:
+			return nil
+		default:
+			return ErrDrainCanceled
+		}
+	}
+}
+
+// Status returns the state of the gateway drain
+func (d *GatewayDrain) Status() GatewayDrainStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.status
+}
+
+func (d *GatewayDrain) run(ctx context.Context, done, withdrawn chan struct{}) {
+	defer close(done)
+
+	isTimedOut, err := d.waitTraffic(ctx)
+	if err != nil {
+		return
+	}
+
+	// Stop waits for the goroutine before the prefixes are advertised back
+
+	d.setPhase(ctx, DrainPhaseWithdrawn)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.status.Phase = DrainPhaseWithdrawn
+	d.status.WithdrawnAt = time.Now()
+	d.status.TimedOut = isTimedOut
+
+	close(withdrawn)
+
+	logger.Warn("gateway drained, aggregated floating ip prefixes withdrawn", "timed out", isTimedOut,
+		"traffic pps", d.status.TrafficPPS)
+}
+
+// waitTraffic waits until packet rate of vrf sub-interfaces falls below the threshold, true is returned on timeout
+func (d *GatewayDrain) waitTraffic(ctx context.Context) (bool, error) {
+	if d.packets == nil {
+		logger.Warn("vpp stats are not connected, gateway drain does not wait for traffic to fall")
+
+		return false, nil
+	}
+
+	interval := time.Duration(d.cfg.Drain.CheckInterval) * time.Second
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	timeout := time.NewTimer(time.Duration(d.cfg.Drain.Timeout) * time.Second)
+	defer timeout.Stop()
+
+	prev, prevErr := d.packets()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-timeout.C:
+			logger.Warn("gateway drain timed out, traffic is above the threshold", "threshold pps", d.cfg.Drain.TrafficThreshold)
+
+			return true, nil
+		case <-ticker.C:
+		}
+
+		cur, err := d.packets()
+		if err != nil {
+			logger.Error("failed to get vrf sub-interfaces traffic counters", "error", err)
+
+			prevErr = err
+
+			continue
+		}
+
+		// counters are compared after two successful reads only, cleared counters are read again
+
+		if prevErr != nil || cur < prev {
+			prev, prevErr = cur, nil
+
+			continue
+		}
+
+		pps := (cur - prev) / uint64(d.cfg.Drain.CheckInterval)
+		prev = cur
+
+		d.mu.Lock()
+		d.status.TrafficPPS = pps
+		d.mu.Unlock()
+
+		if pps < d.cfg.Drain.TrafficThreshold {
+			return false, nil
+		}
+
+		logger.Info("gateway drain waits for traffic to fall", "traffic pps", pps, "threshold pps", d.cfg.Drain.TrafficThreshold)
+	}
+}
+
+// setPhase changes attributes of the advertised aggregated floating ip prefixes (or withdraws them) by the phase
+func (d *GatewayDrain) setPhase(ctx context.Context, phase string) {
+	fipMu.Lock()
+	defer fipMu.Unlock()
+
+	drainPhase.Store(phase)
+
+	refreshAggrPrefixes(ctx, d.bgpSrv, d.cfg, d.storage)
+}
@@ -0,0 +1,154 @@
+package service_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	bgpapi "github.com/osrg/gobgp/v3/api"
+	"github.com/osrg/gobgp/v3/pkg/server"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/gobgp"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/internal/service"
+)
+
+// aggrPath returns the aggregated prefix path of the global rib, nil if it is not advertised
+func aggrPath(t *testing.T, bgpSrv *server.BgpServer) *bgpapi.Path {
+	paths, err := gobgp.ListPaths(context.Background(), bgpSrv, bgpapi.TableType_GLOBAL, bgpapi.Family_AFI_IP,
+		bgpapi.Family_SAFI_MPLS_VPN, "", nil, false)
+	require.NoError(t, err)
+
+	if len(paths) == 0 {
+		return nil
+	}
+
+	return paths[0]
+}
+
+func TestGatewayDrain(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bgpSrv := server.NewBgpServer()
+
+	go bgpSrv.Serve()
+
+	defer bgpSrv.Stop()
+
+	require.NoError(t, bgpSrv.StartBgp(ctx, &bgpapi.StartBgpRequest{
+		Global: &bgpapi.Global{Asn: 65000, RouterId: "192.0.2.1", ListenPort: -1},
+	}))
+
+	cfg := config.Config{
+		TFController: config.TFController{BGPPeerASN: 64512},
+		GoBGP:        config.GoBGP{BGPLocalASN: 65000},
+		Drain: config.Drain{
+			Methods: []string{config.DrainGShut, config.DrainMED}, MED: 1000, TrafficThreshold: 100, CheckInterval: 1, Timeout: 60,
+		},
+	}
+
+	storage := imdb.NewStorage()
+
+	bgpVRF := model.NewBGPVRFTable("vrf1", 1, 65000, 65100, model.RD("192.0.2.1", 1),
+		[]*anypb.Any{model.RT(65000, 1)}, []*anypb.Any{model.RT(64512, 1)})
+	require.NoError(t, storage.BGPVRFStorage.AddVRF(&bgpVRF))
+	require.NoError(t, storage.VPPVRFStorage.AddVRF(&model.VPPVRFTable{
+		Name: "vrf1", ID: 1, LocalAddr: "10.0.1.1", FIPPrefixes: []string{"172.16.1.0/24"}, FIPServed: 1, LinkUp: true,
+	}))
+
+	// 1000 pps through vrf sub-interfaces until the traffic moves to other gateways
+
+	var (
+		packets   atomic.Uint64
+		isMovedTo atomic.Bool
+	)
+
+	drain := service.NewGatewayDrain(ctx, bgpSrv, cfg, storage, func() (uint64, error) {
+		if !isMovedTo.Load() {
+			packets.Add(1000)
+		}
+
+		return packets.Load(), nil
+	})
+
+	require.NoError(t, service.AdvWdrawFIPAggrPrefixes(ctx, bgpSrv, cfg, service.ADVERTISE,
+		storage.VPPVRFStorage.GetVRF(1), storage.BGPVRFStorage.GetVRF(1)))
+	require.NotNil(t, aggrPath(t, bgpSrv))
+
+	status := drain.Start()
+	require.Equal(t, service.DrainPhaseDepreferred, status.Phase)
+
+	path := aggrPath(t, bgpSrv)
+	require.NotNil(t, path)
+	require.Equal(t, []uint32{gobgp.GracefulShutdownCommunity}, communities(t, path))
+	require.Equal(t, uint32(1000), med(t, path))
+
+	time.Sleep(1500 * time.Millisecond)
+	require.Equal(t, service.DrainPhaseDepreferred, drain.Status().Phase)
+
+	isMovedTo.Store(true)
+
+	waitCtx, waitCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer waitCancel()
+
+	require.NoError(t, drain.Wait(waitCtx))
+	require.Equal(t, service.DrainPhaseWithdrawn, drain.Status().Phase)
+	require.False(t, drain.Status().TimedOut)
+	require.Nil(t, aggrPath(t, bgpSrv))
+
+	// undrain advertises the prefix back with normal attributes
+
+	status = drain.Stop(ctx)
+	require.Equal(t, service.DrainPhaseNone, status.Phase)
+	require.ErrorIs(t, drain.Wait(ctx), service.ErrDrainCanceled)
+
+	path = aggrPath(t, bgpSrv)
+	require.NotNil(t, path)
+	require.Empty(t, communities(t, path))
+	require.Equal(t, uint32(0), med(t, path))
+
+	// shutdown withdraws the prefix at once, the drain does not change it after that
+
+	drain.Start()
+	require.NoError(t, drain.Shutdown(ctx))
+	require.Equal(t, service.DrainPhaseWithdrawn, drain.Status().Phase)
+	require.Nil(t, aggrPath(t, bgpSrv))
+
+	drain.Stop(ctx)
+	require.Equal(t, service.DrainPhaseWithdrawn, drain.Status().Phase)
+	require.Nil(t, aggrPath(t, bgpSrv))
+}
+
+func communities(t *testing.T, path *bgpapi.Path) []uint32 {
+	for _, attr := range path.Pattrs {
+		var value bgpapi.CommunitiesAttribute
+
+		if attr.MessageIs(&value) {
+			require.NoError(t, attr.UnmarshalTo(&value))
+
+			return value.Communities
+		}
+	}
+
+	return nil
+}
+
+func med(t *testing.T, path *bgpapi.Path) uint32 {
+	for _, attr := range path.Pattrs {
+		var value bgpapi.MultiExitDiscAttribute
+
+		if attr.MessageIs(&value) {
+			require.NoError(t, attr.UnmarshalTo(&value))
+
+			return value.Med
+		}
+	}
+
+	return 0
+}
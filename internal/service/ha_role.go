@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/osrg/gobgp/v3/pkg/server"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/gobgp"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/pkg/logger"
+)
+
+// haStandby is set while the instance is the standby of ha pair
+var haStandby atomic.Bool
+
+// haPathPreference returns attributes of aggregated floating ip prefixes by ha role, false is returned if the prefixes
+// are not advertised at all (standby in withdraw mode)
+func haPathPreference(cfg config.Config) (gobgp.PathPreference, bool) {
+	if !cfg.HA.Enable || !haStandby.Load() {
+		return gobgp.PathPreference{}, true
+	}
+
+	switch cfg.HA.StandbyMode {
+	case config.HAStandbyLocalPref:
+		return gobgp.PathPreference{LocalPref: cfg.HA.StandbyLocalPref}, true
+	case config.HAStandbyWithdraw:
+		return gobgp.PathPreference{}, false
+	default:
+		prepend := make([]uint32, cfg.HA.StandbyPrepend)
+
+		for i := range prepend {
+			prepend[i] = cfg.GoBGP.BGPLocalASN
+		}
+
+		return gobgp.PathPreference{Prepend: prepend}, true
+	}
+}
+
+// SetHARole makes the instance the standby (the active) of ha pair: aggregated floating ip prefixes of vrfs serving
+// floating ips are advertised again with standby (normal) attributes or, in withdraw mode, withdrawn (advertised)
+func SetHARole(ctx context.Context, bgpSrv *server.BgpServer, cfg config.Config, storage *imdb.Storage, isStandby bool) {
+	// floating ip changes advertise aggregates too, so the role is not changed in the middle of them
+
+	fipMu.Lock()
+	defer fipMu.Unlock()
+
+	if haStandby.Swap(isStandby) == isStandby {
+		return
+	}
+
+	refreshAggrPrefixes(ctx, bgpSrv, cfg, storage)
+
+	logger.Info("ha role changed", "standby", isStandby, "standby mode", cfg.HA.StandbyMode)
+}
@@ -2,13 +2,19 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	bgpapi "github.com/osrg/gobgp/v3/api"
 	"github.com/osrg/gobgp/v3/pkg/server"
 	"go.fd.io/govpp/api"
 	"go.fd.io/govpp/binapi/interface_types"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/protobuf/types/known/anypb"
 
 	"git.crptech.ru/cloud/cloudgw/internal/config"
@@ -16,10 +22,11 @@ import (
 	"git.crptech.ru/cloud/cloudgw/internal/repository/gobgp"
 	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
 	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp"
-	"git.crptech.ru/cloud/cloudgw/pkg/exporter/gobgpexporter"
+	"git.crptech.ru/cloud/cloudgw/pkg/exporter/convergenceexporter"
 	"git.crptech.ru/cloud/cloudgw/pkg/gobgpapi"
 	"git.crptech.ru/cloud/cloudgw/pkg/logger"
 	"git.crptech.ru/cloud/cloudgw/pkg/netutils"
+	"git.crptech.ru/cloud/cloudgw/pkg/tracing"
 )
 
 const (
@@ -27,47 +34,30 @@ const (
 	WITHDRAW  = false
 )
 
+// idleTunnelSettle is the time without best path updates after the end-of-rib, when the updates queued before the
+// end-of-rib are considered handled
+const idleTunnelSettle = 5 * time.Second
+
 // HandleBGPUpdate watches BGP events (tables and peer state) from GoBGP. The function called once!
+// isWarmStarted is set if udp tunnels are restored on warm start, the idle ones are deleted after the initial sync.
 func HandleBGPUpdate(
 	ctx context.Context,
 	vppStream *api.Stream,
 	bgpSrv *server.BgpServer,
 	cfg config.Config,
 	storage *imdb.Storage,
+	isWarmStarted bool,
 ) {
-	// map to be used for bgp update parsing to simplify search Update source (tungsten fabric or physical network)
-	bgpPeerToPeerTypeMap, err := storage.CreateBGPPeerToTypeMap()
+	handler, err := newTFUpdateHandler(storage)
 	if err != nil {
-		logger.Fatal("failed to get bgp peer to type map", "error", err)
-	}
-
-	// map to be used for bgp update parsing to simplify search next-hop
-
-	vppVRFIDToNHMap, err := storage.VPPVRFStorage.CreateVRFIDToNextHopMap()
-	if err != nil {
-		logger.Fatal("failed to get bgp peer to type map", "error", err)
-	}
-
-	// slice to be used for check received address is floating ip or not
-
-	vppAggregatedFIPs := make([]*net.IPNet, 0)
-
-	vrfs := storage.VPPVRFStorage.GetVRFs()
-
-	if vrfs == nil {
-		logger.Fatal("failed to get vpp vrfs from memory storage", "logger", err)
+		logger.Fatal("failed to create tungsten fabric update handler", "error", err)
 	}
 
-	for _, vrf := range vrfs {
-		for _, fipPrefix := range vrf.FIPPrefixes {
-			_, parsedFIPPrefix, err := net.ParseCIDR(fipPrefix)
-			if err != nil {
-				logger.Fatal("failed to parse aggregated floating ip prefixes", "logger", err)
-			}
-
-			vppAggregatedFIPs = append(vppAggregatedFIPs, parsedFIPPrefix)
+	sweep := newIdleTunnelSweep(isWarmStarted, idleTunnelSettle, func() {
+		if deleted := DelIdleUDPTunnels(*vppStream, storage); deleted != 0 {
+			logger.Info("idle udp tunnels deleted", "count", deleted)
 		}
-	}
+	})
 
 	// ========= process bgp updates from tungsten fabric (BEST table) =========================================
 
@@ -82,200 +72,17 @@ func HandleBGPUpdate(
 		},
 	}, func(r *bgpapi.WatchEventResponse) {
 		if t := r.GetTable(); t != nil {
-			for _, path := range t.Paths {
-
-				// skip if the update is not from tungsten fabric (it excludes internal updates)
-
-				if storage.BGPPeerStorage.IsConfiguredBGPPeer(path.NeighborIp) && storage.BGPPeerStorage.IsPHYNET(path.NeighborIp) {
-					continue
-				}
-
-				logger.Debug("got bgp update", "neighbor", path.NeighborIp, "nlri", path.Nlri)
-
-				// parse bgp updates and fill bgpNLRIAttrs structures (except rd/rt)
-
-				fromTF, _, parsedBGPNLRIAttrs, err := ParseBGPUpdate(
-					path,
-					vppVRFIDToNHMap,
-					bgpPeerToPeerTypeMap,
-					cfg.TFController.BGPPeerASN,
-					cfg.GoBGP.BGPLocalASN,
-				)
-				if err != nil {
-					logger.Error(
-						"failed to parse bgp update",
-						"path attrs length", len(path.Pattrs),
-						"path attrs", pathNLRIString(path.Pattrs),
-						"error", err,
-					)
-
-					continue
-				}
-
-				if !fromTF {
-					continue
-				}
-
-				// get vrf where the update came from
-
-				calculatedVPPVRF := storage.VPPVRFStorage.GetVRF(parsedBGPNLRIAttrs.VRFID)
-				if calculatedVPPVRF == nil {
-					logger.Error("vpp vrf not found for received update", "vrf id", parsedBGPNLRIAttrs.VRFID)
-
-					continue
-				}
-
-				calculatedBGPVRF := storage.BGPVRFStorage.GetVRF(parsedBGPNLRIAttrs.VRFID)
-
-				if calculatedBGPVRF == nil {
-					logger.Error("failed to fetch bgp vrf for received update", "vrf id", parsedBGPNLRIAttrs.VRFID, "error", err)
-
-					continue
-				}
-
-				// create vpp ip route structure for floating ip address from parsed bgp update (route with one next-hop as each update has only one next-hop)
-
-				receivedRoute := model.NewVPPIPRoute(
-					parsedBGPNLRIAttrs.VRFID,
-					interface_types.InterfaceIndex(cfg.VPP.MainInterfaceID),
-					calculatedVPPVRF.SubInterfaceID,
-					parsedBGPNLRIAttrs.Prefix,
-					[]string{parsedBGPNLRIAttrs.NextHop},
-					[]uint32{model.UndefinedTunnelID}, // unknown yet
-					[]uint32{parsedBGPNLRIAttrs.MPLSLabel[0]},
-				)
-
-				// ========== process update from tungsten fabric with flag WITHDRAW ===========================
-
-				switch path.IsWithdraw {
-
-				case true: // withdraw from tungsten fabric
-
-					// skip update processing if floating ip + next-hop + mpls label does not exist
-
-					if !storage.VPPFIPRouteStorage.IsFIPWithNHAndLabelExist(parsedBGPNLRIAttrs.Prefix, parsedBGPNLRIAttrs.NextHop, parsedBGPNLRIAttrs.MPLSLabel[0]) {
-						continue
-					}
-
-					// if exists, then find tunnel id and add to vppIPRoute
+			ctx, span := tracing.Start(ctx, "bgp.WatchBestTable", attribute.Int("cloudgw.paths", len(t.Paths)))
+			defer span.End()
 
-					storedUDPTunnel := storage.VPPUDPTunnelStorage.GetUDPTunnel(receivedRoute.NextHops[0]) // [0] as the update always has only one nextHop
-					if storedUDPTunnel == nil {
-						logger.Error("failed to fetch vpp udp tunnel from memory storage", "vrouter", receivedRoute.NextHops[0])
+			fipMu.Lock()
+			defer fipMu.Unlock()
 
-						continue
-					}
-					receivedRoute.TunnelIDs = []uint32{storedUDPTunnel.TunnelID}
-
-					// get existed floating ip route with its paths (nexthop, mpls, tunnel id)
-
-					storedVPPFIPRoute := storage.VPPFIPRouteStorage.GetFIPRoute(receivedRoute.Prefix)
-					if err != nil {
-						logger.Error("failed to fetch vpp floating ip route from memory storage", "prefix", receivedRoute.Prefix)
-
-						continue
-					}
-
-					// delete existed floating ip and tunnel(s) from vpp and storage
-
-					DelFIPAndTunnelFromVPPAndStorage(
-						ctx,
-						vppStream,
-						bgpSrv,
-						cfg,
-						*storedVPPFIPRoute,
-						storage,
-						calculatedVPPVRF,
-						calculatedBGPVRF,
-					)
-
-					// if stored floating ip had only one path, then finish processing
-
-					if len(storedVPPFIPRoute.NextHops) == 1 {
-						continue
-					}
-
-					// if 2 or more paths, then remove path of received update from stored floating ip route
-
-					storedVPPFIPRoute.DelPath(receivedRoute.NextHops[0])
-
-					// and then re-create the floating ip route
-
-					AddFIPAndTunnelInVPPAndStorage(
-						ctx,
-						*vppStream,
-						bgpSrv,
-						cfg,
-						*storedVPPFIPRoute,
-						storage,
-						calculatedVPPVRF,
-						calculatedBGPVRF,
-					)
-
-				case false: // advertise from tungsten fabric
-
-					// skip if received prefix is not floating ip to exclude internal cloud addresses handling
-
-					if !netutils.IsFIP(parsedBGPNLRIAttrs.Prefix, vppAggregatedFIPs) {
-						continue
-					}
-
-					// skip if floating ip + nexthop + mpls label already exists in VPPFIPRouteStorage
-
-					if storage.VPPFIPRouteStorage.IsFIPWithNHAndLabelExist(receivedRoute.Prefix, receivedRoute.NextHops[0], receivedRoute.FIPMPLSLabels[0]) {
-						continue
-					}
-
-					// find stored floating ip for the received prefix
-
-					storedVPPFIPRoute := storage.VPPFIPRouteStorage.GetFIPRoute(receivedRoute.Prefix)
-
-					// if no stored floating ip found create the floating ip and finish processing
-
-					if storedVPPFIPRoute == nil {
-						AddFIPAndTunnelInVPPAndStorage(
-							ctx,
-							*vppStream,
-							bgpSrv,
-							cfg,
-							receivedRoute,
-							storage,
-							calculatedVPPVRF,
-							calculatedBGPVRF,
-						)
-
-						continue
-					}
-
-					// if stored floating found, the delete it first from storage and vpp
-
-					DelFIPAndTunnelFromVPPAndStorage(
-						ctx,
-						vppStream,
-						bgpSrv,
-						cfg,
-						*storedVPPFIPRoute,
-						storage,
-						calculatedVPPVRF,
-						calculatedBGPVRF,
-					)
-
-					// then add received path to the stored prefix and then re-create new floating ip
-
-					storedVPPFIPRoute.AddPath(receivedRoute.NextHops[0], receivedRoute.TunnelIDs[0], receivedRoute.FIPMPLSLabels[0])
-
-					AddFIPAndTunnelInVPPAndStorage(
-						ctx,
-						*vppStream,
-						bgpSrv,
-						cfg,
-						*storedVPPFIPRoute,
-						storage,
-						calculatedVPPVRF,
-						calculatedBGPVRF,
-					)
-				}
+			for _, path := range t.Paths {
+				handler.handle(ctx, *vppStream, bgpSrv, cfg, storage, path)
 			}
+
+			sweep.updated()
 		}
 	}); err != nil {
 		logger.Error("failed to handle event response for table update from tungsten fabric", "error", err)
@@ -294,6 +101,9 @@ func HandleBGPUpdate(
 		},
 	}, func(r *bgpapi.WatchEventResponse) {
 		if t := r.GetTable(); t != nil {
+			ctx, span := tracing.Start(ctx, "bgp.WatchPostPolicyTable", attribute.Int("cloudgw.paths", len(t.Paths)))
+			defer span.End()
+
 			for _, path := range t.Paths {
 
 				// skip if the update is not from physical network
@@ -307,27 +117,38 @@ func HandleBGPUpdate(
 
 				_, fromPN, parsedBGPNLRIAttrs, err := ParseBGPUpdate(
 					path,
-					vppVRFIDToNHMap,
-					bgpPeerToPeerTypeMap,
+					handler.vppVRFIDToNHMap,
+					handler.bgpPeerToPeerTypeMap,
 					cfg.TFController.BGPPeerASN,
 					cfg.GoBGP.BGPLocalASN,
 				)
 				if err != nil {
+					convergenceexporter.UpdateMetrics.IncUpdateProcessed(convergenceexporter.SourcePhyNet)
+					convergenceexporter.UpdateMetrics.IncUpdateRejected(convergenceexporter.SourcePhyNet, convergenceexporter.RejectParseError)
+
 					logger.Error("failed to parse bgp update", "path attrs length", len(path.Pattrs), "path attrs", pathNLRIString(path.Pattrs), "error", err)
+
+					continue
 				}
 
 				if !fromPN {
 					continue
 				}
 
+				convergenceexporter.UpdateMetrics.IncUpdateProcessed(convergenceexporter.SourcePhyNet)
+
 				// get vrf where from the update
 
 				calculatedVPPVRF := storage.VPPVRFStorage.GetVRF(parsedBGPNLRIAttrs.VRFID)
 
 				calculatedBGPVRF := storage.BGPVRFStorage.GetVRF(parsedBGPNLRIAttrs.VRFID)
 
-				if calculatedBGPVRF == nil { //nolint:staticcheck
-					logger.Error("failed to fetch bgp vrf", "vrf id", parsedBGPNLRIAttrs.VRFID, "error", err)
+				if calculatedVPPVRF == nil || calculatedBGPVRF == nil {
+					convergenceexporter.UpdateMetrics.IncUpdateRejected(convergenceexporter.SourcePhyNet, convergenceexporter.RejectUnknownVRF)
+
+					logger.Error("failed to fetch vrf of bgp update from physical network", "vrf id", parsedBGPNLRIAttrs.VRFID)
+
+					continue
 				}
 
 				// get default vrf
@@ -352,7 +173,7 @@ func HandleBGPUpdate(
 					parsedBGPNLRIAttrs.Prefix,
 					defaultVPPVRF.LocalAddr, // as the VPP handles the traffic from vRouters
 					0,
-					calculatedBGPVRF.RD,       //nolint:staticcheck
+					calculatedBGPVRF.RD,
 					calculatedBGPVRF.ExportRT, // tungsten fabric must read the rt and import in local vrf
 					[]uint32{calculatedVPPVRF.MPLSLocalLabel},
 				)
@@ -362,7 +183,11 @@ func HandleBGPUpdate(
 				case true: // withdraw route from physical network
 
 					if err = vpp.AddDelIPRoute(*vppStream, false, vppIPRoute); err != nil {
+						convergenceexporter.UpdateMetrics.IncUpdateRejected(convergenceexporter.SourcePhyNet, convergenceexporter.RejectVPPError)
+
 						logger.Error("failed to delete ip route", "prefix", vppIPRoute.Prefix, "error", err)
+					} else {
+						convergenceexporter.UpdateMetrics.ObserveUpdateInstalled(convergenceexporter.SourcePhyNet, pathReceivedAt(path))
 					}
 
 					// withdraw (delete) physical network's enriched prefix from tungsten fabric
@@ -373,6 +198,7 @@ func HandleBGPUpdate(
 						WITHDRAW,
 						aggrNLRIAttr,
 						calculatedBGPVRF.PeerASN, // need for loop prevention
+						gobgp.PathPreference{},
 					); err != nil {
 						logger.Error("failed to withdraw vpnv4 prefix", "prefix", aggrNLRIAttr.Prefix, "error", err)
 					}
@@ -382,7 +208,11 @@ func HandleBGPUpdate(
 					// create new upstream ipv4 route through physical network
 
 					if err = vpp.AddDelIPRoute(*vppStream, true, vppIPRoute); err != nil {
+						convergenceexporter.UpdateMetrics.IncUpdateRejected(convergenceexporter.SourcePhyNet, convergenceexporter.RejectVPPError)
+
 						logger.Error("failed to run add ip route", "prefix", vppIPRoute.Prefix, "error", err)
+					} else {
+						convergenceexporter.UpdateMetrics.ObserveUpdateInstalled(convergenceexporter.SourcePhyNet, pathReceivedAt(path))
 					}
 
 					// advertise the enriched route from physical network to tungsten fabric with local assigned mpls Label and rt (should match tungsten fabric virtual network settings)
@@ -393,6 +223,7 @@ func HandleBGPUpdate(
 						ADVERTISE,
 						aggrNLRIAttr,
 						calculatedBGPVRF.PeerASN, // need for loop prevention
+						gobgp.PathPreference{},
 					); err != nil {
 						logger.Error("failed to advertise vpnv4 prefix", "prefix", aggrNLRIAttr.Prefix, "error", err)
 
@@ -405,6 +236,40 @@ func HandleBGPUpdate(
 		logger.Error("failed to handle event response for table update from physical network", "error", err)
 	}
 
+	// ========= processing end-of-rib from tungsten fabric (initial routing table sync) ==========
+
+	if err := bgpSrv.WatchEvent(ctx, &bgpapi.WatchEventRequest{
+		Table: &bgpapi.WatchEventRequest_Table{
+			Filters: []*bgpapi.WatchEventRequest_Table_Filter{
+				{
+					Type: bgpapi.WatchEventRequest_Table_Filter_EOR,
+				},
+			},
+		},
+	}, func(r *bgpapi.WatchEventResponse) {
+		if t := r.GetTable(); t != nil {
+			for _, path := range t.Paths {
+				if !storage.BGPPeerStorage.IsTF(path.NeighborIp) || path.Family.GetSafi() != bgpapi.Family_SAFI_MPLS_VPN {
+					continue
+				}
+
+				storage.UpdateEndOfRIB(path.NeighborIp, true)
+
+				logger.Info("end-of-rib received from tungsten fabric", "neighbor", path.NeighborIp)
+
+				// the routing table of the first controller is the initial convergence (controllers send the same table)
+
+				convergenceexporter.UpdateMetrics.ObserveInitialConvergence()
+
+				// the routing table is received, so tunnels restored on warm start and not used by it are idle
+
+				sweep.start(ctx)
+			}
+		}
+	}); err != nil {
+		logger.Error("failed to handle event response for end-of-rib from tungsten fabric", "error", err)
+	}
+
 	// ========= processing bgp peers status change as events =====================================
 
 	if err := bgpSrv.WatchEvent(ctx, &bgpapi.WatchEventRequest{Peer: &bgpapi.WatchEventRequest_Peer{}}, func(r *bgpapi.WatchEventResponse) {
@@ -429,14 +294,10 @@ func HandleBGPUpdate(
 				logger.Error("failed to update bgp peer state", "error", err)
 			}
 
-			// update gobgp metrics
+			// routing table is re-sent by the peer after the session is re-established
 
-			if bgpPeer.BGPPeerState == bgpapi.PeerState_ESTABLISHED { // changed from DOWN to UP
-				gobgpexporter.GoBGPGeneralMetrics.IncActivePeerCount()
-			}
-
-			if bgpPeer.BGPPeerPrevState == bgpapi.PeerState_ESTABLISHED { // changed from UP to DOWN
-				gobgpexporter.GoBGPGeneralMetrics.DecActivePeerCount()
+			if peer.Peer.State.SessionState != bgpapi.PeerState_ESTABLISHED {
+				storage.UpdateEndOfRIB(peerIP, false)
 			}
 
 			// start bfd monitoring when bgp peer state changed to ESTABLISHED
@@ -456,6 +317,333 @@ func HandleBGPUpdate(
 	}
 }
 
+// idleTunnelSweep deletes udp tunnels restored on warm start and not used by the routing table of tungsten fabric.
+// The end-of-rib and best path updates are watched by different goroutines, so the sweep runs once after the first
+// end-of-rib when no best path update is handled for the settle interval (the queued updates reuse the tunnels).
+type idleTunnelSweep struct {
+	isEnabled   bool
+	settle      time.Duration
+	sweep       func()
+	once        sync.Once
+	lastUpdated atomic.Int64 // unix nano of the last handled best path update
+}
+
+func newIdleTunnelSweep(isEnabled bool, settle time.Duration, sweep func()) *idleTunnelSweep {
+	s := &idleTunnelSweep{isEnabled: isEnabled, settle: settle, sweep: sweep}
+	s.updated()
+
+	return s
+}
+
+// updated records the time of the best path update
+func (s *idleTunnelSweep) updated() {
+	s.lastUpdated.Store(time.Now().UnixNano())
+}
+
+// start runs the sweep once in background after the settle interval without best path updates
+func (s *idleTunnelSweep) start(ctx context.Context) {
+	if !s.isEnabled {
+		return
+	}
+
+	s.once.Do(func() {
+		go func() {
+			for {
+				wait := s.settle - time.Since(time.Unix(0, s.lastUpdated.Load()))
+				if wait <= 0 {
+					break
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(wait):
+				}
+			}
+
+			s.sweep()
+		}()
+	})
+}
+
+// tfUpdateHandler installs floating ip routes of bgp updates from tungsten fabric
+type tfUpdateHandler struct {
+	bgpPeerToPeerTypeMap map[string]int    // to find update source (tungsten fabric or physical network)
+	vppVRFIDToNHMap      map[uint32]string // to find next-hop
+	vppAggregatedFIPs    []*net.IPNet      // to check received address is floating ip or not
+}
+
+func newTFUpdateHandler(storage *imdb.Storage) (*tfUpdateHandler, error) {
+	bgpPeerToPeerTypeMap, err := storage.CreateBGPPeerToTypeMap()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bgp peer to type map: %w", err)
+	}
+
+	vppVRFIDToNHMap, err := storage.VPPVRFStorage.CreateVRFIDToNextHopMap()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vrf id to next-hop map: %w", err)
+	}
+
+	vrfs := storage.VPPVRFStorage.GetVRFs()
+
+	if vrfs == nil {
+		return nil, errors.New("failed to get vpp vrfs from memory storage")
+	}
+
+	vppAggregatedFIPs := make([]*net.IPNet, 0)
+
+	for _, vrf := range vrfs {
+		for _, fipPrefix := range vrf.FIPPrefixes {
+			_, parsedFIPPrefix, err := net.ParseCIDR(fipPrefix)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse aggregated floating ip prefixes: %w", err)
+			}
+
+			vppAggregatedFIPs = append(vppAggregatedFIPs, parsedFIPPrefix)
+		}
+	}
+
+	return &tfUpdateHandler{
+		bgpPeerToPeerTypeMap: bgpPeerToPeerTypeMap,
+		vppVRFIDToNHMap:      vppVRFIDToNHMap,
+		vppAggregatedFIPs:    vppAggregatedFIPs,
+	}, nil
+}
+
+// handle adds (removes) the path of tungsten fabric update to (from) the floating ip route, the caller holds fipMu.
+// The floating ip prefix is returned, it is empty if the path is skipped.
+func (h *tfUpdateHandler) handle(
+	ctx context.Context,
+	vppStream api.Stream,
+	bgpSrv *server.BgpServer,
+	cfg config.Config,
+	storage *imdb.Storage,
+	path *bgpapi.Path,
+) string {
+	// skip if the update is not from tungsten fabric (it excludes internal updates)
+
+	if storage.BGPPeerStorage.IsConfiguredBGPPeer(path.NeighborIp) && storage.BGPPeerStorage.IsPHYNET(path.NeighborIp) {
+		return ""
+	}
+
+	logger.Debug("got bgp update", "neighbor", path.NeighborIp, "nlri", path.Nlri)
+
+	ctx, span := tracing.Start(ctx, "bgp.HandleTFUpdate",
+		tracing.NeighborKey.String(path.NeighborIp),
+		tracing.AddKey.Bool(!path.IsWithdraw),
+	)
+
+	var err error
+
+	defer func() { tracing.End(span, err) }()
+
+	// parse bgp updates and fill bgpNLRIAttrs structures (except rd/rt)
+
+	fromTF, parsedBGPNLRIAttrs, err := h.parse(ctx, cfg, path)
+	if err != nil {
+		convergenceexporter.UpdateMetrics.IncUpdateProcessed(convergenceexporter.SourceTF)
+		convergenceexporter.UpdateMetrics.IncUpdateRejected(convergenceexporter.SourceTF, convergenceexporter.RejectParseError)
+
+		logger.Error(
+			"failed to parse bgp update",
+			"path attrs length", len(path.Pattrs),
+			"path attrs", pathNLRIString(path.Pattrs),
+			"error", err,
+		)
+
+		return ""
+	}
+
+	if !fromTF {
+		return ""
+	}
+
+	convergenceexporter.UpdateMetrics.IncUpdateProcessed(convergenceexporter.SourceTF)
+
+	span.SetAttributes(
+		tracing.PrefixKey.String(parsedBGPNLRIAttrs.Prefix),
+		tracing.VRFKey.Int64(int64(parsedBGPNLRIAttrs.VRFID)),
+		tracing.NextHopKey.String(parsedBGPNLRIAttrs.NextHop),
+	)
+
+	// get vrf where the update came from
+
+	calculatedVPPVRF, calculatedBGPVRF := lookupVRF(ctx, storage, parsedBGPNLRIAttrs.VRFID)
+	if calculatedVPPVRF == nil {
+		convergenceexporter.UpdateMetrics.IncUpdateRejected(convergenceexporter.SourceTF, convergenceexporter.RejectUnknownVRF)
+
+		logger.Error("vpp vrf not found for received update", "vrf id", parsedBGPNLRIAttrs.VRFID)
+
+		return ""
+	}
+
+	if calculatedBGPVRF == nil {
+		convergenceexporter.UpdateMetrics.IncUpdateRejected(convergenceexporter.SourceTF, convergenceexporter.RejectUnknownVRF)
+
+		logger.Error("failed to fetch bgp vrf for received update", "vrf id", parsedBGPNLRIAttrs.VRFID, "error", err)
+
+		return ""
+	}
+
+	// create vpp ip route structure for floating ip address from parsed bgp update (route with one next-hop as each update has only one next-hop)
+
+	receivedRoute := model.NewVPPIPRoute(
+		parsedBGPNLRIAttrs.VRFID,
+		interface_types.InterfaceIndex(cfg.VPP.MainInterfaceID),
+		calculatedVPPVRF.SubInterfaceID,
+		parsedBGPNLRIAttrs.Prefix,
+		[]string{parsedBGPNLRIAttrs.NextHop},
+		[]uint32{model.UndefinedTunnelID}, // unknown yet
+		[]uint32{parsedBGPNLRIAttrs.MPLSLabel[0]},
+	)
+
+	// ========== process update from tungsten fabric with flag WITHDRAW ===========================
+
+	switch path.IsWithdraw {
+
+	case true: // withdraw from tungsten fabric
+
+		// skip update processing if floating ip + next-hop + mpls label does not exist, otherwise get existed floating
+		// ip route with its paths (nexthop, mpls, tunnel id)
+
+		pathExists, storedVPPFIPRoute := lookupFIPRoute(ctx, storage, receivedRoute)
+		if !pathExists {
+			return ""
+		}
+
+		if storedVPPFIPRoute == nil {
+			logger.Error("failed to fetch vpp floating ip route from memory storage", "prefix", receivedRoute.Prefix)
+
+			return ""
+		}
+
+		// delete the floating ip route with the last path, otherwise remove the path of received update
+
+		var newVPPFIPRoute *model.VPPIPRoute
+
+		if len(storedVPPFIPRoute.NextHops) > 1 {
+			route := storedVPPFIPRoute.Clone()
+			route.DelPath(receivedRoute.NextHops[0]) // [0] as the update always has only one nextHop
+
+			newVPPFIPRoute = &route
+		}
+
+		if err = ChangeFIPRoute(
+			ctx,
+			vppStream,
+			bgpSrv,
+			cfg,
+			storage,
+			storedVPPFIPRoute,
+			newVPPFIPRoute,
+			calculatedVPPVRF,
+			calculatedBGPVRF,
+		); err != nil {
+			convergenceexporter.UpdateMetrics.IncUpdateRejected(convergenceexporter.SourceTF, convergenceexporter.RejectVPPError)
+
+			logger.Error("failed to withdraw floating ip route path", "prefix", receivedRoute.Prefix, "nh", receivedRoute.NextHops[0], "error", err)
+		} else {
+			convergenceexporter.UpdateMetrics.ObserveUpdateInstalled(convergenceexporter.SourceTF, pathReceivedAt(path))
+		}
+
+	case false: // advertise from tungsten fabric
+
+		// skip if received prefix is not floating ip to exclude internal cloud addresses handling
+
+		if !netutils.IsFIP(parsedBGPNLRIAttrs.Prefix, h.vppAggregatedFIPs) {
+			convergenceexporter.UpdateMetrics.IncUpdateRejected(convergenceexporter.SourceTF, convergenceexporter.RejectNotFIP)
+
+			return ""
+		}
+
+		// skip if floating ip + nexthop + mpls label already exists in VPPFIPRouteStorage, otherwise find stored floating
+		// ip for the received prefix
+
+		pathExists, storedVPPFIPRoute := lookupFIPRoute(ctx, storage, receivedRoute)
+		if pathExists {
+			return ""
+		}
+
+		// create the floating ip route if not stored, otherwise add received path to the stored route
+
+		newVPPFIPRoute := receivedRoute
+
+		if storedVPPFIPRoute != nil {
+			newVPPFIPRoute = storedVPPFIPRoute.Clone()
+			newVPPFIPRoute.AddPath(receivedRoute.NextHops[0], receivedRoute.TunnelIDs[0], receivedRoute.FIPMPLSLabels[0])
+		}
+
+		if err = ChangeFIPRoute(
+			ctx,
+			vppStream,
+			bgpSrv,
+			cfg,
+			storage,
+			storedVPPFIPRoute,
+			&newVPPFIPRoute,
+			calculatedVPPVRF,
+			calculatedBGPVRF,
+		); err != nil {
+			convergenceexporter.UpdateMetrics.IncUpdateRejected(convergenceexporter.SourceTF, convergenceexporter.RejectVPPError)
+
+			logger.Error("failed to add floating ip route path", "prefix", receivedRoute.Prefix, "nh", receivedRoute.NextHops[0], "error", err)
+		} else {
+			convergenceexporter.UpdateMetrics.ObserveUpdateInstalled(convergenceexporter.SourceTF, pathReceivedAt(path))
+		}
+	}
+
+	return parsedBGPNLRIAttrs.Prefix
+}
+
+// parse parses the path of bgp update in the span
+func (h *tfUpdateHandler) parse(ctx context.Context, cfg config.Config, path *bgpapi.Path) (bool, gobgpapi.BGPNLRIAttrs, error) {
+	_, span := tracing.Start(ctx, "bgp.ParseBGPUpdate", tracing.NeighborKey.String(path.NeighborIp))
+
+	fromTF, _, attrs, err := ParseBGPUpdate(
+		path,
+		h.vppVRFIDToNHMap,
+		h.bgpPeerToPeerTypeMap,
+		cfg.TFController.BGPPeerASN,
+		cfg.GoBGP.BGPLocalASN,
+	)
+
+	tracing.End(span, err)
+
+	return fromTF, attrs, err
+}
+
+// lookupVRF fetches vpp and bgp vrfs of the update from memory storage in the span
+func lookupVRF(ctx context.Context, storage *imdb.Storage, vrfID uint32) (*model.VPPVRFTable, *model.BGPVRFTable) {
+	_, span := tracing.Start(ctx, "imdb.GetVRF", tracing.VRFKey.Int64(int64(vrfID)))
+	defer span.End()
+
+	return storage.VPPVRFStorage.GetVRF(vrfID), storage.BGPVRFStorage.GetVRF(vrfID)
+}
+
+// lookupFIPRoute checks the path (next-hop and mpls label) of the received route exists and fetches the stored
+// floating ip route from memory storage in the span
+func lookupFIPRoute(ctx context.Context, storage *imdb.Storage, route model.VPPIPRoute) (bool, *model.VPPIPRoute) {
+	_, span := tracing.Start(ctx, "imdb.GetFIPRoute",
+		tracing.PrefixKey.String(route.Prefix),
+		tracing.VRFKey.Int64(int64(route.VRFID)),
+		tracing.NextHopKey.String(route.NextHops[0]),
+	)
+	defer span.End()
+
+	pathExists := storage.VPPFIPRouteStorage.IsFIPWithNHAndLabelExist(route.Prefix, route.NextHops[0], route.FIPMPLSLabels[0])
+
+	return pathExists, storage.VPPFIPRouteStorage.GetFIPRoute(route.Prefix)
+}
+
+// pathReceivedAt returns the time GoBGP received the path at, it is zero if unknown
+func pathReceivedAt(path *bgpapi.Path) time.Time {
+	if path.Age == nil {
+		return time.Time{}
+	}
+
+	return path.Age.AsTime()
+}
+
 func pathNLRIString(pathAttrs []*anypb.Any) string {
 	tmp := make([]string, len(pathAttrs))
 
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdleTunnelSweep(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const settle = 100 * time.Millisecond
+
+	t.Run("not warm started", func(t *testing.T) {
+		var sweeps atomic.Int32
+
+		sweep := newIdleTunnelSweep(false, settle, func() { sweeps.Add(1) })
+		sweep.start(ctx)
+
+		time.Sleep(2 * settle)
+
+		require.Zero(t, sweeps.Load())
+	})
+
+	t.Run("once after updates settled", func(t *testing.T) {
+		var sweeps atomic.Int32
+
+		sweep := newIdleTunnelSweep(true, settle, func() { sweeps.Add(1) })
+
+		// end-of-rib of every controller starts the sweep, the best path updates queued before it delay the sweep
+
+		startedAt := time.Now()
+
+		sweep.start(ctx)
+
+		for range 3 {
+			time.Sleep(settle / 2)
+
+			sweep.updated()
+			sweep.start(ctx)
+		}
+
+		require.Eventually(t, func() bool { return sweeps.Load() == 1 }, 2*time.Second, 10*time.Millisecond)
+		require.GreaterOrEqual(t, time.Since(startedAt), 3*settle/2+settle)
+
+		// controller re-establishment does not start it again
+
+		sweep.start(ctx)
+
+		time.Sleep(2 * settle)
+
+		require.Equal(t, int32(1), sweeps.Load())
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		var sweeps atomic.Int32
+
+		ctx, cancel := context.WithCancel(ctx)
+
+		sweep := newIdleTunnelSweep(true, settle, func() { sweeps.Add(1) })
+		sweep.start(ctx)
+
+		cancel()
+
+		time.Sleep(2 * settle)
+
+		require.Zero(t, sweeps.Load())
+	})
+}
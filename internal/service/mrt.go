@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	bgpapi "github.com/osrg/gobgp/v3/api"
+	"github.com/osrg/gobgp/v3/pkg/server"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/gobgp"
+	"git.crptech.ru/cloud/cloudgw/pkg/logger"
+)
+
+// mrt file names of MRT.Dir, the time the file is started at is substituted by GoBGP
+const (
+	MRTUpdatesFile = "updates.20060102.1504.mrt"
+	MRTRIBFile     = "rib.20060102.1504.mrt"
+)
+
+// DumpMRT starts dumping updates received from all peers to MRTUpdatesFile rotated every MRT.RotationInterval and,
+// if MRT.TableDumpInterval is set, global rib snapshots to MRTRIBFile, a file per snapshot. Files are written
+// without buffering until GoBGP server is stopped, GoBGP does not disable the dump by the file name.
+func DumpMRT(ctx context.Context, bgpSrv *server.BgpServer, cfg config.MRT) error {
+	if !cfg.Enable {
+		return nil
+	}
+
+	if err := gobgp.EnableMRT(ctx, bgpSrv, bgpapi.EnableMrtRequest_UPDATES, filepath.Join(cfg.Dir, MRTUpdatesFile),
+		0, uint64(cfg.RotationInterval)); err != nil {
+		return fmt.Errorf("failed to enable mrt dump of updates: %w", err)
+	}
+
+	if cfg.TableDumpInterval != 0 {
+		if err := gobgp.EnableMRT(ctx, bgpSrv, bgpapi.EnableMrtRequest_TABLE, filepath.Join(cfg.Dir, MRTRIBFile),
+			0, uint64(cfg.TableDumpInterval)); err != nil {
+			return fmt.Errorf("failed to enable mrt dump of rib: %w", err)
+		}
+	}
+
+	logger.Info("mrt dump enabled", "dir", cfg.Dir, "rotation interval", cfg.RotationInterval,
+		"table dump interval", cfg.TableDumpInterval)
+
+	return nil
+}
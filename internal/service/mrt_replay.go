@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	bgpapi "github.com/osrg/gobgp/v3/api"
+	"github.com/osrg/gobgp/v3/pkg/apiutil"
+	"go.fd.io/govpp/api"
+	"google.golang.org/protobuf/proto"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/gobgp"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+)
+
+// MRTReplayEvent is the best path change of the replayed update handled as floating ip route path
+type MRTReplayEvent struct {
+	Time       time.Time `json:"Time"`
+	PeerIP     string    `json:"PeerIP"`
+	NLRI       string    `json:"NLRI"` // rd:prefix
+	IsWithdraw bool      `json:"IsWithdraw"`
+	FIP        string    `json:"FIP"`
+	NextHops   []string  `json:"NextHops"` // next-hops of the floating ip route after the change, empty if it is deleted
+}
+
+// MRTReplayStats counts replayed updates and their paths
+type MRTReplayStats struct {
+	Updates   int `json:"Updates"`
+	Paths     int `json:"Paths"`     // announced and withdrawn paths of the updates
+	BestPaths int `json:"BestPaths"` // best path changes passed to floating ip route handling
+	FIPPaths  int `json:"FIPPaths"`  // best path changes handled as floating ip route paths
+}
+
+// mrtRoute is the paths of one nlri, a path per peer in the order of announcement
+type mrtRoute []*bgpapi.Path
+
+// MRTReplayer feeds bgp updates recorded to mrt files through the same update parsing and floating ip route handling
+// as HandleBGPUpdate. The handling gets the best path changes of GoBGP BEST table, so the best path is emulated: it
+// is the earliest announced path of the nlri (tungsten fabric controllers announce equal paths), the next one becomes
+// the best when it is withdrawn. Routes are changed through vpp stream (dry-run one), vrf links are down, so
+// aggregated floating ip prefixes are not advertised and GoBGP server is not needed.
+type MRTReplayer struct {
+	ctx       context.Context
+	vppStream api.Stream
+	cfg       config.Config
+	storage   *imdb.Storage
+	handler   *tfUpdateHandler
+	routes    map[string]mrtRoute
+	stats     MRTReplayStats
+}
+
+// NewMRTReplayer creates the replayer of updates to the storage initialized with the config
+func NewMRTReplayer(ctx context.Context, vppStream api.Stream, cfg config.Config, storage *imdb.Storage) (*MRTReplayer, error) {
+	handler, err := newTFUpdateHandler(storage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tungsten fabric update handler: %w", err)
+	}
+
+	return &MRTReplayer{
+		ctx:       ctx,
+		vppStream: vppStream,
+		cfg:       cfg,
+		storage:   storage,
+		handler:   handler,
+		routes:    make(map[string]mrtRoute),
+	}, nil
+}
+
+// Replay replays updates of mrt file, fn is called for every floating ip route change. Files are replayed in the
+// recorded order, best paths are kept between them.
+func (r *MRTReplayer) Replay(reader io.Reader, fn func(MRTReplayEvent)) error {
+	return gobgp.ReadMRTUpdates(reader, func(update gobgp.MRTUpdate) error {
+		r.stats.Updates++
+
+		for _, path := range update.Paths {
+			r.stats.Paths++
+
+			nlri, err := apiutil.GetNativeNlri(path)
+			if err != nil {
+				return fmt.Errorf("failed to decode nlri of %s: %w", update.PeerIP, err)
+			}
+
+			best := r.bestPath(apiutil.ToRouteFamily(path.Family).String()+" "+nlri.String(), path)
+			if best == nil {
+				continue
+			}
+
+			r.stats.BestPaths++
+
+			if event, ok := r.handle(best, nlri.String(), update.Time); ok {
+				r.stats.FIPPaths++
+
+				fn(event)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Stats returns counters of replayed updates
+func (r *MRTReplayer) Stats() MRTReplayStats {
+	return r.stats
+}
+
+// bestPath stores the path and returns the best path change (nil if the best path is not changed), the last withdrawn
+// path is returned with its attributes as GoBGP does
+func (r *MRTReplayer) bestPath(key string, path *bgpapi.Path) *bgpapi.Path {
+	route := r.routes[key]
+
+	i := slices.IndexFunc(route, func(p *bgpapi.Path) bool { return p.NeighborIp == path.NeighborIp })
+
+	if !path.IsWithdraw {
+		if i == -1 {
+			i, route = len(route), append(route, path)
+		} else {
+			route[i] = path // implicit withdraw
+		}
+
+		r.routes[key] = route
+
+		if i == 0 {
+			return path
+		}
+
+		return nil
+	}
+
+	if i == -1 {
+		return nil
+	}
+
+	withdrawn := route[i]
+	route = slices.Delete(route, i, i+1)
+
+	if len(route) == 0 {
+		delete(r.routes, key)
+
+		best := proto.Clone(withdrawn).(*bgpapi.Path)
+		best.IsWithdraw, best.Age = true, path.Age
+
+		return best
+	}
+
+	r.routes[key] = route
+
+	if i == 0 {
+		return route[0]
+	}
+
+	return nil
+}
+
+func (r *MRTReplayer) handle(path *bgpapi.Path, nlri string, updatedAt time.Time) (MRTReplayEvent, bool) {
+	fipMu.Lock()
+	defer fipMu.Unlock()
+
+	fip := r.handler.handle(r.ctx, r.vppStream, nil, r.cfg, r.storage, path)
+	if fip == "" {
+		return MRTReplayEvent{}, false
+	}
+
+	event := MRTReplayEvent{
+		Time:       updatedAt,
+		PeerIP:     path.NeighborIp,
+		NLRI:       nlri,
+		IsWithdraw: path.IsWithdraw,
+		FIP:        fip,
+	}
+
+	if route := r.storage.VPPFIPRouteStorage.GetFIPRoute(fip); route != nil {
+		event.NextHops = append(event.NextHops, route.NextHops...)
+	}
+
+	return event, true
+}
@@ -0,0 +1,114 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/osrg/gobgp/v3/pkg/packet/bgp"
+	"github.com/osrg/gobgp/v3/pkg/packet/mrt"
+	"github.com/stretchr/testify/require"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp/dryrun"
+	"git.crptech.ru/cloud/cloudgw/internal/service"
+)
+
+// mrtUpdate returns mrt record of vpnv4 update from tungsten fabric controller as GoBGP dumps it
+func mrtUpdate(t *testing.T, at time.Time, controller string, isWithdraw bool, prefix, vrouter string, label uint32) []byte {
+	nlri := bgp.NewLabeledVPNIPAddrPrefix(32, prefix, *bgp.NewMPLSLabelStack(label),
+		bgp.NewRouteDistinguisherIPAddressAS(vrouter, 1))
+
+	var msg *bgp.BGPMessage
+
+	if isWithdraw {
+		msg = bgp.NewBGPUpdateMessage(nil, []bgp.PathAttributeInterface{
+			bgp.NewPathAttributeMpUnreachNLRI([]bgp.AddrPrefixInterface{nlri}),
+		}, nil)
+	} else {
+		msg = bgp.NewBGPUpdateMessage(nil, []bgp.PathAttributeInterface{
+			bgp.NewPathAttributeOrigin(bgp.BGP_ORIGIN_ATTR_TYPE_IGP),
+			bgp.NewPathAttributeAsPath([]bgp.AsPathParamInterface{bgp.NewAs4PathParam(bgp.BGP_ASPATH_ATTR_TYPE_SEQ, []uint32{64512})}),
+			bgp.NewPathAttributeLocalPref(100),
+			bgp.NewPathAttributeExtendedCommunities([]bgp.ExtendedCommunityInterface{
+				bgp.NewTwoOctetAsSpecificExtended(bgp.EC_SUBTYPE_ROUTE_TARGET, 64512, 1, true),
+			}),
+			bgp.NewPathAttributeMpReachNLRI(vrouter, []bgp.AddrPrefixInterface{nlri}),
+		}, nil)
+	}
+
+	record, err := mrt.NewMRTMessage(uint32(at.Unix()), mrt.BGP4MP, mrt.MESSAGE_AS4,
+		mrt.NewBGP4MPMessage(64512, 65000, 0, controller, "10.0.0.1", true, msg))
+	require.NoError(t, err)
+
+	data, err := record.Serialize()
+	require.NoError(t, err)
+
+	return data
+}
+
+func TestMRTReplayer(t *testing.T) {
+	ctx := context.Background()
+	stream := dryrun.NewStream(ctx)
+	storage := imdb.NewStorage()
+	cfg := config.Config{
+		TFController: config.TFController{BGPPeerASN: 64512},
+		GoBGP:        config.GoBGP{BGPLocalASN: 65000},
+		VPP:          config.VPP{TunLocalIP: "192.0.0.1/24"},
+	}
+
+	for _, controller := range []string{"10.0.0.10", "10.0.0.11"} {
+		peer := model.NewBGPPeer(model.TF, 64512, controller, 179, "", true, 2, "", 10, 30)
+		require.NoError(t, storage.BGPPeerStorage.AddBGPPeer(&peer))
+	}
+
+	vppVRF := &model.VPPVRFTable{Name: "vrf1", ID: 1, FIPPrefixes: []string{"172.16.1.0/24"}}
+
+	require.NoError(t, storage.VPPVRFStorage.AddVRF(vppVRF))
+	require.NoError(t, storage.BGPVRFStorage.AddVRF(&model.BGPVRFTable{Name: "vrf1", ID: 1}))
+	require.NoError(t, vpp.AddDelVRF(stream, true, *vppVRF))
+
+	// both controllers announce the floating ip, the first one withdraws it, then the second one does.
+	// The internal address is not a floating ip, the truncated last record is being written.
+
+	at := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+
+	var file bytes.Buffer
+
+	file.Write(mrtUpdate(t, at, "10.0.0.10", false, "172.16.1.10", "10.1.1.1", 25))
+	file.Write(mrtUpdate(t, at.Add(time.Second), "10.0.0.11", false, "172.16.1.10", "10.1.1.1", 25))
+	file.Write(mrtUpdate(t, at.Add(2*time.Second), "10.0.0.10", false, "10.10.0.5", "10.1.1.2", 26))
+	file.Write(mrtUpdate(t, at.Add(3*time.Second), "10.0.0.10", true, "172.16.1.10", "10.1.1.1", 25))
+	file.Write(mrtUpdate(t, at.Add(4*time.Second), "10.0.0.11", true, "172.16.1.10", "10.1.1.1", 25))
+	file.Write(mrtUpdate(t, at, "10.0.0.10", false, "172.16.1.11", "10.1.1.1", 25)[:20])
+
+	replayer, err := service.NewMRTReplayer(ctx, stream, cfg, storage)
+	require.NoError(t, err)
+
+	var events []service.MRTReplayEvent
+
+	require.NoError(t, replayer.Replay(&file, func(event service.MRTReplayEvent) {
+		events = append(events, event)
+
+		if !event.IsWithdraw {
+			require.Len(t, stream.FIB().Routes, 1)
+		}
+	}))
+
+	// the best path moves to the second controller without route changes
+
+	require.Equal(t, []service.MRTReplayEvent{
+		{Time: at, PeerIP: "10.0.0.10", NLRI: "10.1.1.1:1:172.16.1.10/32", FIP: "172.16.1.10/32", NextHops: []string{"10.1.1.1"}},
+		{Time: at.Add(4 * time.Second), PeerIP: "10.0.0.11", NLRI: "10.1.1.1:1:172.16.1.10/32", IsWithdraw: true, FIP: "172.16.1.10/32"},
+	}, events)
+	require.Equal(t, service.MRTReplayStats{Updates: 5, Paths: 5, BestPaths: 4, FIPPaths: 2}, replayer.Stats())
+
+	fib := stream.FIB()
+	require.Empty(t, fib.Routes)
+	require.Empty(t, fib.Tunnels)
+	require.Empty(t, storage.VPPFIPRouteStorage.GetFIPRoutes())
+}
@@ -0,0 +1,100 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp/dryrun"
+	"git.crptech.ru/cloud/cloudgw/internal/service"
+	"git.crptech.ru/cloud/cloudgw/pkg/tracing"
+)
+
+func TestTFUpdateTracing(t *testing.T) {
+	exporter := tracing.EnableInMemoryTracing()
+
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	ctx := context.Background()
+	stream := dryrun.NewStream(ctx)
+	storage := imdb.NewStorage()
+	cfg := config.Config{
+		TFController: config.TFController{BGPPeerASN: 64512},
+		GoBGP:        config.GoBGP{BGPLocalASN: 65000},
+		VPP:          config.VPP{TunLocalIP: "192.0.0.1/24"},
+	}
+
+	peer := model.NewBGPPeer(model.TF, 64512, "10.0.0.10", 179, "", true, 2, "", 10, 30)
+	require.NoError(t, storage.BGPPeerStorage.AddBGPPeer(&peer))
+
+	vppVRF := &model.VPPVRFTable{Name: "vrf1", ID: 1, FIPPrefixes: []string{"172.16.1.0/24"}}
+
+	require.NoError(t, storage.VPPVRFStorage.AddVRF(vppVRF))
+	require.NoError(t, storage.BGPVRFStorage.AddVRF(&model.BGPVRFTable{Name: "vrf1", ID: 1}))
+	require.NoError(t, vpp.AddDelVRF(stream, true, *vppVRF))
+
+	replayer, err := service.NewMRTReplayer(ctx, stream, cfg, storage)
+	require.NoError(t, err)
+
+	file := bytes.NewBuffer(mrtUpdate(t, time.Now(), "10.0.0.10", false, "172.16.1.10", "10.1.1.1", 25))
+
+	require.NoError(t, replayer.Replay(file, func(service.MRTReplayEvent) {}))
+
+	// the update span is the parent of parsing, storage lookups and vpp calls spans
+
+	spans := exporter.GetSpans()
+
+	var names []string
+
+	for _, span := range spans {
+		names = append(names, span.Name)
+	}
+
+	require.Equal(t, []string{
+		"bgp.ParseBGPUpdate",
+		"imdb.GetVRF",
+		"imdb.GetFIPRoute",
+		"vpp.AddUDPTunnel",
+		"vpp.AddDelFIPRoute",
+		"bgp.HandleTFUpdate",
+	}, names)
+
+	update := spans[len(spans)-1]
+
+	for _, span := range spans[:len(spans)-1] {
+		require.Equal(t, update.SpanContext.SpanID(), span.Parent.SpanID(), span.Name)
+	}
+
+	require.Subset(t, update.Attributes, []attribute.KeyValue{
+		tracing.PrefixKey.String("172.16.1.10/32"),
+		tracing.VRFKey.Int64(1),
+		tracing.NextHopKey.String("10.1.1.1"),
+	})
+	require.Subset(t, spanByName(spans, "vpp.AddDelFIPRoute").Attributes, []attribute.KeyValue{
+		tracing.VPPMessageKey.String("ip_route_add_del_v2"),
+		tracing.AddKey.Bool(true),
+		tracing.PrefixKey.String("172.16.1.10/32"),
+		tracing.NextHopKey.StringSlice([]string{"10.1.1.1"}),
+	})
+}
+
+func spanByName(spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	for _, span := range spans {
+		if span.Name == name {
+			return span
+		}
+	}
+
+	return tracetest.SpanStub{}
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"go.fd.io/govpp/api"
+
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp"
+	"git.crptech.ru/cloud/cloudgw/pkg/logger"
+)
+
+// LiveFIPRoute returns a copy of floating ip route with paths through reachable vrouters only. If no vrouter is
+// reachable all paths are returned, as failed probing must not black-hole the traffic.
+func LiveFIPRoute(vppIPRoute model.VPPIPRoute, appStorage *imdb.Storage) model.VPPIPRoute {
+	liveRoute := vppIPRoute
+	liveRoute.NextHops = nil
+	liveRoute.TunnelIDs = nil
+	liveRoute.FIPMPLSLabels = nil
+
+	for i, nh := range vppIPRoute.NextHops {
+		if !appStorage.VPPUDPTunnelStorage.IsReachable(nh) {
+			continue
+		}
+
+		liveRoute.NextHops = append(liveRoute.NextHops, nh)
+		liveRoute.TunnelIDs = append(liveRoute.TunnelIDs, vppIPRoute.TunnelIDs[i])
+		liveRoute.FIPMPLSLabels = append(liveRoute.FIPMPLSLabels, vppIPRoute.FIPMPLSLabels[i])
+	}
+
+	if len(liveRoute.NextHops) == 0 {
+		liveRoute.NextHops = append([]string(nil), vppIPRoute.NextHops...)
+		liveRoute.TunnelIDs = append([]uint32(nil), vppIPRoute.TunnelIDs...)
+		liveRoute.FIPMPLSLabels = append([]uint32(nil), vppIPRoute.FIPMPLSLabels...)
+	}
+
+	return liveRoute
+}
+
+// HandleUDPTunnelReachability prunes (restores) paths through the vrouter from (to) all multipath floating ip routes in
+// vpp when the vrouter becomes unreachable (reachable). BGP and floating ip routes in storage are not changed.
+// The stream is owned by the caller (the prober), fipMu serializes route changes with bgp updates.
+func HandleUDPTunnelReachability(vppStream api.Stream, appStorage *imdb.Storage, dstIP string, isReachable bool) {
+	fipMu.Lock()
+	defer fipMu.Unlock()
+
+	if appStorage.VPPUDPTunnelStorage.IsReachable(dstIP) == isReachable {
+		return
+	}
+
+	appStorage.VPPUDPTunnelStorage.SetReachable(dstIP, isReachable)
+
+	logger.Info("vrouter reachability changed", "vrouter", dstIP, "reachable", isReachable)
+
+	// routes are read under fipMu, so the paths are not replaced by the paths of a route changed meanwhile
+
+	for _, fipRoute := range appStorage.VPPFIPRouteStorage.GetFIPRoutesByNextHop(dstIP) {
+		if len(fipRoute.NextHops) < 2 {
+			continue
+		}
+
+		liveRoute := LiveFIPRoute(*fipRoute, appStorage)
+
+		if err := vpp.ReplaceFIPRoutePaths(vppStream, &liveRoute); err != nil {
+			logger.Error("failed to update floating ip route paths in vpp", "prefix", fipRoute.Prefix, "vrouter", dstIP, "error", err)
+
+			continue
+		}
+
+		logger.Info("floating ip route paths updated in vpp", "prefix", liveRoute.Prefix, "nh", liveRoute.NextHops)
+	}
+}
@@ -0,0 +1,127 @@
+package service_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp/dryrun"
+	"git.crptech.ru/cloud/cloudgw/internal/service"
+)
+
+func TestLiveFIPRoute(t *testing.T) {
+	route := model.NewVPPIPRoute(1, 1, model.UndefinedSubIf, "172.16.1.10/32", nil, nil, nil)
+	route.AddPath("10.0.0.1", 1, 25)
+	route.AddPath("10.0.0.2", 2, 26)
+	route.AddPath("10.0.0.3", 3, 27)
+
+	tests := []struct {
+		name        string
+		unreachable []string
+		want        []string
+	}{
+		{name: "all reachable", want: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}},
+		{name: "unknown vrouter is reachable", unreachable: []string{"10.0.0.9"}, want: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}},
+		{name: "one unreachable", unreachable: []string{"10.0.0.2"}, want: []string{"10.0.0.1", "10.0.0.3"}},
+		{name: "all unreachable keeps all paths", unreachable: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}, want: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := imdb.NewStorage()
+
+			for i, nh := range route.NextHops {
+				tunnel := model.NewVPPUDPTunnel(route.TunnelIDs[i], "192.0.0.1", nh, 50000)
+				require.NoError(t, storage.VPPUDPTunnelStorage.AddUDPTunnel(&tunnel))
+			}
+
+			for _, nh := range tt.unreachable {
+				storage.VPPUDPTunnelStorage.SetReachable(nh, false)
+			}
+
+			live := service.LiveFIPRoute(route, storage)
+
+			require.Equal(t, tt.want, live.NextHops)
+
+			// tunnels and labels follow their next hops
+
+			for i, nh := range live.NextHops {
+				j := slices.Index(route.NextHops, nh)
+
+				require.Equal(t, route.TunnelIDs[j], live.TunnelIDs[i], nh)
+				require.Equal(t, route.FIPMPLSLabels[j], live.FIPMPLSLabels[i], nh)
+			}
+
+			require.Len(t, route.NextHops, 3) // the passed route is not changed
+		})
+	}
+}
+
+func TestHandleUDPTunnelReachability(t *testing.T) {
+	ctx := context.Background()
+	stream := dryrun.NewStream(ctx)
+	storage := imdb.NewStorage()
+	cfg := config.Config{VPP: config.VPP{TunLocalIP: "192.0.0.1/24"}}
+
+	vppVRF := &model.VPPVRFTable{Name: "vrf1", ID: 1}
+	bgpVRF := &model.BGPVRFTable{Name: "vrf1", ID: 1}
+
+	require.NoError(t, storage.VPPVRFStorage.AddVRF(vppVRF))
+	require.NoError(t, storage.BGPVRFStorage.AddVRF(bgpVRF))
+	require.NoError(t, vpp.AddDelVRF(stream, true, *vppVRF))
+
+	multipath := model.NewVPPIPRoute(1, 1, model.UndefinedSubIf, "172.16.1.10/32", nil, nil, nil)
+	multipath.AddPath("10.0.0.1", model.UndefinedTunnelID, 25)
+	multipath.AddPath("10.0.0.3", model.UndefinedTunnelID, 26)
+
+	single := model.NewVPPIPRoute(1, 1, model.UndefinedSubIf, "172.16.1.11/32", nil, nil, nil)
+	single.AddPath("10.0.0.1", model.UndefinedTunnelID, 27)
+
+	require.NoError(t, service.ChangeFIPRoute(ctx, stream, nil, cfg, storage, nil, &multipath, vppVRF, bgpVRF))
+	require.NoError(t, service.ChangeFIPRoute(ctx, stream, nil, cfg, storage, nil, &single, vppVRF, bgpVRF))
+
+	fibNextHops := func(prefix string) []string {
+		for _, route := range stream.FIB().Routes {
+			if route.Prefix == prefix {
+				var nextHops []string
+
+				for _, path := range route.Paths {
+					nextHops = append(nextHops, path.NextHop)
+				}
+
+				return nextHops
+			}
+		}
+
+		return nil
+	}
+
+	steps := []struct {
+		vrouter   string
+		reachable bool
+		want      []string
+	}{
+		{vrouter: "10.0.0.1", reachable: false, want: []string{"10.0.0.3"}},             // pruned
+		{vrouter: "10.0.0.3", reachable: false, want: []string{"10.0.0.1", "10.0.0.3"}}, // all unreachable, all kept
+		{vrouter: "10.0.0.1", reachable: true, want: []string{"10.0.0.1"}},              // restored, 10.0.0.3 pruned
+		{vrouter: "10.0.0.3", reachable: true, want: []string{"10.0.0.1", "10.0.0.3"}},  // restored
+	}
+
+	for _, step := range steps {
+		service.HandleUDPTunnelReachability(stream, storage, step.vrouter, step.reachable)
+
+		require.ElementsMatch(t, step.want, fibNextHops("172.16.1.10/32"), "%s reachable %t", step.vrouter, step.reachable)
+		require.Equal(t, step.reachable, storage.VPPUDPTunnelStorage.IsReachable(step.vrouter))
+
+		// single path routes and the stored routes are not changed
+
+		require.Equal(t, []string{"10.0.0.1"}, fibNextHops("172.16.1.11/32"))
+		require.Equal(t, []string{"10.0.0.1", "10.0.0.3"}, storage.VPPFIPRouteStorage.GetFIPRoute("172.16.1.10/32").NextHops)
+	}
+}
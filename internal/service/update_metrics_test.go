@@ -0,0 +1,95 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp/dryrun"
+	"git.crptech.ru/cloud/cloudgw/internal/service"
+	"git.crptech.ru/cloud/cloudgw/pkg/exporter/convergenceexporter"
+)
+
+// updateMetrics returns values of tungsten fabric update counters and the number of observed install latencies
+func updateMetrics(t *testing.T) map[string]float64 {
+	t.Helper()
+
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(convergenceexporter.NewCloudgwExporter())
+
+	families, err := reg.Gather()
+	require.NoError(t, err)
+
+	values := make(map[string]float64)
+
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			key := family.GetName()
+
+			for _, label := range metric.GetLabel() {
+				key += " " + label.GetValue()
+			}
+
+			switch {
+			case metric.GetCounter() != nil:
+				values[key] = metric.GetCounter().GetValue()
+			case metric.GetHistogram() != nil:
+				values[key] = float64(metric.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+
+	return values
+}
+
+func TestTFUpdateMetrics(t *testing.T) {
+	ctx := context.Background()
+	stream := dryrun.NewStream(ctx)
+	storage := imdb.NewStorage()
+	cfg := config.Config{
+		TFController: config.TFController{BGPPeerASN: 64512},
+		GoBGP:        config.GoBGP{BGPLocalASN: 65000},
+		VPP:          config.VPP{TunLocalIP: "192.0.0.1/24"},
+	}
+
+	peer := model.NewBGPPeer(model.TF, 64512, "10.0.0.10", 179, "", true, 2, "", 10, 30)
+	require.NoError(t, storage.BGPPeerStorage.AddBGPPeer(&peer))
+
+	vppVRF := &model.VPPVRFTable{Name: "vrf1", ID: 1, FIPPrefixes: []string{"172.16.1.0/24"}}
+
+	require.NoError(t, storage.VPPVRFStorage.AddVRF(vppVRF))
+	require.NoError(t, storage.BGPVRFStorage.AddVRF(&model.BGPVRFTable{Name: "vrf1", ID: 1}))
+	require.NoError(t, vpp.AddDelVRF(stream, true, *vppVRF))
+
+	replayer, err := service.NewMRTReplayer(ctx, stream, cfg, storage)
+	require.NoError(t, err)
+
+	// the floating ip is installed, the internal address is rejected
+
+	var file bytes.Buffer
+
+	file.Write(mrtUpdate(t, time.Now(), "10.0.0.10", false, "172.16.1.10", "10.1.1.1", 25))
+	file.Write(mrtUpdate(t, time.Now(), "10.0.0.10", false, "10.10.0.5", "10.1.1.2", 26))
+
+	before := updateMetrics(t)
+
+	require.NoError(t, replayer.Replay(&file, func(service.MRTReplayEvent) {}))
+
+	after := updateMetrics(t)
+
+	for key, delta := range map[string]float64{
+		"cloudgw_bgp_update_processed_total tf":        2,
+		"cloudgw_bgp_update_rejected_total not_fip tf": 1,
+		"cloudgw_bgp_update_install_seconds tf":        1,
+	} {
+		require.Equal(t, delta, after[key]-before[key], key)
+	}
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/osrg/gobgp/v3/pkg/server"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/gobgp"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/pkg/gobgpapi"
+	"git.crptech.ru/cloud/cloudgw/pkg/logger"
+	"git.crptech.ru/cloud/cloudgw/pkg/tracing"
+)
+
+const phyNetPeerShutdownCommunication = "vpp sub-interface is down"
+
+// AdvWdrawFIPAggrPrefixes advertises/withdraws all aggregated floating ip prefixes of the vrf to/from physical network,
+// a failed prefix does not stop the others and is returned in the joined error. The standby of ha pair and drained
+// gateway advertise the prefixes with HA.StandbyMode and Drain.Methods attributes or do not advertise them.
+func AdvWdrawFIPAggrPrefixes(
+	ctx context.Context,
+	bgpSrv *server.BgpServer,
+	cfg config.Config,
+	isAdvertise bool,
+	vppVRF *model.VPPVRFTable,
+	bgpVRF *model.BGPVRFTable,
+) error {
+	pref, ok := aggrPathPreference(cfg)
+	if isAdvertise && !ok {
+		return nil
+	}
+
+	return advWdrawAggrPrefixes(ctx, bgpSrv, cfg, isAdvertise, vppVRF, bgpVRF, pref)
+}
+
+func advWdrawAggrPrefixes(
+	ctx context.Context,
+	bgpSrv *server.BgpServer,
+	cfg config.Config,
+	isAdvertise bool,
+	vppVRF *model.VPPVRFTable,
+	bgpVRF *model.BGPVRFTable,
+	pref gobgp.PathPreference,
+) (err error) {
+	ctx, span := tracing.Start(ctx, "bgp.AdvWdrawFIPAggrPrefixes",
+		tracing.AddKey.Bool(isAdvertise),
+		tracing.PrefixKey.StringSlice(vppVRF.FIPPrefixes),
+		tracing.VRFKey.Int64(int64(vppVRF.ID)),
+		tracing.NextHopKey.String(vppVRF.LocalAddr),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	var errs []error
+
+	for _, fipAggrPrefix := range vppVRF.FIPPrefixes {
+		aggrFIPNLRIAttr := gobgpapi.NewBGPNLRIAttrs(
+			fipAggrPrefix,
+			vppVRF.LocalAddr, // as the vpp handles the traffic
+			0,                // as vpnv4 belongs to vrf 0
+			bgpVRF.RD,
+			bgpVRF.ImportRT, // because import to vrf where the rt import configured
+			[]uint32{model.UndefinedLabel},
+		)
+
+		// add/delete the aggregated floating ip prefix to/from bgp vpnv4 (vrf) rib (as adding ipv4 route to vrf doesn't work!)
+		// gobgp sends the update only for first received vpnv4 floating ip address, so no need to suppress subsequent updates
+
+		if err := gobgp.AdvWdrawVpnv4Prefix(
+			ctx,
+			bgpSrv,
+			isAdvertise,
+			aggrFIPNLRIAttr,
+			cfg.TFController.BGPPeerASN, // as the tungsten fabric is source of the floating ip
+			pref,
+		); err != nil {
+			logger.Error(
+				"failed to advertise/withdraw vpnv4 prefix to/from physical network",
+				"prefix", fipAggrPrefix,
+				"advertise", isAdvertise,
+				"error", err,
+			)
+
+			errs = append(errs, fmt.Errorf("prefix %s: %w", fipAggrPrefix, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// aggrPathPreference returns attributes aggregated floating ip prefixes are advertised with by ha role and gateway
+// drain, false is returned if the prefixes are not advertised at all
+func aggrPathPreference(cfg config.Config) (gobgp.PathPreference, bool) {
+	pref, ok := haPathPreference(cfg)
+	if !ok {
+		return pref, false
+	}
+
+	return drainPathPreference(cfg, pref)
+}
+
+// refreshAggrPrefixes advertises aggregated floating ip prefixes of vrfs serving floating ips again with the current
+// attributes (replacing the advertised ones) or withdraws them if they are not advertised by ha role or gateway drain.
+// The caller holds fipMu, as floating ip changes advertise the prefixes too.
+func refreshAggrPrefixes(ctx context.Context, bgpSrv *server.BgpServer, cfg config.Config, storage *imdb.Storage) {
+	_, isAdvertise := aggrPathPreference(cfg)
+
+	for _, vppVRF := range storage.VPPVRFStorage.GetVRFs() {
+		if vppVRF.FIPServed == 0 || !vppVRF.LinkUp || vppVRF.Drained {
+			continue
+		}
+
+		bgpVRF := storage.BGPVRFStorage.GetVRF(vppVRF.ID)
+		if bgpVRF == nil {
+			continue
+		}
+
+		if isAdvertise {
+			_ = AdvWdrawFIPAggrPrefixes(ctx, bgpSrv, cfg, ADVERTISE, vppVRF, bgpVRF)
+		} else {
+			_ = advWdrawAggrPrefixes(ctx, bgpSrv, cfg, WITHDRAW, vppVRF, bgpVRF, gobgp.PathPreference{})
+		}
+	}
+}
+
+// HandleVRFLinkState withdraws aggregated floating ip prefixes of the vrf when vpp main interface or vrf sub-interface
+// goes down and advertises them back (if any floating ip is served) when both interfaces are up. fipMu serializes the
+// advertisement with floating ip changes, gateway drain and ha role changes.
+func HandleVRFLinkState(ctx context.Context, bgpSrv *server.BgpServer, cfg config.Config, storage *imdb.Storage, vrfID uint32, isUp bool) {
+	fipMu.Lock()
+	defer fipMu.Unlock()
+
+	if storage.VPPVRFStorage.IsLinkUp(vrfID) == isUp {
+		return
+	}
+
+	vppVRF := storage.VPPVRFStorage.GetVRF(vrfID)
+	if vppVRF == nil {
+		logger.Error("vpp vrf not found for link state change", "vrf id", vrfID)
+
+		return
+	}
+
+	bgpVRF := storage.BGPVRFStorage.GetVRF(vrfID)
+	if bgpVRF == nil {
+		logger.Error("bgp vrf not found for link state change", "vrf id", vrfID)
+
+		return
+	}
+
+	storage.VPPVRFStorage.SetLinkUp(vrfID, isUp)
+
+	if !isUp {
+		_ = AdvWdrawFIPAggrPrefixes(ctx, bgpSrv, cfg, WITHDRAW, vppVRF, bgpVRF)
+
+		logger.Info("vrf link is down, aggregated floating ip prefixes withdrawn", "vrf", vppVRF.Name)
+
+		return
+	}
+
+	if storage.VPPVRFStorage.GetFIPServed(vrfID) == 0 {
+		logger.Info("vrf link is up, no floating ip served", "vrf", vppVRF.Name)
+
+		return
+	}
+
+	if storage.VPPVRFStorage.IsDrained(vrfID) {
+		logger.Info("vrf link is up, vrf is drained", "vrf", vppVRF.Name)
+
+		return
+	}
+
+	_ = AdvWdrawFIPAggrPrefixes(ctx, bgpSrv, cfg, ADVERTISE, vppVRF, bgpVRF)
+
+	logger.Info("vrf link is up, aggregated floating ip prefixes advertised", "vrf", vppVRF.Name)
+}
+
+// HandlePhyNetLinkState shuts down bgp peer to physical network of the vrf when the vrf sub-interface goes down and brings
+// it up when the sub-interface is up. Routes received from the peer are withdrawn from vpp and tungsten fabric by bgp update handler.
+func HandlePhyNetLinkState(ctx context.Context, bgpSrv *server.BgpServer, storage *imdb.Storage, vrfName string, isUp bool) {
+	fipMu.Lock()
+	defer fipMu.Unlock()
+
+	for _, peer := range storage.BGPPeerStorage.GetBGPPeers() {
+		if peer.PeerType != model.PHYNET || peer.VRFName != vrfName {
+			continue
+		}
+
+		if isUp {
+			if peer.AdminDisabled {
+				logger.Info("bgp peer is not enabled as it is disabled by administrator", "peer address", peer.PeerAddress, "vrf", vrfName)
+
+				continue
+			}
+
+			if err := gobgp.EnableBGPPeer(ctx, bgpSrv, peer); err != nil {
+				logger.Error("failed to enable bgp peer", "peer address", peer.PeerAddress, "error", err)
+
+				continue
+			}
+
+			logger.Info("bgp peer enabled as vrf sub-interface is up", "peer address", peer.PeerAddress, "vrf", vrfName)
+
+			continue
+		}
+
+		if err := gobgp.DisableBGPPeer(ctx, bgpSrv, peer, phyNetPeerShutdownCommunication); err != nil {
+			logger.Error("failed to disable bgp peer", "peer address", peer.PeerAddress, "error", err)
+
+			continue
+		}
+
+		logger.Info("bgp peer disabled as vrf sub-interface is down", "peer address", peer.PeerAddress, "vrf", vrfName)
+	}
+}
@@ -0,0 +1,137 @@
+package snapshot
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Check returns inconsistencies of the snapshot: floating ip routes through missed tunnels or in missed vrfs and
+// served floating ip counters of tunnels and vrfs not matching the routes
+func Check(snap Snapshot) []string {
+	var problems []string
+
+	tunnelFIPs := make(map[string]uint32)
+	vrfFIPs := make(map[uint32]uint32)
+
+	tunnels := make(map[string]bool, len(snap.Tunnels))
+	for _, tunnel := range snap.Tunnels {
+		tunnels[tunnel.DstIP] = true
+	}
+
+	vrfs := make(map[uint32]bool, len(snap.VRFs))
+	for _, vrf := range snap.VRFs {
+		vrfs[vrf.ID] = true
+	}
+
+	for _, route := range snap.FIPRoutes {
+		if !vrfs[route.VRFID] {
+			problems = append(problems, fmt.Sprintf("floating ip %s is in missed vrf %d", route.Prefix, route.VRFID))
+		}
+
+		vrfFIPs[route.VRFID]++
+
+		for _, nh := range route.NextHops {
+			if !tunnels[nh] {
+				problems = append(problems, fmt.Sprintf("floating ip %s is routed through missed tunnel to %s", route.Prefix, nh))
+			}
+
+			tunnelFIPs[nh]++
+		}
+	}
+
+	for _, tunnel := range snap.Tunnels {
+		if tunnel.FIPServed != tunnelFIPs[tunnel.DstIP] {
+			problems = append(problems, fmt.Sprintf("tunnel to %s serves %d floating ip(s), %d routed through it",
+				tunnel.DstIP, tunnel.FIPServed, tunnelFIPs[tunnel.DstIP]))
+		}
+	}
+
+	for _, vrf := range snap.VRFs {
+		if vrf.FIPServed != vrfFIPs[vrf.ID] {
+			problems = append(problems, fmt.Sprintf("vrf %s serves %d floating ip(s), %d routed in it",
+				vrf.Name, vrf.FIPServed, vrfFIPs[vrf.ID]))
+		}
+	}
+
+	return problems
+}
+
+// WriteSummary writes the snapshot as tables of vrfs, udp tunnels, floating ip routes and bgp peers followed by the
+// found inconsistencies
+func WriteSummary(w io.Writer, snap Snapshot) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Snapshot\t%s (%s ago)\thost %s, version %d\n",
+		snap.Time.Format(time.RFC3339), time.Since(snap.Time).Round(time.Second), snap.Hostname, snap.Version)
+
+	fmt.Fprintln(tw, "\nVRF\tID\tSUB-INTERFACE\tVLAN\tLABEL\tFIPS\tLINK\tDRAINED")
+
+	for _, vrf := range snap.VRFs {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%s\t%t\n",
+			vrf.Name, vrf.ID, vrf.SubInterfaceID, vrf.VLAN, vrf.MPLSLocalLabel, vrf.FIPServed, upDown(vrf.LinkUp), vrf.Drained)
+	}
+
+	fmt.Fprintln(tw, "\nTUNNEL\tID\tSOURCE\tSOURCE PORT\tFIPS\tREACHABLE")
+
+	for _, tunnel := range snap.Tunnels {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%d\t%d\t%t\n",
+			tunnel.DstIP, tunnel.TunnelID, tunnel.SrcIP, tunnel.SrcPort, tunnel.FIPServed, tunnel.Reachable)
+	}
+
+	fmt.Fprintln(tw, "\nFLOATING IP\tVRF\tPATHS")
+
+	for _, route := range snap.FIPRoutes {
+		paths := make([]string, 0, len(route.NextHops))
+
+		for i, nh := range route.NextHops {
+			paths = append(paths, fmt.Sprintf("%s tunnel %d label %d", nh, valueAt(route.TunnelIDs, i), valueAt(route.FIPMPLSLabels, i)))
+		}
+
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", route.Prefix, route.VRFID, strings.Join(paths, ", "))
+	}
+
+	fmt.Fprintln(tw, "\nPEER\tTYPE\tASN\tVRF\tSTATE\tEND-OF-RIB\tADMIN DISABLED\tBFD\tLAST ACTIVITY")
+
+	for _, peer := range snap.Peers {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%t\t%t\t%s\t%s\n",
+			peer.Address, peer.Type, peer.ASN, peer.VRF, peer.State, peer.EndOfRIBReceived, peer.AdminDisabled,
+			upDown(peer.BFDEstablished), peer.LastActivity.Format(time.RFC3339))
+	}
+
+	_ = tw.Flush()
+
+	problems := Check(snap)
+	slices.Sort(problems)
+
+	if len(problems) == 0 {
+		fmt.Fprintln(w, "\nno inconsistencies found")
+
+		return
+	}
+
+	fmt.Fprintf(w, "\n%d inconsistencies found:\n", len(problems))
+
+	for _, problem := range problems {
+		fmt.Fprintf(w, "  %s\n", problem)
+	}
+}
+
+func upDown(isUp bool) string {
+	if isUp {
+		return "up"
+	}
+
+	return "down"
+}
+
+func valueAt(values []uint32, i int) uint32 {
+	if i < len(values) {
+		return values[i]
+	}
+
+	return 0
+}
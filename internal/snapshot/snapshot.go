@@ -0,0 +1,185 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	bgpapi "github.com/osrg/gobgp/v3/api"
+
+	"git.crptech.ru/cloud/cloudgw/internal/config"
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/pkg/logger"
+)
+
+// Version of the snapshot format, snapshots of other versions are not read
+const Version = 1
+
+// Snapshot is the memory storage state written to disk for warm start and post-incident analysis (cloudgw inspect)
+type Snapshot struct {
+	Version   int                  `json:"Version"`
+	Time      time.Time            `json:"Time"`
+	Hostname  string               `json:"Hostname"`
+	VRFs      []model.VPPVRFTable  `json:"VRFs"` // with sub-interface ids assigned by vpp and mpls local labels
+	Tunnels   []model.VPPUDPTunnel `json:"Tunnels"`
+	FIPRoutes []model.VPPIPRoute   `json:"FIPRoutes"`
+	Peers     []Peer               `json:"Peers"`
+}
+
+// Peer is the bgp peer state, passwords are not written
+type Peer struct {
+	Type             string    `json:"Type"` // tf or phynet
+	Address          string    `json:"Address"`
+	ASN              uint32    `json:"ASN"`
+	VRF              string    `json:"VRF,omitempty"`
+	State            string    `json:"State"`
+	PrevState        string    `json:"PrevState"`
+	LastActivity     time.Time `json:"LastActivity"`
+	EndOfRIBReceived bool      `json:"EndOfRIBReceived"`
+	AdminDisabled    bool      `json:"AdminDisabled"`
+	BFDEstablished   bool      `json:"BFDEstablished"`
+}
+
+// Capture returns the snapshot of the storage state
+func Capture(storage *imdb.Storage) Snapshot {
+	dump := storage.Dump()
+	hostname, _ := os.Hostname()
+
+	snap := Snapshot{
+		Version:   Version,
+		Time:      time.Now(),
+		Hostname:  hostname,
+		VRFs:      dump.VPPVRFs,
+		Tunnels:   dump.UDPTunnels,
+		FIPRoutes: dump.FIPRoutes,
+	}
+
+	for _, peer := range dump.BGPPeers {
+		snapPeer := Peer{
+			Type:             "phynet",
+			Address:          peer.PeerAddress,
+			ASN:              peer.PeerASN,
+			VRF:              peer.VRFName,
+			State:            stateName(peer.BGPPeerState),
+			PrevState:        stateName(peer.BGPPeerPrevState),
+			LastActivity:     peer.BGPPeerLastActivity,
+			EndOfRIBReceived: peer.EndOfRIBReceived,
+			AdminDisabled:    peer.AdminDisabled,
+		}
+
+		if peer.PeerType == model.TF {
+			snapPeer.Type = "tf"
+		}
+
+		if peer.BFDPeering != nil {
+			snapPeer.BFDEstablished = peer.BFDPeering.BFDPeerEstablished
+		}
+
+		snap.Peers = append(snap.Peers, snapPeer)
+	}
+
+	return snap
+}
+
+// Write writes the snapshot to the file atomically: the snapshot is written to a temporary file in the same directory
+// which replaces the file, so the file always has a complete snapshot (the previous one if writing failed)
+func Write(path string, snap Snapshot) error {
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	dir := filepath.Dir(path)
+
+	if err = os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary snapshot file: %w", err)
+	}
+
+	defer os.Remove(tmp.Name()) // no-op after rename
+
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to write temporary snapshot file: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace snapshot file: %w", err)
+	}
+
+	// the rename is durable after the directory is synced
+
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		_ = d.Close()
+	}
+
+	return nil
+}
+
+// Read reads the snapshot file
+func Read(path string) (Snapshot, error) {
+	var snap Snapshot
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return snap, err
+	}
+
+	if err = json.Unmarshal(data, &snap); err != nil {
+		return snap, fmt.Errorf("failed to decode snapshot %s: %w", path, err)
+	}
+
+	if snap.Version != Version {
+		return snap, fmt.Errorf("snapshot %s has version %d, expected %d", path, snap.Version, Version)
+	}
+
+	return snap, nil
+}
+
+// Save captures the storage snapshot and writes it to the file
+func Save(path string, storage *imdb.Storage) error {
+	return Write(path, Capture(storage))
+}
+
+// Run writes the storage snapshot with the configured interval until the context is canceled
+func Run(ctx context.Context, cfg config.Snapshot, storage *imdb.Storage) {
+	if cfg.Interval <= 0 {
+		return // written on shutdown only
+	}
+
+	ticker := time.NewTicker(time.Duration(cfg.Interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := Save(cfg.Path, storage); err != nil {
+				logger.Error("failed to write state snapshot", "file", cfg.Path, "error", err)
+			}
+		}
+	}
+}
+
+// stateName returns lower case bgp session state, e.g. "established"
+func stateName(state bgpapi.PeerState_SessionState) string {
+	return strings.ToLower(state.String())
+}
@@ -0,0 +1,93 @@
+package snapshot_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/internal/snapshot"
+)
+
+func newStorage(t *testing.T) *imdb.Storage {
+	storage := imdb.NewStorage()
+
+	peer := model.NewBGPPeer(model.PHYNET, 64555, "10.0.1.254", 179, "secret", true, 1, "vrf1", 3, 9)
+	peer.BFDPeering = &model.BFDPeer{BFDPeerEstablished: true}
+
+	require.NoError(t, storage.BGPPeerStorage.AddBGPPeer(&peer))
+	require.NoError(t, storage.VPPVRFStorage.AddVRF(&model.VPPVRFTable{Name: "vrf1", ID: 1, SubInterfaceID: 3, MPLSLocalLabel: 1001, FIPServed: 1}))
+	require.NoError(t, storage.VPPUDPTunnelStorage.AddUDPTunnel(&model.VPPUDPTunnel{TunnelID: 2, SrcIP: "192.0.0.1", DstIP: "10.0.0.1", SrcPort: 50001, FIPServed: 1}))
+	require.NoError(t, storage.VPPFIPRouteStorage.AddFIPRoute(&model.VPPIPRoute{
+		VRFID: 1, Prefix: "172.16.1.10/32", NextHops: []string{"10.0.0.1"}, TunnelIDs: []uint32{2}, FIPMPLSLabels: []uint32{25},
+	}))
+
+	return storage
+}
+
+func TestWriteRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "snapshot.json")
+
+	require.NoError(t, snapshot.Save(path, newStorage(t)))
+
+	snap, err := snapshot.Read(path)
+	require.NoError(t, err)
+	require.Equal(t, snapshot.Version, snap.Version)
+	require.Equal(t, model.VPPUDPTunnel{TunnelID: 2, SrcIP: "192.0.0.1", DstIP: "10.0.0.1", SrcPort: 50001, FIPServed: 1}, snap.Tunnels[0])
+	require.Equal(t, uint32(3), uint32(snap.VRFs[0].SubInterfaceID))
+	require.Equal(t, []string{"10.0.0.1"}, snap.FIPRoutes[0].NextHops)
+	require.Equal(t, snapshot.Peer{
+		Type: "phynet", Address: "10.0.1.254", ASN: 64555, VRF: "vrf1", State: "unknown", PrevState: "unknown",
+		LastActivity: snap.Peers[0].LastActivity, BFDEstablished: true,
+	}, snap.Peers[0])
+	require.Empty(t, snapshot.Check(snap))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "secret")
+
+	// the file is replaced, temporary files are removed
+
+	require.NoError(t, snapshot.Save(path, imdb.NewStorage()))
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	snap, err = snapshot.Read(path)
+	require.NoError(t, err)
+	require.Empty(t, snap.Tunnels)
+}
+
+func TestReadVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+
+	require.NoError(t, snapshot.Write(path, snapshot.Snapshot{Version: snapshot.Version + 1}))
+
+	_, err := snapshot.Read(path)
+	require.ErrorContains(t, err, "version")
+
+	_, err = snapshot.Read(filepath.Join(t.TempDir(), "missed.json"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestCheck(t *testing.T) {
+	snap := snapshot.Capture(newStorage(t))
+	snap.Tunnels[0].FIPServed = 2
+	snap.FIPRoutes[0].NextHops = append(snap.FIPRoutes[0].NextHops, "10.0.0.2")
+
+	require.ElementsMatch(t, []string{
+		"floating ip 172.16.1.10/32 is routed through missed tunnel to 10.0.0.2",
+		"tunnel to 10.0.0.1 serves 2 floating ip(s), 1 routed through it",
+	}, snapshot.Check(snap))
+
+	var buf bytes.Buffer
+
+	snapshot.WriteSummary(&buf, snap)
+	require.Contains(t, buf.String(), "172.16.1.10/32")
+	require.Contains(t, buf.String(), "2 inconsistencies found")
+}
@@ -0,0 +1,103 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"git.crptech.ru/cloud/cloudgw/pkg/logger"
+)
+
+const (
+	ResultOK     = "ok"
+	ResultFailed = "failed"
+)
+
+// Record is an audit record of administrative action
+type Record struct {
+	Time       time.Time `json:"Time"`
+	Actor      string    `json:"Actor"`
+	RemoteAddr string    `json:"RemoteAddr"`
+	Action     string    `json:"Action"`
+	Target     string    `json:"Target"`
+	Result     string    `json:"Result"`
+	Error      string    `json:"Error,omitempty"`
+}
+
+// Logger writes audit records to the app log and (if the file is set) as json lines to the audit file
+type Logger struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// New returns audit logger, audit records are appended to the file at path (if not empty)
+func New(path string) (*Logger, error) {
+	l := &Logger{}
+
+	if path == "" {
+		return l, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log file: %w", err)
+	}
+
+	l.file = file
+
+	return l, nil
+}
+
+// Log writes audit record of the action with result depending on err
+func (l *Logger) Log(actor, remoteAddr, action, target string, err error) {
+	record := Record{
+		Time:       time.Now(),
+		Actor:      actor,
+		RemoteAddr: remoteAddr,
+		Action:     action,
+		Target:     target,
+		Result:     ResultOK,
+	}
+
+	if err != nil {
+		record.Result = ResultFailed
+		record.Error = err.Error()
+	}
+
+	logger.Info(
+		"audit",
+		"actor", record.Actor,
+		"remote address", record.RemoteAddr,
+		"action", record.Action,
+		"target", record.Target,
+		"result", record.Result,
+		"error", record.Error,
+	)
+
+	if l.file == nil {
+		return
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err = l.file.Write(append(line, '\n')); err != nil {
+		logger.Error("failed to write audit record", "error", err)
+	}
+}
+
+// Close closes the audit file
+func (l *Logger) Close() error {
+	if l.file == nil {
+		return nil
+	}
+
+	return l.file.Close()
+}
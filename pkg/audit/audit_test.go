@@ -0,0 +1,62 @@
+package audit_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"git.crptech.ru/cloud/cloudgw/pkg/audit"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	auditLogger, err := audit.New(path)
+	require.NoError(t, err)
+
+	auditLogger.Log("admin", "192.0.2.1:50000", "bgp peer reset", "203.0.113.1", nil)
+	auditLogger.Log("admin", "192.0.2.1:50000", "vrf drain", "vrf1", errors.New("vrf not found"))
+
+	require.NoError(t, auditLogger.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+
+	defer file.Close()
+
+	var records []audit.Record
+
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		var record audit.Record
+
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+
+		records = append(records, record)
+	}
+
+	require.Len(t, records, 2)
+
+	require.Equal(t, "bgp peer reset", records[0].Action)
+	require.Equal(t, "203.0.113.1", records[0].Target)
+	require.Equal(t, audit.ResultOK, records[0].Result)
+	require.Empty(t, records[0].Error)
+
+	require.Equal(t, audit.ResultFailed, records[1].Result)
+	require.Equal(t, "vrf not found", records[1].Error)
+}
+
+func TestLogWithoutFile(t *testing.T) {
+	auditLogger, err := audit.New("")
+	require.NoError(t, err)
+
+	auditLogger.Log("admin", "192.0.2.1:50000", "floating ip resync", "", nil)
+
+	require.NoError(t, auditLogger.Close())
+}
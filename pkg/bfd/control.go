@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
+
+	"github.com/google/gopacket/layers"
 
 	"git.crptech.ru/cloud/cloudgw/pkg/logger"
 )
@@ -15,6 +18,10 @@ type Control struct {
 	Family   int
 	RxQueue  chan *RxData
 	sessions []*Session
+
+	ctx              context.Context
+	mu               sync.Mutex // guards sessions
+	echoReceiverOnce sync.Once
 }
 
 func NewControl(ctx context.Context, localIP string, family int) *Control {
@@ -22,19 +29,21 @@ func NewControl(ctx context.Context, localIP string, family int) *Control {
 		LocalIP: localIP,
 		Family:  family,
 		RxQueue: make(chan *RxData),
+		ctx:     ctx,
 	}
 	c.Run(ctx)
 
 	return c
 }
 
-// AddSession adds a peer that need to be detected
+// AddSession adds a peer that need to be detected (intervals in msec, echoInterval = 0 disables echo function)
 func (c *Control) AddSession(
 	remoteIP string,
 	passive bool,
 	rxInterval,
 	txInterval,
-	detectMult int,
+	detectMult,
+	echoInterval int,
 	fn CallbackFunc,
 	chBFDDone chan struct{},
 ) {
@@ -46,11 +55,22 @@ func (c *Control) AddSession(
 		rxInterval*1000,
 		txInterval*1000,
 		detectMult,
+		echoInterval*1000,
 		fn,
 		chBFDDone,
 	)
 
+	// echo packets are looped back by the remote dataplane to the local address
+
+	if echoInterval > 0 {
+		c.echoReceiverOnce.Do(func() {
+			c.initEchoReceiver(c.ctx)
+		})
+	}
+
+	c.mu.Lock()
 	c.sessions = append(c.sessions, s)
+	c.mu.Unlock()
 
 	logger.Info("bfd session created successfully", "remote ip", remoteIP)
 }
@@ -65,8 +85,13 @@ func (c *Control) DelSession(remoteIP string) error {
 		}
 	}()
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	for i, session := range c.sessions {
 		if session.RemoteIP == remoteIP {
+			session.stopEcho()
+
 			session.clientQuit <- true
 
 			c.sessions = append(c.sessions[:i], c.sessions[i+1:]...)
@@ -76,6 +101,20 @@ func (c *Control) DelSession(remoteIP string) error {
 	return nil
 }
 
+// GetSessionStates returns snapshots of all sessions states
+func (c *Control) GetSessionStates() []SessionState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	states := make([]SessionState, 0, len(c.sessions))
+
+	for _, session := range c.sessions {
+		states = append(states, session.GetState())
+	}
+
+	return states
+}
+
 func (c *Control) Run(ctx context.Context) {
 	go c.backgroundRun(ctx)
 
@@ -112,6 +151,9 @@ func (c *Control) processPackets(rxdt *RxData) {
 
 	bfdPacket := rxdt.Data
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if bfdPacket.YourDiscriminator > 0 {
 		for _, session := range c.sessions {
 			if session.LocalDiscr == bfdPacket.YourDiscriminator {
@@ -131,3 +173,17 @@ func (c *Control) processPackets(rxdt *RxData) {
 		}
 	}
 }
+
+// sessionByDiscr returns the session with the local discriminator or nil
+func (c *Control) sessionByDiscr(discr layers.BFDDiscriminator) *Session {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, session := range c.sessions {
+		if session.LocalDiscr == discr {
+			return session
+		}
+	}
+
+	return nil
+}
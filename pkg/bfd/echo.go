@@ -0,0 +1,456 @@
+package bfd
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"git.crptech.ru/cloud/cloudgw/pkg/logger"
+)
+
+const (
+	EchoPort = 3785 // rfc5881
+
+	echoPacketLen = 16
+	arpTablePath  = "/proc/net/arp"
+)
+
+// EchoStats describes BFD Echo function state and counters of a session
+type EchoStats struct {
+	Enabled             bool          // echo function configured locally
+	Active              bool          // echo packets are being transmitted (session is up and remote accepts echo)
+	TxInterval          uint32        // usec, actual echo transmit interval
+	RemoteMinRxInterval uint32        // usec, Required Min Echo RX Interval received from remote
+	TxPackets           uint64        // echo packets sent
+	RxPackets           uint64        // echo packets looped back by remote
+	Failures            uint64        // number of echo detection time expirations
+	LastRTT             time.Duration // round trip time of last looped back echo packet
+}
+
+// echoPacket is a payload of BFD Echo packet. The payload is a local matter (rfc5880 6.4), so only
+// fields needed to demultiplex the packet to the session and calculate the round trip time are used
+type echoPacket struct {
+	Discriminator layers.BFDDiscriminator
+	Sequence      uint32
+	TxTime        int64 // unix nano
+}
+
+func encodeEchoPacket(p echoPacket) []byte {
+	b := make([]byte, echoPacketLen)
+
+	binary.BigEndian.PutUint32(b[0:4], uint32(p.Discriminator))
+	binary.BigEndian.PutUint32(b[4:8], p.Sequence)
+	binary.BigEndian.PutUint64(b[8:16], uint64(p.TxTime))
+
+	return b
+}
+
+func decodeEchoPacket(b []byte) (echoPacket, error) {
+	if len(b) < echoPacketLen {
+		return echoPacket{}, ErrBFDEchoPacketDecode
+	}
+
+	return echoPacket{
+		Discriminator: layers.BFDDiscriminator(binary.BigEndian.Uint32(b[0:4])),
+		Sequence:      binary.BigEndian.Uint32(b[4:8]),
+		TxTime:        int64(binary.BigEndian.Uint64(b[8:16])),
+	}, nil
+}
+
+// EchoClient sends BFD Echo packets addressed to the local address to the link layer address of remote, so the
+// forwarding plane of remote loops them back without its BFD process involved (rfc5881 4). Echo packets are sent over
+// a packet socket, as an ip socket would deliver a packet to the local address locally.
+type EchoClient struct {
+	fd      int
+	dst     syscall.SockaddrLinklayer
+	localIP net.IP
+	srcPort uint16
+}
+
+// NewEchoClient creates a packet socket on the interface of localAddr sending echo packets to the link layer address
+// of remoteAddr, the address is taken from the neighbor (arp) table filled by bfd control packets
+func NewEchoClient(localAddr, remoteAddr string) (*EchoClient, error) {
+	localIP := net.ParseIP(localAddr).To4()
+	if localIP == nil {
+		return nil, fmt.Errorf("bfd echo local address %q is not ipv4", localAddr)
+	}
+
+	iface, err := interfaceByIP(localIP)
+	if err != nil {
+		return nil, err
+	}
+
+	mac, err := neighborMAC(arpTablePath, remoteAddr, iface.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isAcceptLocal(iface.Name) {
+		logger.Warn("bfd echo packets looped back by remote are dropped as martians, enable accept_local",
+			"sysctl", fmt.Sprintf("net.ipv4.conf.%s.accept_local", iface.Name))
+	}
+
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, int(htons(syscall.ETH_P_IP)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bfd echo packet socket: %w", err)
+	}
+
+	dst := syscall.SockaddrLinklayer{Protocol: htons(syscall.ETH_P_IP), Ifindex: iface.Index, Halen: uint8(len(mac))}
+	copy(dst.Addr[:], mac)
+
+	return &EchoClient{
+		fd:      fd,
+		dst:     dst,
+		localIP: localIP,
+		srcPort: uint16(RandInt(SourcePortMin, SourcePortMax)),
+	}, nil
+}
+
+// Write sends the echo payload in an ip/udp packet from and to the local address
+func (c *EchoClient) Write(payload []byte) (int, error) {
+	packet, err := encodeEchoIPPacket(c.localIP, c.srcPort, payload)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = syscall.Sendto(c.fd, packet, 0, &c.dst); err != nil {
+		return 0, err
+	}
+
+	return len(payload), nil
+}
+
+func (c *EchoClient) Close() error {
+	return syscall.Close(c.fd)
+}
+
+// encodeEchoIPPacket returns ipv4 packet of the echo payload with the local address as source and destination
+func encodeEchoIPPacket(localIP net.IP, srcPort uint16, payload []byte) ([]byte, error) {
+	ip := &layers.IPv4{
+		Version:  4,
+		TOS:      0xC0, // CS6 for control plane packets
+		TTL:      255,
+		Protocol: layers.IPProtocolUDP,
+		SrcIP:    localIP,
+		DstIP:    localIP,
+	}
+
+	udp := &layers.UDP{SrcPort: layers.UDPPort(srcPort), DstPort: EchoPort}
+
+	if err := udp.SetNetworkLayerForChecksum(ip); err != nil {
+		return nil, err
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+
+	if err := gopacket.SerializeLayers(buf, opts, ip, udp, gopacket.Payload(payload)); err != nil {
+		return nil, fmt.Errorf("failed to encode bfd echo packet: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func interfaceByIP(ip net.IP) (*net.Interface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range ifaces {
+		addrs, err := ifaces[i].Addrs()
+		if err != nil {
+			continue
+		}
+
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+				return &ifaces[i], nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("no interface with bfd echo local address %s", ip)
+}
+
+// neighborMAC returns the link layer address of the complete arp entry of ip on the interface
+func neighborMAC(path, ip, ifaceName string) (net.HardwareAddr, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	return parseARPTable(file, ip, ifaceName)
+}
+
+// parseARPTable finds the address in /proc/net/arp: IP address, HW type, Flags, HW address, Mask, Device
+func parseARPTable(r io.Reader, ip, ifaceName string) (net.HardwareAddr, error) {
+	const arpFlagComplete = 0x2
+
+	scanner := bufio.NewScanner(r)
+
+	scanner.Scan() // header
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 || fields[0] != ip || fields[5] != ifaceName {
+			continue
+		}
+
+		flags, err := strconv.ParseUint(fields[2], 0, 32)
+		if err != nil || flags&arpFlagComplete == 0 {
+			continue
+		}
+
+		return net.ParseMAC(fields[3])
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, fmt.Errorf("no arp entry of bfd remote %s on %s", ip, ifaceName)
+}
+
+// isAcceptLocal checks if the interface accepts packets with a local source address (looped back echo packets)
+func isAcceptLocal(ifaceName string) bool {
+	for _, name := range []string{"all", ifaceName} {
+		value, err := os.ReadFile(fmt.Sprintf("/proc/sys/net/ipv4/conf/%s/accept_local", name))
+		if err == nil && strings.TrimSpace(string(value)) == "1" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func htons(v uint16) uint16 {
+	return v<<8 | v>>8
+}
+
+// echoLoop transmits BFD Echo packets while the echo function is active (session and remote are up and remote
+// advertises non-zero Required Min Echo RX Interval). Looped back packets are received by the control.
+func (s *Session) echoLoop() {
+	defer s.closeEchoConn()
+
+	for {
+		select {
+		case <-s.echoQuit:
+			return
+		default:
+		}
+
+		s.mu.Lock()
+		active, txInterval := s.isEchoActive(), s.echoTxInterval()
+		s.mu.Unlock()
+
+		if !active {
+			// the link layer address of remote is resolved again on activation
+
+			s.setEchoActive(false, txInterval)
+			s.closeEchoConn()
+
+			time.Sleep(time.Duration(s.EchoInterval) * time.Microsecond)
+
+			continue
+		}
+
+		if s.echoConn == nil {
+			conn, err := NewEchoClient(s.LocalIP, s.RemoteIP)
+			if err != nil {
+				logger.Error("failed to create bfd echo client", "remote ip", s.RemoteIP, "error", err)
+
+				time.Sleep(time.Duration(s.DetectMult) * time.Duration(txInterval) * time.Microsecond)
+
+				continue
+			}
+
+			s.echoConn = conn
+		}
+
+		s.setEchoActive(true, txInterval)
+
+		s.TxEchoPacket()
+
+		// echo packets are jittered the same way as control packets (up to 25%)
+
+		interval := float64(txInterval) * (1 - (rand.Float64() * 0.25))
+
+		time.Sleep(time.Duration(int(interval)) * time.Microsecond)
+	}
+}
+
+// TxEchoPacket sends a BFD Echo packet to remote
+func (s *Session) TxEchoPacket() {
+	s.echoMu.Lock()
+	s.echoSeq++
+	p := echoPacket{
+		Discriminator: s.LocalDiscr,
+		Sequence:      s.echoSeq,
+		TxTime:        time.Now().UnixNano(),
+	}
+	s.echoMu.Unlock()
+
+	if _, err := s.echoConn.Write(encodeEchoPacket(p)); err != nil {
+		logger.Debug("failed to send bfd echo packet", "remote ip", s.RemoteIP, "error", err)
+
+		return
+	}
+
+	s.echoMu.Lock()
+	s.echoStats.TxPackets++
+	s.echoMu.Unlock()
+}
+
+// RxEchoPacket handles BFD Echo packet looped back by remote
+func (s *Session) RxEchoPacket(p echoPacket) {
+	now := time.Now()
+
+	s.echoMu.Lock()
+	defer s.echoMu.Unlock()
+
+	s.lastEchoRxTime = now.UnixNano() / 1e6 // ms
+	s.echoStats.RxPackets++
+	s.echoStats.LastRTT = time.Duration(now.UnixNano() - p.TxTime)
+}
+
+// isEchoActive checks if echo packets are to be transmitted, the caller holds s.mu
+func (s *Session) isEchoActive() bool {
+	return s.EchoInterval > 0 &&
+		s.State == layers.BFDStateUp &&
+		s.RemoteState == layers.BFDStateUp &&
+		s.remoteMinEchoRxInterval > 0
+}
+
+func (s *Session) setEchoActive(active bool, txInterval uint32) {
+	s.echoMu.Lock()
+	defer s.echoMu.Unlock()
+
+	if s.echoStats.Active == active {
+		return
+	}
+
+	// give the remote full detection time before the first looped back echo packet is expected
+
+	if active {
+		s.lastEchoRxTime = time.Now().UnixNano() / 1e6 // ms
+
+		logger.Info("bfd echo function activated", "remote address", s.RemoteIP, "tx interval", txInterval)
+	} else {
+		logger.Info("bfd echo function deactivated", "remote address", s.RemoteIP)
+	}
+
+	s.echoStats.Active = active
+}
+
+// echoTxInterval returns echo transmit interval (usec), it must not be less than remote Required Min Echo RX Interval.
+// The caller holds s.mu.
+func (s *Session) echoTxInterval() uint32 {
+	if s.remoteMinEchoRxInterval > uint32(s.EchoInterval) {
+		return s.remoteMinEchoRxInterval
+	}
+
+	return uint32(s.EchoInterval)
+}
+
+// echoDetectTimeExpired checks if no echo packets were looped back during the echo detection time, the caller holds s.mu
+func (s *Session) echoDetectTimeExpired() bool {
+	s.echoMu.Lock()
+	defer s.echoMu.Unlock()
+
+	if !s.echoStats.Active {
+		return false
+	}
+
+	detectTime := int64(s.DetectMult) * int64(s.echoTxInterval()) / 1000 // ms
+
+	return time.Now().UnixNano()/1e6-s.lastEchoRxTime > detectTime
+}
+
+func (s *Session) setRemoteMinEchoRxInterval(value uint32) {
+	if value == s.remoteMinEchoRxInterval {
+		return
+	}
+
+	logger.Debug("remote required min echo rx interval changed", "remote address", s.RemoteIP, "interval", value)
+
+	s.remoteMinEchoRxInterval = value
+}
+
+func (s *Session) closeEchoConn() {
+	if s.echoConn != nil {
+		_ = s.echoConn.Close()
+
+		s.echoConn = nil
+	}
+}
+
+// stopEcho stops echo transmitting and receiving
+func (s *Session) stopEcho() {
+	s.echoQuitOnce.Do(func() {
+		close(s.echoQuit)
+	})
+}
+
+// initEchoReceiver starts the receiver of BFD Echo packets looped back by remotes, the packets are addressed to the
+// local address and demultiplexed to sessions by the discriminator. Echo packets of remotes are addressed to remotes
+// themselves and are forwarded back without BFD involved.
+func (c *Control) initEchoReceiver(ctx context.Context) {
+	addr, err := net.ResolveUDPAddr("udp4", fmt.Sprintf("%s:%d", c.LocalIP, EchoPort))
+	if err != nil {
+		logger.Error("failed to resolve bfd echo receiver address", "error", err)
+
+		return
+	}
+
+	conn, err := net.ListenUDP("udp4", addr)
+	if err != nil {
+		logger.Error("failed to start bfd echo receiver", "error", err)
+
+		return
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		_ = conn.Close()
+	}()
+
+	go func() {
+		data := make([]byte, 1024)
+
+		for {
+			n, err := conn.Read(data)
+			if err != nil {
+				logger.Debug("bfd echo receiver stopped", "error", err)
+
+				return
+			}
+
+			p, err := decodeEchoPacket(data[:n])
+			if err != nil {
+				continue
+			}
+
+			if session := c.sessionByDiscr(p.Discriminator); session != nil {
+				session.RxEchoPacket(p)
+			}
+		}
+	}()
+
+	logger.Debug("bfd echo receiver started", "local address", c.LocalIP, "port", EchoPort)
+}
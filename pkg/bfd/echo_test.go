@@ -0,0 +1,173 @@
+package bfd
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEchoPacket(t *testing.T) {
+	p := echoPacket{Discriminator: 0x01020304, Sequence: 7, TxTime: time.Now().UnixNano()}
+
+	b := encodeEchoPacket(p)
+	require.Len(t, b, echoPacketLen)
+
+	decoded, err := decodeEchoPacket(b)
+	require.NoError(t, err)
+	require.Equal(t, p, decoded)
+
+	_, err = decodeEchoPacket(b[:echoPacketLen-1])
+	require.Error(t, err)
+}
+
+func TestEncodeEchoIPPacket(t *testing.T) {
+	localIP := net.ParseIP("10.12.0.1").To4()
+	payload := encodeEchoPacket(echoPacket{Discriminator: 1, Sequence: 2, TxTime: 3})
+
+	b, err := encodeEchoIPPacket(localIP, 50000, payload)
+	require.NoError(t, err)
+
+	packet := gopacket.NewPacket(b, layers.LayerTypeIPv4, gopacket.Default)
+
+	ip, ok := packet.Layer(layers.LayerTypeIPv4).(*layers.IPv4)
+	require.True(t, ok)
+
+	// the packet is addressed to the local address, the remote forwards it back
+
+	require.True(t, ip.SrcIP.Equal(localIP))
+	require.True(t, ip.DstIP.Equal(localIP))
+	require.Equal(t, uint8(255), ip.TTL)
+	require.Equal(t, uint8(0xC0), ip.TOS)
+
+	udp, ok := packet.Layer(layers.LayerTypeUDP).(*layers.UDP)
+	require.True(t, ok)
+	require.Equal(t, layers.UDPPort(50000), udp.SrcPort)
+	require.Equal(t, layers.UDPPort(EchoPort), udp.DstPort)
+	require.Equal(t, payload, udp.Payload)
+}
+
+func TestParseARPTable(t *testing.T) {
+	const table = `IP address       HW type     Flags       HW address            Mask     Device
+10.12.0.3        0x1         0x2         52:54:00:12:34:56     *        eth1
+10.12.0.4        0x1         0x0         00:00:00:00:00:00     *        eth1
+10.13.0.3        0x1         0x2         52:54:00:ab:cd:ef     *        eth2
+`
+
+	tests := []struct {
+		name    string
+		ip      string
+		iface   string
+		want    string
+		wantErr bool
+	}{
+		{name: "complete entry", ip: "10.12.0.3", iface: "eth1", want: "52:54:00:12:34:56"},
+		{name: "incomplete entry", ip: "10.12.0.4", iface: "eth1", wantErr: true},
+		{name: "other interface", ip: "10.13.0.3", iface: "eth1", wantErr: true},
+		{name: "no entry", ip: "10.12.0.9", iface: "eth1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mac, err := parseARPTable(strings.NewReader(table), tt.ip, tt.iface)
+			if tt.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, mac.String())
+		})
+	}
+}
+
+// newEchoSession returns up session with active echo function, no goroutines are started
+func newEchoSession(echoInterval, remoteMinEchoRxInterval uint32, sinceLastRx time.Duration) *Session {
+	return &Session{
+		clientDown:              make(chan bool),
+		callFunc:                func(string, int, int) {},
+		RemoteIP:                "10.12.0.3",
+		State:                   layers.BFDStateUp,
+		RemoteState:             layers.BFDStateUp,
+		DetectMult:              3,
+		EchoInterval:            int(echoInterval),
+		remoteMinEchoRxInterval: remoteMinEchoRxInterval,
+		echoStats:               EchoStats{Enabled: true, Active: true},
+		lastEchoRxTime:          time.Now().Add(-sinceLastRx).UnixNano() / 1e6,
+	}
+}
+
+func TestEchoDetectTimeExpired(t *testing.T) {
+	tests := []struct {
+		name                    string
+		active                  bool
+		echoInterval            uint32 // usec
+		remoteMinEchoRxInterval uint32 // usec
+		sinceLastRx             time.Duration
+		want                    bool
+	}{
+		{name: "inactive", echoInterval: 10000, remoteMinEchoRxInterval: 10000, sinceLastRx: time.Second},
+		{name: "looped back recently", active: true, echoInterval: 10000, remoteMinEchoRxInterval: 10000, sinceLastRx: 10 * time.Millisecond},
+		{name: "expired", active: true, echoInterval: 10000, remoteMinEchoRxInterval: 10000, sinceLastRx: time.Second, want: true},
+		// the detect time follows the remote interval if it is greater than the local one
+		{name: "remote interval is greater", active: true, echoInterval: 10000, remoteMinEchoRxInterval: 500000, sinceLastRx: time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newEchoSession(tt.echoInterval, tt.remoteMinEchoRxInterval, tt.sinceLastRx)
+			s.echoStats.Active = tt.active
+
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			require.Equal(t, tt.want, s.echoDetectTimeExpired())
+		})
+	}
+}
+
+func TestDetectFailureEcho(t *testing.T) {
+	s := newEchoSession(10000, 10000, time.Second)
+
+	chBFDDone := make(chan struct{})
+
+	go s.DetectFailure(chBFDDone)
+
+	defer close(s.clientDown)
+
+	select {
+	case <-chBFDDone:
+	case <-time.After(time.Second):
+		t.Fatal("echo failure is not detected")
+	}
+
+	state := s.GetState()
+
+	require.Equal(t, layers.BFDStateDown.String(), state.State)
+	require.Equal(t, layers.BFDDiagnosticEchoFailed.String(), state.LocalDiag)
+	require.Equal(t, uint64(1), state.Echo.Failures)
+
+	// the session goes up and fails again while the app shuts down, the channel is closed once
+
+	for _, diag := range []layers.BFDDiagnostic{layers.BFDDiagnosticEchoFailed, layers.BFDDiagnosticTimeExpired} {
+		s.mu.Lock()
+		s.State = layers.BFDStateUp
+		s.LocalDiag = layers.BFDDiagnosticNone
+
+		if diag == layers.BFDDiagnosticTimeExpired {
+			s.asyncDetectTime = 30000 // usec
+			s.LastRxPacketTime = time.Now().Add(-time.Second).UnixNano() / 1e6
+		}
+
+		s.mu.Unlock()
+
+		require.Eventually(t, func() bool {
+			return s.GetState().LocalDiag == diag.String()
+		}, time.Second, 10*time.Millisecond, diag.String())
+	}
+}
@@ -11,4 +11,5 @@ var (
 	ErrBFDAuthType           = errors.New("bfd auth type error")
 	ErrBFDAuthKeyID          = errors.New("bfd auth key id error")
 	ErrBFDAuthHeaderData     = errors.New("bfd auth header data error")
+	ErrBFDEchoPacketDecode   = errors.New("bfd echo packet decode error")
 )
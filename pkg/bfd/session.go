@@ -3,6 +3,7 @@ package bfd
 import (
 	"math/rand/v2"
 	"net"
+	"sync"
 	"time"
 
 	"github.com/google/gopacket/layers"
@@ -17,11 +18,10 @@ const (
 
 	VERSION = 1
 
-	DesiredMinTXInterval      = 1000000
-	ControlPlaneIndependent   = false
-	DemandMode                = false
-	MULTIPOINT                = false
-	RequiredMinEchoRxInterval = 0
+	DesiredMinTXInterval    = 1000000
+	ControlPlaneIndependent = false
+	DemandMode              = false
+	MULTIPOINT              = false
 )
 
 type Session struct {
@@ -33,6 +33,12 @@ type Session struct {
 	// callback state
 	callFunc CallbackFunc
 
+	// doneOnce closes the channel to the main function once (see signalDone)
+	doneOnce sync.Once
+
+	// mu guards the state variables written by the receiving and read by the transmitting and detecting goroutines
+	mu sync.Mutex
+
 	// bfd session
 	LocalIP    string
 	RemoteIP   string
@@ -69,6 +75,18 @@ type Session struct {
 	PollSequence         bool
 	remoteDetectMult     uint32 // layers.BFDDetectMultiplier
 	remoteMinTxInterval  uint32 // layers.BFDTimeInterval
+
+	// echo function (rfc5880 6.4), EchoInterval = 0 disables echo transmitting
+	EchoInterval              int    // usec
+	requiredMinEchoRxInterval uint32 // usec, layers.BFDTimeInterval
+	remoteMinEchoRxInterval   uint32 // usec, layers.BFDTimeInterval
+	echoConn                  *EchoClient
+	echoQuit                  chan struct{}
+	echoQuitOnce              sync.Once
+	echoMu                    sync.Mutex
+	echoSeq                   uint32
+	echoStats                 EchoStats
+	lastEchoRxTime            int64 // ms
 }
 
 // NewSession creates a new BFD session
@@ -79,7 +97,8 @@ func NewSession(
 	passive bool,
 	rxInterval,
 	txInterval,
-	detectMult int,
+	detectMult,
+	echoInterval int,
 	f CallbackFunc,
 	chBFDDone chan struct{},
 ) *Session {
@@ -116,6 +135,17 @@ func NewSession(
 		//
 		asyncTxInterval: DesiredMinTXInterval,
 		PollSequence:    false,
+		//
+		EchoInterval: echoInterval,
+		echoQuit:     make(chan struct{}),
+		echoStats:    EchoStats{Enabled: echoInterval > 0},
+	}
+
+	// echo packets of remote are forwarded back by the local dataplane (ip forwarding), they are accepted with the
+	// same rate they are transmitted
+
+	if echoInterval > 0 {
+		s.requiredMinEchoRxInterval = uint32(echoInterval)
 	}
 
 	s.setDesiredMinTxInterval(DesiredMinTXInterval)
@@ -123,6 +153,10 @@ func NewSession(
 
 	go s.sessionLoop(chBFDDone)
 
+	if echoInterval > 0 {
+		go s.echoLoop()
+	}
+
 	return s
 }
 
@@ -144,6 +178,8 @@ func (s *Session) sessionLoop(chBFDDone chan struct{}) {
 	var interval float64
 
 	for {
+		s.mu.Lock()
+
 		if s.DetectMult == 1 {
 			// the interval must not exceed 90% and must be no less than 75%
 			interval = float64(s.asyncTxInterval) * (rand.Float64()*0.75 + 0.15)
@@ -154,6 +190,8 @@ func (s *Session) sessionLoop(chBFDDone chan struct{}) {
 			interval = float64(s.asyncTxInterval) * (1 - (rand.Float64() * 0.25))
 		}
 
+		s.mu.Unlock()
+
 		select {
 		case <-s.clientDown:
 			conn, err = NewClient(s.LocalIP, s.RemoteIP)
@@ -185,6 +223,8 @@ func (s *Session) sessionLoop(chBFDDone chan struct{}) {
 			return
 
 		default:
+			s.mu.Lock()
+
 			if !((s.RemoteDiscr == 0 && s.Passive) ||
 				(s.remoteMinRxInterval == 0) ||
 				(!s.PollSequence && (s.RemoteDemandMode && s.State == layers.BFDStateUp && s.RemoteState == layers.BFDStateUp))) {
@@ -192,6 +232,8 @@ func (s *Session) sessionLoop(chBFDDone chan struct{}) {
 				s.TxPacket(false)
 			}
 
+			s.mu.Unlock()
+
 			// speed (frequency) of packets sending
 			time.Sleep(time.Duration(int(interval)) * time.Microsecond)
 			// Start timeout detection
@@ -206,6 +248,9 @@ func (s *Session) sessionLoop(chBFDDone chan struct{}) {
 
 // RxPacket handles received packets
 func (s *Session) RxPacket(p *layers.BFD) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	if p.AuthPresent && !s.AuthType {
 		logger.Error("received bfd packet with authentication while no authentication is configured locally")
 
@@ -231,6 +276,7 @@ func (s *Session) RxPacket(p *layers.BFD) {
 	s.setRemoteMinRxInterval(uint32(p.RequiredMinRxInterval))
 	s.setRemoteDetectMult(uint32(p.DetectMultiplier))
 	s.setRemoteMinTxInterval(uint32(p.DesiredMinTxInterval))
+	s.setRemoteMinEchoRxInterval(uint32(p.RequiredMinEchoRxInterval))
 
 	if s.State == layers.BFDStateAdminDown {
 		logger.Warn("received bfd packet while in admin_down state", "remote address", s.RemoteIP)
@@ -339,7 +385,7 @@ func (s *Session) RxPacket(p *layers.BFD) {
 	s.LastRxPacketTime = time.Now().UnixNano() / 1e6 // ms
 }
 
-// TxPacket creates a packet to be sent to target
+// TxPacket creates a packet to be sent to target, the caller holds s.mu
 func (s *Session) TxPacket(final bool) {
 	logger.Debug("sending bfd packet", "local address", s.conn.LocalAddr().String())
 
@@ -382,7 +428,7 @@ func (s *Session) TxPacket(final bool) {
 		s.RemoteDiscr,
 		layers.BFDTimeInterval(s.desiredMinTxInterval),
 		layers.BFDTimeInterval(s.requiredMinRxInterval),
-		RequiredMinEchoRxInterval,
+		layers.BFDTimeInterval(s.requiredMinEchoRxInterval),
 		tmpAuth)
 
 	if _, err := s.conn.Write(txByte); err != nil {
@@ -531,6 +577,8 @@ func (s *Session) DetectFailure(chBFDDone chan struct{}) {
 
 			return
 		default:
+			s.mu.Lock()
+
 			if !(s.DemandMode || s.asyncDetectTime == 0) {
 				if (s.State == layers.BFDStateUp) &&
 					((time.Now().UnixNano()/1e6 - s.LastRxPacketTime) > (int64(s.asyncDetectTime) / 1000)) {
@@ -550,14 +598,87 @@ func (s *Session) DetectFailure(chBFDDone chan struct{}) {
 						"detect time", int64(s.asyncDetectTime)/1000,
 					)
 
-					logger.Info("closing the bfd channel to main function")
-
-					close(chBFDDone)
+					s.signalDone(chBFDDone)
 				}
 			}
 
+			// echo function failure feeds the same detection as control packets (rfc5880 6.8.5)
+
+			if s.State == layers.BFDStateUp && s.isEchoActive() && s.echoDetectTimeExpired() {
+				currState := int(s.State)
+
+				go s.callFunc(s.RemoteIP, currState, int(layers.BFDStateDown))
+
+				s.State = layers.BFDStateDown
+				s.LocalDiag = layers.BFDDiagnosticEchoFailed
+
+				s.echoMu.Lock()
+				s.echoStats.Failures++
+				s.echoMu.Unlock()
+
+				s.setDesiredMinTxInterval(DesiredMinTXInterval)
+
+				logger.Error(
+					"detected bfd peer going down, no echo packets looped back during detect time",
+					"remote address", s.RemoteIP,
+					"echo tx interval", s.echoTxInterval(),
+					"detect multiplier", s.DetectMult,
+				)
+
+				s.signalDone(chBFDDone)
+			}
+
+			s.mu.Unlock()
+
 			// waiting time
 			time.Sleep(time.Millisecond * BFDDetectInterval)
 		}
 	}
 }
+
+// signalDone closes chBFDDone on the first detected failure. The session may go up and fail again (by control packets
+// or echo) while the app shuts down, the channel is not closed twice.
+func (s *Session) signalDone(chBFDDone chan struct{}) {
+	s.doneOnce.Do(func() {
+		logger.Info("closing the bfd channel to main function")
+
+		close(chBFDDone)
+	})
+}
+
+// SessionState is a snapshot of BFD session state variables and echo function statistics
+type SessionState struct {
+	LocalIP     string
+	RemoteIP    string
+	State       string
+	RemoteState string
+	LocalDiag   string
+	DetectMult  uint8
+	Echo        EchoStats
+}
+
+// GetState returns a snapshot of the session state
+func (s *Session) GetState() SessionState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.echoMu.Lock()
+	echoStats := s.echoStats
+	s.echoMu.Unlock()
+
+	echoStats.RemoteMinRxInterval = s.remoteMinEchoRxInterval
+
+	if echoStats.Active {
+		echoStats.TxInterval = s.echoTxInterval()
+	}
+
+	return SessionState{
+		LocalIP:     s.LocalIP,
+		RemoteIP:    s.RemoteIP,
+		State:       s.State.String(),
+		RemoteState: s.RemoteState.String(),
+		LocalDiag:   s.LocalDiag.String(),
+		DetectMult:  s.DetectMult,
+		Echo:        echoStats,
+	}
+}
@@ -0,0 +1,102 @@
+package certreload
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"git.crptech.ru/cloud/cloudgw/pkg/logger"
+)
+
+// Reloader keeps tls certificate loaded from cert and key files and reloads it when any of the files is changed
+type Reloader struct {
+	certFile string
+	keyFile  string
+
+	mu          sync.RWMutex
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+// New loads the certificate from cert and key files
+func New(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile}
+
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// GetCertificate returns current certificate, it is used as tls.Config GetCertificate
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cert, nil
+}
+
+// Reload loads the certificate again if modification time of cert or key file is changed. Current certificate is kept
+// if the new one can not be loaded.
+func (r *Reloader) Reload() (bool, error) {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat cert file: %w", err)
+	}
+
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat key file: %w", err)
+	}
+
+	r.mu.RLock()
+	isChanged := r.cert == nil || !certInfo.ModTime().Equal(r.certModTime) || !keyInfo.ModTime().Equal(r.keyModTime)
+	r.mu.RUnlock()
+
+	if !isChanged {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to load cert and key: %w", err)
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.certModTime = certInfo.ModTime()
+	r.keyModTime = keyInfo.ModTime()
+	r.mu.Unlock()
+
+	return true, nil
+}
+
+// Run checks cert and key files every interval and reloads the certificate if they are changed
+func (r *Reloader) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			isReloaded, err := r.Reload()
+			if err != nil {
+				logger.Error("failed to reload tls certificate, current one is kept", "cert file", r.certFile, "error", err)
+
+				continue
+			}
+
+			if isReloaded {
+				logger.Info("tls certificate reloaded", "cert file", r.certFile)
+			}
+		}
+	}
+}
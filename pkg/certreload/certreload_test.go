@@ -0,0 +1,105 @@
+package certreload_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"git.crptech.ru/cloud/cloudgw/pkg/certreload"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+
+	writeCert(t, certFile, keyFile, "cloudgw-1")
+
+	reloader, err := certreload.New(certFile, keyFile)
+	require.NoError(t, err)
+
+	require.Equal(t, "cloudgw-1", leafCN(t, reloader))
+
+	// files are not changed
+
+	isReloaded, err := reloader.Reload()
+	require.NoError(t, err)
+	require.False(t, isReloaded)
+
+	// files are changed
+
+	writeCert(t, certFile, keyFile, "cloudgw-2")
+
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, future, future))
+	require.NoError(t, os.Chtimes(keyFile, future, future))
+
+	isReloaded, err = reloader.Reload()
+	require.NoError(t, err)
+	require.True(t, isReloaded)
+
+	require.Equal(t, "cloudgw-2", leafCN(t, reloader))
+
+	// broken files, current certificate is kept
+
+	require.NoError(t, os.WriteFile(certFile, []byte("broken"), 0o600))
+
+	past := time.Now().Add(-time.Minute)
+	require.NoError(t, os.Chtimes(certFile, past, past))
+
+	_, err = reloader.Reload()
+	require.Error(t, err)
+
+	require.Equal(t, "cloudgw-2", leafCN(t, reloader))
+}
+
+func TestNewWithoutFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := certreload.New(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"))
+	require.Error(t, err)
+}
+
+func leafCN(t *testing.T, reloader *certreload.Reloader) string {
+	t.Helper()
+
+	cert, err := reloader.GetCertificate(nil)
+	require.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+
+	return leaf.Subject.CommonName
+}
+
+func writeCert(t *testing.T, certFile, keyFile, commonName string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+}
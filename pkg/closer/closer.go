@@ -1,27 +1,74 @@
 package closer
 
 import (
+	"context"
+	"errors"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 
 	"git.crptech.ru/cloud/cloudgw/pkg/logger"
 )
 
-var globalCloser = New(syscall.SIGTERM, syscall.SIGINT, syscall.SIGKILL)
+// Phase is a shutdown phase. Phases are run in order, functions of a phase are run concurrently.
+type Phase int
+
+const (
+	PhaseWithdraw  Phase = iota // routes are withdrawn and the state is saved
+	PhasePropagate              // withdrawals propagate to bgp peers
+	PhaseSessions               // bgp and bfd sessions, api servers and app goroutines are stopped
+	PhaseRelease                // gobgp server, vpp connections and files are released
+
+	phaseCount
+)
+
+// DefaultTimeout is the deadline of a phase if it is not set by SetTimeout
+const DefaultTimeout = 10 * time.Second
+
+var phaseNames = [phaseCount]string{"withdraw", "propagate", "sessions", "release"}
+
+func (p Phase) String() string {
+	if p < 0 || p >= phaseCount {
+		return "unknown"
+	}
+
+	return phaseNames[p]
+}
+
+// ErrTimeout is the result of a function not returned before the phase deadline
+var ErrTimeout = errors.New("phase deadline exceeded")
+
+// Result is the result of a closer function
+type Result struct {
+	Phase Phase
+	Name  string
+	Err   error // ErrTimeout if the function is not returned before the phase deadline
+}
+
+var globalCloser = New(syscall.SIGTERM, syscall.SIGINT)
+
+type closeFunc struct {
+	name string
+	fn   func(ctx context.Context) error
+}
 
 type Closer struct {
-	mu    sync.Mutex
-	once  sync.Once
-	done  chan struct{}
-	funcs []func() error
+	mu       sync.Mutex
+	once     sync.Once
+	done     chan struct{}
+	funcs    [phaseCount][]closeFunc
+	timeout  time.Duration
+	onSignal func()
+	results  []Result
 }
 
 // New returns new Closer, If list os.Signal are specified Closer will automatically call CloseAll when one of signals is received from OS
 func New(sig ...os.Signal) *Closer {
 	c := &Closer{
-		done: make(chan struct{}),
+		done:    make(chan struct{}),
+		timeout: DefaultTimeout,
 	}
 
 	if len(sig) > 0 {
@@ -32,6 +79,14 @@ func New(sig ...os.Signal) *Closer {
 			<-ch
 			signal.Stop(ch)
 
+			c.mu.Lock()
+			onSignal := c.onSignal
+			c.mu.Unlock()
+
+			if onSignal != nil {
+				onSignal()
+			}
+
 			c.CloseAll()
 		}()
 	}
@@ -39,9 +94,19 @@ func New(sig ...os.Signal) *Closer {
 	return c
 }
 
-// Add func to closer
-func Add(fn ...func() error) {
-	globalCloser.Add(fn...)
+// Add adds the named func to the phase of the closer, the func context is done on the phase deadline
+func Add(phase Phase, name string, fn func(ctx context.Context) error) {
+	globalCloser.Add(phase, name, fn)
+}
+
+// SetTimeout sets the deadline of each phase
+func SetTimeout(timeout time.Duration) {
+	globalCloser.SetTimeout(timeout)
+}
+
+// OnSignal sets fn called on the signal before closer functions (the second signal is not caught while fn runs)
+func OnSignal(fn func()) {
+	globalCloser.OnSignal(fn)
 }
 
 // Wait blocks until all closer functions are done
@@ -49,45 +114,120 @@ func Wait() {
 	globalCloser.Wait()
 }
 
-// CloseAll calls all closer functions
+// CloseAll calls all closer functions phase by phase
 func CloseAll() {
 	globalCloser.CloseAll()
 }
 
-func (c *Closer) Add(fn ...func() error) {
+func (c *Closer) Add(phase Phase, name string, fn func(ctx context.Context) error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.funcs[phase] = append(c.funcs[phase], closeFunc{name: name, fn: fn})
+}
+
+func (c *Closer) SetTimeout(timeout time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.timeout = timeout
+}
+
+func (c *Closer) OnSignal(fn func()) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.funcs = append(c.funcs, fn...)
+	c.onSignal = fn
 }
 
 func (c *Closer) Wait() {
 	<-c.done
 }
 
-func (c *Closer) CloseAll() {
+// CloseAll runs closer functions phase by phase and returns their results (after the first call, the results of it).
+// A function not returned before the phase deadline is left running and the next phase is started.
+func (c *Closer) CloseAll() []Result {
 	c.once.Do(func() {
 		defer close(c.done)
 
 		c.mu.Lock()
-		funcs := c.funcs
-		c.funcs = nil
+		funcs, timeout := c.funcs, c.timeout
+		c.funcs = [phaseCount][]closeFunc{}
 		c.mu.Unlock()
 
-		errs := make(chan error, len(funcs))
+		for phase := range phaseCount {
+			c.results = append(c.results, runPhase(phase, funcs[phase], timeout)...)
+		}
 
-		for _, fn := range funcs {
-			go func(fn func() error) {
-				errs <- fn()
-			}(fn)
+		logger.Info("all closer functions are done, shutting down the app")
+	})
+
+	<-c.done
+
+	return c.results
+}
+
+type returnedFunc struct {
+	i   int
+	err error
+}
+
+// runPhase runs phase functions concurrently until all of them are returned or the phase deadline is exceeded
+func runPhase(phase Phase, funcs []closeFunc, timeout time.Duration) []Result {
+	if len(funcs) == 0 {
+		return nil
+	}
+
+	startedAt := time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	results := make([]Result, len(funcs))
+
+	// the channel is buffered, so functions returned after the deadline do not block
+
+	returned := make(chan returnedFunc, len(funcs))
+
+	for i, f := range funcs {
+		results[i] = Result{Phase: phase, Name: f.name, Err: ErrTimeout}
+
+		go func() {
+			returned <- returnedFunc{i: i, err: f.fn(ctx)}
+		}()
+	}
+
+loop:
+	for range funcs {
+		select {
+		case r := <-returned:
+			results[r.i].Err = r.err
+		case <-ctx.Done():
+			break loop
 		}
+	}
 
-		for i := 0; i < cap(errs); i++ {
-			if err := <-errs; err != nil {
-				logger.Error("error returned from closer", "error", err)
-			}
+	logPhase(phase, results, time.Since(startedAt))
+
+	return results
+}
+
+func logPhase(phase Phase, results []Result, duration time.Duration) {
+	var failed, timedOut int
+
+	for _, r := range results {
+		switch {
+		case errors.Is(r.Err, ErrTimeout):
+			timedOut++
+
+			logger.Error("closer function is not done before the phase deadline", "phase", phase.String(), "name", r.Name)
+		case r.Err != nil:
+			failed++
+
+			logger.Error("closer function failed", "phase", phase.String(), "name", r.Name, "error", r.Err)
 		}
+	}
 
-		logger.Info("all closer functions are done, shutting down the app")
-	})
+	logger.Info("shutdown phase is done", "phase", phase.String(), "functions", len(results), "failed", failed,
+		"timed out", timedOut, "duration", duration.Round(time.Millisecond).String())
 }
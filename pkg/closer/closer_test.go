@@ -0,0 +1,73 @@
+package closer_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"git.crptech.ru/cloud/cloudgw/pkg/closer"
+)
+
+func TestCloseAll(t *testing.T) {
+	c := closer.New()
+	c.SetTimeout(200 * time.Millisecond)
+
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+
+	add := func(phase closer.Phase, name string, delay time.Duration, err error) {
+		c.Add(phase, name, func(ctx context.Context) error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+
+			return err
+		})
+	}
+
+	errRelease := errors.New("release failed")
+
+	// phases are added out of order, functions of a later phase wait for the slow function of an earlier phase
+
+	add(closer.PhaseRelease, "vpp", 0, errRelease)
+	add(closer.PhaseSessions, "bgp peers", 0, nil)
+	add(closer.PhaseWithdraw, "prefixes", 50*time.Millisecond, nil)
+	add(closer.PhaseWithdraw, "snapshot", 0, nil)
+	add(closer.PhasePropagate, "stuck", time.Hour, nil)
+
+	startedAt := time.Now()
+	results := c.CloseAll()
+
+	require.Less(t, time.Since(startedAt), time.Second)
+	require.Equal(t, []string{"snapshot", "prefixes", "bgp peers", "vpp"}, order)
+	require.Equal(t, []closer.Result{
+		{Phase: closer.PhaseWithdraw, Name: "prefixes"},
+		{Phase: closer.PhaseWithdraw, Name: "snapshot"},
+		{Phase: closer.PhasePropagate, Name: "stuck", Err: closer.ErrTimeout},
+		{Phase: closer.PhaseSessions, Name: "bgp peers"},
+		{Phase: closer.PhaseRelease, Name: "vpp", Err: errRelease},
+	}, results)
+
+	// closer functions are run once
+
+	require.Equal(t, results, c.CloseAll())
+	c.Wait()
+}
+
+func TestPhaseString(t *testing.T) {
+	require.Equal(t, "withdraw", closer.PhaseWithdraw.String())
+	require.Equal(t, "release", closer.PhaseRelease.String())
+	require.Equal(t, "unknown", closer.Phase(10).String())
+}
@@ -0,0 +1,115 @@
+package convergenceexporter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// sources of bgp updates
+const (
+	SourceTF     = "tf"      // tungsten fabric controllers
+	SourcePhyNet = "physnet" // physical network peers
+)
+
+// reasons of rejected bgp updates
+const (
+	RejectNotFIP     = "not_fip"     // announced address of tungsten fabric is not in aggregated floating ip prefixes
+	RejectUnknownVRF = "unknown_vrf" // vrf of the update is not configured
+	RejectParseError = "parse_error" // the update is not parsed
+	RejectVPPError   = "vpp_error"   // the route is not changed in vpp (or the change is rolled back)
+)
+
+// startedAt is the process start the initial convergence is measured from
+var startedAt = time.Now()
+
+// UpdateMetric describes bgp update handling and vpp programming latencies (updated at runtime by update handling
+// and vpp binary api calls)
+type UpdateMetric struct {
+	updateInstall      *prometheus.HistogramVec
+	vppCall            *prometheus.HistogramVec
+	initialConvergence prometheus.Histogram
+	updateProcessed    *prometheus.CounterVec
+	updateRejected     *prometheus.CounterVec
+	convergedOnce      sync.Once
+}
+
+func NewUpdateMetric() *UpdateMetric {
+	m := &UpdateMetric{
+		updateInstall: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    prometheus.BuildFQName("cloudgw", "bgp_update", "install_seconds"),
+			Help:    "Latency from bgp update received by GoBGP to the route changed in vpp",
+			Buckets: prometheus.ExponentialBuckets(0.001, 2, 15), // 1ms - 16s
+		}, []string{"source"}),
+		vppCall: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    prometheus.BuildFQName("cloudgw", "vpp_api", "call_seconds"),
+			Help:    "Latency of vpp binary api call from the request sent to the reply (the last reply of dumps) received or the call failed",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 2, 14), // 100us - 0.8s
+		}, []string{"message", "result"}),
+		initialConvergence: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    prometheus.BuildFQName("cloudgw", "initial_convergence", "seconds"),
+			Help:    "Time from the process start to end-of-rib of the first tungsten fabric controller handled",
+			Buckets: []float64{1, 2, 5, 10, 20, 30, 60, 120, 300, 600},
+		}),
+		updateProcessed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName("cloudgw", "bgp_update", "processed_total"),
+			Help: "Number of handled bgp update paths including rejected ones",
+		}, []string{"source"}),
+		updateRejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName("cloudgw", "bgp_update", "rejected_total"),
+			Help: "Number of bgp update paths not installed to vpp by reason",
+		}, []string{"source", "reason"}),
+	}
+
+	// counters are exposed before the first update
+
+	for _, source := range []string{SourceTF, SourcePhyNet} {
+		m.updateProcessed.WithLabelValues(source)
+
+		for _, reason := range []string{RejectNotFIP, RejectUnknownVRF, RejectParseError, RejectVPPError} {
+			m.updateRejected.WithLabelValues(source, reason)
+		}
+	}
+
+	return m
+}
+
+func (m *UpdateMetric) IncUpdateProcessed(source string) {
+	m.updateProcessed.WithLabelValues(source).Inc()
+}
+
+func (m *UpdateMetric) IncUpdateRejected(source, reason string) {
+	m.updateRejected.WithLabelValues(source, reason).Inc()
+}
+
+// ObserveUpdateInstalled observes the latency of the update received at receivedAt, the update without the receive
+// time is not observed
+func (m *UpdateMetric) ObserveUpdateInstalled(source string, receivedAt time.Time) {
+	if receivedAt.IsZero() {
+		return
+	}
+
+	m.updateInstall.WithLabelValues(source).Observe(time.Since(receivedAt).Seconds())
+}
+
+// ObserveVPPCall observes the latency of the call with result "error" if the call failed (the request not sent, the
+// reply not received or unexpected), "ok" otherwise
+func (m *UpdateMetric) ObserveVPPCall(msg string, latency time.Duration, err error) {
+	result := "ok"
+
+	if err != nil {
+		result = "error"
+	}
+
+	m.vppCall.WithLabelValues(msg, result).Observe(latency.Seconds())
+}
+
+// ObserveInitialConvergence observes the time since the process start once, next calls are ignored
+func (m *UpdateMetric) ObserveInitialConvergence() {
+	m.convergedOnce.Do(func() {
+		m.initialConvergence.Observe(time.Since(startedAt).Seconds())
+	})
+}
+
+var UpdateMetrics = NewUpdateMetric()
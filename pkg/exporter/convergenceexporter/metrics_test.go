@@ -0,0 +1,54 @@
+package convergenceexporter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateMetric(t *testing.T) {
+	m := NewUpdateMetric()
+
+	m.IncUpdateProcessed(SourceTF)
+	m.IncUpdateProcessed(SourceTF)
+	m.IncUpdateRejected(SourceTF, RejectNotFIP)
+	m.ObserveUpdateInstalled(SourceTF, time.Now().Add(-10*time.Millisecond))
+	m.ObserveUpdateInstalled(SourceTF, time.Time{})
+	m.ObserveVPPCall("ip_route_add_del_v2", time.Millisecond, nil)
+	m.ObserveVPPCall("ip_route_v2_dump", time.Second, errors.New("no reply received within the timeout period"))
+	m.ObserveInitialConvergence()
+	m.ObserveInitialConvergence()
+
+	require.Equal(t, 2.0, testutil.ToFloat64(m.updateProcessed.WithLabelValues(SourceTF)))
+	require.Equal(t, 0.0, testutil.ToFloat64(m.updateProcessed.WithLabelValues(SourcePhyNet)))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.updateRejected.WithLabelValues(SourceTF, RejectNotFIP)))
+	require.Equal(t, 0.0, testutil.ToFloat64(m.updateRejected.WithLabelValues(SourcePhyNet, RejectVPPError)))
+
+	// histograms are observed once, unknown receive time and repeated convergence are ignored
+
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(&CloudgwExporter{metrics: m})
+
+	families, err := reg.Gather()
+	require.NoError(t, err)
+
+	counts := make(map[string]uint64)
+
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			if h := metric.GetHistogram(); h != nil {
+				counts[family.GetName()] += h.GetSampleCount()
+			}
+		}
+	}
+
+	require.Equal(t, map[string]uint64{
+		"cloudgw_bgp_update_install_seconds":  1,
+		"cloudgw_vpp_api_call_seconds":        2,
+		"cloudgw_initial_convergence_seconds": 1,
+	}, counts)
+}
@@ -0,0 +1,29 @@
+package convergenceexporter
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type CloudgwExporter struct {
+	metrics *UpdateMetric
+}
+
+func NewCloudgwExporter() *CloudgwExporter {
+	return &CloudgwExporter{metrics: UpdateMetrics}
+}
+
+func (c *CloudgwExporter) Describe(ch chan<- *prometheus.Desc) {
+	c.metrics.updateInstall.Describe(ch)
+	c.metrics.vppCall.Describe(ch)
+	c.metrics.initialConvergence.Describe(ch)
+	c.metrics.updateProcessed.Describe(ch)
+	c.metrics.updateRejected.Describe(ch)
+}
+
+func (c *CloudgwExporter) Collect(metricsCh chan<- prometheus.Metric) {
+	c.metrics.updateInstall.Collect(metricsCh)
+	c.metrics.vppCall.Collect(metricsCh)
+	c.metrics.initialConvergence.Collect(metricsCh)
+	c.metrics.updateProcessed.Collect(metricsCh)
+	c.metrics.updateRejected.Collect(metricsCh)
+}
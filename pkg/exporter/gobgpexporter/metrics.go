@@ -1,31 +1,8 @@
 package gobgpexporter
 
-import (
-	"sync/atomic"
-)
-
-// GoBGPGeneralMetric describes general GoBGP metrics (update at runtime by BGP peer events)
-type GoBGPGeneralMetric struct {
-	VRFCount        int32
-	PeerCount       int32
-	ActivePeerCount int32
-}
-
-func (m *GoBGPGeneralMetric) IncVRFCount() {
-	atomic.AddInt32(&m.VRFCount, 1)
-}
-
-func (m *GoBGPGeneralMetric) IncActivePeerCount() {
-	atomic.AddInt32(&m.ActivePeerCount, 1)
-}
-
-func (m *GoBGPGeneralMetric) DecActivePeerCount() {
-	atomic.AddInt32(&m.ActivePeerCount, -1)
-}
+import "sync"
 
-var GoBGPGeneralMetrics GoBGPGeneralMetric
-
-// GoBGPPerPeerMetric describes GoBGP BGP update counts per BGP peer. Updated by UpdateGoBGPPerPeerMetrics every n * sec
+// GoBGPPerPeerMetric describes GoBGP BGP update counts per BGP peer. Updated by UpdateGoBGPMetrics every n * sec
 type GoBGPPerPeerMetric struct {
 	IPv4RouteRcvd  float64
 	IPv4RouteAdvd  float64
@@ -33,30 +10,32 @@ type GoBGPPerPeerMetric struct {
 	Vpnv4RouteAdvd float64
 }
 
-func NewGoBGPPerPeerMetric() *GoBGPPerPeerMetric {
-	return &GoBGPPerPeerMetric{
-		IPv4RouteRcvd:  0,
-		IPv4RouteAdvd:  0,
-		Vpnv4RouteRcvd: 0,
-		Vpnv4RouteAdvd: 0,
-	}
+// GoBGPPerPeerMetricMap keeps route counts of all bgp peers (peers are added and deleted in runtime)
+type GoBGPPerPeerMetricMap struct {
+	mu      sync.RWMutex
+	metrics map[string]GoBGPPerPeerMetric
 }
 
-func (m *GoBGPPerPeerMetric) SetIPv4UpdateRcvd(value float64) {
-	m.IPv4RouteRcvd = value
-}
+// Replace replaces the metrics of all peers, metrics of peers not in the map are deleted
+func (m *GoBGPPerPeerMetricMap) Replace(metrics map[string]GoBGPPerPeerMetric) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-func (m *GoBGPPerPeerMetric) SetIPv4UpdateSent(value float64) {
-	m.IPv4RouteAdvd = value
+	m.metrics = metrics
 }
 
-func (m *GoBGPPerPeerMetric) SetVpnv4UpdateRcvd(value float64) {
-	m.Vpnv4RouteRcvd = value
-}
+func (m *GoBGPPerPeerMetricMap) Snapshot() map[string]GoBGPPerPeerMetric {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snapshot := make(map[string]GoBGPPerPeerMetric, len(m.metrics))
+
+	for peer, metric := range m.metrics {
+		snapshot[peer] = metric
+	}
 
-func (m *GoBGPPerPeerMetric) SetVpnv4UpdateSent(value float64) {
-	m.Vpnv4RouteAdvd = value
+	return snapshot
 }
 
 // GoBGPPerPeerMetrics contains GoBGP BGP advertised/received routes counts for BGP peer
-var GoBGPPerPeerMetrics = make(map[string]*GoBGPPerPeerMetric)
+var GoBGPPerPeerMetrics = GoBGPPerPeerMetricMap{metrics: make(map[string]GoBGPPerPeerMetric)}
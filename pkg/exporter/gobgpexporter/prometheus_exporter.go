@@ -1,13 +1,19 @@
 package gobgpexporter
 
 import (
+	bgpapi "github.com/osrg/gobgp/v3/api"
 	"github.com/prometheus/client_golang/prometheus"
+
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
 )
 
-type CloudgwExporter struct{}
+// CloudgwExporter exposes vrf and peer counts of the storage and route counts polled by UpdateGoBGPMetrics
+type CloudgwExporter struct {
+	storage *imdb.Storage
+}
 
-func NewCloudgwExporter() *CloudgwExporter {
-	return &CloudgwExporter{}
+func NewCloudgwExporter(storage *imdb.Storage) *CloudgwExporter {
+	return &CloudgwExporter{storage: storage}
 }
 
 func (c *CloudgwExporter) Describe(ch chan<- *prometheus.Desc) {
@@ -21,23 +27,33 @@ func (c *CloudgwExporter) Describe(ch chan<- *prometheus.Desc) {
 }
 
 func (c *CloudgwExporter) Collect(metricsCh chan<- prometheus.Metric) {
+	peers := c.storage.BGPPeerStorage.GetBGPPeers()
+
+	var activePeerCount int
+
+	for _, peer := range peers {
+		if peer.BGPPeerState == bgpapi.PeerState_ESTABLISHED {
+			activePeerCount++
+		}
+	}
+
 	metricsCh <- prometheus.MustNewConstMetric(
 		gobgpVRFCount,
 		prometheus.CounterValue,
-		float64(GoBGPGeneralMetrics.VRFCount),
+		float64(len(c.storage.BGPVRFStorage.GetVRFs())),
 	)
 	metricsCh <- prometheus.MustNewConstMetric(
 		gobgpPeerCount,
 		prometheus.CounterValue,
-		float64(GoBGPGeneralMetrics.PeerCount),
+		float64(len(peers)),
 	)
 	metricsCh <- prometheus.MustNewConstMetric(
 		gobgpActivePeerCount,
 		prometheus.CounterValue,
-		float64(GoBGPGeneralMetrics.ActivePeerCount),
+		float64(activePeerCount),
 	)
 
-	for peer, peerMetrics := range GoBGPPerPeerMetrics {
+	for peer, peerMetrics := range GoBGPPerPeerMetrics.Snapshot() {
 		metricsCh <- prometheus.MustNewConstMetric(
 			gobgpIPv4RouteRcvd,
 			prometheus.CounterValue,
@@ -9,20 +9,15 @@ import (
 
 	"git.crptech.ru/cloud/cloudgw/internal/model"
 	"git.crptech.ru/cloud/cloudgw/internal/repository/gobgp"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
 	"git.crptech.ru/cloud/cloudgw/pkg/logger"
 )
 
-// UpdateGoBGPMetrics updates the GoBGPPerPeerMetrics var with GoBGP metrics every [poolingInterval] sec
-func UpdateGoBGPMetrics(ctx context.Context, bgpSrv *server.BgpServer, poolingInterval int, bgpPeers []*model.BGPPeer) error {
-	// Initialize GoBGPPerPeerMetrics map with all VRFs
-	for _, peer := range bgpPeers {
-		GoBGPPerPeerMetrics[peer.PeerAddress] = NewGoBGPPerPeerMetric()
-	}
-
+// UpdateGoBGPMetrics updates the GoBGPPerPeerMetrics var with GoBGP metrics of the stored bgp peers every
+// [poolingInterval] sec, metrics of deleted peers are deleted
+func UpdateGoBGPMetrics(ctx context.Context, bgpSrv *server.BgpServer, poolingInterval int, storage *imdb.BGPPeerStorage) error {
 	ticker := time.NewTicker(time.Duration(poolingInterval) * time.Second)
 
-	done := make(chan bool)
-
 	defer ticker.Stop()
 
 	for {
@@ -32,49 +27,66 @@ func UpdateGoBGPMetrics(ctx context.Context, bgpSrv *server.BgpServer, poolingIn
 
 			return nil
 		case <-ticker.C:
-			for _, peer := range bgpPeers {
-				var safi bgpapi.Family_Safi
+			metrics := make(map[string]GoBGPPerPeerMetric)
 
-				if peer.PeerType == model.TF {
-					safi = bgpapi.Family_SAFI_MPLS_VPN
-				} else {
-					safi = bgpapi.Family_SAFI_UNICAST
-				}
-
-				outputAdjIn, err := gobgp.UpdateGoBGPPerPeerMetrics(
-					ctx,
-					bgpSrv,
-					bgpapi.TableType_ADJ_IN,
-					bgpapi.Family_AFI_IP,
-					safi,
-					peer.PeerAddress,
-				)
+			for _, peer := range storage.GetBGPPeers() {
+				metric, err := peerMetric(ctx, bgpSrv, peer)
 				if err != nil {
-					return err
-				}
+					logger.Error("failed to update gobgp peer metrics", "peer", peer.PeerAddress, "error", err)
 
-				outputAdjOut, err := gobgp.UpdateGoBGPPerPeerMetrics(
-					ctx,
-					bgpSrv,
-					bgpapi.TableType_ADJ_OUT,
-					bgpapi.Family_AFI_IP,
-					safi,
-					peer.PeerAddress,
-				)
-				if err != nil {
-					return err
+					continue
 				}
 
-				if peer.PeerType == model.TF {
-					GoBGPPerPeerMetrics[peer.PeerAddress].SetVpnv4UpdateRcvd(outputAdjIn)
-					GoBGPPerPeerMetrics[peer.PeerAddress].SetVpnv4UpdateSent(outputAdjOut)
-				} else {
-					GoBGPPerPeerMetrics[peer.PeerAddress].SetIPv4UpdateRcvd(outputAdjIn)
-					GoBGPPerPeerMetrics[peer.PeerAddress].SetIPv4UpdateSent(outputAdjOut)
-				}
+				metrics[peer.PeerAddress] = metric
 			}
-		case <-done:
-			return nil
+
+			GoBGPPerPeerMetrics.Replace(metrics)
 		}
 	}
 }
+
+// peerMetric returns received and advertised route counts of the peer
+func peerMetric(ctx context.Context, bgpSrv *server.BgpServer, peer *model.BGPPeer) (GoBGPPerPeerMetric, error) {
+	var (
+		metric GoBGPPerPeerMetric
+		safi   bgpapi.Family_Safi
+	)
+
+	if peer.PeerType == model.TF {
+		safi = bgpapi.Family_SAFI_MPLS_VPN
+	} else {
+		safi = bgpapi.Family_SAFI_UNICAST
+	}
+
+	outputAdjIn, err := gobgp.UpdateGoBGPPerPeerMetrics(
+		ctx,
+		bgpSrv,
+		bgpapi.TableType_ADJ_IN,
+		bgpapi.Family_AFI_IP,
+		safi,
+		peer.PeerAddress,
+	)
+	if err != nil {
+		return metric, err
+	}
+
+	outputAdjOut, err := gobgp.UpdateGoBGPPerPeerMetrics(
+		ctx,
+		bgpSrv,
+		bgpapi.TableType_ADJ_OUT,
+		bgpapi.Family_AFI_IP,
+		safi,
+		peer.PeerAddress,
+	)
+	if err != nil {
+		return metric, err
+	}
+
+	if peer.PeerType == model.TF {
+		metric.Vpnv4RouteRcvd, metric.Vpnv4RouteAdvd = outputAdjIn, outputAdjOut
+	} else {
+		metric.IPv4RouteRcvd, metric.IPv4RouteAdvd = outputAdjIn, outputAdjOut
+	}
+
+	return metric, nil
+}
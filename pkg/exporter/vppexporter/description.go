@@ -21,6 +21,48 @@ var (
 		[]string{"table"},
 		nil,
 	)
+	vppUDPTunnelReachable = prometheus.NewDesc(
+		prometheus.BuildFQName("vpp", "udp_tunnel", "reachable"),
+		"Reachability of vrouter (udp tunnel destination) detected by probing (1 - reachable, 0 - unreachable)",
+		[]string{"vrouter"},
+		nil,
+	)
+	vppUDPTunnelProbeFailedCount = prometheus.NewDesc(
+		prometheus.BuildFQName("vpp", "udp_tunnel", "probe_failed_count"),
+		"Number of failed probes of vrouter (udp tunnel destination)",
+		[]string{"vrouter"},
+		nil,
+	)
+	vppFIPPacketCount = prometheus.NewDesc(
+		prometheus.BuildFQName("vpp", "floating_ip", "packet_count"),
+		"Number of packets routed to the floating ip through its vrouters",
+		[]string{"vrf", "prefix", "vrouter"},
+		nil,
+	)
+	vppFIPByteCount = prometheus.NewDesc(
+		prometheus.BuildFQName("vpp", "floating_ip", "byte_count"),
+		"Number of bytes routed to the floating ip through its vrouters",
+		[]string{"vrf", "prefix", "vrouter"},
+		nil,
+	)
+	vppUDPTunnelPacketCount = prometheus.NewDesc(
+		prometheus.BuildFQName("vpp", "udp_tunnel", "packet_count"),
+		"Number of packets encapsulated to the udp tunnel to vrouter",
+		[]string{"vrouter"},
+		nil,
+	)
+	vppUDPTunnelByteCount = prometheus.NewDesc(
+		prometheus.BuildFQName("vpp", "udp_tunnel", "byte_count"),
+		"Number of bytes encapsulated to the udp tunnel to vrouter",
+		[]string{"vrouter"},
+		nil,
+	)
+	vppTrafficCounterHidden = prometheus.NewDesc(
+		prometheus.BuildFQName("vpp", "traffic_counter", "hidden"),
+		"Number of floating ips (kind fip) and udp tunnels (kind tunnel) with traffic counters not exposed as metrics",
+		[]string{"kind"},
+		nil,
+	)
 	// vpp_network_... absolute values
 
 	vppNetworkInterfaceID = prometheus.NewDesc(
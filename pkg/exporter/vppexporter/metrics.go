@@ -1,16 +1,78 @@
 package vppexporter
 
+import "sync"
+
 // VPPUDPTunnelMetric describes udp tunnel total
 type VPPUDPTunnelMetric struct {
-	UDPTunnelTotal float64
+	mu             sync.RWMutex
+	udpTunnelTotal float64
 }
 
 func (m *VPPUDPTunnelMetric) SetUDPTunnelTotal(value float64) {
-	m.UDPTunnelTotal = value
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.udpTunnelTotal = value
+}
+
+func (m *VPPUDPTunnelMetric) UDPTunnelTotal() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.udpTunnelTotal
 }
 
 var VPPUDPTunnelMetrics VPPUDPTunnelMetric
 
+// VPPUDPTunnelProbeMetric describes probing results of vrouter (udp tunnel destination)
+type VPPUDPTunnelProbeMetric struct {
+	Reachable        bool
+	ProbeFailedCount float64
+}
+
+// VPPUDPTunnelProbeMetricMap keeps probing results of all vrouters (tunnels are created and deleted in runtime)
+type VPPUDPTunnelProbeMetricMap struct {
+	mu      sync.RWMutex
+	metrics map[string]VPPUDPTunnelProbeMetric
+}
+
+func (m *VPPUDPTunnelProbeMetricMap) SetProbeResult(vrouter string, reachable, failed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	metric := m.metrics[vrouter]
+
+	metric.Reachable = reachable
+
+	if failed {
+		metric.ProbeFailedCount++
+	}
+
+	m.metrics[vrouter] = metric
+}
+
+func (m *VPPUDPTunnelProbeMetricMap) Del(vrouter string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.metrics, vrouter)
+}
+
+func (m *VPPUDPTunnelProbeMetricMap) Snapshot() map[string]VPPUDPTunnelProbeMetric {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snapshot := make(map[string]VPPUDPTunnelProbeMetric, len(m.metrics))
+
+	for vrouter, metric := range m.metrics {
+		snapshot[vrouter] = metric
+	}
+
+	return snapshot
+}
+
+var VPPUDPTunnelProbeMetrics = VPPUDPTunnelProbeMetricMap{metrics: make(map[string]VPPUDPTunnelProbeMetric)}
+
 // VPPVRFMetric describes vrf floating ip and ipv4 route total
 type VPPVRFMetric struct {
 	VRFName        string
@@ -18,18 +80,34 @@ type VPPVRFMetric struct {
 	IPv4RouteTotal float64
 }
 
-func NewVPPVRFMetric(vrfName string) *VPPVRFMetric {
-	return &VPPVRFMetric{
-		VRFName: vrfName,
-	}
+// VPPVRFMetricMap keeps route totals of all vrfs (vrfs are added and deleted in runtime)
+type VPPVRFMetricMap struct {
+	mu      sync.RWMutex
+	metrics map[uint32]VPPVRFMetric
 }
 
-func (m *VPPVRFMetric) SetVPPRouteTotal(ipRouteNum, fipRouteNum float64) {
-	m.FIPRouteTotal = fipRouteNum
-	m.IPv4RouteTotal = ipRouteNum
+// Replace replaces the metrics of all vrfs, metrics of vrfs not in the map are deleted
+func (m *VPPVRFMetricMap) Replace(metrics map[uint32]VPPVRFMetric) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.metrics = metrics
 }
 
-var VPPVRFMetrics = make(map[uint32]*VPPVRFMetric)
+func (m *VPPVRFMetricMap) Snapshot() map[uint32]VPPVRFMetric {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snapshot := make(map[uint32]VPPVRFMetric, len(m.metrics))
+
+	for vrfID, metric := range m.metrics {
+		snapshot[vrfID] = metric
+	}
+
+	return snapshot
+}
+
+var VPPVRFMetrics = VPPVRFMetricMap{metrics: make(map[uint32]VPPVRFMetric)}
 
 // VPPInterfaceMetric describes vpp interface metrics for all vrfs
 type VPPInterfaceMetric struct {
@@ -63,4 +141,121 @@ func (m *VPPInterfaceMetric) SetVPPInterfaceMetric(
 	m.dropPackets = dropPackets
 }
 
-var VPPInterfaceMetrics = make(map[uint32]*VPPInterfaceMetric)
+// VPPInterfaceMetricMap keeps metrics of all vpp interfaces (sub-interfaces are created and deleted in runtime)
+type VPPInterfaceMetricMap struct {
+	mu      sync.RWMutex
+	metrics map[uint32]VPPInterfaceMetric
+}
+
+// Replace replaces the metrics of all interfaces, metrics of interfaces not in the map are deleted
+func (m *VPPInterfaceMetricMap) Replace(metrics map[uint32]VPPInterfaceMetric) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.metrics = metrics
+}
+
+func (m *VPPInterfaceMetricMap) Snapshot() map[uint32]VPPInterfaceMetric {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snapshot := make(map[uint32]VPPInterfaceMetric, len(m.metrics))
+
+	for swIfIndex, metric := range m.metrics {
+		snapshot[swIfIndex] = metric
+	}
+
+	return snapshot
+}
+
+var VPPInterfaceMetrics = VPPInterfaceMetricMap{metrics: make(map[uint32]VPPInterfaceMetric)}
+
+// VPPTrafficCounter is packets and bytes forwarded by vpp, counters of all vpp threads are summed up
+type VPPTrafficCounter struct {
+	Packets uint64
+	Bytes   uint64
+}
+
+// VPPFIPKey identifies floating ip route
+type VPPFIPKey struct {
+	VRFID  uint32
+	Prefix string
+}
+
+// VPPFIPTrafficMetric describes traffic to floating ip through all vrouters of its route (/net/route/to)
+type VPPFIPTrafficMetric struct {
+	VRFName string
+	Prefix  string
+	VRouter string // next hops of the route joined by comma
+	VPPTrafficCounter
+}
+
+// VPPTunnelTrafficMetric describes traffic through udp tunnel to vrouter (/net/udp-encap)
+type VPPTunnelTrafficMetric struct {
+	VRouter string
+	VPPTrafficCounter
+}
+
+// VPPTrafficMetricMap keeps traffic counters of all floating ips and udp tunnels, only the exposed ones are
+// collected as metrics to cap the number of series
+type VPPTrafficMetricMap struct {
+	mu             sync.RWMutex
+	updated        bool // counters are read at least once
+	fips           map[VPPFIPKey]VPPFIPTrafficMetric
+	tunnels        map[uint32]VPPTunnelTrafficMetric
+	exposedFIPs    map[VPPFIPKey]bool
+	exposedTunnels map[uint32]bool
+}
+
+// Replace replaces the counters of all floating ips and tunnels, counters of deleted ones are deleted
+func (m *VPPTrafficMetricMap) Replace(
+	fips map[VPPFIPKey]VPPFIPTrafficMetric, tunnels map[uint32]VPPTunnelTrafficMetric,
+	exposedFIPs map[VPPFIPKey]bool, exposedTunnels map[uint32]bool,
+) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.updated = true
+	m.fips, m.tunnels = fips, tunnels
+	m.exposedFIPs, m.exposedTunnels = exposedFIPs, exposedTunnels
+}
+
+// FIP returns the counter of the floating ip route, false if counters are not read or the route is not installed
+func (m *VPPTrafficMetricMap) FIP(key VPPFIPKey) (VPPTrafficCounter, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	metric, ok := m.fips[key]
+
+	return metric.VPPTrafficCounter, ok
+}
+
+// Tunnel returns the counter of the udp tunnel, false if counters are not read or the tunnel is not created
+func (m *VPPTrafficMetricMap) Tunnel(tunnelID uint32) (VPPTrafficCounter, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	metric, ok := m.tunnels[tunnelID]
+
+	return metric.VPPTrafficCounter, ok
+}
+
+// Exposed returns the counters collected as metrics and the numbers of floating ips and tunnels left out
+func (m *VPPTrafficMetricMap) Exposed() (
+	fips []VPPFIPTrafficMetric, tunnels []VPPTunnelTrafficMetric, hiddenFIPs, hiddenTunnels int, updated bool,
+) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for key := range m.exposedFIPs {
+		fips = append(fips, m.fips[key])
+	}
+
+	for tunnelID := range m.exposedTunnels {
+		tunnels = append(tunnels, m.tunnels[tunnelID])
+	}
+
+	return fips, tunnels, len(m.fips) - len(fips), len(m.tunnels) - len(tunnels), m.updated
+}
+
+var VPPTrafficMetrics VPPTrafficMetricMap
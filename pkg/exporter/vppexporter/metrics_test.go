@@ -0,0 +1,46 @@
+package vppexporter
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectWhileUpdated(t *testing.T) {
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		for i := range 100 {
+			VPPVRFMetrics.Replace(map[uint32]VPPVRFMetric{1: {VRFName: "vrf1", FIPRouteTotal: float64(i)}})
+			VPPInterfaceMetrics.Replace(map[uint32]VPPInterfaceMetric{1: *NewVPPInterfaceMetric("eth0", 1)})
+			VPPUDPTunnelMetrics.SetUDPTunnelTotal(float64(i))
+			VPPTrafficMetrics.Replace(
+				map[VPPFIPKey]VPPFIPTrafficMetric{{VRFID: 1, Prefix: "172.16.1.10/32"}: {VRFName: "vrf1", Prefix: "172.16.1.10/32"}},
+				map[uint32]VPPTunnelTrafficMetric{0: {VRouter: "10.1.1.1"}},
+				map[VPPFIPKey]bool{{VRFID: 1, Prefix: "172.16.1.10/32"}: true},
+				map[uint32]bool{0: true},
+			)
+		}
+	}()
+
+	for range 100 {
+		ch := make(chan prometheus.Metric, 100)
+
+		NewCloudgwExporter().Collect(ch)
+		close(ch)
+	}
+
+	wg.Wait()
+
+	// the deleted vrf is not exposed
+
+	VPPVRFMetrics.Replace(map[uint32]VPPVRFMetric{})
+
+	require.Empty(t, VPPVRFMetrics.Snapshot())
+}
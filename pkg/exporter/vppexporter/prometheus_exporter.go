@@ -14,6 +14,13 @@ func (c *CloudgwExporter) Describe(ch chan<- *prometheus.Desc) {
 	ch <- vppIPv4RouteTotal
 	ch <- vppFIPRouteTotal
 	ch <- vppUDPTunnelTotal
+	ch <- vppUDPTunnelReachable
+	ch <- vppUDPTunnelProbeFailedCount
+	ch <- vppFIPPacketCount
+	ch <- vppFIPByteCount
+	ch <- vppUDPTunnelPacketCount
+	ch <- vppUDPTunnelByteCount
+	ch <- vppTrafficCounterHidden
 	ch <- vppNetworkInterfaceID
 	ch <- vppNetworkRxPacketCount
 	ch <- vppNetworkRxByteCount
@@ -25,7 +32,7 @@ func (c *CloudgwExporter) Describe(ch chan<- *prometheus.Desc) {
 }
 
 func (c *CloudgwExporter) Collect(metricsCh chan<- prometheus.Metric) {
-	for _, vrfMetric := range VPPVRFMetrics {
+	for _, vrfMetric := range VPPVRFMetrics.Snapshot() {
 		metricsCh <- prometheus.MustNewConstMetric(
 			vppIPv4RouteTotal,
 			prometheus.GaugeValue,
@@ -43,11 +50,34 @@ func (c *CloudgwExporter) Collect(metricsCh chan<- prometheus.Metric) {
 	metricsCh <- prometheus.MustNewConstMetric(
 		vppUDPTunnelTotal,
 		prometheus.GaugeValue,
-		VPPUDPTunnelMetrics.UDPTunnelTotal,
+		VPPUDPTunnelMetrics.UDPTunnelTotal(),
 		"default",
 	)
 
-	for _, i := range VPPInterfaceMetrics {
+	for vrouter, probeMetric := range VPPUDPTunnelProbeMetrics.Snapshot() {
+		reachable := float64(0)
+
+		if probeMetric.Reachable {
+			reachable = 1
+		}
+
+		metricsCh <- prometheus.MustNewConstMetric(
+			vppUDPTunnelReachable,
+			prometheus.GaugeValue,
+			reachable,
+			vrouter,
+		)
+		metricsCh <- prometheus.MustNewConstMetric(
+			vppUDPTunnelProbeFailedCount,
+			prometheus.CounterValue,
+			probeMetric.ProbeFailedCount,
+			vrouter,
+		)
+	}
+
+	c.collectTraffic(metricsCh)
+
+	for _, i := range VPPInterfaceMetrics.Snapshot() {
 		metricsCh <- prometheus.MustNewConstMetric(
 			vppNetworkInterfaceID,
 			prometheus.GaugeValue,
@@ -98,3 +128,44 @@ func (c *CloudgwExporter) Collect(metricsCh chan<- prometheus.Metric) {
 		)
 	}
 }
+
+// collectTraffic collects floating ip and udp tunnel traffic counters if they are enabled
+func (c *CloudgwExporter) collectTraffic(metricsCh chan<- prometheus.Metric) {
+	fips, tunnels, hiddenFIPs, hiddenTunnels, updated := VPPTrafficMetrics.Exposed()
+	if !updated {
+		return
+	}
+
+	for _, fip := range fips {
+		metricsCh <- prometheus.MustNewConstMetric(
+			vppFIPPacketCount,
+			prometheus.CounterValue,
+			float64(fip.Packets),
+			fip.VRFName, fip.Prefix, fip.VRouter,
+		)
+		metricsCh <- prometheus.MustNewConstMetric(
+			vppFIPByteCount,
+			prometheus.CounterValue,
+			float64(fip.Bytes),
+			fip.VRFName, fip.Prefix, fip.VRouter,
+		)
+	}
+
+	for _, tunnel := range tunnels {
+		metricsCh <- prometheus.MustNewConstMetric(
+			vppUDPTunnelPacketCount,
+			prometheus.CounterValue,
+			float64(tunnel.Packets),
+			tunnel.VRouter,
+		)
+		metricsCh <- prometheus.MustNewConstMetric(
+			vppUDPTunnelByteCount,
+			prometheus.CounterValue,
+			float64(tunnel.Bytes),
+			tunnel.VRouter,
+		)
+	}
+
+	metricsCh <- prometheus.MustNewConstMetric(vppTrafficCounterHidden, prometheus.GaugeValue, float64(hiddenFIPs), "fip")
+	metricsCh <- prometheus.MustNewConstMetric(vppTrafficCounterHidden, prometheus.GaugeValue, float64(hiddenTunnels), "tunnel")
+}
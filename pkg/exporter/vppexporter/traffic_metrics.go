@@ -0,0 +1,251 @@
+package vppexporter
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"go.fd.io/govpp/adapter"
+	"go.fd.io/govpp/api"
+
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp"
+	"git.crptech.ru/cloud/cloudgw/pkg/logger"
+)
+
+// vpp stats segment vectors of combined counters: per route (by stats index of the route) and per udp encap (by id)
+const (
+	routeCountersName  = "/net/route/to"
+	tunnelCountersName = "/net/udp-encap"
+)
+
+// UpdateVPPTrafficMetrics updates the VPPTrafficMetrics with counters of the stored floating ip routes and udp tunnels
+// every [poolingInterval] sec. At most maxSeries floating ips (and as many tunnels) are exposed as metrics, with
+// topN > 0 only topN ones with the most bytes since the previous update are exposed.
+func UpdateVPPTrafficMetrics(
+	ctx context.Context, stats adapter.StatsAPI, vppConn api.Connection, poolingInterval int, storage *imdb.Storage,
+	maxSeries, topN int,
+) error {
+	if stats == nil {
+		return nil
+	}
+
+	// routes are dumped on a dedicated stream, as replies to dumps on the shared stream interleave with replies to
+	// requests of other goroutines
+
+	stream, err := vppConn.NewStream(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create vpp stream for traffic metrics: %w", err)
+	}
+
+	updater := newTrafficMetricsUpdater(maxSeries, topN)
+
+	ticker := time.NewTicker(time.Duration(poolingInterval) * time.Second)
+
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			_ = stream.Close()
+
+			logger.Info("vpp traffic metrics update stopped")
+
+			return nil
+		case <-ticker.C:
+			if err := updater.update(stats, stream, storage); err != nil {
+				logger.Error("failed to update vpp traffic metrics", "error", err)
+			}
+		}
+	}
+}
+
+// trafficMetricsUpdater reads floating ip and udp tunnel counters and selects the exposed ones
+type trafficMetricsUpdater struct {
+	maxSeries int
+	topN      int
+
+	fipBytes       map[VPPFIPKey]uint64 // bytes of the previous update
+	tunnelBytes    map[uint32]uint64
+	exposedFIPs    map[VPPFIPKey]bool
+	exposedTunnels map[uint32]bool
+}
+
+func newTrafficMetricsUpdater(maxSeries, topN int) *trafficMetricsUpdater {
+	return &trafficMetricsUpdater{maxSeries: maxSeries, topN: topN}
+}
+
+// update reads the counters of the stored floating ip routes and udp tunnels and replaces the VPPTrafficMetrics
+func (u *trafficMetricsUpdater) update(stats adapter.StatsAPI, stream api.Stream, storage *imdb.Storage) error {
+	routeCounters, err := combinedCounters(stats, routeCountersName)
+	if err != nil {
+		return err
+	}
+
+	tunnelCounters, err := combinedCounters(stats, tunnelCountersName)
+	if err != nil {
+		return err
+	}
+
+	vrfNames := make(map[uint32]string)
+
+	for _, vrf := range storage.VPPVRFStorage.GetVRFs() {
+		vrfNames[vrf.ID] = vrf.Name
+	}
+
+	// the stats index of a route is known to vpp only, routes of a vrf are dumped once per update
+
+	statsIndexes := make(map[uint32]map[string]uint32)
+	fips := make(map[VPPFIPKey]VPPFIPTrafficMetric)
+
+	for _, route := range storage.VPPFIPRouteStorage.GetFIPRoutes() {
+		indexes, ok := statsIndexes[route.VRFID]
+		if !ok {
+			if indexes, err = vpp.DumpFIPRouteStatsIndexes(stream, route.VRFID); err != nil {
+				return fmt.Errorf("failed to dump floating ip routes of vrf %d: %w", route.VRFID, err)
+			}
+
+			statsIndexes[route.VRFID] = indexes
+		}
+
+		index, ok := indexes[route.Prefix]
+		if !ok || int(index) >= len(routeCounters) { // not installed to vpp (e.g. paths are pruned)
+			continue
+		}
+
+		fips[VPPFIPKey{VRFID: route.VRFID, Prefix: route.Prefix}] = VPPFIPTrafficMetric{
+			VRFName:           vrfNames[route.VRFID],
+			Prefix:            route.Prefix,
+			VRouter:           strings.Join(route.NextHops, ","),
+			VPPTrafficCounter: routeCounters[index],
+		}
+	}
+
+	tunnels := make(map[uint32]VPPTunnelTrafficMetric)
+
+	for _, tunnel := range storage.VPPUDPTunnelStorage.GetUDPTunnels() {
+		if int(tunnel.TunnelID) >= len(tunnelCounters) {
+			continue
+		}
+
+		tunnels[tunnel.TunnelID] = VPPTunnelTrafficMetric{
+			VRouter:           tunnel.DstIP,
+			VPPTrafficCounter: tunnelCounters[tunnel.TunnelID],
+		}
+	}
+
+	fipBytes := make(map[VPPFIPKey]uint64, len(fips))
+
+	for key, fip := range fips {
+		fipBytes[key] = fip.Bytes
+	}
+
+	tunnelBytes := make(map[uint32]uint64, len(tunnels))
+
+	for tunnelID, tunnel := range tunnels {
+		tunnelBytes[tunnelID] = tunnel.Bytes
+	}
+
+	u.exposedFIPs = exposedKeys(fipBytes, u.fipBytes, u.exposedFIPs, u.maxSeries, u.topN, compareFIPKeys)
+	u.exposedTunnels = exposedKeys(tunnelBytes, u.tunnelBytes, u.exposedTunnels, u.maxSeries, u.topN, cmp.Compare[uint32])
+	u.fipBytes, u.tunnelBytes = fipBytes, tunnelBytes
+
+	VPPTrafficMetrics.Replace(fips, tunnels, u.exposedFIPs, u.exposedTunnels)
+
+	return nil
+}
+
+// combinedCounters returns the combined counter vector of the stats segment summed up over vpp threads, the vector
+// is empty if vpp has not created it yet
+func combinedCounters(stats adapter.StatsAPI, name string) ([]VPPTrafficCounter, error) {
+	entries, err := stats.DumpStats("^" + regexp.QuoteMeta(name) + "$")
+	if err != nil {
+		return nil, fmt.Errorf("failed to dump vpp stats %s: %w", name, err)
+	}
+
+	for _, entry := range entries {
+		if string(entry.Name) != name {
+			continue
+		}
+
+		threads, ok := entry.Data.(adapter.CombinedCounterStat)
+		if !ok {
+			return nil, fmt.Errorf("vpp stats %s is %T, expected combined counters", name, entry.Data)
+		}
+
+		var counters []VPPTrafficCounter
+
+		for _, thread := range threads {
+			if len(thread) > len(counters) {
+				counters = append(counters, make([]VPPTrafficCounter, len(thread)-len(counters))...)
+			}
+
+			for i, counter := range thread {
+				counters[i].Packets += counter.Packets()
+				counters[i].Bytes += counter.Bytes()
+			}
+		}
+
+		return counters, nil
+	}
+
+	return nil, nil
+}
+
+// exposedKeys returns the series exposed as metrics. In top-n mode they are topN ones with the most bytes since the
+// previous update, otherwise the series exposed before are kept (they do not churn) and new ones added up to maxSeries.
+func exposedKeys[K comparable](
+	bytes, prevBytes map[K]uint64, exposed map[K]bool, maxSeries, topN int, compare func(a, b K) int,
+) map[K]bool {
+	keys := make([]K, 0, len(bytes))
+
+	for key := range bytes {
+		keys = append(keys, key)
+	}
+
+	slices.SortFunc(keys, compare)
+
+	result := make(map[K]bool)
+
+	if topN > 0 {
+		delta := func(key K) uint64 {
+			if bytes[key] < prevBytes[key] { // counters are reset on vpp restart or route re-creation
+				return bytes[key]
+			}
+
+			return bytes[key] - prevBytes[key]
+		}
+
+		slices.SortStableFunc(keys, func(a, b K) int { return cmp.Compare(delta(b), delta(a)) })
+
+		for _, key := range keys[:min(topN, maxSeries, len(keys))] {
+			result[key] = true
+		}
+
+		return result
+	}
+
+	for _, key := range keys {
+		if exposed[key] {
+			result[key] = true
+		}
+	}
+
+	for _, key := range keys {
+		if len(result) >= maxSeries {
+			break
+		}
+
+		result[key] = true
+	}
+
+	return result
+}
+
+func compareFIPKeys(a, b VPPFIPKey) int {
+	return cmp.Or(cmp.Compare(a.VRFID, b.VRFID), strings.Compare(a.Prefix, b.Prefix))
+}
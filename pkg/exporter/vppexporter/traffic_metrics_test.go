@@ -0,0 +1,144 @@
+package vppexporter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.fd.io/govpp/adapter"
+
+	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp/dryrun"
+)
+
+// fakeStats is vpp stats segment with route and udp encap counters of two threads
+type fakeStats struct {
+	adapter.StatsAPI
+
+	routes  adapter.CombinedCounterStat
+	tunnels adapter.CombinedCounterStat
+}
+
+func (s *fakeStats) DumpStats(...string) ([]adapter.StatEntry, error) {
+	return []adapter.StatEntry{
+		{StatIdentifier: adapter.StatIdentifier{Name: []byte(routeCountersName)}, Data: s.routes},
+		{StatIdentifier: adapter.StatIdentifier{Name: []byte(tunnelCountersName)}, Data: s.tunnels},
+	}, nil
+}
+
+func TestUpdateTrafficMetrics(t *testing.T) {
+	stream := dryrun.NewStream(context.Background())
+	storage := imdb.NewStorage()
+
+	vrf := &model.VPPVRFTable{Name: "vrf1", ID: 1}
+
+	require.NoError(t, storage.VPPVRFStorage.AddVRF(vrf))
+	require.NoError(t, vpp.AddDelVRF(stream, true, *vrf))
+
+	// floating ips get route stats indexes 0, 1 and 2, the tunnels get ids 0 and 1
+
+	for _, vrouter := range []string{"10.1.1.1", "10.1.1.2"} {
+		tunnel := &model.VPPUDPTunnel{SrcIP: "192.0.0.1", DstIP: vrouter, SrcPort: 50000, DstPort: 6635}
+
+		require.NoError(t, vpp.AddUDPTunnel(stream, tunnel))
+		require.NoError(t, storage.VPPUDPTunnelStorage.AddUDPTunnel(tunnel))
+	}
+
+	for i, prefix := range []string{"172.16.1.10/32", "172.16.1.11/32", "172.16.1.12/32"} {
+		route := model.NewVPPIPRoute(1, 1, model.UndefinedSubIf, prefix, []string{"10.1.1.1"}, []uint32{0}, []uint32{uint32(25 + i)})
+
+		require.NoError(t, vpp.AddDelFIPRoute(stream, true, &route))
+		require.NoError(t, storage.VPPFIPRouteStorage.AddFIPRoute(&route))
+	}
+
+	stats := &fakeStats{
+		routes: adapter.CombinedCounterStat{
+			{{1, 100}, {2, 200}, {3, 300}},
+			{{1, 100}, {2, 200}, {3, 300}},
+		},
+		tunnels: adapter.CombinedCounterStat{
+			{{6, 600}, {0, 0}},
+			{{6, 600}, {0, 0}},
+		},
+	}
+
+	// the cap keeps the first floating ips exposed, counters of all are read
+
+	updater := newTrafficMetricsUpdater(2, 0)
+
+	require.NoError(t, updater.update(stats, stream, storage))
+
+	counter, ok := VPPTrafficMetrics.FIP(VPPFIPKey{VRFID: 1, Prefix: "172.16.1.12/32"})
+	require.True(t, ok)
+	require.Equal(t, VPPTrafficCounter{Packets: 6, Bytes: 600}, counter)
+
+	counter, ok = VPPTrafficMetrics.Tunnel(0)
+	require.True(t, ok)
+	require.Equal(t, VPPTrafficCounter{Packets: 12, Bytes: 1200}, counter)
+
+	fips, tunnels, hiddenFIPs, hiddenTunnels, updated := VPPTrafficMetrics.Exposed()
+	require.True(t, updated)
+	require.ElementsMatch(t, []string{"172.16.1.10/32", "172.16.1.11/32"}, fipPrefixes(fips))
+	require.Equal(t, VPPFIPTrafficMetric{
+		VRFName:           "vrf1",
+		Prefix:            "172.16.1.10/32",
+		VRouter:           "10.1.1.1",
+		VPPTrafficCounter: VPPTrafficCounter{Packets: 2, Bytes: 200},
+	}, fips[fipIndex(fips, "172.16.1.10/32")])
+	require.Len(t, tunnels, 2)
+	require.Equal(t, 1, hiddenFIPs)
+	require.Equal(t, 0, hiddenTunnels)
+
+	// top-n exposes the floating ips with the most bytes since the previous update
+
+	updater = newTrafficMetricsUpdater(2, 1)
+
+	require.NoError(t, updater.update(stats, stream, storage))
+
+	fips, _, hiddenFIPs, _, _ = VPPTrafficMetrics.Exposed()
+	require.Equal(t, []string{"172.16.1.12/32"}, fipPrefixes(fips))
+	require.Equal(t, 2, hiddenFIPs)
+
+	stats.routes[0][0] = adapter.CombinedCounter{1000, 100000}
+
+	require.NoError(t, updater.update(stats, stream, storage))
+
+	fips, _, _, _, _ = VPPTrafficMetrics.Exposed()
+	require.Equal(t, []string{"172.16.1.10/32"}, fipPrefixes(fips))
+
+	// the deleted floating ip is not exposed
+
+	route := storage.VPPFIPRouteStorage.GetFIPRoute("172.16.1.10/32")
+
+	require.NoError(t, vpp.AddDelFIPRoute(stream, false, route))
+	require.NoError(t, storage.VPPFIPRouteStorage.DelFIPRoute(route.Prefix))
+	require.NoError(t, updater.update(stats, stream, storage))
+
+	_, ok = VPPTrafficMetrics.FIP(VPPFIPKey{VRFID: 1, Prefix: "172.16.1.10/32"})
+	require.False(t, ok)
+
+	fips, _, _, _, _ = VPPTrafficMetrics.Exposed()
+	require.Equal(t, []string{"172.16.1.11/32"}, fipPrefixes(fips))
+}
+
+func fipPrefixes(fips []VPPFIPTrafficMetric) []string {
+	prefixes := make([]string, 0, len(fips))
+
+	for _, fip := range fips {
+		prefixes = append(prefixes, fip.Prefix)
+	}
+
+	return prefixes
+}
+
+func fipIndex(fips []VPPFIPTrafficMetric, prefix string) int {
+	for i, fip := range fips {
+		if fip.Prefix == prefix {
+			return i
+		}
+	}
+
+	return -1
+}
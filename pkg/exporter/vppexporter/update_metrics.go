@@ -8,12 +8,13 @@ import (
 	"go.fd.io/govpp/binapi/ip"
 	"go.fd.io/govpp/core"
 
-	"git.crptech.ru/cloud/cloudgw/internal/model"
+	"git.crptech.ru/cloud/cloudgw/internal/repository/imdb"
 	"git.crptech.ru/cloud/cloudgw/internal/repository/vpp"
 	"git.crptech.ru/cloud/cloudgw/pkg/logger"
 )
 
-// UpdateVPPInterfaceMetrics updates the VPPInterfaceMetrics var with VPP interface metrics every [poolingInterval] sec
+// UpdateVPPInterfaceMetrics updates the VPPInterfaceMetrics var with VPP interface metrics every [poolingInterval] sec,
+// metrics of deleted interfaces are deleted
 func UpdateVPPInterfaceMetrics(ctx context.Context, vppStatsConn *core.StatsConnection, poolingInterval int) error {
 	if vppStatsConn == nil {
 		return nil
@@ -21,22 +22,14 @@ func UpdateVPPInterfaceMetrics(ctx context.Context, vppStatsConn *core.StatsConn
 
 	stats := new(api.InterfaceStats)
 
-	if err := vppStatsConn.GetInterfaceStats(stats); err != nil {
+	if err := updateVPPInterfaceMetrics(vppStatsConn, stats); err != nil {
 		logger.Error("setting vpp interface stats failed", "error", err)
 
 		return err
 	}
 
-	// initialize VPPInterfaceMetrics with all interfaces
-
-	for _, i := range stats.Interfaces[1:] { // skip 'local0' interface with id=0
-		VPPInterfaceMetrics[i.InterfaceIndex] = NewVPPInterfaceMetric(i.InterfaceName, float64(i.InterfaceIndex))
-	}
-
 	ticker := time.NewTicker(time.Duration(poolingInterval) * time.Second)
 
-	done := make(chan bool)
-
 	defer ticker.Stop()
 
 	for {
@@ -46,36 +39,51 @@ func UpdateVPPInterfaceMetrics(ctx context.Context, vppStatsConn *core.StatsConn
 
 			return nil
 		case <-ticker.C:
-			// collect data
-			if err := vppStatsConn.GetInterfaceStats(stats); err != nil {
+			if err := updateVPPInterfaceMetrics(vppStatsConn, stats); err != nil {
 				logger.Error("getting vpp interface stats failed", "error", err)
 
 				return err
 			}
+		}
+	}
+}
 
-			for _, i := range stats.Interfaces[1:] { // skip 'local0' with id=0
-				VPPInterfaceMetrics[i.InterfaceIndex].SetVPPInterfaceMetric(
-					float64(i.Rx.Packets),
-					float64(i.Rx.Bytes),
-					float64(i.RxErrors),
-					float64(i.Tx.Packets),
-					float64(i.Tx.Bytes),
-					float64(i.TxErrors),
-					float64(i.Drops),
-				)
-			}
-		case <-done:
-			return nil
+func updateVPPInterfaceMetrics(vppStatsConn *core.StatsConnection, stats *api.InterfaceStats) error {
+	if err := vppStatsConn.GetInterfaceStats(stats); err != nil {
+		return err
+	}
+
+	metrics := make(map[uint32]VPPInterfaceMetric, len(stats.Interfaces))
+
+	for _, i := range stats.Interfaces {
+		if i.InterfaceIndex == 0 { // skip 'local0'
+			continue
 		}
+
+		metric := NewVPPInterfaceMetric(i.InterfaceName, float64(i.InterfaceIndex))
+		metric.SetVPPInterfaceMetric(
+			float64(i.Rx.Packets),
+			float64(i.Rx.Bytes),
+			float64(i.RxErrors),
+			float64(i.Tx.Packets),
+			float64(i.Tx.Bytes),
+			float64(i.TxErrors),
+			float64(i.Drops),
+		)
+
+		metrics[i.InterfaceIndex] = *metric
 	}
+
+	VPPInterfaceMetrics.Replace(metrics)
+
+	return nil
 }
 
-// UpdateVPPUDPVRFMetrics updates the VPPUDPTunnelMetrics and VPPVRFMetrics every [poolingInterval] sec
-func UpdateVPPUDPVRFMetrics(ctx context.Context, stream *api.Stream, poolingInterval int, vppVRFTables []*model.VPPVRFTable) error {
+// UpdateVPPUDPVRFMetrics updates the VPPUDPTunnelMetrics and VPPVRFMetrics of the stored vrfs every [poolingInterval]
+// sec, metrics of deleted vrfs are deleted
+func UpdateVPPUDPVRFMetrics(ctx context.Context, stream *api.Stream, poolingInterval int, storage *imdb.VPPVRFStorage) error {
 	ticker := time.NewTicker(time.Duration(poolingInterval) * time.Second)
 
-	done := make(chan bool)
-
 	defer ticker.Stop()
 
 	for {
@@ -92,20 +100,24 @@ func UpdateVPPUDPVRFMetrics(ctx context.Context, stream *api.Stream, poolingInte
 
 			VPPUDPTunnelMetrics.SetUDPTunnelTotal(udpCount)
 
-			for _, vrf := range vppVRFTables {
+			metrics := make(map[uint32]VPPVRFMetric)
+
+			for _, vrf := range storage.GetVRFs() {
 				if vrf.ID == 0 { // skip global routing table
 					continue
 				}
 
 				ipRouteCount, fipRouteCount, err := vpp.CountRoutesPerTable(*stream, ip.IPTable{TableID: vrf.ID})
 				if err != nil {
-					logger.Error("failed to count vpp routes", "error", err)
+					logger.Error("failed to count vpp routes", "vrf", vrf.Name, "error", err)
+
+					continue
 				}
 
-				VPPVRFMetrics[vrf.ID].SetVPPRouteTotal(ipRouteCount, fipRouteCount)
+				metrics[vrf.ID] = VPPVRFMetric{VRFName: vrf.Name, FIPRouteTotal: fipRouteCount, IPv4RouteTotal: ipRouteCount}
 			}
-		case <-done:
-			return nil
+
+			VPPVRFMetrics.Replace(metrics)
 		}
 	}
 }
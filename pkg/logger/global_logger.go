@@ -5,7 +5,10 @@ import (
 	"os"
 )
 
-var globalLogger *slog.Logger
+var (
+	globalLogger *slog.Logger
+	globalLevel  = new(slog.LevelVar)
+)
 
 func init() {
 	if globalLogger == nil {
@@ -14,7 +17,12 @@ func init() {
 }
 
 func Init(opts ...func(*Config)) {
-	globalLogger = New(opts...)
+	globalLogger = New(append(opts, withLevelVar(globalLevel))...)
+}
+
+// SetLevel changes level of the global logger initialized by Init
+func SetLevel(level string) {
+	globalLevel.Set(logLevel(level))
 }
 
 func Debug(msg string, args ...any) {
@@ -1,6 +1,8 @@
 package logger
 
 import (
+	"context"
+	"log/slog"
 	"testing"
 )
 
@@ -23,3 +25,19 @@ func Test_initGlobalLogger(_ *testing.T) {
 	Warn("debug test", "key", "value")
 	Error("debug test", "key", "value", "error", "not found")
 }
+
+func TestSetLevel(t *testing.T) {
+	Init(WithLevel("info"))
+
+	if GetLogger().Enabled(context.Background(), slog.LevelDebug) {
+		t.Fatal("debug level is enabled for info logger")
+	}
+
+	SetLevel("debug")
+
+	if !GetLogger().Enabled(context.Background(), slog.LevelDebug) {
+		t.Fatal("debug level is not enabled after level change")
+	}
+
+	SetLevel("info")
+}
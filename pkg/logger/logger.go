@@ -7,9 +7,10 @@ import (
 )
 
 type Config struct {
-	level  string
-	format string
-	output string
+	level    string
+	format   string
+	output   string
+	levelVar *slog.LevelVar // level can be changed at runtime if set
 }
 
 func New(opts ...func(*Config)) *slog.Logger {
@@ -23,7 +24,14 @@ func New(opts ...func(*Config)) *slog.Logger {
 		opt(cfg)
 	}
 
-	level := logLevel(cfg.level)
+	var level slog.Leveler = logLevel(cfg.level)
+
+	if cfg.levelVar != nil {
+		cfg.levelVar.Set(logLevel(cfg.level))
+
+		level = cfg.levelVar
+	}
+
 	output := logOutput(cfg.output)
 
 	switch cfg.format {
@@ -85,6 +93,12 @@ func WithFormat(format string) func(*Config) {
 	}
 }
 
+func withLevelVar(levelVar *slog.LevelVar) func(*Config) {
+	return func(s *Config) {
+		s.levelVar = levelVar
+	}
+}
+
 func WithOutput(output string) func(*Config) {
 	return func(s *Config) {
 		s.output = output
@@ -0,0 +1,239 @@
+package openapi
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Document is an OpenAPI 3.0 document. Schemas of request and response types are generated from go types by
+// reflection: json tags give property names (properties without omitempty are required) and `enum:"a,b"` tags give
+// allowed values.
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Paths      map[string]map[string]Operation `json:"paths"`
+	Components Components                      `json:"components"`
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme,omitempty"`
+}
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // path or query
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+var (
+	ginPathParam   = regexp.MustCompile(`:([A-Za-z0-9_]+)`)
+	timeType       = reflect.TypeOf(time.Time{})
+	genericArgName = regexp.MustCompile(`\[(?:[^\]]*\.)?([A-Za-z0-9_]+)\]$`)
+)
+
+func New(title, version string) *Document {
+	return &Document{
+		OpenAPI:    "3.0.3",
+		Info:       Info{Title: title, Version: version},
+		Paths:      make(map[string]map[string]Operation),
+		Components: Components{Schemas: make(map[string]*Schema)},
+	}
+}
+
+// AddBearerAuth adds bearer token security scheme with the name
+func (d *Document) AddBearerAuth(name string) {
+	if d.Components.SecuritySchemes == nil {
+		d.Components.SecuritySchemes = make(map[string]SecurityScheme)
+	}
+
+	d.Components.SecuritySchemes[name] = SecurityScheme{Type: "http", Scheme: "bearer"}
+}
+
+// Add adds the operation to gin style path (/peers/:ip), path parameters not described in op are added as strings
+func (d *Document) Add(method, path string, op Operation) {
+	for _, match := range ginPathParam.FindAllStringSubmatch(path, -1) {
+		isDescribed := false
+
+		for _, param := range op.Parameters {
+			if param.In == "path" && param.Name == match[1] {
+				isDescribed = true
+			}
+		}
+
+		if !isDescribed {
+			op.Parameters = append(op.Parameters, Parameter{Name: match[1], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+
+	path = ginPathParam.ReplaceAllString(path, "{$1}")
+
+	if d.Paths[path] == nil {
+		d.Paths[path] = make(map[string]Operation)
+	}
+
+	d.Paths[path][strings.ToLower(method)] = op
+}
+
+// JSONResponse returns response with json body of the value type
+func (d *Document) JSONResponse(description string, v any) Response {
+	return d.ContentResponse(description, "application/json", v)
+}
+
+// ContentResponse returns response with body of the content type described by the value type (e.g. json data of
+// text/event-stream events)
+func (d *Document) ContentResponse(description, contentType string, v any) Response {
+	return Response{
+		Description: description,
+		Content:     map[string]MediaType{contentType: {Schema: d.Schema(v)}},
+	}
+}
+
+// Schema returns schema of the value type, structs are added to components and referenced
+func (d *Document) Schema(v any) *Schema {
+	return d.schemaOf(reflect.TypeOf(v))
+}
+
+func (d *Document) schemaOf(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: d.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t == timeType {
+			return &Schema{Type: "string", Format: "date-time"}
+		}
+
+		return d.structRef(t)
+	default:
+		return &Schema{}
+	}
+}
+
+func (d *Document) structRef(t reflect.Type) *Schema {
+	name := schemaName(t)
+	ref := &Schema{Ref: "#/components/schemas/" + name}
+
+	if _, ok := d.Components.Schemas[name]; ok {
+		return ref
+	}
+
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	d.Components.Schemas[name] = schema // added before properties for recursive types
+
+	d.addProperties(schema, t)
+
+	return ref
+}
+
+func (d *Document) addProperties(schema *Schema, t reflect.Type) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+
+		if !field.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			d.addProperties(schema, field.Type)
+
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		property := d.schemaOf(field.Type)
+
+		if enum := field.Tag.Get("enum"); enum != "" {
+			property.Enum = strings.Split(enum, ",")
+		}
+
+		schema.Properties[name] = property
+
+		if !strings.Contains(options, "omitempty") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// schemaName returns type name, generic Page[pkg.Item] is named ItemPage
+func schemaName(t reflect.Type) string {
+	name := t.Name()
+
+	if match := genericArgName.FindStringSubmatch(name); match != nil {
+		return match[1] + name[:strings.Index(name, "[")]
+	}
+
+	return name
+}
@@ -0,0 +1,73 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"git.crptech.ru/cloud/cloudgw/pkg/openapi"
+
+	"github.com/stretchr/testify/require"
+)
+
+type peer struct {
+	Address string    `json:"Address"`
+	State   string    `json:"State" enum:"idle,established"`
+	VRF     string    `json:"VRF,omitempty"`
+	Updated time.Time `json:"Updated"`
+	Labels  []uint32  `json:"Labels,omitempty"`
+	BFD     *bfd      `json:"BFD,omitempty"`
+	secret  string
+	Ignored string `json:"-"`
+}
+
+type bfd struct {
+	Enabled bool `json:"Enabled"`
+}
+
+type Page[T any] struct {
+	Total int `json:"Total"`
+	Items []T `json:"Items"`
+}
+
+func TestSchema(t *testing.T) {
+	doc := openapi.New("test", "1")
+
+	ref := doc.Schema(Page[peer]{})
+	require.Equal(t, "#/components/schemas/peerPage", ref.Ref)
+
+	page := doc.Components.Schemas["peerPage"]
+	require.NotNil(t, page)
+	require.Equal(t, []string{"Total", "Items"}, page.Required)
+	require.Equal(t, "array", page.Properties["Items"].Type)
+	require.Equal(t, "#/components/schemas/peer", page.Properties["Items"].Items.Ref)
+
+	schema := doc.Components.Schemas["peer"]
+	require.NotNil(t, schema)
+	require.Equal(t, []string{"Address", "State", "Updated"}, schema.Required)
+	require.Equal(t, []string{"idle", "established"}, schema.Properties["State"].Enum)
+	require.Equal(t, "date-time", schema.Properties["Updated"].Format)
+	require.Equal(t, "int32", schema.Properties["Labels"].Items.Format)
+	require.Equal(t, "#/components/schemas/bfd", schema.Properties["BFD"].Ref)
+	require.NotContains(t, schema.Properties, "secret")
+	require.NotContains(t, schema.Properties, "Ignored")
+	require.Contains(t, doc.Components.Schemas, "bfd")
+}
+
+func TestAdd(t *testing.T) {
+	doc := openapi.New("test", "1")
+
+	doc.Add("GET", "/peers/:ip/rib", openapi.Operation{
+		OperationID: "getPeerRIB",
+		Parameters:  []openapi.Parameter{{Name: "limit", In: "query", Schema: &openapi.Schema{Type: "integer"}}},
+		Responses:   map[string]openapi.Response{"200": doc.JSONResponse("ok", peer{})},
+	})
+
+	op, ok := doc.Paths["/peers/{ip}/rib"]["get"]
+	require.True(t, ok)
+	require.Len(t, op.Parameters, 2)
+	require.Equal(t, openapi.Parameter{Name: "ip", In: "path", Required: true, Schema: &openapi.Schema{Type: "string"}}, op.Parameters[1])
+
+	_, err := json.Marshal(doc)
+	require.NoError(t, err)
+}
@@ -0,0 +1,88 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "git.crptech.ru/cloud/cloudgw"
+
+// span attribute keys of the update to fib path
+const (
+	PrefixKey     = attribute.Key("cloudgw.prefix")
+	VRFKey        = attribute.Key("cloudgw.vrf")
+	NextHopKey    = attribute.Key("cloudgw.next_hop")
+	NeighborKey   = attribute.Key("cloudgw.neighbor")
+	VPPMessageKey = attribute.Key("cloudgw.vpp.message")
+	AddKey        = attribute.Key("cloudgw.add") // add or advertise (true), delete or withdraw (false)
+)
+
+// EnableOTLPTracing sets the global tracer provider exporting spans to the otlp grpc collector (host:port), a sample
+// ratio of traces is recorded. Spans are not recorded until it is called. The returned function flushes buffered
+// spans and stops the exporter.
+func EnableOTLPTracing(
+	ctx context.Context,
+	serviceName, hostName, endpoint string,
+	tls bool,
+	sampleRatio float64,
+) (func(context.Context) error, error) {
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint)}
+
+	if !tls {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+
+	exporter, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otlp trace exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName(serviceName),
+			semconv.HostName(hostName),
+		)),
+	)
+
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// EnableInMemoryTracing sets the global tracer provider recording all spans to the returned exporter synchronously,
+// it is used by tests
+func EnableInMemoryTracing() *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+
+	return exporter
+}
+
+// Start starts the span of cloudgw tracer, the span is a child of the span of ctx (if any)
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records the error (if any) to the span and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
@@ -137,7 +137,7 @@ func TestVPPStaticFunc(t *testing.T) {
 
 	// testing connect to vp stream api through local host unix sock file
 
-	vppStream, disconnect, _, err := vpp.ConnectToVPPAPIAsync(ctx, vppAPISockFile)
+	vppStream, _, disconnect, _, err := vpp.ConnectToVPPAPIAsync(ctx, vppAPISockFile)
 
 	defer disconnect()
 
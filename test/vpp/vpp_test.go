@@ -142,7 +142,7 @@ func TestVPPFunc(t *testing.T) {
 
 	// testing connect to vpp api through local host unix sock file
 
-	vppStream, disconnect, _, err := vpp.ConnectToVPPAPIAsync(ctx, vppAPISockFile)
+	vppStream, _, disconnect, _, err := vpp.ConnectToVPPAPIAsync(ctx, vppAPISockFile)
 	defer disconnect()
 	require.NoError(t, err)
 